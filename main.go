@@ -1,11 +1,23 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/connection"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/elements"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/mcp"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/recording"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/server"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/speech"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
 )
 
 // StartServer 启动 WebRTC 服务器
@@ -13,8 +25,87 @@ func StartServer(addr string) error {
 	rtcServer := server.NewWebRTCServer(9000)
 	rtcServer.Start()
 
+	// 注册模型可调用的函数
+	registry := tools.NewRegistry()
+	if err := tools.RegisterBuiltins(registry); err != nil {
+		return err
+	}
+
+	// 连接 MCP 服务端，其工具以 <server>_<tool> 的名称注册
+	if path := os.Getenv("MCP_CONFIG"); path != "" {
+		servers, err := mcp.LoadConfig(path)
+		if err != nil {
+			return err
+		}
+		for _, c := range mcp.ConnectAll(context.Background(), registry, servers) {
+			defer c.Close()
+		}
+	}
+	rtcServer.SetTools(registry)
+
+	// 可选的实时模型，客户端通过 /session?provider=<name> 选择
+	rtcServer.AddModel(connection.DefaultGeminiModel())
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		rtcServer.AddModel(realtime.NewOpenAI(realtime.OpenAIConfig{
+			APIKey:  apiKey,
+			BaseURL: os.Getenv("OPENAI_REALTIME_URL"),
+			Model:   os.Getenv("OPENAI_REALTIME_MODEL"),
+			Voice:   os.Getenv("OPENAI_VOICE"),
+		}))
+	}
+
+	// 只输出文本的会话使用的语音合成引擎
+	switch engine := os.Getenv("TTS_ENGINE"); engine {
+	case "":
+	case "tone":
+		rtcServer.SetTTSEngine(speech.NewToneEngine(24000))
+	default:
+		return fmt.Errorf("unknown TTS_ENGINE %q", engine)
+	}
+
+	// 客户端会话配置的允许范围和默认值
+	if path := os.Getenv("SESSION_POLICY"); path != "" {
+		policy, err := server.LoadSessionPolicy(path)
+		if err != nil {
+			return err
+		}
+		rtcServer.SetSessionPolicy(policy)
+	}
+
+	// 调试用的音频转储，写在 DUMP_DIR/<session id>/ 下
+	dumpConfig, err := audio.DumpConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	rtcServer.SetDumpConfig(dumpConfig)
+
+	// 模拟麦克风：每个会话播放同一个录音文件，代替浏览器的上行音频
+	if path := os.Getenv("MIC_FILE"); path != "" {
+		opts := elements.FileSourceOptions{Realtime: true}
+		if v := os.Getenv("MIC_FILE_COUNT"); v != "" {
+			if opts.Count, err = strconv.Atoi(v); err != nil {
+				return fmt.Errorf("invalid MIC_FILE_COUNT %q", v)
+			}
+		}
+		rtcServer.SetMicrophoneFile(path, opts)
+	}
+
 	http.HandleFunc("/session", rtcServer.HandleNegotiate)
 
+	// 通话录音，会话结束后可通过 /recordings/<session id> 下载
+	if dir := os.Getenv("RECORDING_DIR"); dir != "" {
+		recorder, err := recording.NewRecorder(dir)
+		if err != nil {
+			return err
+		}
+		recorder.Token = os.Getenv("RECORDING_TOKEN")
+		if recorder.Format, err = recording.ParseFormat(os.Getenv("RECORDING_FORMAT")); err != nil {
+			return err
+		}
+		rtcServer.SetRecorder(recorder)
+		http.Handle("/recordings/", recorder)
+	}
+
 	log.Printf("WebRTC server starting on %s", addr)
 	return http.ListenAndServe(addr, nil)
 }
@@ -25,5 +116,7 @@ func main() {
 	// 	log.Fatal(err)
 	// }
 
-	StartServer(":8080")
+	if err := StartServer(":8080"); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,260 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+// 支持的最大声道数（7.1）
+const MaxChannels = 8
+
+// DownmixStrategy 立体声下混为单声道的策略
+type DownmixStrategy string
+
+const (
+	// DownmixAverage 左右声道取平均
+	DownmixAverage DownmixStrategy = "average"
+	// DownmixLeft 只取左声道
+	DownmixLeft DownmixStrategy = "left"
+	// DownmixRight 只取右声道
+	DownmixRight DownmixStrategy = "right"
+	// DownmixLoudest 取能量较大的声道（带滞回，避免频繁切换）
+	DownmixLoudest DownmixStrategy = "loudest"
+)
+
+const (
+	// 切换到另一声道所需的能量比（约 3dB）
+	loudestSwitchRatio = 2.0
+	// 声道能量平滑系数
+	loudestSmoothing = 0.2
+	// -3dB
+	minus3dB = 0.7071067811865476
+)
+
+// ParseDownmixStrategy 解析下混策略，空字符串返回 DownmixAverage
+func ParseDownmixStrategy(s string) (DownmixStrategy, error) {
+	switch DownmixStrategy(s) {
+	case "":
+		return DownmixAverage, nil
+	case DownmixAverage, DownmixLeft, DownmixRight, DownmixLoudest:
+		return DownmixStrategy(s), nil
+	default:
+		return "", fmt.Errorf("unknown downmix strategy: %q", s)
+	}
+}
+
+// ChannelMixer 按混音矩阵对 S16LE 交错 PCM 做声道转换
+// matrix[out][in] 为输入声道 in 对输出声道 out 的增益
+type ChannelMixer struct {
+	inChannels  int
+	outChannels int
+	matrix      [][]float64
+
+	// loudest 策略的状态
+	loudest bool
+	energy  []float64
+	current int
+}
+
+// NewChannelMixer 使用默认矩阵创建声道转换器，strategy 仅在下混为单声道时生效
+func NewChannelMixer(inChannels, outChannels int, strategy DownmixStrategy) (*ChannelMixer, error) {
+	if !validChannels(inChannels) || !validChannels(outChannels) {
+		return nil, fmt.Errorf("unsupported channel layout: %d -> %d channels", inChannels, outChannels)
+	}
+
+	if outChannels == 1 && inChannels > 1 {
+		switch strategy {
+		case "", DownmixAverage:
+		case DownmixLeft:
+			return NewChannelMixerWithMatrix(selectChannel(inChannels, 0))
+		case DownmixRight:
+			return NewChannelMixerWithMatrix(selectChannel(inChannels, 1))
+		case DownmixLoudest:
+			m, err := NewChannelMixerWithMatrix(selectChannel(inChannels, 0))
+			if err != nil {
+				return nil, err
+			}
+			m.loudest = true
+			m.energy = make([]float64, inChannels)
+			return m, nil
+		default:
+			return nil, fmt.Errorf("unknown downmix strategy: %q", strategy)
+		}
+	}
+
+	return NewChannelMixerWithMatrix(DefaultMixMatrix(inChannels, outChannels))
+}
+
+// NewChannelMixerWithMatrix 使用自定义矩阵创建声道转换器，矩阵维度为 [outChannels][inChannels]
+func NewChannelMixerWithMatrix(matrix [][]float64) (*ChannelMixer, error) {
+	outChannels := len(matrix)
+	if !validChannels(outChannels) {
+		return nil, fmt.Errorf("invalid mix matrix: %d output channels", outChannels)
+	}
+	inChannels := len(matrix[0])
+	if !validChannels(inChannels) {
+		return nil, fmt.Errorf("invalid mix matrix: %d input channels", inChannels)
+	}
+
+	m := make([][]float64, outChannels)
+	for o, row := range matrix {
+		if len(row) != inChannels {
+			return nil, fmt.Errorf("invalid mix matrix: row %d has %d columns, want %d", o, len(row), inChannels)
+		}
+		m[o] = append([]float64(nil), row...)
+	}
+
+	return &ChannelMixer{
+		inChannels:  inChannels,
+		outChannels: outChannels,
+		matrix:      m,
+	}, nil
+}
+
+// DefaultMixMatrix 返回常见布局之间的默认混音矩阵
+// 声道顺序遵循 WAV/SMPTE：L R C LFE BL BR SL SR，下混系数参考 ITU-R BS.775
+func DefaultMixMatrix(inChannels, outChannels int) [][]float64 {
+	m := make([][]float64, outChannels)
+	for o := range m {
+		m[o] = make([]float64, inChannels)
+	}
+
+	switch {
+	case inChannels == outChannels:
+		for i := range m {
+			m[i][i] = 1
+		}
+	case inChannels == 1:
+		// 单声道复制到左右声道（多声道布局中放在前置左右）
+		m[0][0] = 1
+		m[1][0] = 1
+	case outChannels == 1:
+		// 先按立体声下混，再取左右平均
+		stereo := DefaultMixMatrix(inChannels, 2)
+		for i := 0; i < inChannels; i++ {
+			m[0][i] = (stereo[0][i] + stereo[1][i]) / 2
+		}
+	case outChannels == 2:
+		m[0][0], m[1][1] = 1, 1
+		for i := 2; i < inChannels; i++ {
+			switch {
+			case i == 2:
+				// 中置均分到左右
+				m[0][i], m[1][i] = minus3dB, minus3dB
+			case i == 3:
+				// LFE 丢弃
+			case i%2 == 0:
+				m[0][i] = minus3dB
+			default:
+				m[1][i] = minus3dB
+			}
+		}
+		normalizeRows(m)
+	case inChannels == 2:
+		// 立体声上混：只写入前置左右声道
+		m[0][0], m[1][1] = 1, 1
+	default:
+		// 其他布局：公共声道直通，多余声道丢弃
+		for i := 0; i < min(inChannels, outChannels); i++ {
+			m[i][i] = 1
+		}
+	}
+	return m
+}
+
+// InChannels 返回输入声道数
+func (m *ChannelMixer) InChannels() int {
+	return m.inChannels
+}
+
+// OutChannels 返回输出声道数
+func (m *ChannelMixer) OutChannels() int {
+	return m.outChannels
+}
+
+// Mix 转换一块交错 PCM
+func (m *ChannelMixer) Mix(data []byte) ([]byte, error) {
+	bytesPerFrame := 2 * m.inChannels
+	if len(data)%bytesPerFrame != 0 {
+		return nil, fmt.Errorf("input length %d is not a multiple of frame size %d", len(data), bytesPerFrame)
+	}
+	numFrames := len(data) / bytesPerFrame
+
+	if m.loudest {
+		m.selectLoudest(data, numFrames)
+	}
+
+	out := make([]byte, numFrames*2*m.outChannels)
+	in := make([]float64, m.inChannels)
+	for f := 0; f < numFrames; f++ {
+		for c := range in {
+			p := (f*m.inChannels + c) * 2
+			in[c] = float64(int16(uint16(data[p]) | uint16(data[p+1])<<8))
+		}
+		for o, row := range m.matrix {
+			var acc float64
+			for c, g := range row {
+				acc += g * in[c]
+			}
+			putInt16(out[(f*m.outChannels+o)*2:], toInt16(acc))
+		}
+	}
+	return out, nil
+}
+
+// selectLoudest 根据平滑后的声道能量更新下混矩阵
+func (m *ChannelMixer) selectLoudest(data []byte, numFrames int) {
+	if numFrames == 0 {
+		return
+	}
+	for c := range m.energy {
+		var sum float64
+		for f := 0; f < numFrames; f++ {
+			p := (f*m.inChannels + c) * 2
+			v := float64(int16(uint16(data[p]) | uint16(data[p+1])<<8))
+			sum += v * v
+		}
+		m.energy[c] += (sum/float64(numFrames) - m.energy[c]) * loudestSmoothing
+	}
+
+	best := m.current
+	for c, e := range m.energy {
+		if e > m.energy[best] {
+			best = c
+		}
+	}
+	if best != m.current && m.energy[best] > m.energy[m.current]*loudestSwitchRatio {
+		m.current = best
+		m.matrix = selectChannel(m.inChannels, best)
+	}
+}
+
+func selectChannel(inChannels, channel int) [][]float64 {
+	row := make([]float64, inChannels)
+	row[channel] = 1
+	return [][]float64{row}
+}
+
+// normalizeRows 缩放矩阵，保证任一输出声道的增益和不超过 1，避免削波
+func normalizeRows(m [][]float64) {
+	var peak float64
+	for _, row := range m {
+		var sum float64
+		for _, g := range row {
+			sum += math.Abs(g)
+		}
+		peak = math.Max(peak, sum)
+	}
+	if peak <= 1 {
+		return
+	}
+	for _, row := range m {
+		for i := range row {
+			row[i] /= peak
+		}
+	}
+}
+
+func validChannels(channels int) bool {
+	return channels >= 1 && channels <= MaxChannels
+}
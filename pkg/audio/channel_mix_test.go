@@ -0,0 +1,154 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// interleave 将各声道样本交错为一个切片
+func interleave(channels ...[]int16) []int16 {
+	out := make([]int16, 0, len(channels)*len(channels[0]))
+	for i := range channels[0] {
+		for _, ch := range channels {
+			out = append(out, ch[i])
+		}
+	}
+	return out
+}
+
+func constant(n int, v int16) []int16 {
+	out := make([]int16, n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
+
+func mix(t *testing.T, m *ChannelMixer, samples []int16) []int16 {
+	out, err := m.Mix(int16sToBytes(samples))
+	require.NoError(t, err)
+	return bytesToInt16s(out)
+}
+
+func TestChannelMixerStereoToMono(t *testing.T) {
+	stereo := interleave(constant(4, 1000), constant(4, 3000))
+
+	tests := []struct {
+		strategy DownmixStrategy
+		expected int16
+	}{
+		{DownmixAverage, 2000},
+		{DownmixLeft, 1000},
+		{DownmixRight, 3000},
+		{DownmixLoudest, 3000},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			m, err := NewChannelMixer(2, 1, tt.strategy)
+			require.NoError(t, err)
+			assert.Equal(t, constant(4, tt.expected), mix(t, m, stereo))
+		})
+	}
+}
+
+func TestChannelMixerLoudestHysteresis(t *testing.T) {
+	m, err := NewChannelMixer(2, 1, DownmixLoudest)
+	require.NoError(t, err)
+
+	// 右声道明显更响，切换到右声道
+	for i := 0; i < 10; i++ {
+		mix(t, m, interleave(constant(480, 100), constant(480, 8000)))
+	}
+	out := mix(t, m, interleave(constant(480, 100), constant(480, 8000)))
+	assert.Equal(t, int16(8000), out[0])
+
+	// 左声道只略大于右声道，保持在右声道
+	for i := 0; i < 20; i++ {
+		out = mix(t, m, interleave(constant(480, 8500), constant(480, 8000)))
+	}
+	assert.Equal(t, int16(8000), out[0])
+
+	// 左声道明显更响后才切回
+	for i := 0; i < 20; i++ {
+		out = mix(t, m, interleave(constant(480, 16000), constant(480, 1000)))
+	}
+	assert.Equal(t, int16(16000), out[0])
+}
+
+func TestChannelMixerDefaultMatrices(t *testing.T) {
+	t.Run("mono_to_stereo", func(t *testing.T) {
+		m, err := NewChannelMixer(1, 2, DownmixAverage)
+		require.NoError(t, err)
+		assert.Equal(t, []int16{5, 5, -7, -7}, mix(t, m, []int16{5, -7}))
+	})
+
+	t.Run("5.1_to_stereo", func(t *testing.T) {
+		m, err := NewChannelMixer(6, 2, DownmixAverage)
+		require.NoError(t, err)
+
+		// 只有中置声道有信号时左右声道相等，LFE 被丢弃
+		out := mix(t, m, []int16{0, 0, 10000, 0, 0, 0, 0, 0, 0, 30000, 0, 0})
+		assert.Equal(t, out[0], out[1])
+		assert.Greater(t, out[0], int16(0))
+		assert.Equal(t, []int16{0, 0}, out[2:])
+
+		// 满幅输入不削波
+		out = mix(t, m, constant(6, 32767))
+		assert.LessOrEqual(t, out[0], int16(32767))
+		assert.Greater(t, out[0], int16(16000))
+	})
+
+	t.Run("5.1_to_mono", func(t *testing.T) {
+		m, err := NewChannelMixer(6, 1, DownmixAverage)
+		require.NoError(t, err)
+		out := mix(t, m, []int16{1000, 1000, 0, 0, 0, 0})
+		assert.Len(t, out, 1)
+		assert.Greater(t, out[0], int16(0))
+	})
+
+	t.Run("stereo_to_quad", func(t *testing.T) {
+		m, err := NewChannelMixer(2, 4, DownmixAverage)
+		require.NoError(t, err)
+		assert.Equal(t, []int16{1, 2, 0, 0}, mix(t, m, []int16{1, 2}))
+	})
+}
+
+func TestChannelMixerCustomMatrix(t *testing.T) {
+	// 交换左右声道
+	m, err := NewChannelMixerWithMatrix([][]float64{{0, 1}, {1, 0}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, m.InChannels())
+	assert.Equal(t, 2, m.OutChannels())
+	assert.Equal(t, []int16{2, 1, 4, 3}, mix(t, m, []int16{1, 2, 3, 4}))
+
+	// 增益溢出时饱和
+	m, err = NewChannelMixerWithMatrix([][]float64{{2}})
+	require.NoError(t, err)
+	assert.Equal(t, []int16{32767, -32768}, mix(t, m, []int16{20000, -20000}))
+}
+
+func TestChannelMixerInvalid(t *testing.T) {
+	_, err := NewChannelMixer(0, 1, DownmixAverage)
+	assert.Error(t, err)
+	_, err = NewChannelMixer(2, 9, DownmixAverage)
+	assert.Error(t, err)
+	_, err = NewChannelMixer(2, 1, "middle")
+	assert.Error(t, err)
+	_, err = NewChannelMixerWithMatrix([][]float64{{1, 0}, {1}})
+	assert.Error(t, err)
+	_, err = NewChannelMixerWithMatrix(nil)
+	assert.Error(t, err)
+
+	m, err := NewChannelMixer(2, 1, DownmixAverage)
+	require.NoError(t, err)
+	_, err = m.Mix([]byte{1, 2, 3})
+	assert.Error(t, err)
+
+	_, err = ParseDownmixStrategy("middle")
+	assert.Error(t, err)
+	s, err := ParseDownmixStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, DownmixAverage, s)
+}
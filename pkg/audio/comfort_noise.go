@@ -0,0 +1,238 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// NoiseType 舒适噪声类型
+type NoiseType string
+
+const (
+	// NoiseNone 不生成噪声，输出数字静音
+	NoiseNone NoiseType = "none"
+	// NoiseWhite 白噪声
+	NoiseWhite NoiseType = "white"
+	// NoisePink 粉红噪声（-3dB/倍频程）
+	NoisePink NoiseType = "pink"
+	// NoiseShaped 按最近一段模型音频的频谱包络整形的噪声，分析前与粉红噪声相同
+	NoiseShaped NoiseType = "shaped"
+)
+
+const (
+	// DefaultComfortNoiseLevel 默认噪声电平（dBov）
+	DefaultComfortNoiseLevel = -60.0
+	// DefaultCrossfade 默认的静音/语音切换淡化时长
+	DefaultCrossfade = 5 * time.Millisecond
+
+	// 频谱整形使用的 LPC 阶数和带宽扩展系数
+	lpcOrder     = 12
+	lpcBandwidth = 0.98
+	// 低于该电平（dBov）的音频不参与频谱分析
+	minAnalysisLevel = -50.0
+	// 计算滤波器增益时截取的冲激响应长度（粉红噪声滤波器的极点更接近单位圆）
+	pinkImpulseLength = 16384
+	lpcImpulseLength  = 1024
+)
+
+// ParseNoiseType 解析舒适噪声类型，空字符串返回 NoiseNone
+func ParseNoiseType(s string) (NoiseType, error) {
+	switch NoiseType(s) {
+	case "":
+		return NoiseNone, nil
+	case NoiseNone, NoiseWhite, NoisePink, NoiseShaped:
+		return NoiseType(s), nil
+	default:
+		return "", fmt.Errorf("unknown comfort noise type: %q", s)
+	}
+}
+
+// ComfortNoiseConfig PlayoutBuffer 在没有语音时的输出配置
+type ComfortNoiseConfig struct {
+	Type      NoiseType
+	Level     float64       // 噪声电平，dBov（相对满量程方波的 RMS）
+	Crossfade time.Duration // 静音与语音之间的交叉淡化时长，0 表示不淡化
+}
+
+// DefaultComfortNoiseConfig 默认配置：数字静音，切换时淡入淡出
+func DefaultComfortNoiseConfig() ComfortNoiseConfig {
+	return ComfortNoiseConfig{
+		Type:      NoiseNone,
+		Level:     DefaultComfortNoiseLevel,
+		Crossfade: DefaultCrossfade,
+	}
+}
+
+// ComfortNoise 舒适噪声发生器，输出以 S16 样本值为单位
+type ComfortNoise struct {
+	typ NoiseType
+	rms float64
+	rng *rand.Rand
+
+	// 粉红噪声滤波器状态及其增益
+	pink     pinkFilter
+	pinkGain float64
+
+	// 频谱整形的全极点滤波器 1/A(z)
+	lpc     []float64 // a[1..p]
+	history []float64
+	lpcGain float64
+}
+
+// NewComfortNoise 创建指定类型和电平（dBov）的噪声发生器
+func NewComfortNoise(typ NoiseType, level float64) (*ComfortNoise, error) {
+	if _, err := ParseNoiseType(string(typ)); err != nil {
+		return nil, err
+	}
+	if level > 0 {
+		return nil, fmt.Errorf("comfort noise level must be <= 0 dBov, got %.1f", level)
+	}
+
+	return &ComfortNoise{
+		typ:      typ,
+		rms:      32767 * math.Pow(10, level/20),
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		pinkGain: filterGain(new(pinkFilter).next, pinkImpulseLength),
+	}, nil
+}
+
+// Sample 生成下一个噪声样本
+func (n *ComfortNoise) Sample() float64 {
+	switch n.typ {
+	case NoiseWhite:
+		return n.rng.NormFloat64() * n.rms
+	case NoisePink:
+		return n.pink.next(n.rng.NormFloat64()) * n.rms / n.pinkGain
+	case NoiseShaped:
+		if n.lpc == nil {
+			return n.pink.next(n.rng.NormFloat64()) * n.rms / n.pinkGain
+		}
+		return n.shape(n.rng.NormFloat64()*n.rms/n.lpcGain, n.history)
+	default:
+		return 0
+	}
+}
+
+// Analyze 用一段模型音频更新噪声的频谱包络，仅对 NoiseShaped 生效
+func (n *ComfortNoise) Analyze(pcm []int16) {
+	if n.typ != NoiseShaped || len(pcm) <= lpcOrder {
+		return
+	}
+
+	// 汉宁窗加权后计算自相关
+	x := make([]float64, len(pcm))
+	var energy float64
+	for i, v := range pcm {
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(len(pcm)-1))
+		x[i] = float64(v) * w
+		energy += float64(v) * float64(v)
+	}
+	if 10*math.Log10(energy/float64(len(pcm))/(32767*32767)+1e-20) < minAnalysisLevel {
+		return
+	}
+
+	r := make([]float64, lpcOrder+1)
+	for lag := range r {
+		for i := lag; i < len(x); i++ {
+			r[lag] += x[i] * x[i-lag]
+		}
+	}
+	r[0] *= 1.0001 // 白噪声校正，保证数值稳定
+
+	a, ok := levinson(r)
+	if !ok {
+		return
+	}
+	// 带宽扩展，使包络更平滑
+	g := lpcBandwidth
+	for i := range a {
+		a[i] *= g
+		g *= lpcBandwidth
+	}
+
+	n.lpc = a
+	if n.history == nil {
+		n.history = make([]float64, lpcOrder)
+	}
+	impulseHistory := make([]float64, lpcOrder)
+	n.lpcGain = filterGain(func(v float64) float64 {
+		return n.shape(v, impulseHistory)
+	}, lpcImpulseLength)
+}
+
+// shape 全极点滤波：y = e - Σ a[i]·y[n-i]，history 按新到旧保存输出
+func (n *ComfortNoise) shape(e float64, history []float64) float64 {
+	y := e
+	for i, a := range n.lpc {
+		y -= a * history[i]
+	}
+	copy(history[1:], history)
+	history[0] = y
+	return y
+}
+
+// levinson 由自相关求 LPC 系数 a[1..p]，滤波器不稳定时返回 false
+func levinson(r []float64) ([]float64, bool) {
+	p := len(r) - 1
+	if r[0] <= 0 {
+		return nil, false
+	}
+
+	a := make([]float64, p+1)
+	tmp := make([]float64, p+1)
+	a[0] = 1
+	errPower := r[0]
+	for i := 1; i <= p; i++ {
+		acc := r[i]
+		for j := 1; j < i; j++ {
+			acc += a[j] * r[i-j]
+		}
+		k := -acc / errPower
+		if math.Abs(k) >= 1 {
+			return nil, false
+		}
+		copy(tmp, a)
+		for j := 1; j < i; j++ {
+			a[j] = tmp[j] + k*tmp[i-j]
+		}
+		a[i] = k
+		errPower *= 1 - k*k
+	}
+	return a[1:], true
+}
+
+// filterGain 返回滤波器冲激响应的能量开方，即单位方差白噪声输入时的输出 RMS
+func filterGain(filter func(float64) float64, length int) float64 {
+	var energy float64
+	for i := 0; i < length; i++ {
+		v := 0.0
+		if i == 0 {
+			v = 1
+		}
+		h := filter(v)
+		energy += h * h
+	}
+	if energy <= 0 {
+		return 1
+	}
+	return math.Sqrt(energy)
+}
+
+// pinkFilter Paul Kellet 的粉红噪声滤波器
+type pinkFilter struct {
+	b [7]float64
+}
+
+func (f *pinkFilter) next(white float64) float64 {
+	f.b[0] = 0.99886*f.b[0] + white*0.0555179
+	f.b[1] = 0.99332*f.b[1] + white*0.0750759
+	f.b[2] = 0.96900*f.b[2] + white*0.1538520
+	f.b[3] = 0.86650*f.b[3] + white*0.3104856
+	f.b[4] = 0.55000*f.b[4] + white*0.5329522
+	f.b[5] = -0.7616*f.b[5] - white*0.0168980
+	out := f.b[0] + f.b[1] + f.b[2] + f.b[3] + f.b[4] + f.b[5] + f.b[6] + white*0.5362
+	f.b[6] = white * 0.115926
+	return out
+}
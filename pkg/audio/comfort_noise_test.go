@@ -0,0 +1,170 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func levelDBov(samples []float64) float64 {
+	var energy float64
+	for _, v := range samples {
+		energy += v * v
+	}
+	return 20 * math.Log10(math.Sqrt(energy/float64(len(samples)))/32767)
+}
+
+func generateNoise(n *ComfortNoise, count int) []float64 {
+	out := make([]float64, count)
+	for i := range out {
+		out[i] = n.Sample()
+	}
+	return out
+}
+
+// bandEnergy 用 Goertzel 估计 center±200Hz 内的平均能量（单个频点的方差太大）
+func bandEnergy(samples []float64, rate int, center float64) float64 {
+	var total float64
+	var count int
+	for f := center - 200; f <= center+200; f += 10 {
+		count++
+		coeff := 2 * math.Cos(2*math.Pi*f/float64(rate))
+		var s1, s2 float64
+		for _, v := range samples {
+			s1, s2 = v+coeff*s1-s2, s1
+		}
+		total += s1*s1 + s2*s2 - coeff*s1*s2
+	}
+	return total / float64(count)
+}
+
+func TestComfortNoiseLevel(t *testing.T) {
+	for _, typ := range []NoiseType{NoiseWhite, NoisePink, NoiseShaped} {
+		n, err := NewComfortNoise(typ, -50)
+		require.NoError(t, err)
+		// 跳过粉红噪声滤波器的建立过程
+		generateNoise(n, 48000)
+		assert.InDelta(t, -50, levelDBov(generateNoise(n, 48000)), 1, "type %s", typ)
+	}
+
+	_, err := NewComfortNoise("brown", -50)
+	assert.Error(t, err)
+	_, err = NewComfortNoise(NoiseWhite, 6)
+	assert.Error(t, err)
+}
+
+func TestComfortNoiseSpectrum(t *testing.T) {
+	const rate = 48000
+
+	white, err := NewComfortNoise(NoiseWhite, -40)
+	require.NoError(t, err)
+	pink, err := NewComfortNoise(NoisePink, -40)
+	require.NoError(t, err)
+
+	w := generateNoise(white, rate)
+	p := generateNoise(pink, rate)
+	// 粉红噪声 400Hz 与 6.4kHz 相差 4 个倍频程，约 12dB
+	whiteTilt := 10 * math.Log10(bandEnergy(w, rate, 400)/bandEnergy(w, rate, 6400))
+	pinkTilt := 10 * math.Log10(bandEnergy(p, rate, 400)/bandEnergy(p, rate, 6400))
+	assert.Less(t, math.Abs(whiteTilt), 3.0)
+	assert.Greater(t, pinkTilt, 8.0)
+
+	// 整形噪声跟随分析信号的频谱：能量集中在 3kHz 以下
+	shaped, err := NewComfortNoise(NoiseShaped, -40)
+	require.NoError(t, err)
+	speech := make([]int16, 960)
+	for i := range speech {
+		ti := float64(i) / rate
+		speech[i] = int16(8000*math.Sin(2*math.Pi*3000*ti) + 800*math.Sin(2*math.Pi*300*ti))
+	}
+	shaped.Analyze(speech)
+	s := generateNoise(shaped, rate)
+	assert.InDelta(t, -40, levelDBov(s), 1.5)
+	assert.Greater(t, bandEnergy(s, rate, 3000), 10*bandEnergy(s, rate, 10000))
+
+	// 过低电平的音频不改变频谱包络
+	before := shaped.lpc
+	shaped.Analyze(make([]int16, 960))
+	assert.Equal(t, before, shaped.lpc)
+}
+
+func frameSamples(frame []byte) []float64 {
+	out := make([]float64, len(frame)/2)
+	for i := range out {
+		out[i] = float64(int16(binary.LittleEndian.Uint16(frame[i*2:])))
+	}
+	return out
+}
+
+func TestPlayoutBufferComfortNoise(t *testing.T) {
+	pb, err := NewPlayoutBufferWithRate(24000)
+	require.NoError(t, err)
+	defer pb.Close()
+
+	require.NoError(t, pb.SetComfortNoise(ComfortNoiseConfig{Type: NoisePink, Level: -55, Crossfade: 5 * time.Millisecond}))
+
+	var background []float64
+	for i := 0; i < 50; i++ {
+		background = append(background, frameSamples(pb.ReadFrame())...)
+	}
+	assert.InDelta(t, -55, levelDBov(background), 2)
+
+	assert.Error(t, pb.SetComfortNoise(ComfortNoiseConfig{Type: "brown"}))
+}
+
+func TestPlayoutBufferCrossfade(t *testing.T) {
+	const rate = 24000
+	pb, err := NewPlayoutBufferWithRate(rate)
+	require.NoError(t, err)
+	defer pb.Close()
+	require.NoError(t, pb.SetComfortNoise(ComfortNoiseConfig{Type: NoiseNone, Crossfade: 20 * time.Millisecond}))
+
+	// 带直流偏置的信号在突然开始或截断时最容易产生爆音
+	tone := make([]int16, rate/2)
+	for i := range tone {
+		tone[i] = int16(18000 + 2000*math.Sin(2*math.Pi*200*float64(i)/rate))
+	}
+	write := func() {
+		data := make([]byte, len(tone)*2)
+		for i, v := range tone {
+			binary.LittleEndian.PutUint16(data[i*2:], uint16(v))
+		}
+		require.NoError(t, pb.Write(data))
+	}
+
+	// maxStep 返回相邻样本的最大跳变
+	maxStep := func(samples []float64) float64 {
+		var m float64
+		for i := 1; i < len(samples); i++ {
+			m = math.Max(m, math.Abs(samples[i]-samples[i-1]))
+		}
+		return m
+	}
+
+	// 语音开始淡入、缓冲区耗尽时淡出
+	var out []float64
+	out = append(out, frameSamples(pb.ReadFrame())...)
+	write()
+	for i := 0; i < 30; i++ {
+		out = append(out, frameSamples(pb.ReadFrame())...)
+	}
+	assert.Less(t, maxStep(out), 2000.0)
+	assert.Equal(t, 0.0, out[len(out)-1])
+
+	// 播放中被打断，从最后一个样本平滑过渡
+	write()
+	out = out[:0]
+	for i := 0; i < 5; i++ {
+		out = append(out, frameSamples(pb.ReadFrame())...)
+	}
+	pb.Clear()
+	for i := 0; i < 3; i++ {
+		out = append(out, frameSamples(pb.ReadFrame())...)
+	}
+	assert.Less(t, maxStep(out), 2000.0)
+	assert.Equal(t, 0.0, out[len(out)-1])
+}
@@ -0,0 +1,93 @@
+package audio
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// DiskQuota 限制一组转储文件占用的总空间。超出时按创建顺序删除已关闭的旧文件，
+// 仍然不够（只剩正在写入的文件）时拒绝写入
+type DiskQuota struct {
+	mu       sync.Mutex
+	maxBytes int64
+	used     int64
+	files    []*quotaFile
+}
+
+type quotaFile struct {
+	path string
+	size int64
+	open bool
+}
+
+// NewDiskQuota 创建总空间上限为 maxBytes 的配额
+func NewDiskQuota(maxBytes int64) *DiskQuota {
+	return &DiskQuota{maxBytes: maxBytes}
+}
+
+// Used 当前计入配额的字节数
+func (q *DiskQuota) Used() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.used
+}
+
+// add 登记一个新创建的文件
+func (q *DiskQuota) add(path string) *quotaFile {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f := &quotaFile{path: path, open: true}
+	q.files = append(q.files, f)
+	return f
+}
+
+// reserve 为文件增加 n 字节，空间不足时先回收旧文件，仍不足时返回 false
+func (q *DiskQuota) reserve(f *quotaFile, n int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.used+n > q.maxBytes {
+		if !q.evictOldest() {
+			return false
+		}
+	}
+	f.size += n
+	q.used += n
+	return true
+}
+
+// evictOldest 删除最早的已关闭文件
+func (q *DiskQuota) evictOldest() bool {
+	for i, f := range q.files {
+		if f.open {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("remove audio dump %s error: %v", f.path, err)
+		}
+		q.used -= f.size
+		q.files = append(q.files[:i], q.files[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// close 文件写完，之后可被回收
+func (q *DiskQuota) close(f *quotaFile) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f.open = false
+}
+
+// release 撤销登记（文件没有创建成功）
+func (q *DiskQuota) release(f *quotaFile) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.used -= f.size
+	for i, g := range q.files {
+		if g == f {
+			q.files = append(q.files[:i], q.files[i+1:]...)
+			return
+		}
+	}
+}
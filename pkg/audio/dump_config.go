@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DumpPoint 管线中可以转储音频的位置
+type DumpPoint string
+
+const (
+	// DumpPointUplink 解码后的上行音频（协商编码的采样率和声道数）
+	DumpPointUplink DumpPoint = "uplink"
+	// DumpPointModelInput 重采样后送给模型的音频
+	DumpPointModelInput DumpPoint = "model_input"
+	// DumpPointModelOutput 模型（或 TTS）输出的音频，24kHz
+	DumpPointModelOutput DumpPoint = "model_output"
+	// DumpPointPlayout 下行实际播放的每一帧（协商编码的采样率，含静音）
+	DumpPointPlayout DumpPoint = "playout"
+)
+
+// DumpPoints 所有转储点
+var DumpPoints = []DumpPoint{DumpPointUplink, DumpPointModelInput, DumpPointModelOutput, DumpPointPlayout}
+
+// DefaultDumpDir 未设置 DUMP_DIR 时的转储目录
+const DefaultDumpDir = "dumps"
+
+// DumpConfig 调试用的音频转储配置，所有会话共用一份，每个会话的文件写在 Dir/<session id>/ 下
+type DumpConfig struct {
+	Dir    string
+	Points map[DumpPoint]bool
+	// MaxFileSize/MaxDuration 单个文件的轮转条件，0 表示不限
+	MaxFileSize int64
+	MaxDuration time.Duration
+	// Quota 非 nil 时限制所有转储文件的总大小
+	Quota *DiskQuota
+}
+
+// DumpConfigFromEnv 从环境变量读取转储配置，DUMP_POINTS 为空时返回 nil（不转储）：
+//   - DUMP_POINTS：逗号分隔的转储点，或 all
+//   - DUMP_DIR：转储目录，默认 dumps
+//   - DUMP_MAX_FILE_MB / DUMP_MAX_DURATION：单个文件的大小（MB）和时长（如 10m）上限
+//   - DUMP_QUOTA_MB：所有转储文件的总大小上限（MB），超出时删除最早的文件
+func DumpConfigFromEnv() (*DumpConfig, error) {
+	points := os.Getenv("DUMP_POINTS")
+	if points == "" {
+		return nil, nil
+	}
+	c := &DumpConfig{Dir: os.Getenv("DUMP_DIR"), Points: make(map[DumpPoint]bool)}
+	if c.Dir == "" {
+		c.Dir = DefaultDumpDir
+	}
+	for _, p := range strings.Split(points, ",") {
+		p = strings.TrimSpace(p)
+		if p == "all" {
+			for _, point := range DumpPoints {
+				c.Points[point] = true
+			}
+			continue
+		}
+		if !isDumpPoint(DumpPoint(p)) {
+			return nil, fmt.Errorf("unknown DUMP_POINTS entry %q", p)
+		}
+		c.Points[DumpPoint(p)] = true
+	}
+
+	if v := os.Getenv("DUMP_MAX_FILE_MB"); v != "" {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || mb <= 0 {
+			return nil, fmt.Errorf("invalid DUMP_MAX_FILE_MB %q", v)
+		}
+		c.MaxFileSize = mb << 20
+	}
+	if v := os.Getenv("DUMP_MAX_DURATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid DUMP_MAX_DURATION %q", v)
+		}
+		c.MaxDuration = d
+	}
+	if v := os.Getenv("DUMP_QUOTA_MB"); v != "" {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || mb <= 0 {
+			return nil, fmt.Errorf("invalid DUMP_QUOTA_MB %q", v)
+		}
+		c.Quota = NewDiskQuota(mb << 20)
+	}
+	return c, nil
+}
+
+func isDumpPoint(p DumpPoint) bool {
+	for _, point := range DumpPoints {
+		if p == point {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled 是否转储该位置，c 为 nil 时返回 false
+func (c *DumpConfig) Enabled(p DumpPoint) bool {
+	return c != nil && c.Points[p]
+}
+
+// NewDumper 为会话的一个转储点创建 16 位 PCM 转储文件
+func (c *DumpConfig) NewDumper(sessionID string, p DumpPoint, sampleRate, channels int) (*Dumper, error) {
+	return NewDumperWithOptions(string(p), sampleRate, channels, DumperOptions{
+		Dir:         c.SessionDir(sessionID),
+		MaxFileSize: c.MaxFileSize,
+		MaxDuration: c.MaxDuration,
+		Quota:       c.Quota,
+	})
+}
+
+// SessionDir 会话的转储目录
+func (c *DumpConfig) SessionDir(sessionID string) string {
+	// 会话 ID 只作为一级目录名
+	name := filepath.Base(filepath.Clean("/" + sessionID))
+	if name == "/" || name == "." {
+		name = "unknown"
+	}
+	return filepath.Join(c.Dir, name)
+}
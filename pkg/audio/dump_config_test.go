@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpConfigFromEnv(t *testing.T) {
+	t.Setenv("DUMP_POINTS", "")
+	c, err := DumpConfigFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, c)
+	assert.False(t, c.Enabled(DumpPointUplink), "nil config dumps nothing")
+
+	t.Setenv("DUMP_POINTS", "uplink, playout")
+	t.Setenv("DUMP_MAX_FILE_MB", "10")
+	t.Setenv("DUMP_MAX_DURATION", "5m")
+	t.Setenv("DUMP_QUOTA_MB", "100")
+	c, err = DumpConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDumpDir, c.Dir)
+	assert.True(t, c.Enabled(DumpPointUplink))
+	assert.True(t, c.Enabled(DumpPointPlayout))
+	assert.False(t, c.Enabled(DumpPointModelInput))
+	assert.Equal(t, int64(10<<20), c.MaxFileSize)
+	assert.Equal(t, 5*time.Minute, c.MaxDuration)
+	require.NotNil(t, c.Quota)
+
+	t.Setenv("DUMP_POINTS", "all")
+	c, err = DumpConfigFromEnv()
+	require.NoError(t, err)
+	for _, p := range DumpPoints {
+		assert.True(t, c.Enabled(p), p)
+	}
+
+	t.Setenv("DUMP_POINTS", "speaker")
+	_, err = DumpConfigFromEnv()
+	assert.Error(t, err)
+	t.Setenv("DUMP_POINTS", "all")
+	t.Setenv("DUMP_MAX_DURATION", "soon")
+	_, err = DumpConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestDumpConfigSessionDir(t *testing.T) {
+	dir := t.TempDir()
+	c := &DumpConfig{Dir: dir}
+	assert.Equal(t, filepath.Join(dir, "abc"), c.SessionDir("abc"))
+	assert.Equal(t, filepath.Join(dir, "passwd"), c.SessionDir("../../etc/passwd"))
+	assert.Equal(t, filepath.Join(dir, "unknown"), c.SessionDir(""))
+
+	d, err := c.NewDumper("abc", DumpPointModelInput, 16000, 1)
+	require.NoError(t, err)
+	require.NoError(t, d.Write(make([]byte, 640)))
+	require.NoError(t, d.Close())
+	assert.Equal(t, filepath.Join(dir, "abc"), filepath.Dir(d.GetFilename()))
+	assert.Contains(t, filepath.Base(d.GetFilename()), "tag_model_input_audio_")
+}
@@ -1,196 +1,115 @@
 package audio
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
-	"io"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
-type WavStreamWriter struct {
-	file          *os.File
-	sampleRate    uint32
-	numChannels   uint16
-	bitsPerSample uint16
-	dataBytes     uint32
-	closed        bool
+// ErrDiskQuota 转储文件的总大小达到 DiskQuota 的上限
+var ErrDiskQuota = errors.New("audio dump disk quota exceeded")
+
+// DumperOptions 转储的轮转和空间限制，零值表示不限制
+type DumperOptions struct {
+	// Dir 文件所在目录，不存在时自动创建，空为当前目录
+	Dir string
+	// MaxFileSize 单个文件的最大字节数，写入将超过时换一个新文件；无论如何都会在 4GB 之前轮转
+	MaxFileSize int64
+	// MaxDuration 单个文件的最大时长，达到后换一个新文件
+	MaxDuration time.Duration
+	// Quota 非 nil 时所有共用它的转储文件总大小受限
+	Quota *DiskQuota
 }
 
-// NewWavStreamWriter 创建并初始化一个 WAV 文件（写入头部占位）
-func NewWavStreamWriter(filename string, sampleRate uint32, numChannels, bitsPerSample uint16) (*WavStreamWriter, error) {
-	f, err := os.Create(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	w := &WavStreamWriter{
-		file:          f,
-		sampleRate:    sampleRate,
-		numChannels:   numChannels,
-		bitsPerSample: bitsPerSample,
-		dataBytes:     0,
-		closed:        false,
-	}
-
-	// 写初始头部（用占位值）
-	err = w.writeInitialHeader()
-	if err != nil {
-		f.Close()
-		return nil, err
-	}
+// Dumper 用于保存音频数据到WAV文件，可按大小或时长轮转
+type Dumper struct {
+	sampleRate int // 采样率
+	channels   int // 通道数
+	tag        string
+	opts       DumperOptions
+	writer     *WavStreamWriter
+	mu         sync.Mutex
+	filename   string
+	// part 已轮转的文件数，quota 当前文件在配额中的记录
+	part  int
+	quota *quotaFile
+}
 
-	return w, nil
+// NewDumper 创建新的音频数据保存器，文件写在当前目录，不轮转
+func NewDumper(tag string, sampleRate, channels int) (*Dumper, error) {
+	return NewDumperWithOptions(tag, sampleRate, channels, DumperOptions{})
 }
 
-// Write 往 WAV 文件追加写入PCM数据，并**每次**更新头部长度
-func (w *WavStreamWriter) Write(pcm []byte) (int, error) {
-	if w.closed {
-		return 0, fmt.Errorf("WavStreamWriter: 已关闭，不能再写入")
+// NewDumperWithOptions 按 opts 创建音频数据保存器
+func NewDumperWithOptions(tag string, sampleRate, channels int, opts DumperOptions) (*Dumper, error) {
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("创建转储目录失败: %w", err)
+		}
 	}
-	n, err := w.file.Write(pcm)
-	if err != nil {
-		return n, err
+	d := &Dumper{
+		sampleRate: sampleRate,
+		channels:   channels,
+		tag:        tag,
+		opts:       opts,
 	}
-	w.dataBytes += uint32(n)
-
-	// 关键之处：每次写完，都回头更新头部
-	if err := w.updateHeader(); err != nil {
-		return n, fmt.Errorf("更新WAV头部失败: %v", err)
+	if err := d.open(); err != nil {
+		return nil, err
 	}
-
-	return n, nil
+	return d, nil
 }
 
-// Close 只做关闭动作（此时文件头已经在 Write() 时就不断更新了）
-func (w *WavStreamWriter) Close() error {
-	if w.closed {
-		return nil
+// open 创建一个新文件：tag_<tag>_audio_<时间>_<采样率>Hz_<声道>ch[_<序号>].wav
+func (d *Dumper) open() error {
+	name := fmt.Sprintf("tag_%s_audio_%s_%dHz_%dch", d.tag, time.Now().Format("20060102_150405"), d.sampleRate, d.channels)
+	if d.part > 0 {
+		name += fmt.Sprintf("_%d", d.part)
 	}
-	w.closed = true
-	return w.file.Close()
-}
-
-// ------------------------ 内部方法 ------------------------
+	filename := filepath.Join(d.opts.Dir, name+".wav")
 
-func (w *WavStreamWriter) writeInitialHeader() error {
-	// 1. "RIFF"
-	if _, err := w.file.Write([]byte("RIFF")); err != nil {
-		return err
-	}
-	// 2. ChunkSize 占位
-	if err := binary.Write(w.file, binary.LittleEndian, uint32(0)); err != nil {
-		return err
-	}
-	// 3. "WAVE"
-	if _, err := w.file.Write([]byte("WAVE")); err != nil {
-		return err
-	}
-	// 4. "fmt "
-	if _, err := w.file.Write([]byte("fmt ")); err != nil {
-		return err
-	}
-	// 5. Subchunk1Size (16)
-	if err := binary.Write(w.file, binary.LittleEndian, uint32(16)); err != nil {
-		return err
-	}
-	// 6. AudioFormat = 1 (PCM)
-	if err := binary.Write(w.file, binary.LittleEndian, uint16(1)); err != nil {
-		return err
-	}
-	// 7. NumChannels
-	if err := binary.Write(w.file, binary.LittleEndian, w.numChannels); err != nil {
-		return err
-	}
-	// 8. SampleRate
-	if err := binary.Write(w.file, binary.LittleEndian, w.sampleRate); err != nil {
-		return err
-	}
-	// 9. ByteRate
-	byteRate := w.sampleRate * uint32(w.numChannels) * uint32(w.bitsPerSample/8)
-	if err := binary.Write(w.file, binary.LittleEndian, byteRate); err != nil {
-		return err
-	}
-	// 10. BlockAlign
-	blockAlign := w.numChannels * w.bitsPerSample / 8
-	if err := binary.Write(w.file, binary.LittleEndian, blockAlign); err != nil {
-		return err
-	}
-	// 11. BitsPerSample
-	if err := binary.Write(w.file, binary.LittleEndian, w.bitsPerSample); err != nil {
-		return err
-	}
-	// 12. "data"
-	if _, err := w.file.Write([]byte("data")); err != nil {
-		return err
+	var quota *quotaFile
+	if d.opts.Quota != nil {
+		quota = d.opts.Quota.add(filename)
+		if !d.opts.Quota.reserve(quota, wavHeaderSize) {
+			d.opts.Quota.release(quota)
+			return ErrDiskQuota
+		}
 	}
-	// 13. Subchunk2Size 占位
-	if err := binary.Write(w.file, binary.LittleEndian, uint32(0)); err != nil {
-		return err
+	writer, err := NewWavStreamWriter(filename, uint32(d.sampleRate), uint16(d.channels), 16)
+	if err != nil {
+		if quota != nil {
+			d.opts.Quota.release(quota)
+		}
+		return fmt.Errorf("创建WavStreamWriter失败: %w", err)
 	}
+	d.writer, d.filename, d.quota = writer, filename, quota
 	return nil
 }
 
-// updateHeader 用于在每次写完后回填最新的数据长度
-func (w *WavStreamWriter) updateHeader() error {
-	chunkSize := 36 + w.dataBytes // 36 = 44 - 8
-	// 回到 offset=4 写 chunkSize
-	if _, err := w.file.Seek(4, io.SeekStart); err != nil {
-		return err
-	}
-	if err := binary.Write(w.file, binary.LittleEndian, chunkSize); err != nil {
-		return err
-	}
-
-	// 回到 offset=40 写 Subchunk2Size
-	if _, err := w.file.Seek(40, io.SeekStart); err != nil {
+// rotate 关闭当前文件并换一个新文件
+func (d *Dumper) rotate() error {
+	if err := d.closeWriter(); err != nil {
 		return err
 	}
-	if err := binary.Write(w.file, binary.LittleEndian, w.dataBytes); err != nil {
-		return err
-	}
-
-	// 再次 seek 到文件末尾，方便下一次 Write 在正确位置追加
-	if _, err := w.file.Seek(0, io.SeekEnd); err != nil {
-		return err
-	}
-
-	return nil
+	d.part++
+	return d.open()
 }
 
-// Dumper 用于保存音频数据到WAV文件
-type Dumper struct {
-	sampleRate int // 采样率
-	channels   int // 通道数
-	writer     *WavStreamWriter
-	mu         sync.Mutex
-	filename   string
-}
-
-// NewDumper 创建新的音频数据保存器
-func NewDumper(tag string, sampleRate, channels int) (*Dumper, error) {
-	// 生成文件名：tag_timestamp_samplerate_channels.wav
-	filename := fmt.Sprintf("tag_%s_audio_%s_%dHz_%dch.wav",
-		tag,
-		time.Now().Format("20060102_150405"),
-		sampleRate,
-		channels)
-
-	writer, err := NewWavStreamWriter(filename, uint32(sampleRate), uint16(channels), 16)
-	if err != nil {
-		return nil, fmt.Errorf("创建WavStreamWriter失败: %w", err)
+// full 当前文件再写入 n 字节是否需要轮转
+func (d *Dumper) full(n int) bool {
+	if d.writer.DataBytes() == 0 {
+		return false
 	}
-
-	return &Dumper{
-		sampleRate: sampleRate,
-		channels:   channels,
-		writer:     writer,
-		filename:   filename,
-	}, nil
+	size := d.writer.Size() + int64(n)
+	return size > maxRIFFSize ||
+		(d.opts.MaxFileSize > 0 && size > d.opts.MaxFileSize) ||
+		(d.opts.MaxDuration > 0 && d.writer.Duration() >= d.opts.MaxDuration)
 }
 
-// Write 写入音频数据
+// Write 写入音频数据，达到配额时返回 ErrDiskQuota 并丢弃数据
 func (d *Dumper) Write(data []byte) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -199,6 +118,15 @@ func (d *Dumper) Write(data []byte) error {
 		return fmt.Errorf("dumper已关闭")
 	}
 
+	if d.full(len(data)) {
+		if err := d.rotate(); err != nil {
+			return err
+		}
+	}
+	if d.quota != nil && !d.opts.Quota.reserve(d.quota, int64(len(data))) {
+		return ErrDiskQuota
+	}
+
 	// 写入WAV文件
 	if _, err := d.writer.Write(data); err != nil {
 		return fmt.Errorf("写入WAV数据失败: %w", err)
@@ -207,22 +135,34 @@ func (d *Dumper) Write(data []byte) error {
 	return nil
 }
 
+// closeWriter 关闭当前文件，之后它可以被配额回收
+func (d *Dumper) closeWriter() error {
+	err := d.writer.Close()
+	if d.quota != nil {
+		d.opts.Quota.close(d.quota)
+	}
+	d.writer, d.quota = nil, nil
+	if err != nil {
+		return fmt.Errorf("关闭文件失败: %w", err)
+	}
+	return nil
+}
+
 // Close 关闭文件
 func (d *Dumper) Close() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	if d.writer != nil {
-		if err := d.writer.Close(); err != nil {
-			return fmt.Errorf("关闭文件失败: %w", err)
-		}
-		d.writer = nil
+		return d.closeWriter()
 	}
 	return nil
 }
 
 // GetFilename 获取当前录制文件的名称
 func (d *Dumper) GetFilename() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	return d.filename
 }
 
@@ -3,6 +3,7 @@ package audio
 import (
 	"math"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -102,3 +103,79 @@ func TestDumperWithSineWave(t *testing.T) {
 	expectedMinSize := numSamples*2 + 44 // 44 bytes is standard WAV header size
 	assert.Equal(t, info.Size(), int64(expectedMinSize))
 }
+
+func TestDumperRotation(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dumps")
+	frame := make([]byte, 320) // 8kHz 单声道 20ms
+
+	// 按大小：每个文件最多 44 + 3 帧
+	d, err := NewDumperWithOptions("size", 8000, 1, DumperOptions{Dir: dir, MaxFileSize: 44 + 3*320})
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		require.NoError(t, d.Write(frame))
+	}
+	require.NoError(t, d.Close())
+	files, err := filepath.Glob(filepath.Join(dir, "tag_size_*.wav"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	var total int
+	for _, f := range files {
+		_, data, err := ReadWav(f)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(data), 3*320)
+		total += len(data)
+	}
+	assert.Equal(t, 7*320, total)
+
+	// 按时长：每个文件 100ms
+	d, err = NewDumperWithOptions("duration", 8000, 1, DumperOptions{Dir: dir, MaxDuration: 100 * time.Millisecond})
+	require.NoError(t, err)
+	for i := 0; i < 12; i++ {
+		require.NoError(t, d.Write(frame))
+	}
+	assert.Contains(t, d.GetFilename(), "_8000Hz_1ch_2.wav")
+	require.NoError(t, d.Close())
+	files, err = filepath.Glob(filepath.Join(dir, "tag_duration_*.wav"))
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+}
+
+func TestDumperQuota(t *testing.T) {
+	dir := t.TempDir()
+	frame := make([]byte, 320)
+	// 两个文件共用配额：最多约 5 个文件
+	quota := NewDiskQuota(5 * (44 + 2*320))
+	opts := DumperOptions{Dir: dir, MaxFileSize: 44 + 2*320, Quota: quota}
+
+	a, err := NewDumperWithOptions("a", 8000, 1, opts)
+	require.NoError(t, err)
+	first := a.GetFilename()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, a.Write(frame))
+	}
+	// 最早的文件被回收
+	assert.NoFileExists(t, first)
+	assert.LessOrEqual(t, quota.Used(), int64(5*(44+2*320)))
+
+	// 另一个转储占满配额后，正在写入的文件不会被回收
+	b, err := NewDumperWithOptions("b", 8000, 1, DumperOptions{Dir: dir, Quota: quota})
+	require.NoError(t, err)
+	var quotaErr error
+	for i := 0; i < 20 && quotaErr == nil; i++ {
+		quotaErr = b.Write(frame)
+	}
+	assert.ErrorIs(t, quotaErr, ErrDiskQuota)
+	assert.FileExists(t, a.GetFilename())
+	require.NoError(t, a.Close())
+	require.NoError(t, b.Close())
+
+	var size int64
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		info, err := e.Info()
+		require.NoError(t, err)
+		size += info.Size()
+	}
+	assert.Equal(t, quota.Used(), size)
+}
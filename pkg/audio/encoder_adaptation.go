@@ -0,0 +1,178 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认 Opus 码率范围（语音）
+	DefaultMinBitrate   = 12000
+	DefaultMaxBitrate   = 64000
+	DefaultStartBitrate = 32000
+
+	// 开启 FEC 后码率不应低于该值，否则 libopus 无法为 LBRR 分配足够的比特
+	minFECBitrate = 16000
+
+	// 丢包率阈值（带滞回）
+	fecEnableLoss     = 0.02
+	fecDisableLoss    = 0.01
+	bitrateDownLoss   = 0.10
+	bitrateUpLoss     = 0.02
+	highJitter        = 60 * time.Millisecond
+	bitrateDownFactor = 0.85
+	bitrateUpFactor   = 1.05
+
+	// 丢包率平滑系数
+	lossSmoothing = 0.3
+)
+
+// EncoderSettings Opus 编码器可动态调整的参数
+type EncoderSettings struct {
+	Bitrate        int  `json:"bitrate"`
+	InBandFEC      bool `json:"inBandFec"`
+	PacketLossPerc int  `json:"packetLossPerc"`
+	DTX            bool `json:"dtx"`
+}
+
+func (s EncoderSettings) String() string {
+	return fmt.Sprintf("bitrate=%d fec=%v loss=%d%% dtx=%v", s.Bitrate, s.InBandFEC, s.PacketLossPerc, s.DTX)
+}
+
+// NetworkReport 从 RTCP 接收报告中提取的下行链路质量
+type NetworkReport struct {
+	FractionLost float64       `json:"fractionLost"` // 0~1
+	TotalLost    uint32        `json:"totalLost"`
+	Jitter       time.Duration `json:"jitter"`
+}
+
+// EncoderAdaptation 一次编码参数调整决策，作为总线事件的载荷
+type EncoderAdaptation struct {
+	Previous EncoderSettings `json:"previous"`
+	Current  EncoderSettings `json:"current"`
+	Report   NetworkReport   `json:"report"`
+	Reason   string          `json:"reason"`
+}
+
+// EncoderController 根据 RTCP 接收报告中的丢包与抖动调整 Opus 编码参数
+type EncoderController struct {
+	mu sync.Mutex
+
+	minBitrate int
+	maxBitrate int
+	enableDTX  bool
+
+	smoothedLoss float64
+	hasReport    bool
+	settings     EncoderSettings
+}
+
+// NewEncoderController 创建编码参数控制器，enableDTX 为 true 时允许在静音段使用 DTX
+func NewEncoderController(minBitrate, maxBitrate, startBitrate int, enableDTX bool) *EncoderController {
+	if maxBitrate < minBitrate {
+		maxBitrate = minBitrate
+	}
+	startBitrate = clampInt(startBitrate, minBitrate, maxBitrate)
+
+	return &EncoderController{
+		minBitrate: minBitrate,
+		maxBitrate: maxBitrate,
+		enableDTX:  enableDTX,
+		settings: EncoderSettings{
+			Bitrate: startBitrate,
+			DTX:     enableDTX,
+		},
+	}
+}
+
+// Settings 返回当前的编码参数
+func (c *EncoderController) Settings() EncoderSettings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.settings
+}
+
+// Update 根据一份接收报告计算新的编码参数
+// 只有参数发生变化时才返回 true
+func (c *EncoderController) Update(report NetworkReport) (EncoderAdaptation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.hasReport {
+		c.smoothedLoss = report.FractionLost
+		c.hasReport = true
+	} else {
+		c.smoothedLoss += (report.FractionLost - c.smoothedLoss) * lossSmoothing
+	}
+	loss := c.smoothedLoss
+
+	prev := c.settings
+	next := prev
+	var reasons []string
+
+	// 1. 带内 FEC（带滞回，避免频繁切换）
+	if !prev.InBandFEC && loss >= fecEnableLoss {
+		next.InBandFEC = true
+		reasons = append(reasons, fmt.Sprintf("loss %.1f%% >= %.1f%%, enable FEC", loss*100, fecEnableLoss*100))
+	} else if prev.InBandFEC && loss < fecDisableLoss {
+		next.InBandFEC = false
+		reasons = append(reasons, fmt.Sprintf("loss %.1f%% < %.1f%%, disable FEC", loss*100, fecDisableLoss*100))
+	}
+
+	// 2. 预期丢包率，按 5% 取整以减少抖动
+	next.PacketLossPerc = clampInt(int(math.Round(loss*100/5))*5, 0, 100)
+	if next.PacketLossPerc == 0 && loss >= fecEnableLoss {
+		next.PacketLossPerc = int(math.Ceil(loss * 100))
+	}
+
+	// 3. 码率
+	switch {
+	case loss >= bitrateDownLoss:
+		next.Bitrate = int(float64(prev.Bitrate) * bitrateDownFactor)
+		reasons = append(reasons, fmt.Sprintf("loss %.1f%% high, lower bitrate", loss*100))
+	case report.Jitter >= highJitter:
+		next.Bitrate = int(float64(prev.Bitrate) * bitrateDownFactor)
+		reasons = append(reasons, fmt.Sprintf("jitter %v high, lower bitrate", report.Jitter))
+	case loss < bitrateUpLoss:
+		next.Bitrate = int(float64(prev.Bitrate) * bitrateUpFactor)
+	}
+	minBitrate := c.minBitrate
+	if next.InBandFEC && minBitrate < minFECBitrate {
+		minBitrate = minFECBitrate
+	}
+	next.Bitrate = clampInt(next.Bitrate, minBitrate, c.maxBitrate)
+	if next.Bitrate > prev.Bitrate && len(reasons) == 0 {
+		reasons = append(reasons, fmt.Sprintf("loss %.1f%% low, raise bitrate", loss*100))
+	}
+
+	// 4. DTX 仅在配置允许时开启
+	next.DTX = c.enableDTX
+
+	if next == prev {
+		return EncoderAdaptation{}, false
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, fmt.Sprintf("expected loss %d%%", next.PacketLossPerc))
+	}
+
+	c.settings = next
+	return EncoderAdaptation{
+		Previous: prev,
+		Current:  next,
+		Report:   report,
+		Reason:   strings.Join(reasons, "; "),
+	}, true
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
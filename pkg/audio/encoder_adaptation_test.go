@@ -0,0 +1,82 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoderControllerInitial(t *testing.T) {
+	c := NewEncoderController(DefaultMinBitrate, DefaultMaxBitrate, DefaultStartBitrate, false)
+	s := c.Settings()
+	assert.Equal(t, DefaultStartBitrate, s.Bitrate)
+	assert.False(t, s.InBandFEC)
+	assert.False(t, s.DTX)
+
+	// 起始码率超出范围时被限制
+	c = NewEncoderController(16000, 24000, 64000, true)
+	assert.Equal(t, 24000, c.Settings().Bitrate)
+	assert.True(t, c.Settings().DTX)
+}
+
+func TestEncoderControllerCleanLinkRaisesBitrate(t *testing.T) {
+	c := NewEncoderController(DefaultMinBitrate, DefaultMaxBitrate, DefaultStartBitrate, false)
+
+	adaptation, changed := c.Update(NetworkReport{FractionLost: 0, Jitter: 5 * time.Millisecond})
+	require.True(t, changed)
+	assert.Greater(t, adaptation.Current.Bitrate, adaptation.Previous.Bitrate)
+	assert.False(t, adaptation.Current.InBandFEC)
+	assert.NotEmpty(t, adaptation.Reason)
+
+	// 持续良好的链路最终达到最大码率后不再变化
+	for i := 0; i < 100; i++ {
+		c.Update(NetworkReport{})
+	}
+	assert.Equal(t, DefaultMaxBitrate, c.Settings().Bitrate)
+	_, changed = c.Update(NetworkReport{})
+	assert.False(t, changed)
+}
+
+func TestEncoderControllerLossEnablesFEC(t *testing.T) {
+	c := NewEncoderController(DefaultMinBitrate, DefaultMaxBitrate, DefaultStartBitrate, false)
+
+	adaptation, changed := c.Update(NetworkReport{FractionLost: 0.05})
+	require.True(t, changed)
+	assert.True(t, adaptation.Current.InBandFEC)
+	assert.Equal(t, 5, adaptation.Current.PacketLossPerc)
+	assert.Contains(t, adaptation.Reason, "enable FEC")
+
+	// 丢包恢复后，平滑后的丢包率降到阈值以下才关闭 FEC
+	_, _ = c.Update(NetworkReport{FractionLost: 0})
+	assert.True(t, c.Settings().InBandFEC, "FEC should stay on while smoothed loss is above the disable threshold")
+	for i := 0; i < 20; i++ {
+		c.Update(NetworkReport{FractionLost: 0})
+	}
+	assert.False(t, c.Settings().InBandFEC)
+	assert.Equal(t, 0, c.Settings().PacketLossPerc)
+}
+
+func TestEncoderControllerHeavyLossLowersBitrate(t *testing.T) {
+	c := NewEncoderController(DefaultMinBitrate, DefaultMaxBitrate, DefaultStartBitrate, false)
+
+	for i := 0; i < 50; i++ {
+		c.Update(NetworkReport{FractionLost: 0.25})
+	}
+	s := c.Settings()
+	assert.True(t, s.InBandFEC)
+	assert.Equal(t, 25, s.PacketLossPerc)
+	// FEC 开启时码率不低于 FEC 所需的最小码率
+	assert.Equal(t, minFECBitrate, s.Bitrate)
+}
+
+func TestEncoderControllerHighJitterLowersBitrate(t *testing.T) {
+	c := NewEncoderController(DefaultMinBitrate, DefaultMaxBitrate, DefaultStartBitrate, false)
+
+	adaptation, changed := c.Update(NetworkReport{Jitter: 80 * time.Millisecond})
+	require.True(t, changed)
+	assert.Less(t, adaptation.Current.Bitrate, adaptation.Previous.Bitrate)
+	assert.Contains(t, adaptation.Reason, "jitter")
+	assert.Equal(t, 80*time.Millisecond, adaptation.Report.Jitter)
+}
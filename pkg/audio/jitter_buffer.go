@@ -0,0 +1,343 @@
+package audio
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// 默认抖动缓冲参数
+	DefaultJitterMinDelay = 20 * time.Millisecond
+	DefaultJitterMaxDelay = 200 * time.Millisecond
+	// 连续丢包隐藏的最大帧数，超过后直接跳到下一个可用包
+	maxConcealFrames = 5
+	// 缓冲区最多保存的包数（约 2 秒 @ 20ms）
+	maxJitterPackets = 100
+	// 序列号跳变超过该值时认为是流重启，重置缓冲区
+	maxSequenceJump = 1000
+)
+
+// JitterPacket 抖动缓冲中的一个 RTP 包
+type JitterPacket struct {
+	SequenceNumber uint16
+	Timestamp      uint32
+	Payload        []byte
+}
+
+// JitterFrame 抖动缓冲按序输出的一帧
+type JitterFrame struct {
+	SequenceNumber uint16
+	Timestamp      uint32
+	// Payload 正常帧为包负载；丢失帧为紧随其后的包负载（可用于 Opus 带内 FEC），没有则为 nil
+	Payload []byte
+	// Lost 表示该帧丢失，需要解码器做丢包隐藏
+	Lost bool
+}
+
+// JitterStats 抖动缓冲统计信息
+type JitterStats struct {
+	Received    int
+	Reordered   int
+	Late        int
+	Duplicated  int
+	Concealed   int
+	Skipped     int
+	Jitter      time.Duration
+	TargetDelay time.Duration
+}
+
+// JitterBuffer 基于 RTP 序列号和时间戳的自适应抖动缓冲
+// 按序列号重排数据包，按时间戳调度输出，并根据 RFC 3550 抖动估计动态调整目标延迟
+type JitterBuffer struct {
+	mu sync.Mutex
+
+	clockRate int
+	minDelay  time.Duration
+	maxDelay  time.Duration
+
+	packets map[uint16]JitterPacket
+
+	// 时间戳展开（处理 32 位回绕）
+	tsInitialized bool
+	lastTS        uint32
+	lastExtTS     int64
+
+	// 最小传输时延（arrival - ts/clockRate），作为调度参考点
+	baseTransit time.Duration
+	hasBase     bool
+
+	// RFC 3550 抖动估计
+	jitter         float64 // 秒
+	lastArrival    time.Time
+	lastArrivalExt int64
+	targetDelay    time.Duration
+
+	// 输出状态
+	started        bool
+	nextSeq        uint16
+	highestSeq     uint16
+	playedExtTS    int64
+	frameSamples   int64
+	concealedInRow int
+
+	stats JitterStats
+}
+
+// NewJitterBuffer 创建新的抖动缓冲
+func NewJitterBuffer(clockRate int, minDelay, maxDelay time.Duration) *JitterBuffer {
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	return &JitterBuffer{
+		clockRate:    clockRate,
+		minDelay:     minDelay,
+		maxDelay:     maxDelay,
+		packets:      make(map[uint16]JitterPacket),
+		targetDelay:  minDelay,
+		frameSamples: int64(clockRate / 50), // 默认 20ms
+	}
+}
+
+// Push 放入一个到达的 RTP 包
+func (jb *JitterBuffer) Push(pkt JitterPacket, arrival time.Time) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	jb.stats.Received++
+
+	// 迟到的包同样反映网络抖动，需要参与延迟估计
+	extTS := jb.unwrap(pkt.Timestamp)
+	jb.updateTiming(extTS, arrival)
+
+	if jb.started {
+		diff := seqDiff(pkt.SequenceNumber, jb.nextSeq)
+		if diff > maxSequenceJump || diff < -maxSequenceJump {
+			// 序列号大幅跳变，按新流重新同步
+			jb.resetLocked()
+			extTS = jb.unwrap(pkt.Timestamp)
+			jb.updateTiming(extTS, arrival)
+		} else if diff < 0 {
+			// 已经播放过（或已判定丢失）的包
+			jb.stats.Late++
+			return
+		}
+	}
+
+	if _, ok := jb.packets[pkt.SequenceNumber]; ok {
+		jb.stats.Duplicated++
+		return
+	}
+
+	if len(jb.packets) > 0 && seqDiff(pkt.SequenceNumber, jb.highestSeq) < 0 {
+		jb.stats.Reordered++
+	} else {
+		jb.highestSeq = pkt.SequenceNumber
+	}
+
+	jb.packets[pkt.SequenceNumber] = pkt
+
+	// 溢出时丢弃最旧的包
+	for len(jb.packets) > maxJitterPackets {
+		oldest, _ := jb.lowestSeq()
+		delete(jb.packets, oldest)
+		jb.stats.Skipped++
+		if jb.started && oldest == jb.nextSeq {
+			jb.nextSeq++
+		}
+	}
+}
+
+// Pop 取出下一帧。当下一帧尚未到播放时间时返回 false
+// 调用方应在返回 true 时继续调用，直到返回 false
+func (jb *JitterBuffer) Pop(now time.Time) (JitterFrame, bool) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	if !jb.started {
+		seq, ok := jb.lowestSeq()
+		if !ok {
+			return JitterFrame{}, false
+		}
+		pkt := jb.packets[seq]
+		extTS := jb.extOf(pkt.Timestamp)
+		if now.Before(jb.playoutTime(extTS)) {
+			return JitterFrame{}, false
+		}
+		jb.started = true
+		jb.nextSeq = seq
+		jb.playedExtTS = extTS - jb.frameSamples
+	}
+
+	for {
+		if pkt, ok := jb.packets[jb.nextSeq]; ok {
+			extTS := jb.extOf(pkt.Timestamp)
+			if now.Before(jb.playoutTime(extTS)) {
+				return JitterFrame{}, false
+			}
+			delete(jb.packets, jb.nextSeq)
+			jb.nextSeq++
+			if d := extTS - jb.playedExtTS; d > 0 && d <= int64(jb.clockRate/8) && jb.concealedInRow == 0 {
+				jb.frameSamples = d
+			}
+			jb.playedExtTS = extTS
+			jb.concealedInRow = 0
+			return JitterFrame{
+				SequenceNumber: pkt.SequenceNumber,
+				Timestamp:      pkt.Timestamp,
+				Payload:        pkt.Payload,
+			}, true
+		}
+
+		// 下一个包缺失：只有在后续包已经到达时才能确认丢失
+		if len(jb.packets) == 0 {
+			return JitterFrame{}, false
+		}
+
+		if jb.concealedInRow >= maxConcealFrames {
+			// 连续丢失太多，直接跳到下一个可用包
+			seq, _ := jb.lowestSeq()
+			jb.stats.Skipped += int(seqDiff(seq, jb.nextSeq))
+			jb.nextSeq = seq
+			jb.concealedInRow = 0
+			jb.playedExtTS = jb.extOf(jb.packets[seq].Timestamp) - jb.frameSamples
+			continue
+		}
+
+		estTS := jb.playedExtTS + jb.frameSamples
+		if now.Before(jb.playoutTime(estTS)) {
+			return JitterFrame{}, false
+		}
+
+		frame := JitterFrame{
+			SequenceNumber: jb.nextSeq,
+			Timestamp:      uint32(estTS),
+			Lost:           true,
+		}
+		if next, ok := jb.packets[jb.nextSeq+1]; ok {
+			frame.Payload = next.Payload
+		}
+		jb.nextSeq++
+		jb.playedExtTS = estTS
+		jb.concealedInRow++
+		jb.stats.Concealed++
+		return frame, true
+	}
+}
+
+// TargetDelay 返回当前的目标缓冲延迟
+func (jb *JitterBuffer) TargetDelay() time.Duration {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	return jb.targetDelay
+}
+
+// Stats 返回统计信息
+func (jb *JitterBuffer) Stats() JitterStats {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	stats := jb.stats
+	stats.Jitter = time.Duration(jb.jitter * float64(time.Second))
+	stats.TargetDelay = jb.targetDelay
+	return stats
+}
+
+// Len 返回缓冲中的包数量
+func (jb *JitterBuffer) Len() int {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	return len(jb.packets)
+}
+
+// Reset 清空缓冲区并重新开始同步
+func (jb *JitterBuffer) Reset() {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	jb.resetLocked()
+}
+
+// ------------------------ 内部方法 ------------------------
+
+func (jb *JitterBuffer) resetLocked() {
+	jb.packets = make(map[uint16]JitterPacket)
+	jb.tsInitialized = false
+	jb.hasBase = false
+	jb.started = false
+	jb.concealedInRow = 0
+	jb.lastArrival = time.Time{}
+}
+
+// unwrap 将 32 位 RTP 时间戳展开为 64 位，并更新展开参考点
+func (jb *JitterBuffer) unwrap(ts uint32) int64 {
+	if !jb.tsInitialized {
+		jb.tsInitialized = true
+		jb.lastTS = ts
+		jb.lastExtTS = int64(ts)
+		return jb.lastExtTS
+	}
+	ext := jb.lastExtTS + int64(int32(ts-jb.lastTS))
+	if ext > jb.lastExtTS {
+		jb.lastTS = ts
+		jb.lastExtTS = ext
+	}
+	return ext
+}
+
+// extOf 计算时间戳的展开值，不更新参考点
+func (jb *JitterBuffer) extOf(ts uint32) int64 {
+	return jb.lastExtTS + int64(int32(ts-jb.lastTS))
+}
+
+func (jb *JitterBuffer) tsDuration(extTS int64) time.Duration {
+	return time.Duration(extTS * int64(time.Second) / int64(jb.clockRate))
+}
+
+// updateTiming 更新传输时延参考点和抖动估计
+func (jb *JitterBuffer) updateTiming(extTS int64, arrival time.Time) {
+	transit := time.Duration(arrival.UnixNano()) - jb.tsDuration(extTS)
+	if !jb.hasBase || transit < jb.baseTransit {
+		jb.baseTransit = transit
+		jb.hasBase = true
+	}
+
+	if !jb.lastArrival.IsZero() {
+		d := arrival.Sub(jb.lastArrival) - jb.tsDuration(extTS-jb.lastArrivalExt)
+		if d < 0 {
+			d = -d
+		}
+		jb.jitter += (d.Seconds() - jb.jitter) / 16
+	}
+	jb.lastArrival = arrival
+	jb.lastArrivalExt = extTS
+
+	// 目标延迟取抖动的 3 倍，限制在 [minDelay, maxDelay]
+	target := time.Duration(3 * jb.jitter * float64(time.Second))
+	if target < jb.minDelay {
+		target = jb.minDelay
+	}
+	if target > jb.maxDelay {
+		target = jb.maxDelay
+	}
+	jb.targetDelay = target
+}
+
+// playoutTime 计算指定时间戳的播放时刻
+func (jb *JitterBuffer) playoutTime(extTS int64) time.Time {
+	return time.Unix(0, int64(jb.baseTransit+jb.tsDuration(extTS)+jb.targetDelay))
+}
+
+func (jb *JitterBuffer) lowestSeq() (uint16, bool) {
+	var lowest uint16
+	found := false
+	for seq := range jb.packets {
+		if !found || seqDiff(seq, lowest) < 0 {
+			lowest = seq
+			found = true
+		}
+	}
+	return lowest, found
+}
+
+// seqDiff 计算考虑回绕的序列号差值 a - b
+func seqDiff(a, b uint16) int16 {
+	return int16(a - b)
+}
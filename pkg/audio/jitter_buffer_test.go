@@ -0,0 +1,229 @@
+package audio
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClockRate    = 48000
+	testFrameSamples = 960 // 20ms @ 48kHz
+	testFrameTime    = 20 * time.Millisecond
+)
+
+type rtpArrival struct {
+	seq     uint16
+	ts      uint32
+	arrival time.Duration // 相对起始时间
+}
+
+// buildTrace 生成一段 20ms 间隔的 RTP 轨迹
+func buildTrace(startSeq uint16, startTS uint32, count int) []rtpArrival {
+	trace := make([]rtpArrival, count)
+	for i := 0; i < count; i++ {
+		trace[i] = rtpArrival{
+			seq:     startSeq + uint16(i),
+			ts:      startTS + uint32(i*testFrameSamples),
+			arrival: time.Duration(i) * testFrameTime,
+		}
+	}
+	return trace
+}
+
+// runTrace 按到达时间推入数据包，并以 10ms 节拍取出所有帧
+func runTrace(jb *JitterBuffer, trace []rtpArrival, duration time.Duration) []JitterFrame {
+	sort.SliceStable(trace, func(i, j int) bool { return trace[i].arrival < trace[j].arrival })
+
+	base := time.Unix(1700000000, 0)
+	var frames []JitterFrame
+	next := 0
+	for now := time.Duration(0); now <= duration; now += 10 * time.Millisecond {
+		for next < len(trace) && trace[next].arrival <= now {
+			p := trace[next]
+			jb.Push(JitterPacket{
+				SequenceNumber: p.seq,
+				Timestamp:      p.ts,
+				Payload:        []byte{byte(p.seq), byte(p.seq >> 8)},
+			}, base.Add(p.arrival))
+			next++
+		}
+		for {
+			frame, ok := jb.Pop(base.Add(now))
+			if !ok {
+				break
+			}
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+func sequenceOf(frames []JitterFrame) []uint16 {
+	seqs := make([]uint16, len(frames))
+	for i, f := range frames {
+		seqs[i] = f.SequenceNumber
+	}
+	return seqs
+}
+
+func TestJitterBufferInOrder(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, DefaultJitterMinDelay, DefaultJitterMaxDelay)
+	trace := buildTrace(100, 5000, 50)
+
+	frames := runTrace(jb, trace, 2*time.Second)
+	require.Len(t, frames, 50)
+	for i, f := range frames {
+		assert.Equal(t, uint16(100+i), f.SequenceNumber)
+		assert.False(t, f.Lost)
+	}
+	assert.Equal(t, 0, jb.Stats().Concealed)
+}
+
+func TestJitterBufferReorder(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, 40*time.Millisecond, DefaultJitterMaxDelay)
+	trace := buildTrace(0, 0, 20)
+	// seq 5 和 seq 12 各延迟 25ms，晚于其后一个包到达
+	trace[5].arrival += 25 * time.Millisecond
+	trace[12].arrival += 25 * time.Millisecond
+
+	frames := runTrace(jb, trace, time.Second)
+	require.Len(t, frames, 20)
+	for i, f := range frames {
+		assert.Equal(t, uint16(i), f.SequenceNumber)
+		assert.False(t, f.Lost)
+	}
+	assert.Equal(t, 2, jb.Stats().Reordered)
+}
+
+func TestJitterBufferLoss(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, DefaultJitterMinDelay, DefaultJitterMaxDelay)
+	trace := buildTrace(0, 0, 20)
+	// 丢掉 seq 7，以及连续的 seq 12、13
+	var lossy []rtpArrival
+	for _, p := range trace {
+		if p.seq == 7 || p.seq == 12 || p.seq == 13 {
+			continue
+		}
+		lossy = append(lossy, p)
+	}
+
+	frames := runTrace(jb, lossy, time.Second)
+	require.Len(t, frames, 20)
+	for i, f := range frames {
+		assert.Equal(t, uint16(i), f.SequenceNumber)
+	}
+
+	// seq 7 丢失，后一个包可用于 FEC
+	assert.True(t, frames[7].Lost)
+	assert.Equal(t, []byte{8, 0}, frames[7].Payload)
+	assert.Equal(t, uint32(7*testFrameSamples), frames[7].Timestamp)
+
+	// seq 12 丢失且 seq 13 也丢失，只能做 PLC
+	assert.True(t, frames[12].Lost)
+	assert.Nil(t, frames[12].Payload)
+	// seq 13 丢失，seq 14 可用于 FEC
+	assert.True(t, frames[13].Lost)
+	assert.Equal(t, []byte{14, 0}, frames[13].Payload)
+
+	assert.Equal(t, 3, jb.Stats().Concealed)
+}
+
+func TestJitterBufferLatePacketDropped(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, DefaultJitterMinDelay, DefaultJitterMaxDelay)
+	trace := buildTrace(0, 0, 10)
+	// seq 3 延迟 300ms 才到达，早已被判定为丢失
+	trace[3].arrival += 300 * time.Millisecond
+
+	frames := runTrace(jb, trace, time.Second)
+	require.Len(t, frames, 10)
+	assert.True(t, frames[3].Lost)
+	assert.Equal(t, 1, jb.Stats().Late)
+}
+
+func TestJitterBufferSequenceWrap(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, 40*time.Millisecond, DefaultJitterMaxDelay)
+	trace := buildTrace(65530, 4294967295-3*testFrameSamples, 12)
+	// 回绕后乱序
+	trace[7].arrival += 25 * time.Millisecond
+
+	frames := runTrace(jb, trace, time.Second)
+	require.Len(t, frames, 12)
+	expected := uint16(65530)
+	for _, f := range frames {
+		assert.Equal(t, expected, f.SequenceNumber)
+		assert.False(t, f.Lost)
+		expected++
+	}
+}
+
+func TestJitterBufferDuplicate(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, DefaultJitterMinDelay, DefaultJitterMaxDelay)
+	trace := buildTrace(0, 0, 10)
+	dup := trace[4]
+	dup.arrival += time.Millisecond
+	trace = append(trace, dup)
+
+	frames := runTrace(jb, trace, time.Second)
+	assert.Equal(t, []uint16{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, sequenceOf(frames))
+	assert.Equal(t, 1, jb.Stats().Duplicated)
+}
+
+func TestJitterBufferAdaptiveDelay(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, DefaultJitterMinDelay, DefaultJitterMaxDelay)
+	trace := buildTrace(0, 0, 100)
+	// 每隔一个包额外延迟 40ms，模拟较大的网络抖动
+	for i := range trace {
+		if i%2 == 1 {
+			trace[i].arrival += 40 * time.Millisecond
+		}
+	}
+
+	frames := runTrace(jb, trace, 3*time.Second)
+	require.Len(t, frames, 100)
+	assert.Greater(t, jb.TargetDelay(), DefaultJitterMinDelay)
+	assert.LessOrEqual(t, jb.TargetDelay(), DefaultJitterMaxDelay)
+	assert.Greater(t, jb.Stats().Jitter, time.Duration(0))
+}
+
+func TestJitterBufferLongGapSkips(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, DefaultJitterMinDelay, DefaultJitterMaxDelay)
+	trace := buildTrace(0, 0, 40)
+	// 丢失 seq 10..29 共 20 个包，超过最大隐藏帧数
+	lossy := append(append([]rtpArrival{}, trace[:10]...), trace[30:]...)
+
+	frames := runTrace(jb, lossy, 2*time.Second)
+	concealed := 0
+	for _, f := range frames {
+		if f.Lost {
+			concealed++
+		}
+	}
+	assert.Equal(t, maxConcealFrames, concealed)
+	assert.Equal(t, uint16(39), frames[len(frames)-1].SequenceNumber)
+	assert.Equal(t, 20-maxConcealFrames, jb.Stats().Skipped)
+}
+
+func TestJitterBufferStreamRestart(t *testing.T) {
+	jb := NewJitterBuffer(testClockRate, DefaultJitterMinDelay, DefaultJitterMaxDelay)
+	trace := buildTrace(100, 0, 10)
+	// 发送端重启，序列号和时间戳都重新开始
+	restart := buildTrace(30000, 123456, 10)
+	for i := range restart {
+		restart[i].arrival += 200 * time.Millisecond
+	}
+
+	frames := runTrace(jb, append(trace, restart...), 2*time.Second)
+	// 重新同步时旧流中尚未播放的尾部会被丢弃，新流完整输出且不产生丢包隐藏
+	require.GreaterOrEqual(t, len(frames), 10)
+	newStream := frames[len(frames)-10:]
+	for i, f := range newStream {
+		assert.Equal(t, uint16(30000+i), f.SequenceNumber)
+	}
+	for _, f := range frames {
+		assert.False(t, f.Lost)
+	}
+}
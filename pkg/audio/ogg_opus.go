@@ -0,0 +1,317 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+)
+
+const (
+	// OpusClockRate Opus 的粒度位置和 RTP 时间戳都以 48kHz 计
+	OpusClockRate = 48000
+	// DefaultOpusPreSkip libopus 编码器的前瞻（6.5ms），解码时丢弃
+	DefaultOpusPreSkip = 312
+	// DefaultOggPageDuration 每页最多容纳的音频时长，异常退出时最多丢失这么多
+	DefaultOggPageDuration = time.Second
+
+	// MaxOggGapFill WriteRTP 最多用空帧填补的空缺，更大的时间戳跳变视为发送端重置时间线，不再填补
+	MaxOggGapFill = 5 * time.Second
+
+	oggHeaderSize  = 27
+	oggMaxSegments = 255
+	oggVendor      = "gemini-realtime-webrtc"
+
+	oggFlagContinued = 0x01
+	oggFlagBOS       = 0x02
+	oggFlagEOS       = 0x04
+)
+
+// OpusHead Ogg Opus 的标识头（RFC 7845 5.1），只支持映射族 0（单声道或立体声）
+type OpusHead struct {
+	Channels        int
+	PreSkip         int
+	InputSampleRate int
+	OutputGain      int16
+}
+
+// OpusPacketSamples 按 TOC 计算一个 Opus 包包含的采样数（48kHz，RFC 6716 3.1）
+func OpusPacketSamples(packet []byte) (int, error) {
+	if len(packet) == 0 {
+		return 0, errors.New("empty opus packet")
+	}
+	toc := packet[0]
+	config := toc >> 3
+	var frame int // 单帧采样数
+	switch {
+	case config < 12: // SILK：10/20/40/60ms
+		frame = []int{480, 960, 1920, 2880}[config&3]
+	case config < 16: // Hybrid：10/20ms
+		frame = []int{480, 960}[config&1]
+	default: // CELT：2.5/5/10/20ms
+		frame = []int{120, 240, 480, 960}[config&3]
+	}
+
+	var frames int
+	switch toc & 3 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, errors.New("opus packet with code 3 has no frame count")
+		}
+		frames = int(packet[1] & 0x3f)
+		if frames == 0 {
+			return 0, errors.New("opus packet with zero frames")
+		}
+	}
+	if samples := frames * frame; samples <= 5760 { // 最长 120ms
+		return samples, nil
+	}
+	return 0, errors.New("opus packet longer than 120ms")
+}
+
+// OggOpusWriter 将 Opus 包直接封装为 Ogg Opus 文件（RFC 7845），不解码也不重新编码。
+// WritePacket 用于连续的编码器输出；WriteRTP 按 RTP 时间戳放置，乱序迟到的包丢弃，
+// 空缺（丢包、DTX）用只有 TOC 的空包填补，解码器对其做丢包隐藏，保证粒度位置与时间线一致。
+// 超过 MaxOggGapFill 的跳变直接接在后面，不填补
+type OggOpusWriter struct {
+	w      *bufio.Writer
+	file   *os.File // 由 NewOggOpusFileWriter 创建时非 nil
+	serial uint32
+	seq    uint32
+
+	// 当前页尚未写出的包
+	packets     [][]byte
+	pageSamples int
+	// PageDuration 每页的最大时长，0 表示 DefaultOggPageDuration
+	PageDuration time.Duration
+
+	granule int64 // 已封装的采样数（48kHz，含 pre-skip）
+
+	// WriteRTP 的状态
+	started  bool
+	nextTS   uint32 // 期望的下一个 RTP 时间戳
+	lastTOC  byte
+	lastSize int // 上一个包的单帧采样数
+	// gapRemainder 之前的空缺中不足一帧、没有填补的采样数，累计到下一次空缺
+	gapRemainder int
+
+	closed bool
+}
+
+// NewOggOpusWriter 写出 OpusHead 和 OpusTags 两页头部
+func NewOggOpusWriter(w io.Writer, head OpusHead) (*OggOpusWriter, error) {
+	if head.Channels != 1 && head.Channels != 2 {
+		return nil, fmt.Errorf("ogg opus: unsupported channel count %d", head.Channels)
+	}
+	if head.InputSampleRate == 0 {
+		head.InputSampleRate = OpusClockRate
+	}
+	o := &OggOpusWriter{
+		w:      bufio.NewWriter(w),
+		serial: rand.Uint32(),
+	}
+
+	id := make([]byte, 19)
+	copy(id, "OpusHead")
+	id[8] = 1 // version
+	id[9] = byte(head.Channels)
+	binary.LittleEndian.PutUint16(id[10:], uint16(head.PreSkip))
+	binary.LittleEndian.PutUint32(id[12:], uint32(head.InputSampleRate))
+	binary.LittleEndian.PutUint16(id[16:], uint16(head.OutputGain))
+	// id[18] = 0：映射族 0
+	if err := o.writePage([][]byte{id}, oggFlagBOS, 0); err != nil {
+		return nil, err
+	}
+
+	tags := []byte("OpusTags")
+	tags = binary.LittleEndian.AppendUint32(tags, uint32(len(oggVendor)))
+	tags = append(tags, oggVendor...)
+	tags = binary.LittleEndian.AppendUint32(tags, 0) // 没有注释
+	if err := o.writePage([][]byte{tags}, 0, 0); err != nil {
+		return nil, err
+	}
+	return o, o.w.Flush()
+}
+
+// NewOggOpusFileWriter 创建 Ogg Opus 文件
+func NewOggOpusFileWriter(filename string, head OpusHead) (*OggOpusWriter, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	o, err := NewOggOpusWriter(f, head)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	o.file = f
+	return o, nil
+}
+
+// WritePacket 追加一个紧接上一个包的 Opus 包
+func (o *OggOpusWriter) WritePacket(packet []byte) error {
+	if o.closed {
+		return errors.New("ogg opus: writer is closed")
+	}
+	samples, err := OpusPacketSamples(packet)
+	if err != nil {
+		return err
+	}
+	return o.append(packet, samples)
+}
+
+// WriteRTP 按 RTP 时间戳（48kHz）追加一个 Opus 包，第一个包的时间戳作为起点
+func (o *OggOpusWriter) WriteRTP(packet []byte, timestamp uint32) error {
+	if o.closed {
+		return errors.New("ogg opus: writer is closed")
+	}
+	samples, err := OpusPacketSamples(packet)
+	if err != nil {
+		return err
+	}
+
+	if o.started {
+		diff := int32(timestamp - o.nextTS)
+		if diff < 0 {
+			// 重复或迟到的包，时间线上的位置已经被占用
+			return nil
+		}
+		if time.Duration(diff)*time.Second/OpusClockRate > MaxOggGapFill {
+			// 时间戳跳变（发送端重启、SSRC 复用等），按新的时间线继续
+			o.gapRemainder = 0
+		} else if diff > 0 {
+			// 用与上一个包相同配置的空帧填补空缺，不足一帧的余数留到下一次
+			gap := int(diff) + o.gapRemainder
+			for ; gap >= o.lastSize; gap -= o.lastSize {
+				if err := o.append([]byte{o.lastTOC}, o.lastSize); err != nil {
+					return err
+				}
+			}
+			o.gapRemainder = gap
+		}
+	}
+
+	o.started = true
+	o.nextTS = timestamp + uint32(samples)
+	o.lastTOC = packet[0] &^ 3 // code 0：单帧
+	o.lastSize, _ = OpusPacketSamples([]byte{o.lastTOC})
+	return o.append(packet, samples)
+}
+
+func (o *OggOpusWriter) append(packet []byte, samples int) error {
+	segments := 0
+	for _, p := range o.packets {
+		segments += len(p)/255 + 1
+	}
+	pageDuration := o.PageDuration
+	if pageDuration <= 0 {
+		pageDuration = DefaultOggPageDuration
+	}
+	if len(o.packets) > 0 && (segments+len(packet)/255+1 > oggMaxSegments ||
+		time.Duration(o.pageSamples)*time.Second/OpusClockRate >= pageDuration) {
+		if err := o.flushPage(0); err != nil {
+			return err
+		}
+	}
+
+	o.packets = append(o.packets, append([]byte(nil), packet...))
+	o.pageSamples += samples
+	o.granule += int64(samples)
+	return nil
+}
+
+// flushPage 把缓存的包写为一页
+func (o *OggOpusWriter) flushPage(flags byte) error {
+	if err := o.writePage(o.packets, flags, o.granule); err != nil {
+		return err
+	}
+	o.packets = o.packets[:0]
+	o.pageSamples = 0
+	return o.w.Flush()
+}
+
+// Duration 已写入的时长（不含 pre-skip）
+func (o *OggOpusWriter) Duration() time.Duration {
+	return time.Duration(o.granule) * time.Second / OpusClockRate
+}
+
+// Close 写出最后一页（带 EOS 标志）；由 NewOggOpusFileWriter 创建时同时关闭文件
+func (o *OggOpusWriter) Close() error {
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	err := o.flushPage(oggFlagEOS)
+	if o.file != nil {
+		if cerr := o.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// writePage 写出一页，每个包按 lacing 规则拆为若干 255 字节的段。
+// 调用方保证段数不超过 255（单个包超过 65025 字节的情况不会出现在 Opus 中）
+func (o *OggOpusWriter) writePage(packets [][]byte, flags byte, granule int64) error {
+	var table, body []byte
+	for _, p := range packets {
+		n := len(p)
+		for ; n >= 255; n -= 255 {
+			table = append(table, 255)
+		}
+		table = append(table, byte(n))
+		body = append(body, p...)
+	}
+	if len(table) > oggMaxSegments {
+		return fmt.Errorf("ogg page with %d segments", len(table))
+	}
+
+	page := make([]byte, oggHeaderSize, oggHeaderSize+len(table)+len(body))
+	copy(page, "OggS")
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:], uint64(granule))
+	binary.LittleEndian.PutUint32(page[14:], o.serial)
+	binary.LittleEndian.PutUint32(page[18:], o.seq)
+	page[26] = byte(len(table))
+	page = append(page, table...)
+	page = append(page, body...)
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+	o.seq++
+
+	_, err := o.w.Write(page)
+	return err
+}
+
+// oggCRCTable Ogg 使用的 CRC-32：多项式 0x04c11db7，不反转，初值 0
+var oggCRCTable = func() [256]uint32 {
+	var t [256]uint32
+	for i := range t {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		t[i] = r
+	}
+	return t
+}()
+
+// oggCRC 计算整页的校验和，校验和字段须为 0
+func oggCRC(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
@@ -0,0 +1,186 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// OggOpusPacket 读出的一个 Opus 包
+type OggOpusPacket struct {
+	Data []byte
+	// Samples 包含的采样数（48kHz）
+	Samples int
+	// Position 包起始处在时间线上的采样位置（48kHz，含 pre-skip）
+	Position int64
+}
+
+// OggOpusReader 按顺序读取 Ogg Opus 文件中的包，只读取第一个逻辑流。
+// 页的校验和不对时返回错误；写入中途中断的文件读到最后一个完整的页为止
+type OggOpusReader struct {
+	r      *bufio.Reader
+	closer io.Closer
+	head   OpusHead
+
+	serial     uint32
+	haveSerial bool
+
+	// 当前页中尚未返回的包，partial 为跨页的未完成包
+	packets  [][]byte
+	partial  []byte
+	position int64
+	eos      bool
+}
+
+// NewOggOpusReader 读取并校验 OpusHead 和 OpusTags
+func NewOggOpusReader(r io.Reader) (*OggOpusReader, error) {
+	o := &OggOpusReader{r: bufio.NewReader(r)}
+
+	id, err := o.nextRawPacket()
+	if err != nil {
+		return nil, fmt.Errorf("ogg opus: read header: %w", err)
+	}
+	if len(id) < 19 || string(id[:8]) != "OpusHead" {
+		return nil, errors.New("ogg opus: not an Opus stream")
+	}
+	if id[18] != 0 {
+		return nil, fmt.Errorf("ogg opus: unsupported channel mapping family %d", id[18])
+	}
+	o.head = OpusHead{
+		Channels:        int(id[9]),
+		PreSkip:         int(binary.LittleEndian.Uint16(id[10:])),
+		InputSampleRate: int(binary.LittleEndian.Uint32(id[12:])),
+		OutputGain:      int16(binary.LittleEndian.Uint16(id[16:])),
+	}
+
+	tags, err := o.nextRawPacket()
+	if err != nil {
+		return nil, fmt.Errorf("ogg opus: read tags: %w", err)
+	}
+	if len(tags) < 8 || string(tags[:8]) != "OpusTags" {
+		return nil, errors.New("ogg opus: missing OpusTags")
+	}
+	return o, nil
+}
+
+// OpenOggOpus 打开 Ogg Opus 文件，Close 时关闭文件
+func OpenOggOpus(path string) (*OggOpusReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	o, err := NewOggOpusReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	o.closer = f
+	return o, nil
+}
+
+// Head 标识头
+func (o *OggOpusReader) Head() OpusHead {
+	return o.head
+}
+
+// ReadPacket 返回下一个音频包，流结束时返回 io.EOF
+func (o *OggOpusReader) ReadPacket() (OggOpusPacket, error) {
+	data, err := o.nextRawPacket()
+	if err != nil {
+		return OggOpusPacket{}, err
+	}
+	samples, err := OpusPacketSamples(data)
+	if err != nil {
+		return OggOpusPacket{}, err
+	}
+	pkt := OggOpusPacket{Data: data, Samples: samples, Position: o.position}
+	o.position += int64(samples)
+	return pkt, nil
+}
+
+// Close 关闭由 OpenOggOpus 打开的文件
+func (o *OggOpusReader) Close() error {
+	if o.closer != nil {
+		return o.closer.Close()
+	}
+	return nil
+}
+
+// nextRawPacket 返回下一个完整的包，必要时读取新的页
+func (o *OggOpusReader) nextRawPacket() ([]byte, error) {
+	for len(o.packets) == 0 {
+		if o.eos {
+			return nil, io.EOF
+		}
+		if err := o.readPage(); err != nil {
+			return nil, err
+		}
+	}
+	p := o.packets[0]
+	o.packets = o.packets[1:]
+	return p, nil
+}
+
+// readPage 读取一页，把其中完整的包放入 packets
+func (o *OggOpusReader) readPage() error {
+	header := make([]byte, oggHeaderSize)
+	if _, err := io.ReadFull(o.r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return io.EOF
+		}
+		return err
+	}
+	if string(header[:4]) != "OggS" {
+		return errors.New("ogg: missing capture pattern")
+	}
+	table := make([]byte, header[26])
+	if _, err := io.ReadFull(o.r, table); err != nil {
+		return io.EOF
+	}
+	size := 0
+	for _, s := range table {
+		size += int(s)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(o.r, body); err != nil {
+		return io.EOF
+	}
+
+	page := append(append(header, table...), body...)
+	crc := binary.LittleEndian.Uint32(page[22:])
+	binary.LittleEndian.PutUint32(page[22:], 0)
+	if oggCRC(page) != crc {
+		return errors.New("ogg: page checksum mismatch")
+	}
+
+	serial := binary.LittleEndian.Uint32(header[14:])
+	flags := header[5]
+	if flags&oggFlagBOS != 0 && !o.haveSerial {
+		o.serial = serial
+		o.haveSerial = true
+	}
+	if serial != o.serial {
+		// 其他逻辑流
+		return nil
+	}
+	if flags&oggFlagContinued == 0 {
+		o.partial = nil
+	}
+
+	off := 0
+	for _, s := range table {
+		o.partial = append(o.partial, body[off:off+int(s)]...)
+		off += int(s)
+		if s < 255 {
+			o.packets = append(o.packets, o.partial)
+			o.partial = nil
+		}
+	}
+	if flags&oggFlagEOS != 0 {
+		o.eos = true
+	}
+	return nil
+}
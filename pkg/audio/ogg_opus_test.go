@@ -0,0 +1,201 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4/pkg/media/oggreader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// opusFrame 一个 20ms CELT 全带单声道包（config 31，code 0），负载为 n 个 fill
+func opusFrame(n int, fill byte) []byte {
+	return append([]byte{31 << 3}, bytes.Repeat([]byte{fill}, n)...)
+}
+
+func TestOpusPacketSamples(t *testing.T) {
+	for _, tc := range []struct {
+		packet  []byte
+		samples int
+	}{
+		{[]byte{31 << 3}, 960},           // CELT 20ms
+		{[]byte{28 << 3}, 120},           // CELT 2.5ms
+		{[]byte{1 << 3}, 960},            // SILK NB 20ms
+		{[]byte{3 << 3}, 2880},           // SILK NB 60ms
+		{[]byte{13 << 3}, 960},           // Hybrid FB 20ms
+		{[]byte{31<<3 | 1, 0, 0}, 1920},  // 两帧
+		{[]byte{31<<3 | 3, 3, 0}, 2880},  // code 3，三帧
+		{[]byte{31<<3 | 0x4, 0xff}, 960}, // 立体声标志不影响时长
+	} {
+		samples, err := OpusPacketSamples(tc.packet)
+		require.NoError(t, err, "%x", tc.packet)
+		assert.Equal(t, tc.samples, samples, "%x", tc.packet)
+	}
+
+	for _, bad := range [][]byte{nil, {31<<3 | 3}, {31<<3 | 3, 0}, {3<<3 | 3, 3}} {
+		_, err := OpusPacketSamples(bad)
+		assert.Error(t, err, "%x", bad)
+	}
+}
+
+func TestOggOpusRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewOggOpusWriter(&buf, OpusHead{Channels: 1, PreSkip: DefaultOpusPreSkip})
+	require.NoError(t, err)
+
+	// 第 100 个包跨越多个 lacing 段
+	var written [][]byte
+	for i := 0; i < 120; i++ {
+		size := 40
+		if i == 100 {
+			size = 700
+		}
+		written = append(written, opusFrame(size, byte(i)))
+		require.NoError(t, w.WritePacket(written[i]))
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, 2400*time.Millisecond, w.Duration())
+	assert.Error(t, w.WritePacket(opusFrame(1, 0)))
+
+	// pion 的读取器校验页结构和校验和
+	pion, header, err := oggreader.NewWith(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), header.Channels)
+	assert.Equal(t, uint16(DefaultOpusPreSkip), header.PreSkip)
+	var lastGranule uint64
+	for {
+		_, page, err := pion.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, page.GranulePosition, lastGranule)
+		lastGranule = page.GranulePosition
+	}
+	assert.Equal(t, uint64(120*960), lastGranule)
+
+	r, err := NewOggOpusReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, OpusHead{Channels: 1, PreSkip: DefaultOpusPreSkip, InputSampleRate: OpusClockRate}, r.Head())
+	for i := range written {
+		pkt, err := r.ReadPacket()
+		require.NoError(t, err, "packet %d", i)
+		assert.Equal(t, written[i], pkt.Data)
+		assert.Equal(t, int64(i*960), pkt.Position)
+	}
+	_, err = r.ReadPacket()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestOggOpusWriteRTP(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewOggOpusWriter(&buf, OpusHead{Channels: 2})
+	require.NoError(t, err)
+
+	var base uint32 = 0xffffff00 // 跨越时间戳回绕
+	require.NoError(t, w.WriteRTP(opusFrame(10, 1), base))
+	require.NoError(t, w.WriteRTP(opusFrame(10, 2), base+960))
+	// 丢了两个包
+	require.NoError(t, w.WriteRTP(opusFrame(10, 3), base+4*960))
+	// 迟到和重复的包丢弃
+	require.NoError(t, w.WriteRTP(opusFrame(10, 9), base+2*960))
+	require.NoError(t, w.WriteRTP(opusFrame(10, 9), base+4*960))
+	require.NoError(t, w.WriteRTP(opusFrame(10, 4), base+5*960))
+	require.NoError(t, w.Close())
+
+	r, err := NewOggOpusReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	var got [][]byte
+	var positions []int64
+	for {
+		pkt, err := r.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, pkt.Data)
+		positions = append(positions, pkt.Position)
+	}
+	gap := []byte{31 << 3}
+	assert.Equal(t, [][]byte{opusFrame(10, 1), opusFrame(10, 2), gap, gap, opusFrame(10, 3), opusFrame(10, 4)}, got)
+	assert.Equal(t, []int64{0, 960, 1920, 2880, 3840, 4800}, positions)
+}
+
+func TestOggOpusWriteRTPGaps(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewOggOpusWriter(&buf, OpusHead{Channels: 1})
+	require.NoError(t, err)
+
+	var ts uint32 = 1000
+	require.NoError(t, w.WriteRTP(opusFrame(10, 1), ts))
+	// 两次各多出半帧的空缺：第一次填一帧，余数累计到第二次后再填一帧
+	ts += 960 + 960 + 480
+	require.NoError(t, w.WriteRTP(opusFrame(10, 2), ts))
+	ts += 960 + 960 + 480
+	require.NoError(t, w.WriteRTP(opusFrame(10, 3), ts))
+	// 超过 MaxOggGapFill 的跳变不填补
+	ts += 960 + uint32(MaxOggGapFill/time.Second+1)*OpusClockRate
+	require.NoError(t, w.WriteRTP(opusFrame(10, 4), ts))
+	require.NoError(t, w.WriteRTP(opusFrame(10, 5), ts+960))
+	require.NoError(t, w.Close())
+
+	r, err := NewOggOpusReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	var got [][]byte
+	var positions []int64
+	for {
+		pkt, err := r.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, pkt.Data)
+		positions = append(positions, pkt.Position)
+	}
+	gap := []byte{31 << 3}
+	assert.Equal(t, [][]byte{
+		opusFrame(10, 1), gap, opusFrame(10, 2), gap, gap, opusFrame(10, 3), opusFrame(10, 4), opusFrame(10, 5),
+	}, got)
+	assert.Equal(t, []int64{0, 960, 1920, 2880, 3840, 4800, 5760, 6720}, positions)
+	assert.Equal(t, 7680*time.Second/OpusClockRate, w.Duration())
+}
+
+func TestOggOpusReaderTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewOggOpusWriter(&buf, OpusHead{Channels: 1})
+	require.NoError(t, err)
+	w.PageDuration = 100 * time.Millisecond
+	for i := 0; i < 20; i++ {
+		require.NoError(t, w.WritePacket(opusFrame(20, byte(i))))
+	}
+	require.NoError(t, w.Close())
+
+	// 截断在最后一页中间：读出之前完整的页
+	data := buf.Bytes()[:buf.Len()-10]
+	r, err := NewOggOpusReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	n := 0
+	for {
+		_, err := r.ReadPacket()
+		if err != nil {
+			assert.ErrorIs(t, err, io.EOF)
+			break
+		}
+		n++
+	}
+	assert.Equal(t, 15, n)
+
+	// 校验和错误
+	corrupt := append([]byte(nil), buf.Bytes()...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	r, err = NewOggOpusReader(bytes.NewReader(corrupt))
+	require.NoError(t, err)
+	for err == nil {
+		_, err = r.ReadPacket()
+	}
+	assert.ErrorContains(t, err, "checksum")
+}
@@ -1,10 +1,10 @@
 package audio
 
 import (
+	"encoding/binary"
 	"log"
+	"math"
 	"sync"
-
-	"github.com/asticode/go-astiav"
 )
 
 const (
@@ -23,26 +23,65 @@ const (
 	BytesPerFrame48kHz   = SamplesPerFrame48kHz * BytesPerSample * Channels
 )
 
-// PlayoutBuffer 实现固定长度的音频输出，支持24kHz输入重采样到48kHz输出
+// PlayoutBuffer 实现固定长度的音频输出，支持24kHz输入重采样到输出采样率（默认48kHz）
+// 没有语音时输出舒适噪声（默认为数字静音），语音开始、结束和被打断时做短暂的交叉淡化以避免爆音
 type PlayoutBuffer struct {
 	buffer       []byte
 	mu           sync.Mutex
-	resampler    *Resample
+	resampler    Resampler
 	accumulating bool // 是否正在积累数据
+	frameBytes   int  // 输出采样率下 20ms 帧的字节数
+	sampleRate   int
+
+	noise     *ComfortNoise // nil 表示数字静音
+	crossfade int           // 交叉淡化的采样点数
+	playing   bool          // 上一帧结束时语音仍在继续
+	last      float64       // 上一帧的最后一个输出样本
 }
 
-// NewPlayoutBuffer 创建新的 PlayoutBuffer
+// NewPlayoutBuffer 创建输出为48kHz的 PlayoutBuffer
 func NewPlayoutBuffer() (*PlayoutBuffer, error) {
-	resampler, err := NewResample(InputSampleRate, OutputSampleRate, astiav.ChannelLayoutMono, astiav.ChannelLayoutMono)
+	return NewPlayoutBufferWithRate(OutputSampleRate)
+}
+
+// NewPlayoutBufferWithRate 创建指定输出采样率的 PlayoutBuffer（如 G.711 为 8kHz）
+func NewPlayoutBufferWithRate(outputSampleRate int) (*PlayoutBuffer, error) {
+	resampler, err := NewResampler(InputSampleRate, outputSampleRate, Channels, Channels)
 	if err != nil {
 		return nil, err
 	}
 
-	return &PlayoutBuffer{
-		buffer:       make([]byte, 0, BytesPerFrame48kHz*100), // 预分配2秒的容量
+	frameBytes := outputSampleRate * 20 / 1000 * BytesPerSample * Channels
+	pb := &PlayoutBuffer{
+		buffer:       make([]byte, 0, frameBytes*100), // 预分配2秒的容量
 		resampler:    resampler,
 		accumulating: false,
-	}, nil
+		frameBytes:   frameBytes,
+		sampleRate:   outputSampleRate,
+	}
+	if err := pb.SetComfortNoise(DefaultComfortNoiseConfig()); err != nil {
+		resampler.Free()
+		return nil, err
+	}
+	return pb, nil
+}
+
+// SetComfortNoise 设置没有语音时的输出和交叉淡化时长
+func (pb *PlayoutBuffer) SetComfortNoise(cfg ComfortNoiseConfig) error {
+	var noise *ComfortNoise
+	if cfg.Type != NoiseNone && cfg.Type != "" {
+		var err error
+		noise, err = NewComfortNoise(cfg.Type, cfg.Level)
+		if err != nil {
+			return err
+		}
+	}
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.noise = noise
+	pb.crossfade = int(cfg.Crossfade.Seconds() * float64(pb.sampleRate))
+	return nil
 }
 
 // Write 写入24kHz采样率的音频数据
@@ -51,51 +90,91 @@ func (pb *PlayoutBuffer) Write(data []byte) error {
 		return nil
 	}
 
-	// 重采样到48kHz
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	// 重采样到48kHz（重采样器带有跨调用的状态，需要与 Clear 互斥）
 	resampledData, err := pb.resampler.Resample(data)
 	if err != nil {
 		return err
 	}
-
-	pb.mu.Lock()
-	defer pb.mu.Unlock()
 	pb.buffer = append(pb.buffer, resampledData...)
 	return nil
 }
 
-// ReadFrame 读取固定20ms的48kHz音频帧
-// 如果没有足够的数据，将返回静音数据
+// ReadFrame 读取固定20ms的音频帧
+// 如果没有足够的数据，不足的部分以舒适噪声（或静音）填充
 func (pb *PlayoutBuffer) ReadFrame() []byte {
 	pb.mu.Lock()
 	defer pb.mu.Unlock()
 
-	// 准备输出缓冲区
-	frame := make([]byte, BytesPerFrame48kHz)
-
 	// 如果正在积累数据且缓冲区小于100ms，返回静音
-	if pb.accumulating && len(pb.buffer) < BytesPerFrame48kHz*10 { // 10帧 = 200ms
-		return frame
+	if pb.accumulating && len(pb.buffer) < pb.frameBytes*10 { // 10帧 = 200ms
+		return pb.render(nil)
 	}
 
 	// 如果有足够数据，关闭积累状态
-	if pb.accumulating && len(pb.buffer) >= BytesPerFrame48kHz*5 {
+	if pb.accumulating && len(pb.buffer) >= pb.frameBytes*5 {
 		pb.accumulating = false
 		log.Printf("accumulated enough data (%d bytes), starting playback", len(pb.buffer))
 	}
 
-	if len(pb.buffer) >= BytesPerFrame48kHz {
-		// 有足够的数据，复制一帧
-		copy(frame, pb.buffer[:BytesPerFrame48kHz])
-		// 移除已读取的数据
-		pb.buffer = pb.buffer[BytesPerFrame48kHz:]
-	} else if len(pb.buffer) > 0 {
-		// 有部分数据，复制可用部分，其余填充静音
-		copy(frame, pb.buffer)
-		// 清空缓冲区
-		pb.buffer = pb.buffer[:0]
+	// 取出最多一帧数据，不足一帧时其余部分填充背景
+	n := min(len(pb.buffer), pb.frameBytes)
+	speech := pb.buffer[:n]
+	pb.buffer = pb.buffer[n:]
+
+	return pb.render(speech)
+}
+
+// render 将语音与背景（舒适噪声或静音）合成为一帧
+// 语音开始时淡入；缓冲区耗尽时在语音末尾淡出；语音被 Clear 打断时从上一帧的末尾样本平滑过渡到背景
+func (pb *PlayoutBuffer) render(speech []byte) []byte {
+	samples := make([]float64, pb.frameBytes/BytesPerSample)
+	if pb.noise != nil {
+		for i := range samples {
+			samples[i] = pb.noise.Sample()
+		}
 	}
-	// 如果没有数据，frame 保持为零值（静音）
 
+	k := len(speech) / BytesPerSample
+	cf := pb.crossfade
+	if k > 0 {
+		pcm := make([]int16, k)
+		for i := range pcm {
+			pcm[i] = int16(binary.LittleEndian.Uint16(speech[i*2:]))
+		}
+		if pb.noise != nil {
+			pb.noise.Analyze(pcm)
+		}
+
+		drained := len(pb.buffer) == 0
+		for i, v := range pcm {
+			g := 1.0
+			if !pb.playing && i < cf {
+				g = float64(i+1) / float64(cf+1)
+			}
+			if drained && k-1-i < cf {
+				g = math.Min(g, float64(k-i)/float64(cf+1))
+			}
+			samples[i] = g*float64(v) + (1-g)*samples[i]
+		}
+	} else if pb.playing {
+		for i := 0; i < cf && i < len(samples); i++ {
+			g := float64(i+1) / float64(cf+1)
+			samples[i] = (1-g)*pb.last + g*samples[i]
+		}
+	}
+
+	pb.playing = k > 0 && len(pb.buffer) > 0
+	pb.last = samples[len(samples)-1]
+
+	frame := make([]byte, pb.frameBytes)
+	for i, v := range samples {
+		v = math.Round(v)
+		v = math.Max(-32768, math.Min(32767, v))
+		binary.LittleEndian.PutUint16(frame[i*2:], uint16(int16(v)))
+	}
 	return frame
 }
 
@@ -106,6 +185,13 @@ func (pb *PlayoutBuffer) Clear() {
 	log.Printf("clear buffer: %d, starting accumulation", len(pb.buffer))
 	pb.buffer = pb.buffer[:0]
 	pb.accumulating = true
+
+	// 丢弃重采样器中残留的上一段音频尾部，避免混入新的回复
+	if pb.resampler != nil {
+		if _, err := pb.resampler.Flush(); err != nil {
+			log.Printf("flush resampler error: %v", err)
+		}
+	}
 }
 
 // Available 返回当前可用的音频数据长度（字节）
@@ -0,0 +1,152 @@
+package audio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RecordChannel 录音中的声道
+type RecordChannel int
+
+const (
+	// RecordUser 左声道：用户
+	RecordUser RecordChannel = iota
+	// RecordAssistant 右声道：助手
+	RecordAssistant
+)
+
+// DefaultRecordTolerance 音频到达时间的抖动容限，超过时才按墙上时钟补静音
+const DefaultRecordTolerance = 60 * time.Millisecond
+
+// StereoRecorder 将用户和助手两路单声道 S16LE 音频按墙上时钟对齐，写为立体声 WAV（用户在左，助手在右）。
+// 每块音频按到达时间放到时间线上：某一路落后于时钟（没有音频、丢包、DTX）时补静音，
+// 另一路长时间没有音频时同样补静音，因此录音时长与会话时长一致，停顿得以保留
+type StereoRecorder struct {
+	mu         sync.Mutex
+	writer     *WavStreamWriter
+	sampleRate int
+	tolerance  int // 采样数
+	start      time.Time
+	now        func() time.Time
+
+	// pending 每一路尚未写入文件的采样（S16LE），written 已写入的立体声帧数
+	pending [2][]byte
+	written int64
+	closed  bool
+}
+
+// NewStereoRecorder 创建录音文件，两路输入的采样率都须为 sampleRate，超过 4GB 时自动改为 RF64
+func NewStereoRecorder(filename string, sampleRate int) (*StereoRecorder, error) {
+	writer, err := NewWavStreamWriterWithOptions(filename, uint32(sampleRate), 2, 16, WavWriterOptions{RF64: true})
+	if err != nil {
+		return nil, err
+	}
+	return newStereoRecorder(writer, sampleRate, time.Now), nil
+}
+
+func newStereoRecorder(writer *WavStreamWriter, sampleRate int, now func() time.Time) *StereoRecorder {
+	return &StereoRecorder{
+		writer:     writer,
+		sampleRate: sampleRate,
+		tolerance:  int(DefaultRecordTolerance.Seconds() * float64(sampleRate)),
+		start:      now(),
+		now:        now,
+	}
+}
+
+// SampleRate 录音的采样率
+func (r *StereoRecorder) SampleRate() int {
+	return r.sampleRate
+}
+
+// Write 写入一路刚到达（刚播放）的音频
+func (r *StereoRecorder) Write(ch RecordChannel, pcm []byte) error {
+	if ch != RecordUser && ch != RecordAssistant {
+		return fmt.Errorf("unknown record channel %d", ch)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("recorder is closed")
+	}
+
+	elapsed := int64(r.now().Sub(r.start).Seconds() * float64(r.sampleRate))
+	tolerance := int64(r.tolerance)
+	// 本块音频对应时间线上的 [elapsed-n, elapsed)，之前的空档超过容限时补静音
+	if start := elapsed - int64(len(pcm)/2); start-r.end(ch) > tolerance {
+		r.pad(ch, start)
+	}
+	r.pending[ch] = append(r.pending[ch], pcm...)
+	// 另一路落后于时钟超过容限（没有音频）时补静音，保持在容限之内
+	if other := 1 - ch; elapsed-r.end(other) > tolerance {
+		r.pad(other, elapsed-tolerance)
+	}
+
+	return r.flush(false)
+}
+
+// end 该路在时间线上的结束位置（采样数）
+func (r *StereoRecorder) end(ch RecordChannel) int64 {
+	return r.written + int64(len(r.pending[ch])/2)
+}
+
+// pad 用静音把该路补到 pos
+func (r *StereoRecorder) pad(ch RecordChannel, pos int64) {
+	if gap := pos - r.end(ch); gap > 0 {
+		r.pending[ch] = append(r.pending[ch], make([]byte, 2*gap)...)
+	}
+}
+
+// flush 写入两路都已到达的部分，all 为 true 时先用静音补齐较短的一路
+func (r *StereoRecorder) flush(all bool) error {
+	left, right := r.pending[RecordUser], r.pending[RecordAssistant]
+	if all {
+		if len(left) < len(right) {
+			left = append(left, make([]byte, len(right)-len(left))...)
+		} else {
+			right = append(right, make([]byte, len(left)-len(right))...)
+		}
+	}
+	n := min(len(left), len(right)) / 2
+	if n == 0 {
+		return nil
+	}
+
+	stereo := make([]byte, 4*n)
+	for i := 0; i < n; i++ {
+		copy(stereo[4*i:], left[2*i:2*i+2])
+		copy(stereo[4*i+2:], right[2*i:2*i+2])
+	}
+	r.pending[RecordUser] = append(left[:0:0], left[2*n:]...)
+	r.pending[RecordAssistant] = append(right[:0:0], right[2*n:]...)
+	r.written += int64(n)
+
+	_, err := r.writer.Write(stereo)
+	return err
+}
+
+// Duration 已写入文件的时长
+func (r *StereoRecorder) Duration() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Duration(r.written) * time.Second / time.Duration(r.sampleRate)
+}
+
+// Close 用静音补齐到当前时刻，写入剩余的音频并关闭文件
+func (r *StereoRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	elapsed := int64(r.now().Sub(r.start).Seconds() * float64(r.sampleRate))
+	r.pad(RecordUser, elapsed)
+	r.pad(RecordAssistant, elapsed)
+	err := r.flush(true)
+	if cerr := r.writer.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
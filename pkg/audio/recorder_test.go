@@ -0,0 +1,71 @@
+package audio
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock 测试用的墙上时钟
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// constantPCM 生成 samples 个值为 value 的 S16LE 采样
+func constantPCM(samples int, value int16) []byte {
+	pcm := make([]byte, 0, 2*samples)
+	for i := 0; i < samples; i++ {
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(value))
+	}
+	return pcm
+}
+
+func TestStereoRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "call.wav")
+	writer, err := NewWavStreamWriter(path, 1000, 2, 16)
+	require.NoError(t, err)
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	r := newStereoRecorder(writer, 1000, clock.now)
+
+	// 0~100ms 用户说话，助手每 20ms 播放一帧静音
+	for i := 0; i < 5; i++ {
+		clock.advance(20 * time.Millisecond)
+		require.NoError(t, r.Write(RecordUser, constantPCM(20, 100)))
+		require.NoError(t, r.Write(RecordAssistant, constantPCM(20, 0)))
+	}
+	// 100~300ms 用户没有音频（DTX），助手说话
+	for i := 0; i < 10; i++ {
+		clock.advance(20 * time.Millisecond)
+		require.NoError(t, r.Write(RecordAssistant, constantPCM(20, 200)))
+	}
+	// 500ms 用户又发来 20ms，之前的空档补静音
+	clock.advance(200 * time.Millisecond)
+	require.NoError(t, r.Write(RecordUser, constantPCM(20, 300)))
+	clock.advance(100 * time.Millisecond)
+	require.NoError(t, r.Close())
+	assert.Equal(t, 600*time.Millisecond, r.Duration())
+	assert.Error(t, r.Write(RecordUser, constantPCM(20, 0)))
+
+	format, pcm, err := ReadWav(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, format.Channels)
+	require.Len(t, pcm, 600*4)
+
+	sample := func(ms int, ch RecordChannel) int16 {
+		return int16(binary.LittleEndian.Uint16(pcm[4*ms+2*int(ch):]))
+	}
+	assert.Equal(t, int16(100), sample(50, RecordUser))
+	assert.Equal(t, int16(0), sample(50, RecordAssistant))
+	assert.Equal(t, int16(0), sample(200, RecordUser))
+	assert.Equal(t, int16(200), sample(200, RecordAssistant))
+	assert.Equal(t, int16(0), sample(400, RecordAssistant))
+	assert.Equal(t, int16(0), sample(479, RecordUser))
+	assert.Equal(t, int16(300), sample(480, RecordUser))
+	assert.Equal(t, int16(300), sample(499, RecordUser))
+	assert.Equal(t, int16(0), sample(550, RecordUser))
+}
@@ -1,7 +1,10 @@
+//go:build !purego
+
 package audio
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/asticode/go-astiav"
 )
@@ -14,10 +17,32 @@ type Resample struct {
 	outLayout astiav.ChannelLayout
 	inRate    int
 	outRate   int
+
+	initialized bool // 是否已完成第一次转换（swr 在此时初始化）
+}
+
+const resampleAlign = 0
+
+const defaultResamplerKind = ResamplerFFmpeg
+
+// newFFmpegResampler 根据声道数创建基于 libswresample 的重采样器
+func newFFmpegResampler(inRate, outRate, inChannels, outChannels int) (Resampler, error) {
+	return NewResample(inRate, outRate, channelLayoutOf(inChannels), channelLayoutOf(outChannels))
+}
+
+func channelLayoutOf(channels int) astiav.ChannelLayout {
+	if channels == 2 {
+		return astiav.ChannelLayoutStereo
+	}
+	return astiav.ChannelLayoutMono
 }
 
 // NewResample 创建新的重采样器
 func NewResample(inRate, outRate int, inLayout, outLayout astiav.ChannelLayout) (*Resample, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate: %d -> %d", inRate, outRate)
+	}
+
 	r := &Resample{
 		inRate:    inRate,
 		outRate:   outRate,
@@ -65,70 +90,120 @@ func (r *Resample) Free() {
 }
 
 // Resample 执行音频重采样
+// 输出样本数按 swr 内部缓冲的延迟加上本次输入计算，小数部分的相位由 swr 跨调用保持，
+// 因此分块输入与一次性输入得到的输出完全一致
 func (r *Resample) Resample(inputData []byte) ([]byte, error) {
-	const align = 0
-
-	// 设置输入帧参数
-	r.inFrame.SetChannelLayout(r.inLayout)
-	r.inFrame.SetSampleFormat(astiav.SampleFormatS16)
-	r.inFrame.SetSampleRate(r.inRate)
-
 	// 计算每个采样的字节数
 	bytesPerSample := 2 // S16 格式为 2 字节
-	var inChannels int
-	if r.inLayout == astiav.ChannelLayoutMono {
-		inChannels = 1
-	} else if r.inLayout == astiav.ChannelLayoutStereo {
-		inChannels = 2
-	} else {
-		return nil, fmt.Errorf("unsupported channel layout")
+	inChannels, err := channelsOf(r.inLayout)
+	if err != nil {
+		return nil, err
 	}
 	bytesPerFrame := bytesPerSample * inChannels
+	if len(inputData) == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+	if len(inputData)%bytesPerFrame != 0 {
+		return nil, fmt.Errorf("input length %d is not a multiple of frame size %d", len(inputData), bytesPerFrame)
+	}
 
 	// 计算采样点数
 	numSamples := len(inputData) / bytesPerFrame
-	r.inFrame.SetNbSamples(numSamples)
 
-	// 设置输出帧参数
-	r.outFrame.SetChannelLayout(r.outLayout)
-	r.outFrame.SetSampleFormat(astiav.SampleFormatS16)
-	r.outFrame.SetSampleRate(r.outRate)
-
-	// 计算输出采样点数，考虑采样率转换
-	outNumSamples := (numSamples * r.outRate) / r.inRate
-	r.outFrame.SetNbSamples(outNumSamples)
+	// 设置输入帧参数
+	r.inFrame.Unref()
+	r.inFrame.SetChannelLayout(r.inLayout)
+	r.inFrame.SetSampleFormat(astiav.SampleFormatS16)
+	r.inFrame.SetSampleRate(r.inRate)
+	r.inFrame.SetNbSamples(numSamples)
 
-	// 分配帧缓冲区
-	if err := r.inFrame.AllocBuffer(align); err != nil {
+	if err := r.inFrame.AllocBuffer(resampleAlign); err != nil {
 		return nil, fmt.Errorf("failed to allocate input buffer: %w", err)
 	}
-	if err := r.outFrame.AllocBuffer(align); err != nil {
-		return nil, fmt.Errorf("failed to allocate output buffer: %w", err)
+	if err := r.inFrame.Data().SetBytes(inputData, resampleAlign); err != nil {
+		return nil, fmt.Errorf("setting frame's data failed: %w", err)
 	}
 
-	// 复制输入数据到输入帧
-	if err := r.inFrame.AllocSamples(align); err != nil {
-		return nil, fmt.Errorf("failed to allocate samples: %w", err)
+	// 输出容量 = ceil((缓冲延迟 + 本次输入) * outRate / inRate)，实际输出数由 swr 决定
+	pending := r.delay(int64(r.inRate)) + int64(numSamples)
+	outNumSamples := int((pending*int64(r.outRate) + int64(r.inRate) - 1) / int64(r.inRate))
+	if err := r.prepareOutFrame(outNumSamples); err != nil {
+		return nil, err
 	}
 
-	if err := r.inFrame.MakeWritable(); err != nil {
-		return nil, fmt.Errorf("making frame writable failed: %w", err)
+	// 执行重采样
+	if err := r.ctx.ConvertFrame(r.inFrame, r.outFrame); err != nil {
+		return nil, fmt.Errorf("failed to resample: %w", err)
 	}
+	r.initialized = true
 
-	if err := r.inFrame.Data().SetBytes(inputData, align); err != nil {
-		return nil, fmt.Errorf("setting frame's data failed: %w", err)
+	return r.output()
+}
+
+// Flush 输出 swr 内部缓冲的剩余样本，在流结束时调用
+func (r *Resample) Flush() ([]byte, error) {
+	pending := r.delay(int64(r.outRate))
+	if pending <= 0 {
+		return []byte{}, nil
 	}
 
-	// 执行重采样
-	if err := r.ctx.ConvertFrame(r.inFrame, r.outFrame); err != nil {
-		return nil, fmt.Errorf("failed to resample: %w", err)
+	// 额外预留一个样本，避免舍入导致尾部残留
+	if err := r.prepareOutFrame(int(pending) + 1); err != nil {
+		return nil, err
+	}
+	if err := r.ctx.ConvertFrame(nil, r.outFrame); err != nil {
+		return nil, fmt.Errorf("failed to flush resampler: %w", err)
+	}
+
+	return r.output()
+}
+
+// Latency 返回 0：swr 初始化时把相位前移半个滤波器长度，输出与输入对齐。
+// 内部缓冲的样本（swr_get_delay）只推迟输出的时机，不改变其在输出中的位置
+func (r *Resample) Latency() time.Duration {
+	return 0
+}
+
+// delay 返回以 1/base 秒为单位的内部延迟
+// swr 在第一次转换时才完成初始化，此前调用 swr_get_delay 会以 0 采样率做除法
+func (r *Resample) delay(base int64) int64 {
+	if !r.initialized {
+		return 0
 	}
+	return r.ctx.Delay(base)
+}
+
+func (r *Resample) prepareOutFrame(nbSamples int) error {
+	r.outFrame.Unref()
+	r.outFrame.SetChannelLayout(r.outLayout)
+	r.outFrame.SetSampleFormat(astiav.SampleFormatS16)
+	r.outFrame.SetSampleRate(r.outRate)
+	r.outFrame.SetNbSamples(nbSamples)
 
-	// 获取输出数据
-	outputData, err := r.outFrame.Data().Bytes(align)
+	if err := r.outFrame.AllocBuffer(resampleAlign); err != nil {
+		return fmt.Errorf("failed to allocate output buffer: %w", err)
+	}
+	return nil
+}
+
+// output 返回输出帧中实际写入的样本
+func (r *Resample) output() ([]byte, error) {
+	if r.outFrame.NbSamples() == 0 {
+		return []byte{}, nil
+	}
+	outputData, err := r.outFrame.Data().Bytes(resampleAlign)
 	if err != nil {
 		return nil, fmt.Errorf("getting output data failed: %w", err)
 	}
-
 	return outputData, nil
 }
+
+func channelsOf(layout astiav.ChannelLayout) (int, error) {
+	if layout.Equal(astiav.ChannelLayoutMono) {
+		return 1, nil
+	}
+	if layout.Equal(astiav.ChannelLayoutStereo) {
+		return 2, nil
+	}
+	return 0, fmt.Errorf("unsupported channel layout")
+}
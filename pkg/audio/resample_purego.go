@@ -0,0 +1,12 @@
+//go:build purego
+
+package audio
+
+import "fmt"
+
+const defaultResamplerKind = ResamplerSinc
+
+// newFFmpegResampler 在 purego 构建下不可用（未链接 libswresample）
+func newFFmpegResampler(inRate, outRate, inChannels, outChannels int) (Resampler, error) {
+	return nil, fmt.Errorf("ffmpeg resampler is not available in purego builds")
+}
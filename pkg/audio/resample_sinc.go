@@ -0,0 +1,301 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// 每个相位的 sinc 过零点数（相对较低采样率），越大过渡带越窄
+	sincZeroCrossings = 24
+	// 阻带衰减（dB），决定 Kaiser 窗的 beta
+	sincStopbandAttenuation = 90.0
+)
+
+// SincResampler 纯 Go 实现的多相加窗 sinc 重采样器
+// 采样率比例化简为 L/M，原型低通滤波器工作在 inRate*L，按相位拆分后逐点计算输出；
+// 滤波器历史和相位在多次调用之间保持，因此分块输入与一次性输入的输出逐样本一致；
+// 输出相对输入有固定的群延迟（见 Latency），流结束时调用 Flush 取出尾部样本
+type SincResampler struct {
+	inRate      int
+	outRate     int
+	inChannels  int
+	outChannels int
+
+	up   int64 // L
+	down int64 // M
+	taps int   // 每个相位的抽头数
+
+	phases [][]float64 // [L][taps]
+
+	// history[ch] 保存绝对下标从 base 开始的输入样本
+	history [][]float64
+	base    int64
+	totalIn int64
+	outPos  int64
+}
+
+// NewSincResampler 创建纯 Go 重采样器
+func NewSincResampler(inRate, outRate, inChannels, outChannels int) (*SincResampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate: %d -> %d", inRate, outRate)
+	}
+	if !supportedChannels(inChannels) || !supportedChannels(outChannels) {
+		return nil, fmt.Errorf("unsupported channel layout: %d -> %d channels", inChannels, outChannels)
+	}
+
+	g := gcd(inRate, outRate)
+	r := &SincResampler{
+		inRate:      inRate,
+		outRate:     outRate,
+		inChannels:  inChannels,
+		outChannels: outChannels,
+		up:          int64(outRate / g),
+		down:        int64(inRate / g),
+	}
+
+	// 降采样时滤波器需要按比例加长，才能保持相同的过渡带
+	ratio := (inRate + outRate - 1) / outRate
+	if ratio < 1 {
+		ratio = 1
+	}
+	r.taps = 2 * sincZeroCrossings * ratio
+	r.phases = designPolyphaseFilter(inRate, outRate, int(r.up), r.taps)
+
+	filterChannels := min(inChannels, outChannels)
+	r.history = make([][]float64, filterChannels)
+
+	return r, nil
+}
+
+// Resample 执行音频重采样
+func (r *SincResampler) Resample(inputData []byte) ([]byte, error) {
+	bytesPerFrame := 2 * r.inChannels
+	if len(inputData) == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+	if len(inputData)%bytesPerFrame != 0 {
+		return nil, fmt.Errorf("input length %d is not a multiple of frame size %d", len(inputData), bytesPerFrame)
+	}
+
+	numFrames := len(inputData) / bytesPerFrame
+	r.appendInput(inputData, numFrames)
+
+	return r.produce(r.naturalEnd()), nil
+}
+
+// Flush 在流结束时补零，输出滤波器延迟线中剩余的样本，然后重置状态以便开始新的流
+func (r *SincResampler) Flush() ([]byte, error) {
+	if r.totalIn == 0 {
+		return []byte{}, nil
+	}
+
+	// 最后一个真实输入样本经过群延迟 c 后出现在上采样下标 (totalIn-1)*L + c 处
+	c := r.groupDelay()
+	end := ((r.totalIn-1)*r.up+c)/r.down + 1
+
+	// 补足计算这些输出所需的零样本
+	zeros := int((c+r.up-1)/r.up) + 1
+	for ch := range r.history {
+		r.history[ch] = append(r.history[ch], make([]float64, zeros)...)
+	}
+	r.totalIn += int64(zeros)
+
+	out := r.produce(end)
+	r.reset()
+	return out, nil
+}
+
+// Latency 返回滤波器的群延迟，与已输入的样本无关
+func (r *SincResampler) Latency() time.Duration {
+	seconds := float64(r.groupDelay()) / float64(int64(r.inRate)*r.up)
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Free 释放资源
+func (r *SincResampler) Free() {
+	r.history = nil
+	r.phases = nil
+}
+
+// groupDelay 原型滤波器的群延迟（以 inRate*L 的上采样样本为单位）
+func (r *SincResampler) groupDelay() int64 {
+	return (int64(r.taps)*r.up - 1) / 2
+}
+
+func (r *SincResampler) reset() {
+	for ch := range r.history {
+		r.history[ch] = r.history[ch][:0]
+	}
+	r.base = 0
+	r.totalIn = 0
+	r.outPos = 0
+}
+
+// appendInput 将输入转换为浮点并按需下混后追加到历史缓冲
+func (r *SincResampler) appendInput(data []byte, numFrames int) {
+	for i := 0; i < numFrames; i++ {
+		off := i * 2 * r.inChannels
+		if r.inChannels == 2 && len(r.history) == 1 {
+			// 立体声下混为单声道
+			left := float64(int16(uint16(data[off]) | uint16(data[off+1])<<8))
+			right := float64(int16(uint16(data[off+2]) | uint16(data[off+3])<<8))
+			r.history[0] = append(r.history[0], (left+right)/2)
+			continue
+		}
+		for ch := range r.history {
+			p := off + ch*2
+			r.history[ch] = append(r.history[ch], float64(int16(uint16(data[p])|uint16(data[p+1])<<8)))
+		}
+	}
+	r.totalIn += int64(numFrames)
+}
+
+// naturalEnd 返回当前输入足以确定的输出下标上界（满足 n*M < totalIn*L 的最大 n + 1）
+func (r *SincResampler) naturalEnd() int64 {
+	return (r.totalIn*r.up + r.down - 1) / r.down
+}
+
+// produce 计算 [outPos, end) 范围内的输出样本
+func (r *SincResampler) produce(end int64) []byte {
+	L, M := r.up, r.down
+	count := int(end - r.outPos)
+	if count <= 0 {
+		return []byte{}
+	}
+
+	out := make([]byte, count*r.outChannels*2)
+	for j := 0; j < count; j++ {
+		pos := (r.outPos + int64(j)) * M
+		i := pos / L
+		phase := r.phases[pos%L]
+
+		for ch, hist := range r.history {
+			var acc float64
+			for k, h := range phase {
+				idx := i - int64(k) - r.base
+				if idx < 0 {
+					break
+				}
+				acc += h * hist[idx]
+			}
+			v := toInt16(acc)
+
+			if r.outChannels > len(r.history) {
+				// 单声道上混为立体声
+				o := j * r.outChannels * 2
+				putInt16(out[o:], v)
+				putInt16(out[o+2:], v)
+			} else {
+				o := (j*r.outChannels + ch) * 2
+				putInt16(out[o:], v)
+			}
+		}
+	}
+	r.outPos = end
+
+	r.trimHistory()
+	return out
+}
+
+// trimHistory 丢弃后续输出不再需要的历史样本
+func (r *SincResampler) trimHistory() {
+	nextI := r.outPos * r.down / r.up
+	keepFrom := nextI - int64(r.taps) + 1
+	drop := keepFrom - r.base
+	if drop <= 0 {
+		return
+	}
+	for ch := range r.history {
+		if int(drop) >= len(r.history[ch]) {
+			r.history[ch] = r.history[ch][:0]
+			continue
+		}
+		n := copy(r.history[ch], r.history[ch][drop:])
+		r.history[ch] = r.history[ch][:n]
+	}
+	r.base = keepFrom
+}
+
+// designPolyphaseFilter 设计 Kaiser 窗 sinc 原型低通滤波器并拆分为 up 个相位
+func designPolyphaseFilter(inRate, outRate, up, taps int) [][]float64 {
+	n := taps * up
+	beta := 0.1102 * (sincStopbandAttenuation - 8.7)
+
+	// 过渡带宽度（Hz），截止频率放在较低奈奎斯特频率减去半个过渡带处
+	transition := (sincStopbandAttenuation - 7.95) / (14.36 * float64(taps)) * float64(inRate)
+	nyquist := float64(min(inRate, outRate)) / 2
+	cutoff := (nyquist - transition/2) / float64(inRate) // 相对输入采样率的归一化频率
+
+	center := float64(n-1) / 2
+	i0Beta := besselI0(beta)
+	proto := make([]float64, n)
+	for j := range proto {
+		t := (float64(j) - center) / float64(up) // 以输入样本为单位
+		x := 2 * float64(j) / float64(n-1)
+		w := besselI0(beta*math.Sqrt(math.Max(0, 1-(x-1)*(x-1)))) / i0Beta
+		proto[j] = 2 * cutoff * sinc(2*cutoff*t) * w
+	}
+
+	phases := make([][]float64, up)
+	for p := 0; p < up; p++ {
+		phase := make([]float64, taps)
+		var sum float64
+		for k := 0; k < taps; k++ {
+			phase[k] = proto[p+k*up]
+			sum += phase[k]
+		}
+		// 每个相位归一化为单位直流增益
+		if sum != 0 {
+			for k := range phase {
+				phase[k] /= sum
+			}
+		}
+		phases[p] = phase
+	}
+	return phases
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// besselI0 第一类零阶修正贝塞尔函数（级数展开）
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func toInt16(v float64) int16 {
+	v = math.Round(v)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+func putInt16(b []byte, v int16) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+}
@@ -1,3 +1,5 @@
+//go:build !purego
+
 package audio
 
 import (
@@ -71,13 +73,19 @@ func TestResample(t *testing.T) {
 			assert.NoError(t, err)
 			assert.NotNil(t, outputData)
 
+			// Part of the input may stay in the filter until Flush
+			tail, err := r.Flush()
+			assert.NoError(t, err)
+			outputData = append(outputData, tail...)
+
 			// Verify output length matches expected ratio
 			expectedSamples := (tt.inputSamples * tt.outRate) / tt.inRate
-			expectedBytes := expectedSamples * 2 // 2 bytes per sample for S16
+			bytesPerSample := 2 // 2 bytes per sample for S16
 			if tt.outLayout == astiav.ChannelLayoutStereo {
-				expectedBytes *= 2 // double for stereo
+				bytesPerSample *= 2 // double for stereo
 			}
-			assert.Equal(t, expectedBytes, len(outputData))
+			assert.Zero(t, len(outputData)%bytesPerSample)
+			assert.InDelta(t, expectedSamples, len(outputData)/bytesPerSample, 1)
 		})
 	}
 }
@@ -0,0 +1,64 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Resampler 对 S16LE 交错 PCM 做采样率和声道转换
+type Resampler interface {
+	// Resample 转换一块输入数据，返回当前已能确定的输出数据
+	// 实现需要跨调用保持滤波器状态和小数相位，分块输入与一次性输入的输出一致
+	Resample(inputData []byte) ([]byte, error)
+	// Flush 在流结束时取出内部缓冲的剩余样本
+	Flush() ([]byte, error)
+	// Latency 返回算法固有的延迟：输入中 t 时刻的样本出现在输出的 t+Latency 处。
+	// 该值在创建后固定，与调用时机和内部已缓冲的样本无关，缓冲的样本由 Flush 取出
+	Latency() time.Duration
+	// Free 释放资源
+	Free()
+}
+
+// ResamplerKind 重采样器实现类型
+type ResamplerKind string
+
+const (
+	// ResamplerFFmpeg 基于 libswresample（go-astiav，需要 cgo）
+	ResamplerFFmpeg ResamplerKind = "ffmpeg"
+	// ResamplerSinc 纯 Go 多相加窗 sinc 实现
+	ResamplerSinc ResamplerKind = "sinc"
+)
+
+// NewResampler 使用默认实现创建重采样器
+// 默认实现由构建标签决定（purego 构建下为 sinc），可通过环境变量 AUDIO_RESAMPLER=ffmpeg|sinc 覆盖
+func NewResampler(inRate, outRate, inChannels, outChannels int) (Resampler, error) {
+	kind := defaultResamplerKind
+	if v := os.Getenv("AUDIO_RESAMPLER"); v != "" {
+		kind = ResamplerKind(v)
+	}
+	return NewResamplerWithKind(kind, inRate, outRate, inChannels, outChannels)
+}
+
+// NewResamplerWithKind 创建指定实现的重采样器
+func NewResamplerWithKind(kind ResamplerKind, inRate, outRate, inChannels, outChannels int) (Resampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate: %d -> %d", inRate, outRate)
+	}
+	if !supportedChannels(inChannels) || !supportedChannels(outChannels) {
+		return nil, fmt.Errorf("unsupported channel layout: %d -> %d channels", inChannels, outChannels)
+	}
+
+	switch kind {
+	case ResamplerFFmpeg:
+		return newFFmpegResampler(inRate, outRate, inChannels, outChannels)
+	case ResamplerSinc:
+		return NewSincResampler(inRate, outRate, inChannels, outChannels)
+	default:
+		return nil, fmt.Errorf("unknown resampler kind: %q", kind)
+	}
+}
+
+func supportedChannels(channels int) bool {
+	return channels == 1 || channels == 2
+}
@@ -0,0 +1,411 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 各实现的混叠上限（dB，相对输入电平）
+var maxAliasingDB = map[ResamplerKind]float64{
+	ResamplerFFmpeg: -40,
+	ResamplerSinc:   -70,
+}
+
+// availableResamplers 返回当前构建下可用的重采样实现
+func availableResamplers(t *testing.T) []ResamplerKind {
+	var kinds []ResamplerKind
+	for _, kind := range []ResamplerKind{ResamplerFFmpeg, ResamplerSinc} {
+		r, err := NewResamplerWithKind(kind, 48000, 16000, 1, 1)
+		if err != nil {
+			t.Logf("resampler %s not available: %v", kind, err)
+			continue
+		}
+		r.Free()
+		kinds = append(kinds, kind)
+	}
+	require.NotEmpty(t, kinds)
+	return kinds
+}
+
+func genTone(rate int, freq, amp float64, seconds float64) []int16 {
+	n := int(float64(rate) * seconds)
+	out := make([]int16, n)
+	for i := range out {
+		out[i] = int16(amp * 32767 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return out
+}
+
+// genChirp 生成线性扫频信号，频率从 f0 线性变化到 f1
+func genChirp(rate int, f0, f1, amp, seconds float64) []int16 {
+	n := int(float64(rate) * seconds)
+	out := make([]int16, n)
+	k := (f1 - f0) / seconds
+	for i := range out {
+		t := float64(i) / float64(rate)
+		out[i] = int16(amp * 32767 * math.Sin(2*math.Pi*(f0*t+k*t*t/2)))
+	}
+	return out
+}
+
+func int16sToBytes(samples []int16) []byte {
+	out := make([]byte, len(samples)*2)
+	for i, v := range samples {
+		putInt16(out[i*2:], v)
+	}
+	return out
+}
+
+func bytesToInt16s(data []byte) []int16 {
+	out := make([]int16, len(data)/2)
+	for i := range out {
+		out[i] = int16(uint16(data[2*i]) | uint16(data[2*i+1])<<8)
+	}
+	return out
+}
+
+func rms(samples []int16) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range samples {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+func toDB(ratio float64) float64 {
+	if ratio <= 0 {
+		return -200
+	}
+	return 20 * math.Log10(ratio)
+}
+
+// toneLevel 用 Goertzel 算法计算指定频率分量的幅度
+func toneLevel(samples []int16, rate int, freq float64) float64 {
+	w := 2 * math.Pi * freq / float64(rate)
+	coeff := 2 * math.Cos(w)
+	var s1, s2 float64
+	for _, v := range samples {
+		s := float64(v) + coeff*s1 - s2
+		s2, s1 = s1, s
+	}
+	power := s1*s1 + s2*s2 - coeff*s1*s2
+	return 2 * math.Sqrt(math.Max(power, 0)) / float64(len(samples))
+}
+
+// resampleChunked 以 20ms 为块进行重采样
+func resampleChunked(t *testing.T, r Resampler, in []int16, inRate int) []int16 {
+	chunk := inRate / 50
+	var out []byte
+	for i := 0; i < len(in); i += chunk {
+		end := min(i+chunk, len(in))
+		data, err := r.Resample(int16sToBytes(in[i:end]))
+		require.NoError(t, err)
+		out = append(out, data...)
+	}
+	return bytesToInt16s(out)
+}
+
+// trim 去掉首尾的过渡段
+func trim(samples []int16, rate int) []int16 {
+	skip := rate / 10
+	if len(samples) <= 2*skip {
+		return samples
+	}
+	return samples[skip : len(samples)-skip]
+}
+
+func TestResamplerPassband(t *testing.T) {
+	conversions := []struct{ in, out int }{
+		{48000, 16000},
+		{16000, 48000},
+		{24000, 48000},
+		{48000, 24000},
+		{44100, 48000},
+	}
+
+	for _, kind := range availableResamplers(t) {
+		for _, c := range conversions {
+			t.Run(fmt.Sprintf("%s_%d_to_%d", kind, c.in, c.out), func(t *testing.T) {
+				r, err := NewResamplerWithKind(kind, c.in, c.out, 1, 1)
+				require.NoError(t, err)
+				defer r.Free()
+
+				in := genTone(c.in, 1000, 0.5, 1)
+				out := resampleChunked(t, r, in, c.in)
+
+				// 输出长度符合采样率比例（允许滤波器延迟带来的少量差异）
+				expected := len(in) * c.out / c.in
+				assert.InDelta(t, expected, len(out), float64(c.out)/100)
+
+				gain := toDB(toneLevel(trim(out, c.out), c.out, 1000) / toneLevel(trim(in, c.in), c.in, 1000))
+				assert.InDelta(t, 0, gain, 0.5, "passband gain %.2f dB", gain)
+			})
+		}
+	}
+}
+
+func TestResamplerAliasing(t *testing.T) {
+	for _, kind := range availableResamplers(t) {
+		// 降采样：高于输出奈奎斯特频率的分量必须被滤除
+		for _, freq := range []float64{9000, 12000, 20000} {
+			t.Run(fmt.Sprintf("%s_down_%.0fHz", kind, freq), func(t *testing.T) {
+				r, err := NewResamplerWithKind(kind, 48000, 16000, 1, 1)
+				require.NoError(t, err)
+				defer r.Free()
+
+				in := genTone(48000, freq, 0.5, 1)
+				out := resampleChunked(t, r, in, 48000)
+
+				level := toDB(rms(trim(out, 16000)) / rms(in))
+				assert.Less(t, level, maxAliasingDB[kind], "aliasing %.1f dB", level)
+			})
+		}
+
+		// 升采样：镜像频率分量必须被抑制
+		t.Run(fmt.Sprintf("%s_up_imaging", kind), func(t *testing.T) {
+			r, err := NewResamplerWithKind(kind, 16000, 48000, 1, 1)
+			require.NoError(t, err)
+			defer r.Free()
+
+			in := genTone(16000, 5000, 0.5, 1)
+			out := trim(resampleChunked(t, r, in, 16000), 48000)
+
+			signal := toneLevel(out, 48000, 5000)
+			for _, image := range []float64{11000, 21000} {
+				level := toDB(toneLevel(out, 48000, image) / signal)
+				assert.Less(t, level, maxAliasingDB[kind], "image at %.0f Hz: %.1f dB", image, level)
+			}
+		})
+	}
+}
+
+func TestResamplerSweep(t *testing.T) {
+	const (
+		seconds = 2.0
+		topFreq = 24000.0
+	)
+
+	for _, kind := range availableResamplers(t) {
+		t.Run(string(kind), func(t *testing.T) {
+			r, err := NewResamplerWithKind(kind, 48000, 16000, 1, 1)
+			require.NoError(t, err)
+			defer r.Free()
+
+			in := genChirp(48000, 0, topFreq, 0.5, seconds)
+			out := resampleChunked(t, r, in, 48000)
+
+			// 按 20ms 窗口统计电平，窗口对应的扫频瞬时频率 f = topFreq * t / seconds
+			window := 16000 / 50
+			var passband, stopband float64
+			var passCount, stopCount int
+			for i := 0; i+window <= len(out); i += window {
+				freq := topFreq * float64(i) / 16000 / seconds
+				level := rms(out[i : i+window])
+				switch {
+				case freq > 200 && freq < 6000:
+					passband += level
+					passCount++
+				case freq > 9000:
+					stopband = math.Max(stopband, level)
+					stopCount++
+				}
+			}
+			require.NotZero(t, passCount)
+			require.NotZero(t, stopCount)
+
+			aliasing := toDB(stopband / (passband / float64(passCount)))
+			assert.Less(t, aliasing, maxAliasingDB[kind], "sweep aliasing %.1f dB", aliasing)
+		})
+	}
+}
+
+func TestResamplerCompareImplementations(t *testing.T) {
+	kinds := availableResamplers(t)
+	if len(kinds) < 2 {
+		t.Skip("only one resampler implementation available in this build")
+	}
+
+	// 通带内扫频，两种实现的电平包络应一致
+	in := genChirp(48000, 100, 6000, 0.5, 1)
+	envelopes := make(map[ResamplerKind][]float64)
+	for _, kind := range kinds {
+		r, err := NewResamplerWithKind(kind, 48000, 16000, 1, 1)
+		require.NoError(t, err)
+		out := trim(resampleChunked(t, r, in, 48000), 16000)
+		r.Free()
+
+		window := 16000 / 50
+		for i := 0; i+window <= len(out); i += window {
+			envelopes[kind] = append(envelopes[kind], rms(out[i:i+window]))
+		}
+	}
+
+	a, b := envelopes[kinds[0]], envelopes[kinds[1]]
+	n := min(len(a), len(b))
+	require.Greater(t, n, 10)
+	for i := 1; i < n-1; i++ {
+		diff := toDB(a[i] / b[i])
+		assert.InDelta(t, 0, diff, 0.5, "window %d differs by %.2f dB", i, diff)
+	}
+}
+
+func TestResamplerChannels(t *testing.T) {
+	for _, kind := range availableResamplers(t) {
+		t.Run(string(kind)+"_mono_to_stereo", func(t *testing.T) {
+			r, err := NewResamplerWithKind(kind, 48000, 48000, 1, 2)
+			require.NoError(t, err)
+			defer r.Free()
+
+			out := bytesToInt16s(mustResample(t, r, int16sToBytes(genTone(48000, 1000, 0.5, 0.1))))
+			require.Zero(t, len(out)%2)
+			for i := 0; i < len(out); i += 2 {
+				assert.Equal(t, out[i], out[i+1])
+			}
+		})
+
+		t.Run(string(kind)+"_stereo_to_mono", func(t *testing.T) {
+			r, err := NewResamplerWithKind(kind, 48000, 16000, 2, 1)
+			require.NoError(t, err)
+			defer r.Free()
+
+			// 左声道为正弦，右声道静音，下混后电平约降低 6dB
+			mono := genTone(48000, 1000, 0.5, 1)
+			stereo := make([]int16, len(mono)*2)
+			for i, v := range mono {
+				stereo[2*i] = v
+			}
+			out := bytesToInt16s(mustResample(t, r, int16sToBytes(stereo)))
+			gain := toDB(toneLevel(trim(out, 16000), 16000, 1000) / toneLevel(mono, 48000, 1000))
+			assert.InDelta(t, -6.02, gain, 0.5)
+		})
+	}
+}
+
+func TestResamplerInvalidParams(t *testing.T) {
+	for _, kind := range []ResamplerKind{ResamplerFFmpeg, ResamplerSinc} {
+		_, err := NewResamplerWithKind(kind, 0, 16000, 1, 1)
+		assert.Error(t, err)
+		_, err = NewResamplerWithKind(kind, 48000, 16000, 6, 1)
+		assert.Error(t, err)
+	}
+	_, err := NewResamplerWithKind("unknown", 48000, 16000, 1, 1)
+	assert.Error(t, err)
+
+	r, err := NewSincResampler(48000, 16000, 2, 1)
+	require.NoError(t, err)
+	_, err = r.Resample(nil)
+	assert.Error(t, err)
+	_, err = r.Resample([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func mustResample(t *testing.T, r Resampler, data []byte) []byte {
+	out, err := r.Resample(data)
+	require.NoError(t, err)
+	return out
+}
+
+// resampleStream 按给定块长（输入样本数）重采样并在结尾 Flush
+func resampleStream(t *testing.T, r Resampler, in []int16, chunk int) []int16 {
+	var out []byte
+	for i := 0; i < len(in); i += chunk {
+		end := min(i+chunk, len(in))
+		out = append(out, mustResample(t, r, int16sToBytes(in[i:end]))...)
+	}
+	tail, err := r.Flush()
+	require.NoError(t, err)
+	return bytesToInt16s(append(out, tail...))
+}
+
+func TestResamplerStreamingMatchesOneShot(t *testing.T) {
+	conversions := []struct{ in, out int }{
+		{48000, 16000},
+		{24000, 48000},
+		{44100, 48000},
+		{48000, 44100},
+	}
+	// 包含与转换比例不对齐的块长，确保小数相位跨调用保持
+	chunks := []int{1, 137, 480, 1021}
+
+	for _, kind := range availableResamplers(t) {
+		for _, c := range conversions {
+			t.Run(fmt.Sprintf("%s_%d_to_%d", kind, c.in, c.out), func(t *testing.T) {
+				in := genTone(c.in, 440, 0.5, 3)
+
+				r, err := NewResamplerWithKind(kind, c.in, c.out, 1, 1)
+				require.NoError(t, err)
+				defer r.Free()
+				oneShot := resampleStream(t, r, in, len(in))
+
+				// Flush 之后可以开始新的流
+				again := resampleStream(t, r, in, len(in))
+				require.Equal(t, oneShot, again)
+
+				for _, chunk := range chunks {
+					r, err := NewResamplerWithKind(kind, c.in, c.out, 1, 1)
+					require.NoError(t, err)
+					chunked := resampleStream(t, r, in, chunk)
+					r.Free()
+
+					require.Equal(t, len(oneShot), len(chunked), "chunk %d", chunk)
+					assert.Equal(t, oneShot, chunked, "chunk %d", chunk)
+				}
+
+				// Flush 后输出长度至少覆盖全部输入和滤波器延迟
+				expected := len(in) * c.out / c.in
+				assert.GreaterOrEqual(t, len(oneShot), expected)
+				latency := int(r.Latency().Seconds()*float64(c.out)) + 2
+				assert.LessOrEqual(t, len(oneShot), expected+2*latency+2)
+			})
+		}
+	}
+}
+
+func TestResamplerLatency(t *testing.T) {
+	r, err := NewSincResampler(24000, 48000, 1, 1)
+	require.NoError(t, err)
+	defer r.Free()
+	assert.Greater(t, r.Latency(), time.Duration(0))
+
+	for _, kind := range availableResamplers(t) {
+		t.Run(string(kind), func(t *testing.T) {
+			r, err := NewResamplerWithKind(kind, 24000, 48000, 1, 1)
+			require.NoError(t, err)
+			defer r.Free()
+
+			latency := r.Latency()
+			assert.GreaterOrEqual(t, latency, time.Duration(0))
+			assert.Less(t, latency, 5*time.Millisecond)
+
+			// 冲激响应的峰值位置与报告的延迟一致
+			impulse := make([]int16, 2400)
+			impulse[0] = 16384
+			var out []byte
+			for i := 0; i < len(impulse); i += 480 {
+				out = append(out, mustResample(t, r, int16sToBytes(impulse[i:i+480]))...)
+				// 延迟固定，不随已缓冲的输入变化
+				assert.Equal(t, latency, r.Latency())
+			}
+			tail, err := r.Flush()
+			require.NoError(t, err)
+			samples := bytesToInt16s(append(out, tail...))
+
+			peak := 0
+			for i, v := range samples {
+				if math.Abs(float64(v)) > math.Abs(float64(samples[peak])) {
+					peak = i
+				}
+			}
+			expected := latency.Seconds() * 48000
+			assert.InDelta(t, expected, float64(peak), 1)
+		})
+	}
+}
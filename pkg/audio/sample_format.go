@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/utils"
+)
+
+// ConvertSampleFormat 在两种 PCM 样本格式之间转换交错数据
+// 整数格式之间以 32 位满量程为中间表示，浮点格式直接与目标格式互转，避免多余的量化
+func ConvertSampleFormat(data []byte, from, to pipeline.SampleFormat) ([]byte, error) {
+	if from.BytesPerSample() == 0 {
+		return nil, fmt.Errorf("unsupported sample format: %q", from)
+	}
+	if to.BytesPerSample() == 0 {
+		return nil, fmt.Errorf("unsupported sample format: %q", to)
+	}
+	if len(data)%from.BytesPerSample() != 0 {
+		return nil, fmt.Errorf("input length %d is not a multiple of %s sample size %d", len(data), from, from.BytesPerSample())
+	}
+	if from == to {
+		return bytes.Clone(data), nil
+	}
+
+	switch {
+	case from == pipeline.SampleFormatF32:
+		samples, err := utils.ByteSliceToFloat32Slice(data)
+		if err != nil {
+			return nil, err
+		}
+		if to == pipeline.SampleFormatS32 {
+			return utils.Int32SliceToByteSlice(utils.Float32SliceToInt32Slice(samples)), nil
+		}
+		return encodeInt16(utils.Float32SliceToInt16Slice(samples), to)
+	case to == pipeline.SampleFormatF32:
+		samples, err := decodeInt32(data, from)
+		if err != nil {
+			return nil, err
+		}
+		return utils.Float32SliceToByteSlice(utils.Int32SliceToFloat32Slice(samples)), nil
+	case to == pipeline.SampleFormatS32:
+		samples, err := decodeInt32(data, from)
+		if err != nil {
+			return nil, err
+		}
+		return utils.Int32SliceToByteSlice(samples), nil
+	case from == pipeline.SampleFormatS32:
+		samples, err := utils.ByteSliceToInt32Slice(data)
+		if err != nil {
+			return nil, err
+		}
+		return encodeInt16(utils.Int32SliceToInt16Slice(samples), to)
+	default:
+		// 16 位及以下的格式之间以 S16 为中间表示
+		samples, err := decodeInt16(data, from)
+		if err != nil {
+			return nil, err
+		}
+		return encodeInt16(samples, to)
+	}
+}
+
+// decodeInt16 将 16 位及以下的格式解码为 S16
+func decodeInt16(data []byte, from pipeline.SampleFormat) ([]int16, error) {
+	switch from {
+	case pipeline.SampleFormatS16:
+		return utils.ByteSliceToInt16Slice(data)
+	case pipeline.SampleFormatU8:
+		return utils.Uint8SliceToInt16Slice(data), nil
+	case pipeline.SampleFormatMuLaw:
+		return utils.MuLawToInt16Slice(data), nil
+	case pipeline.SampleFormatALaw:
+		return utils.ALawToInt16Slice(data), nil
+	default:
+		return nil, fmt.Errorf("cannot decode %s as 16-bit samples", from)
+	}
+}
+
+// decodeInt32 将整数格式解码为 32 位满量程样本
+func decodeInt32(data []byte, from pipeline.SampleFormat) ([]int32, error) {
+	if from == pipeline.SampleFormatS32 {
+		return utils.ByteSliceToInt32Slice(data)
+	}
+	samples, err := decodeInt16(data, from)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Int16SliceToInt32Slice(samples), nil
+}
+
+// encodeInt16 将 S16 编码为 16 位及以下的格式
+func encodeInt16(samples []int16, to pipeline.SampleFormat) ([]byte, error) {
+	switch to {
+	case pipeline.SampleFormatS16:
+		return utils.Int16SliceToByteSlice(samples), nil
+	case pipeline.SampleFormatU8:
+		return utils.Int16SliceToUint8Slice(samples), nil
+	case pipeline.SampleFormatMuLaw:
+		return utils.Int16SliceToMuLaw(samples), nil
+	case pipeline.SampleFormatALaw:
+		return utils.Int16SliceToALaw(samples), nil
+	default:
+		return nil, fmt.Errorf("cannot encode 16-bit samples as %s", to)
+	}
+}
@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var allSampleFormats = []pipeline.SampleFormat{
+	pipeline.SampleFormatS16,
+	pipeline.SampleFormatS32,
+	pipeline.SampleFormatF32,
+	pipeline.SampleFormatU8,
+	pipeline.SampleFormatMuLaw,
+	pipeline.SampleFormatALaw,
+}
+
+// 各格式往返 S16 后允许的最大误差（S16 满量程）
+var maxRoundTripError = map[pipeline.SampleFormat]float64{
+	pipeline.SampleFormatS16:   0,
+	pipeline.SampleFormatS32:   0,
+	pipeline.SampleFormatF32:   0,
+	pipeline.SampleFormatU8:    256,
+	pipeline.SampleFormatMuLaw: 1024, // 最大段量化步长的一半
+	pipeline.SampleFormatALaw:  1024,
+}
+
+func TestConvertSampleFormatRoundTrip(t *testing.T) {
+	in := genTone(8000, 440, 0.9, 0.1)
+	in = append(in, math.MaxInt16, math.MinInt16, 0, 1, -1)
+	src := int16sToBytes(in)
+
+	for _, format := range allSampleFormats {
+		t.Run(string(format), func(t *testing.T) {
+			converted, err := ConvertSampleFormat(src, pipeline.SampleFormatS16, format)
+			require.NoError(t, err)
+			assert.Len(t, converted, len(in)*format.BytesPerSample())
+
+			back, err := ConvertSampleFormat(converted, format, pipeline.SampleFormatS16)
+			require.NoError(t, err)
+			out := bytesToInt16s(back)
+			require.Len(t, out, len(in))
+
+			var maxErr float64
+			for i := range in {
+				maxErr = math.Max(maxErr, math.Abs(float64(in[i])-float64(out[i])))
+			}
+			assert.LessOrEqual(t, maxErr, maxRoundTripError[format])
+		})
+	}
+}
+
+func TestConvertSampleFormatPairs(t *testing.T) {
+	in := int16sToBytes(genTone(8000, 1000, 0.5, 0.05))
+	ref := toneLevel(bytesToInt16s(in), 8000, 1000)
+
+	// 任意两种格式之间转换后再转回 S16，信号电平保持不变
+	for _, from := range allSampleFormats {
+		for _, to := range allSampleFormats {
+			src, err := ConvertSampleFormat(in, pipeline.SampleFormatS16, from)
+			require.NoError(t, err)
+			converted, err := ConvertSampleFormat(src, from, to)
+			require.NoError(t, err, "%s -> %s", from, to)
+			back, err := ConvertSampleFormat(converted, to, pipeline.SampleFormatS16)
+			require.NoError(t, err)
+
+			level := toneLevel(bytesToInt16s(back), 8000, 1000)
+			assert.InDelta(t, 0, toDB(level/ref), 0.25, "%s -> %s", from, to)
+		}
+	}
+}
+
+func TestConvertSampleFormatFloat(t *testing.T) {
+	// 超出 [-1, 1] 的浮点样本饱和
+	data := utils.Float32SliceToByteSlice([]float32{0, 0.5, -1, 1.5, -2})
+	out, err := ConvertSampleFormat(data, pipeline.SampleFormatF32, pipeline.SampleFormatS16)
+	require.NoError(t, err)
+	assert.Equal(t, []int16{0, 16384, -32768, 32767, -32768}, bytesToInt16s(out))
+
+	out, err = ConvertSampleFormat(int16sToBytes([]int16{-32768, 16384}), pipeline.SampleFormatS16, pipeline.SampleFormatF32)
+	require.NoError(t, err)
+	floats, err := utils.ByteSliceToFloat32Slice(out)
+	require.NoError(t, err)
+	assert.Equal(t, []float32{-1, 0.5}, floats)
+}
+
+func TestConvertSampleFormatInvalid(t *testing.T) {
+	_, err := ConvertSampleFormat([]byte{1, 2, 3}, pipeline.SampleFormatS16, pipeline.SampleFormatF32)
+	assert.Error(t, err)
+	_, err = ConvertSampleFormat([]byte{1, 2}, pipeline.SampleFormatF32, pipeline.SampleFormatS16)
+	assert.Error(t, err)
+	_, err = ConvertSampleFormat([]byte{1, 2}, "s24le", pipeline.SampleFormatS16)
+	assert.Error(t, err)
+	_, err = ConvertSampleFormat([]byte{1, 2}, pipeline.SampleFormatS16, "s24le")
+	assert.Error(t, err)
+
+	_, err = utils.ByteSliceToInt16Slice([]byte{1})
+	assert.Error(t, err)
+	_, err = utils.ByteSliceToInt32Slice([]byte{1, 2})
+	assert.Error(t, err)
+}
+
+func TestG711ReferenceValues(t *testing.T) {
+	assert.Equal(t, byte(0xFF), utils.MuLawEncode(0))
+	assert.Equal(t, int16(0), utils.MuLawDecode(0xFF))
+	assert.Equal(t, int16(-32124), utils.MuLawDecode(0x00))
+	assert.Equal(t, int16(32124), utils.MuLawDecode(0x80))
+	assert.Equal(t, byte(0x80), utils.MuLawEncode(math.MaxInt16))
+
+	assert.Equal(t, byte(0xD5), utils.ALawEncode(0))
+	assert.Equal(t, int16(8), utils.ALawDecode(0xD5))
+	assert.Equal(t, int16(-8), utils.ALawDecode(0x55))
+	assert.Equal(t, int16(32256), utils.ALawDecode(0xAA))
+	assert.Equal(t, byte(0xAA), utils.ALawEncode(math.MaxInt16))
+
+	// 解码后再编码得到同一个码字
+	for i := 0; i < 256; i++ {
+		assert.Equal(t, byte(i), utils.ALawEncode(utils.ALawDecode(byte(i))), "A-law %#x", i)
+		if byte(i) != 0x7F { // µ-law 的负零与正零解码相同
+			assert.Equal(t, byte(i), utils.MuLawEncode(utils.MuLawDecode(byte(i))), "µ-law %#x", i)
+		}
+	}
+}
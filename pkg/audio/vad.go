@@ -0,0 +1,137 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultVADThreshold 默认灵敏度，对应 -40 dBov
+	DefaultVADThreshold = 0.5
+	// DefaultVADMinSpeech 判定开始说话所需的连续语音时长，过滤按键声、咳嗽等短促噪声
+	DefaultVADMinSpeech = 100 * time.Millisecond
+	// DefaultVADSilenceDuration 判定说完所需的连续静音时长
+	DefaultVADSilenceDuration = 700 * time.Millisecond
+
+	vadFrameDuration = 20 * time.Millisecond
+	// Threshold 0~1 线性映射到 vadMinLevel~vadMaxLevel（dBov），越大越不敏感
+	vadMinLevel = -60.0
+	vadMaxLevel = -20.0
+)
+
+// VADEvent 语音活动状态的变化
+type VADEvent int
+
+const (
+	VADSpeechStart VADEvent = iota + 1
+	VADSpeechEnd
+)
+
+// VADConfig 能量 VAD 参数，0 值使用默认值
+type VADConfig struct {
+	// Threshold 0~1，越大需要越响的声音才判为语音
+	Threshold       float64
+	MinSpeech       time.Duration
+	SilenceDuration time.Duration
+}
+
+// VAD 基于帧能量的语音活动检测，输入单声道 S16LE PCM，按 20ms 分帧。
+// 连续 MinSpeech 的语音帧判为开始说话，之后连续 SilenceDuration 的静音帧判为说完（端点）
+type VAD struct {
+	level        float64
+	frameBytes   int
+	minSpeech    int // 帧数
+	silenceLimit int // 帧数
+
+	buf      []byte
+	speaking bool
+	voiced   int // 未说话时连续的语音帧数
+	silent   int // 说话时连续的静音帧数
+}
+
+// NewVAD 创建指定采样率的 VAD
+func NewVAD(sampleRate int, cfg VADConfig) *VAD {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultVADThreshold
+	}
+	if cfg.MinSpeech <= 0 {
+		cfg.MinSpeech = DefaultVADMinSpeech
+	}
+	if cfg.SilenceDuration <= 0 {
+		cfg.SilenceDuration = DefaultVADSilenceDuration
+	}
+	frames := func(d time.Duration) int {
+		return max(1, int((d+vadFrameDuration-1)/vadFrameDuration))
+	}
+	return &VAD{
+		level:        vadMinLevel + math.Min(cfg.Threshold, 1)*(vadMaxLevel-vadMinLevel),
+		frameBytes:   int(vadFrameDuration.Seconds()*float64(sampleRate)) * 2,
+		minSpeech:    frames(cfg.MinSpeech),
+		silenceLimit: frames(cfg.SilenceDuration),
+	}
+}
+
+// Speaking 当前是否处于说话状态
+func (v *VAD) Speaking() bool {
+	return v.speaking
+}
+
+// Process 处理一段音频，返回期间发生的状态变化（按时间顺序），不足一帧的部分留到下次
+func (v *VAD) Process(pcm []byte) []VADEvent {
+	v.buf = append(v.buf, pcm...)
+
+	var events []VADEvent
+	for len(v.buf) >= v.frameBytes {
+		voiced := frameLevel(v.buf[:v.frameBytes]) >= v.level
+		v.buf = v.buf[v.frameBytes:]
+
+		if !v.speaking {
+			if !voiced {
+				v.voiced = 0
+				continue
+			}
+			if v.voiced++; v.voiced >= v.minSpeech {
+				v.speaking = true
+				v.silent = 0
+				events = append(events, VADSpeechStart)
+			}
+			continue
+		}
+
+		if voiced {
+			v.silent = 0
+			continue
+		}
+		if v.silent++; v.silent >= v.silenceLimit {
+			v.speaking = false
+			v.voiced = 0
+			events = append(events, VADSpeechEnd)
+		}
+	}
+	// 避免 append 持续增长底层数组
+	v.buf = append(v.buf[:0:0], v.buf...)
+	return events
+}
+
+// Reset 回到未说话状态
+func (v *VAD) Reset() {
+	v.buf = nil
+	v.speaking = false
+	v.voiced = 0
+	v.silent = 0
+}
+
+// frameLevel 一帧 S16LE 的 RMS 电平（dBov）
+func frameLevel(frame []byte) float64 {
+	n := len(frame) / 2
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	var energy float64
+	for i := 0; i < n; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(frame[2*i:])))
+		energy += s * s
+	}
+	return 10 * math.Log10(energy/float64(n)/(32767*32767)+1e-20)
+}
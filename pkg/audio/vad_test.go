@@ -0,0 +1,65 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vadSignal 生成 d 时长的 S16LE 正弦波，amplitude 为 0 时为静音
+func vadSignal(sampleRate int, d time.Duration, amplitude float64) []byte {
+	n := int(d.Seconds() * float64(sampleRate))
+	pcm := make([]byte, 0, 2*n)
+	for i := 0; i < n; i++ {
+		v := int16(amplitude * math.Sin(2*math.Pi*440*float64(i)/float64(sampleRate)))
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(v))
+	}
+	return pcm
+}
+
+func TestVAD(t *testing.T) {
+	v := NewVAD(16000, VADConfig{})
+
+	// 60ms 的短促声音不足 MinSpeech
+	assert.Empty(t, v.Process(vadSignal(16000, 60*time.Millisecond, 8000)))
+	assert.Empty(t, v.Process(vadSignal(16000, 100*time.Millisecond, 0)))
+	assert.False(t, v.Speaking())
+
+	// 按 10ms 的小块输入，不足一帧的部分留到下次
+	var events []VADEvent
+	speech := vadSignal(16000, 300*time.Millisecond, 8000)
+	for i := 0; i < len(speech); i += 320 {
+		events = append(events, v.Process(speech[i:i+320])...)
+	}
+	assert.Equal(t, []VADEvent{VADSpeechStart}, events)
+	assert.True(t, v.Speaking())
+
+	// 说话中的短暂停顿不算说完
+	assert.Empty(t, v.Process(vadSignal(16000, 400*time.Millisecond, 0)))
+	assert.Empty(t, v.Process(vadSignal(16000, 100*time.Millisecond, 8000)))
+	assert.Empty(t, v.Process(vadSignal(16000, 600*time.Millisecond, 0)))
+	assert.Equal(t, []VADEvent{VADSpeechEnd}, v.Process(vadSignal(16000, 100*time.Millisecond, 0)))
+	assert.False(t, v.Speaking())
+
+	// 一次输入中先说完又开始说话
+	v.Process(vadSignal(16000, 200*time.Millisecond, 8000))
+	chunk := append(vadSignal(16000, time.Second, 0), vadSignal(16000, 200*time.Millisecond, 8000)...)
+	assert.Equal(t, []VADEvent{VADSpeechEnd, VADSpeechStart}, v.Process(chunk))
+
+	v.Reset()
+	assert.False(t, v.Speaking())
+}
+
+func TestVADThreshold(t *testing.T) {
+	// -40 dBov 左右的小音量只被高灵敏度检测到
+	quiet := vadSignal(16000, 200*time.Millisecond, 400)
+	assert.Equal(t, []VADEvent{VADSpeechStart}, NewVAD(16000, VADConfig{Threshold: 0.2}).Process(quiet))
+	assert.Empty(t, NewVAD(16000, VADConfig{Threshold: 0.8}).Process(quiet))
+
+	v := NewVAD(16000, VADConfig{MinSpeech: 20 * time.Millisecond, SilenceDuration: 100 * time.Millisecond})
+	assert.Equal(t, []VADEvent{VADSpeechStart}, v.Process(vadSignal(16000, 20*time.Millisecond, 8000)))
+	assert.Equal(t, []VADEvent{VADSpeechEnd}, v.Process(vadSignal(16000, 100*time.Millisecond, 0)))
+}
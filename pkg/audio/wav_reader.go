@@ -0,0 +1,113 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WavFormat WAV 文件的 PCM 格式
+type WavFormat struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+}
+
+// ReadWav 读取 16 位 PCM WAV 文件，返回格式和 S16LE 交错数据。
+// 数据块长度为 0 或超出文件（写入中途中断的文件）时读取到文件末尾
+func ReadWav(path string) (WavFormat, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return WavFormat{}, nil, err
+	}
+	defer f.Close()
+	return DecodeWav(f)
+}
+
+// DecodeWav 从 r 解析 16 位 PCM WAV（含 RF64），跳过 fmt 和 data 之外的块
+func DecodeWav(r io.Reader) (WavFormat, []byte, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return WavFormat{}, nil, fmt.Errorf("read wav header: %w", err)
+	}
+	if (string(header[0:4]) != "RIFF" && string(header[0:4]) != "RF64") || string(header[8:12]) != "WAVE" {
+		return WavFormat{}, nil, fmt.Errorf("not a wav file")
+	}
+
+	var format WavFormat
+	haveFormat := false
+	// RF64 的 ds64 块给出的数据长度，-1 表示没有
+	ds64DataSize := int64(-1)
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return WavFormat{}, nil, fmt.Errorf("wav data chunk not found: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return WavFormat{}, nil, fmt.Errorf("invalid wav fmt chunk size %d", size)
+			}
+			buf := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return WavFormat{}, nil, fmt.Errorf("read wav fmt chunk: %w", err)
+			}
+			audioFormat := binary.LittleEndian.Uint16(buf[0:2])
+			format = WavFormat{
+				Channels:      int(binary.LittleEndian.Uint16(buf[2:4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(buf[4:8])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(buf[14:16])),
+			}
+			// 1 为 PCM，0xFFFE 为 WAVE_FORMAT_EXTENSIBLE
+			if audioFormat != 1 && audioFormat != 0xFFFE {
+				return WavFormat{}, nil, fmt.Errorf("unsupported wav format %d", audioFormat)
+			}
+			if format.BitsPerSample != 16 {
+				return WavFormat{}, nil, fmt.Errorf("unsupported wav bits per sample %d", format.BitsPerSample)
+			}
+			if format.Channels <= 0 || format.SampleRate <= 0 {
+				return WavFormat{}, nil, fmt.Errorf("invalid wav format %+v", format)
+			}
+			haveFormat = true
+
+		case "ds64":
+			if size < 24 {
+				return WavFormat{}, nil, fmt.Errorf("invalid wav ds64 chunk size %d", size)
+			}
+			buf := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return WavFormat{}, nil, fmt.Errorf("read wav ds64 chunk: %w", err)
+			}
+			ds64DataSize = int64(binary.LittleEndian.Uint64(buf[8:16]))
+
+		case "data":
+			if !haveFormat {
+				return WavFormat{}, nil, fmt.Errorf("wav data chunk before fmt chunk")
+			}
+			var data []byte
+			var err error
+			if size == 0xFFFFFFFF && ds64DataSize >= 0 {
+				data, err = io.ReadAll(io.LimitReader(r, ds64DataSize))
+			} else if size == 0 || size == 0xFFFFFFFF {
+				data, err = io.ReadAll(r)
+			} else {
+				data, err = io.ReadAll(io.LimitReader(r, int64(size)))
+			}
+			if err != nil {
+				return WavFormat{}, nil, fmt.Errorf("read wav data: %w", err)
+			}
+			// 丢弃不完整的采样帧
+			frame := format.Channels * 2
+			return format, data[:len(data)/frame*frame], nil
+
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return WavFormat{}, nil, fmt.Errorf("skip wav chunk %q: %w", id, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package audio
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWav(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wav")
+	w, err := NewWavStreamWriter(path, 24000, 2, 16)
+	require.NoError(t, err)
+	pcm := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	_, err = w.Write(pcm)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	format, data, err := ReadWav(path)
+	require.NoError(t, err)
+	assert.Equal(t, WavFormat{SampleRate: 24000, Channels: 2, BitsPerSample: 16}, format)
+	assert.Equal(t, pcm, data)
+
+	// 头部长度未回填时读取到文件末尾，并丢弃不完整的采样帧
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	copy(raw[40:44], []byte{0, 0, 0, 0})
+	raw = append(raw, 9, 10, 11, 12, 13)
+	_, data, err = DecodeWav(bytes.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, append(pcm, 9, 10, 11, 12), data)
+
+	// 跳过未知的块
+	withList := append([]byte{}, raw[:36]...)
+	withList = append(withList, []byte("LIST\x03\x00\x00\x00abc\x00")...)
+	withList = append(withList, raw[36:]...)
+	_, data, err = DecodeWav(bytes.NewReader(withList))
+	require.NoError(t, err)
+	assert.Equal(t, append(pcm, 9, 10, 11, 12), data)
+
+	_, _, err = DecodeWav(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00AVI ")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,299 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultWavBufferSize 写缓冲大小
+	DefaultWavBufferSize = 64 * 1024
+	// DefaultWavHeaderInterval 回填头部长度的间隔，进程异常退出时头部最多落后这么多（可用 RecoverWav 修复）
+	DefaultWavHeaderInterval = time.Second
+
+	// 普通 WAV 头部：RIFF + fmt + data，共 44 字节
+	wavHeaderSize = 44
+	// RF64 预留的头部：RIFF + JUNK(28) + fmt + data，共 80 字节，超过 4GB 时 JUNK 原地改为 ds64
+	rf64HeaderSize = 80
+	// RIFF 块长度字段的上限，超过时须使用 RF64
+	maxRIFFSize = 0xFFFFFFFF
+)
+
+// ErrWavTooLarge 普通 WAV 的数据超过 4GB，需要使用 RF64
+var ErrWavTooLarge = errors.New("wav data exceeds 4GB, use RF64")
+
+// WavWriterOptions WavStreamWriter 的选项，零值使用默认值
+type WavWriterOptions struct {
+	// BufferSize 写缓冲大小，0 为 DefaultWavBufferSize
+	BufferSize int
+	// HeaderInterval 回填头部长度的间隔，0 为 DefaultWavHeaderInterval，负数表示只在 Flush 和 Close 时回填
+	HeaderInterval time.Duration
+	// RF64 为 true 时预留 ds64 块，数据超过 4GB 时自动改为 RF64（EBU Tech 3306），
+	// 否则头部为标准的 44 字节，超过 4GB 时 Write 返回 ErrWavTooLarge
+	RF64 bool
+}
+
+// WavStreamWriter 以流的方式写入 16 位 PCM WAV 文件。
+// 数据经缓冲写入，头部中的长度按 HeaderInterval 定期回填，Close 时定稿
+type WavStreamWriter struct {
+	file          *os.File
+	buf           *bufio.Writer
+	sampleRate    uint32
+	numChannels   uint16
+	bitsPerSample uint16
+	dataBytes     int64
+	closed        bool
+
+	rf64           bool
+	headerSize     int64
+	headerInterval time.Duration
+	lastHeader     time.Time
+	// maxRIFF RIFF 长度字段的上限，测试中调小以触发 RF64
+	maxRIFF int64
+}
+
+// NewWavStreamWriter 使用默认选项创建 WAV 文件
+func NewWavStreamWriter(filename string, sampleRate uint32, numChannels, bitsPerSample uint16) (*WavStreamWriter, error) {
+	return NewWavStreamWriterWithOptions(filename, sampleRate, numChannels, bitsPerSample, WavWriterOptions{})
+}
+
+// NewWavStreamWriterWithOptions 创建并初始化一个 WAV 文件（写入头部占位）
+func NewWavStreamWriterWithOptions(filename string, sampleRate uint32, numChannels, bitsPerSample uint16, opts WavWriterOptions) (*WavStreamWriter, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultWavBufferSize
+	}
+	if opts.HeaderInterval == 0 {
+		opts.HeaderInterval = DefaultWavHeaderInterval
+	}
+	w := &WavStreamWriter{
+		file:           f,
+		buf:            bufio.NewWriterSize(f, opts.BufferSize),
+		sampleRate:     sampleRate,
+		numChannels:    numChannels,
+		bitsPerSample:  bitsPerSample,
+		rf64:           opts.RF64,
+		headerSize:     wavHeaderSize,
+		headerInterval: opts.HeaderInterval,
+		lastHeader:     time.Now(),
+		maxRIFF:        maxRIFFSize,
+	}
+	if opts.RF64 {
+		w.headerSize = rf64HeaderSize
+	}
+
+	// 写初始头部（长度为 0）
+	if _, err := f.Write(w.header()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write 往 WAV 文件追加写入 PCM 数据，按 HeaderInterval 回填头部长度
+func (w *WavStreamWriter) Write(pcm []byte) (int, error) {
+	if w.closed {
+		return 0, fmt.Errorf("WavStreamWriter: 已关闭，不能再写入")
+	}
+	if !w.rf64 && w.headerSize-8+w.dataBytes+int64(len(pcm)) > w.maxRIFF {
+		return 0, ErrWavTooLarge
+	}
+	n, err := w.buf.Write(pcm)
+	w.dataBytes += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	if w.headerInterval > 0 && time.Since(w.lastHeader) >= w.headerInterval {
+		if err := w.Flush(); err != nil {
+			return n, fmt.Errorf("更新WAV头部失败: %v", err)
+		}
+	}
+	return n, nil
+}
+
+// Flush 把缓冲的数据写入文件并回填头部长度
+func (w *WavStreamWriter) Flush() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	w.lastHeader = time.Now()
+	_, err := w.file.WriteAt(w.header(), 0)
+	return err
+}
+
+// DataBytes 已写入的 PCM 字节数（含缓冲中的）
+func (w *WavStreamWriter) DataBytes() int64 {
+	return w.dataBytes
+}
+
+// Size 文件的总字节数（含头部和缓冲中的数据）
+func (w *WavStreamWriter) Size() int64 {
+	return w.headerSize + w.dataBytes
+}
+
+// Duration 已写入的时长
+func (w *WavStreamWriter) Duration() time.Duration {
+	frame := int64(w.numChannels) * int64(w.bitsPerSample/8)
+	return time.Duration(w.dataBytes/frame) * time.Second / time.Duration(w.sampleRate)
+}
+
+// Close 写入剩余的数据、定稿头部并关闭文件
+func (w *WavStreamWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	err := w.Flush()
+	w.closed = true
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// header 按当前数据长度生成头部
+func (w *WavStreamWriter) header() []byte {
+	return wavHeader(wavHeaderFields{
+		sampleRate:    w.sampleRate,
+		numChannels:   w.numChannels,
+		bitsPerSample: w.bitsPerSample,
+		dataBytes:     w.dataBytes,
+		reserveDS64:   w.rf64,
+		maxRIFF:       w.maxRIFF,
+	})
+}
+
+type wavHeaderFields struct {
+	sampleRate    uint32
+	numChannels   uint16
+	bitsPerSample uint16
+	dataBytes     int64
+	reserveDS64   bool
+	maxRIFF       int64
+}
+
+// wavHeader 生成 WAV 头部。reserveDS64 时在 fmt 之前放一个 28 字节的块：
+// 未超过 4GB 时为 JUNK（普通 WAV），超过后为 ds64，RIFF 改为 RF64，32 位长度字段填 0xFFFFFFFF
+func wavHeader(h wavHeaderFields) []byte {
+	le := binary.LittleEndian
+	blockAlign := h.numChannels * h.bitsPerSample / 8
+	headerSize := int64(wavHeaderSize)
+	if h.reserveDS64 {
+		headerSize = rf64HeaderSize
+	}
+	riffSize := headerSize - 8 + h.dataBytes
+
+	b := make([]byte, 0, headerSize)
+	rf64 := h.reserveDS64 && riffSize > h.maxRIFF
+	if rf64 {
+		b = append(b, "RF64"...)
+		b = le.AppendUint32(b, 0xFFFFFFFF)
+	} else {
+		b = append(b, "RIFF"...)
+		b = le.AppendUint32(b, uint32(riffSize))
+	}
+	b = append(b, "WAVE"...)
+
+	if h.reserveDS64 {
+		if rf64 {
+			b = append(b, "ds64"...)
+			b = le.AppendUint32(b, 28)
+			b = le.AppendUint64(b, uint64(riffSize))
+			b = le.AppendUint64(b, uint64(h.dataBytes))
+			b = le.AppendUint64(b, uint64(h.dataBytes/int64(blockAlign))) // 采样帧数
+			b = le.AppendUint32(b, 0)                                     // 没有其他块的长度表
+		} else {
+			b = append(b, "JUNK"...)
+			b = le.AppendUint32(b, 28)
+			b = append(b, make([]byte, 28)...)
+		}
+	}
+
+	b = append(b, "fmt "...)
+	b = le.AppendUint32(b, 16)
+	b = le.AppendUint16(b, 1) // PCM
+	b = le.AppendUint16(b, h.numChannels)
+	b = le.AppendUint32(b, h.sampleRate)
+	b = le.AppendUint32(b, h.sampleRate*uint32(blockAlign))
+	b = le.AppendUint16(b, blockAlign)
+	b = le.AppendUint16(b, h.bitsPerSample)
+
+	b = append(b, "data"...)
+	if rf64 {
+		b = le.AppendUint32(b, 0xFFFFFFFF)
+	} else {
+		b = le.AppendUint32(b, uint32(h.dataBytes))
+	}
+	return b
+}
+
+// RecoverWav 修复写入中途中断的 WAV 文件：按文件实际长度回填头部中的长度，
+// 丢弃末尾不完整的采样帧，需要时改为 RF64。只支持本包写出的布局（数据块紧跟在 fmt 之后）。
+// 返回恢复出的 PCM 字节数
+func RecoverWav(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	head := make([]byte, rf64HeaderSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, fmt.Errorf("read wav header: %w", err)
+	}
+	head = head[:n]
+	if len(head) < wavHeaderSize || (string(head[0:4]) != "RIFF" && string(head[0:4]) != "RF64") || string(head[8:12]) != "WAVE" {
+		return 0, fmt.Errorf("not a wav file")
+	}
+
+	h := wavHeaderFields{maxRIFF: maxRIFFSize}
+	headerSize := int64(wavHeaderSize)
+	fmtOffset := 12
+	if id := string(head[12:16]); id == "JUNK" || id == "ds64" {
+		if len(head) < rf64HeaderSize {
+			return 0, fmt.Errorf("truncated wav header")
+		}
+		h.reserveDS64 = true
+		headerSize = rf64HeaderSize
+		fmtOffset = 48
+	}
+	if string(head[fmtOffset:fmtOffset+4]) != "fmt " || string(head[fmtOffset+24:fmtOffset+28]) != "data" {
+		return 0, fmt.Errorf("unsupported wav layout")
+	}
+	h.numChannels = binary.LittleEndian.Uint16(head[fmtOffset+10:])
+	h.sampleRate = binary.LittleEndian.Uint32(head[fmtOffset+12:])
+	h.bitsPerSample = binary.LittleEndian.Uint16(head[fmtOffset+22:])
+	frame := int64(h.numChannels) * int64(h.bitsPerSample/8)
+	if frame <= 0 {
+		return 0, fmt.Errorf("invalid wav format")
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	h.dataBytes = (info.Size() - headerSize) / frame * frame
+	if !h.reserveDS64 && headerSize-8+h.dataBytes > maxRIFFSize {
+		return 0, ErrWavTooLarge
+	}
+	if err := f.Truncate(headerSize + h.dataBytes); err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteAt(wavHeader(h), 0); err != nil {
+		return 0, err
+	}
+	return h.dataBytes, nil
+}
@@ -0,0 +1,137 @@
+package audio
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWavStreamWriterBuffered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffered.wav")
+	w, err := NewWavStreamWriterWithOptions(path, 8000, 1, 16, WavWriterOptions{HeaderInterval: -1})
+	require.NoError(t, err)
+
+	pcm := make([]byte, 320)
+	for i := 0; i < 10; i++ {
+		_, err = w.Write(pcm)
+		require.NoError(t, err)
+	}
+	// 数据仍在缓冲中，头部未回填
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(wavHeaderSize), info.Size())
+	assert.Equal(t, int64(3200), w.DataBytes())
+	assert.Equal(t, 200*time.Millisecond, w.Duration())
+
+	require.NoError(t, w.Flush())
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, raw, wavHeaderSize+3200)
+	assert.Equal(t, uint32(36+3200), binary.LittleEndian.Uint32(raw[4:]))
+	assert.Equal(t, uint32(3200), binary.LittleEndian.Uint32(raw[40:]))
+
+	require.NoError(t, w.Close())
+	_, err = w.Write(pcm)
+	assert.Error(t, err)
+}
+
+func TestWavStreamWriterHeaderInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "interval.wav")
+	w, err := NewWavStreamWriterWithOptions(path, 8000, 1, 16, WavWriterOptions{HeaderInterval: time.Nanosecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write(make([]byte, 320))
+	require.NoError(t, err)
+	// 间隔已过，写入后立即回填
+	_, data, err := ReadWav(path)
+	require.NoError(t, err)
+	assert.Len(t, data, 320)
+}
+
+func TestRecoverWav(t *testing.T) {
+	for _, rf64 := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "crash.wav")
+		w, err := NewWavStreamWriterWithOptions(path, 8000, 2, 16, WavWriterOptions{HeaderInterval: -1, RF64: rf64})
+		require.NoError(t, err)
+		_, err = w.Write(make([]byte, 4000))
+		require.NoError(t, err)
+		require.NoError(t, w.Flush())
+		// 模拟异常退出：头部之后又写了数据，末尾有半个采样帧
+		_, err = w.Write(make([]byte, 4002))
+		require.NoError(t, err)
+		require.NoError(t, w.buf.Flush())
+		require.NoError(t, w.file.Close())
+
+		_, data, err := ReadWav(path)
+		require.NoError(t, err)
+		assert.Len(t, data, 4000, "stale header")
+
+		n, err := RecoverWav(path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(8000), n)
+		format, data, err := ReadWav(path)
+		require.NoError(t, err)
+		assert.Equal(t, WavFormat{SampleRate: 8000, Channels: 2, BitsPerSample: 16}, format)
+		assert.Len(t, data, 8000)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, w.headerSize+8000, info.Size())
+	}
+
+	notWav := filepath.Join(t.TempDir(), "x.wav")
+	require.NoError(t, os.WriteFile(notWav, []byte("hello"), 0o644))
+	_, err := RecoverWav(notWav)
+	assert.Error(t, err)
+}
+
+func TestWavStreamWriterRF64(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "large.wav")
+	w, err := NewWavStreamWriterWithOptions(path, 8000, 1, 16, WavWriterOptions{RF64: true})
+	require.NoError(t, err)
+	w.maxRIFF = 1000 // 模拟 4GB 上限
+
+	pcm := make([]byte, 800)
+	for i := range pcm {
+		pcm[i] = byte(i)
+	}
+	_, err = w.Write(pcm)
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "RIFF", string(raw[:4]))
+	assert.Equal(t, "JUNK", string(raw[12:16]))
+
+	_, err = w.Write(pcm)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	raw, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "RF64", string(raw[:4]))
+	assert.Equal(t, "ds64", string(raw[12:16]))
+	assert.Equal(t, uint64(1600), binary.LittleEndian.Uint64(raw[28:]))
+	assert.Equal(t, uint64(800), binary.LittleEndian.Uint64(raw[36:]), "sample frames")
+
+	// 数据块之后追加其他块时，按 ds64 的长度读取
+	raw = append(raw, []byte("LIST\x00\x00\x00\x00")...)
+	require.NoError(t, os.WriteFile(path, raw, 0o644))
+	_, data, err := ReadWav(path)
+	require.NoError(t, err)
+	assert.Equal(t, append(append([]byte(nil), pcm...), pcm...), data)
+
+	// 普通 WAV 超过上限时报错
+	plain, err := NewWavStreamWriter(filepath.Join(t.TempDir(), "plain.wav"), 8000, 1, 16)
+	require.NoError(t, err)
+	defer plain.Close()
+	plain.maxRIFF = 1000
+	_, err = plain.Write(pcm)
+	require.NoError(t, err)
+	_, err = plain.Write(pcm)
+	assert.ErrorIs(t, err, ErrWavTooLarge)
+}
@@ -0,0 +1,202 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v4"
+)
+
+// Codec 描述一种 WebRTC 音频编码
+type Codec struct {
+	Name        string
+	MimeType    string
+	MediaType   string // 管道中编码数据的 MediaType
+	PayloadType webrtc.PayloadType
+	ClockRate   uint32 // RTP 时钟频率
+	SampleRate  int    // PCM 采样率（G.722 为 16kHz，但 RTP 时钟频率按历史约定为 8kHz）
+	Channels    int    // SDP 中声明的声道数
+	SDPFmtpLine string
+}
+
+var (
+	Opus = Codec{
+		Name:        "opus",
+		MimeType:    webrtc.MimeTypeOpus,
+		MediaType:   "audio/x-opus",
+		PayloadType: 111,
+		ClockRate:   48000,
+		SampleRate:  48000,
+		Channels:    2,
+		SDPFmtpLine: "minptime=10;useinbandfec=1",
+	}
+	G722 = Codec{
+		Name:        "G722",
+		MimeType:    webrtc.MimeTypeG722,
+		MediaType:   "audio/G722",
+		PayloadType: 9,
+		ClockRate:   8000,
+		SampleRate:  16000,
+		Channels:    1,
+	}
+	PCMU = Codec{
+		Name:        "PCMU",
+		MimeType:    webrtc.MimeTypePCMU,
+		MediaType:   "audio/x-mulaw",
+		PayloadType: 0,
+		ClockRate:   8000,
+		SampleRate:  8000,
+		Channels:    1,
+	}
+	PCMA = Codec{
+		Name:        "PCMA",
+		MimeType:    webrtc.MimeTypePCMA,
+		MediaType:   "audio/x-alaw",
+		PayloadType: 8,
+		ClockRate:   8000,
+		SampleRate:  8000,
+		Channels:    1,
+	}
+)
+
+// Supported 服务端支持的音频编码，按偏好顺序排列
+var Supported = []Codec{Opus, G722, PCMU, PCMA}
+
+// MimeTypeTelephoneEvent RFC 4733 DTMF 事件
+const MimeTypeTelephoneEvent = "audio/telephone-event"
+
+var (
+	TelephoneEvent48k = Codec{
+		Name:        "telephone-event",
+		MimeType:    MimeTypeTelephoneEvent,
+		MediaType:   "audio/telephone-event",
+		PayloadType: 110,
+		ClockRate:   48000,
+		Channels:    1,
+		SDPFmtpLine: "0-16",
+	}
+	TelephoneEvent8k = Codec{
+		Name:        "telephone-event",
+		MimeType:    MimeTypeTelephoneEvent,
+		MediaType:   "audio/telephone-event",
+		PayloadType: 126,
+		ClockRate:   8000,
+		Channels:    1,
+		SDPFmtpLine: "0-16",
+	}
+)
+
+// TelephoneEvents 支持的 telephone-event 编码，时钟频率需与音频编码一致
+var TelephoneEvents = []Codec{TelephoneEvent48k, TelephoneEvent8k}
+
+// TelephoneEventFor 返回与音频编码时钟频率匹配的 telephone-event 编码
+func TelephoneEventFor(c Codec) (Codec, bool) {
+	for _, te := range TelephoneEvents {
+		if te.ClockRate == c.ClockRate {
+			return te, true
+		}
+	}
+	return Codec{}, false
+}
+
+// IsOpus 是否为 Opus
+func (c Codec) IsOpus() bool {
+	return strings.EqualFold(c.MimeType, webrtc.MimeTypeOpus)
+}
+
+// FrameSamples 返回 20ms 帧的 PCM 采样点数（每声道）
+func (c Codec) FrameSamples() int {
+	return c.SampleRate / 50
+}
+
+// Capability 返回用于创建本地轨道的 RTP 能力描述
+func (c Codec) Capability() webrtc.RTPCodecCapability {
+	var channels uint16
+	if c.Channels > 1 {
+		channels = uint16(c.Channels)
+	}
+	return webrtc.RTPCodecCapability{
+		MimeType:    c.MimeType,
+		ClockRate:   c.ClockRate,
+		Channels:    channels,
+		SDPFmtpLine: c.SDPFmtpLine,
+	}
+}
+
+// Parameters 返回注册到 MediaEngine 的编码参数
+func (c Codec) Parameters() webrtc.RTPCodecParameters {
+	return webrtc.RTPCodecParameters{
+		RTPCodecCapability: c.Capability(),
+		PayloadType:        c.PayloadType,
+	}
+}
+
+func (c Codec) String() string {
+	return fmt.Sprintf("%s/%d", c.Name, c.ClockRate)
+}
+
+// RegisterCodecs 在 MediaEngine 中注册所有支持的音频编码
+func RegisterCodecs(m *webrtc.MediaEngine) error {
+	for _, c := range append(append([]Codec{}, Supported...), TelephoneEvents...) {
+		if err := m.RegisterCodec(c.Parameters(), webrtc.RTPCodecTypeAudio); err != nil {
+			return fmt.Errorf("register codec %s: %w", c, err)
+		}
+	}
+	return nil
+}
+
+// Lookup 按 MIME 类型查找支持的编码
+func Lookup(mimeType string) (Codec, bool) {
+	for _, c := range Supported {
+		if strings.EqualFold(c.MimeType, mimeType) {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// SelectFromOffer 从 SDP offer 的音频媒体中选出服务端最偏好的编码
+func SelectFromOffer(offerSDP string) (Codec, error) {
+	desc := &sdp.SessionDescription{}
+	if err := desc.Unmarshal([]byte(offerSDP)); err != nil {
+		return Codec{}, fmt.Errorf("parse offer: %w", err)
+	}
+
+	offered := make(map[string]bool)
+	for _, media := range desc.MediaDescriptions {
+		if media.MediaName.Media != "audio" {
+			continue
+		}
+		for _, format := range media.MediaName.Formats {
+			pt, err := strconv.ParseUint(format, 10, 8)
+			if err != nil {
+				continue
+			}
+			if name, ok := offeredCodecName(desc, uint8(pt)); ok {
+				offered[name] = true
+			}
+		}
+	}
+
+	for _, c := range Supported {
+		if offered[strings.ToLower(c.Name)] {
+			return c, nil
+		}
+	}
+	return Codec{}, fmt.Errorf("no supported audio codec in offer")
+}
+
+// offeredCodecName 返回 payload type 对应的编码名（小写），没有 rtpmap 时按静态 payload type 识别
+func offeredCodecName(desc *sdp.SessionDescription, pt uint8) (string, bool) {
+	if c, err := desc.GetCodecForPayloadType(pt); err == nil && c.Name != "" {
+		return strings.ToLower(c.Name), true
+	}
+	for _, c := range Supported {
+		if c.PayloadType < 96 && uint8(c.PayloadType) == pt {
+			return strings.ToLower(c.Name), true
+		}
+	}
+	return "", false
+}
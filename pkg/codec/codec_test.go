@@ -0,0 +1,193 @@
+package codec
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func genTone(rate int, freq, amp float64, samples int) []int16 {
+	out := make([]int16, samples)
+	for i := range out {
+		out[i] = int16(amp * 32767 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return out
+}
+
+// snr 在 0~maxDelay 范围内搜索最佳对齐，返回信噪比（dB）
+func snr(ref, out []int16, maxDelay int) float64 {
+	best := math.Inf(-1)
+	for delay := 0; delay <= maxDelay; delay++ {
+		var signal, noise float64
+		for i := 0; i+delay < len(out) && i < len(ref); i++ {
+			diff := float64(out[i+delay]) - float64(ref[i])
+			signal += float64(ref[i]) * float64(ref[i])
+			noise += diff * diff
+		}
+		if noise == 0 {
+			return math.Inf(1)
+		}
+		best = math.Max(best, 10*math.Log10(signal/noise))
+	}
+	return best
+}
+
+func TestG711RoundTrip(t *testing.T) {
+	in := genTone(8000, 440, 0.5, 1600)
+	for _, c := range []Codec{PCMU, PCMA} {
+		t.Run(c.Name, func(t *testing.T) {
+			enc, err := NewEncoder(c)
+			require.NoError(t, err)
+			dec, err := NewDecoder(c)
+			require.NoError(t, err)
+
+			payload, err := enc.Encode(in)
+			require.NoError(t, err)
+			assert.Len(t, payload, len(in))
+
+			out, err := dec.Decode(payload)
+			require.NoError(t, err)
+			assert.Greater(t, snr(in, out, 0), 30.0)
+		})
+	}
+}
+
+func TestG722RoundTrip(t *testing.T) {
+	enc := NewG722Encoder()
+	dec := NewG722Decoder()
+
+	for _, freq := range []float64{300, 1000, 3000, 6000} {
+		in := genTone(16000, freq, 0.3, 16000)
+
+		// 按 20ms 分包编码，验证跨包状态保持
+		var out []int16
+		for i := 0; i < len(in); i += 320 {
+			payload, err := enc.Encode(in[i : i+320])
+			require.NoError(t, err)
+			require.Len(t, payload, 160)
+
+			pcm, err := dec.Decode(payload)
+			require.NoError(t, err)
+			out = append(out, pcm...)
+		}
+		require.Len(t, out, len(in))
+
+		// 跳过自适应收敛阶段
+		ratio := snr(in[1600:len(in)-64], out[1600:], 64)
+		assert.Greater(t, ratio, 20.0, "%.0f Hz: SNR %.1f dB", freq, ratio)
+	}
+
+	_, err := enc.Encode(make([]int16, 3))
+	assert.Error(t, err)
+}
+
+func TestG722Silence(t *testing.T) {
+	payload, err := NewG722Encoder().Encode(make([]int16, 320))
+	require.NoError(t, err)
+	out, err := NewG722Decoder().Decode(payload)
+	require.NoError(t, err)
+	for _, v := range out {
+		assert.LessOrEqual(t, math.Abs(float64(v)), 4.0)
+	}
+}
+
+const testOffer = `v=0
+o=- 4215775240449105457 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF %s
+c=IN IP4 0.0.0.0
+a=mid:0
+a=sendrecv
+%s`
+
+func offerWith(formats string, rtpmaps string) string {
+	return strings.ReplaceAll(fmt.Sprintf(testOffer, formats, rtpmaps), "\n", "\r\n")
+}
+
+func TestSelectFromOffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		formats  string
+		rtpmaps  string
+		expected Codec
+		wantErr  bool
+	}{
+		{
+			name:     "browser prefers opus",
+			formats:  "111 9 0 8",
+			rtpmaps:  "a=rtpmap:111 opus/48000/2\na=rtpmap:9 G722/8000\na=rtpmap:0 PCMU/8000\na=rtpmap:8 PCMA/8000\n",
+			expected: Opus,
+		},
+		{
+			name:     "sip gateway offers g711 only",
+			formats:  "8 0",
+			rtpmaps:  "",
+			expected: PCMU,
+		},
+		{
+			name:     "pcma only",
+			formats:  "8 101",
+			rtpmaps:  "a=rtpmap:8 PCMA/8000\na=rtpmap:101 telephone-event/8000\n",
+			expected: PCMA,
+		},
+		{
+			name:     "g722 without rtpmap",
+			formats:  "9 0",
+			rtpmaps:  "",
+			expected: G722,
+		},
+		{
+			name:    "unsupported",
+			formats: "18",
+			rtpmaps: "a=rtpmap:18 G729/8000\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := SelectFromOffer(offerWith(tt.formats, tt.rtpmaps))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, c)
+		})
+	}
+}
+
+func TestRegisterCodecs(t *testing.T) {
+	m := &webrtc.MediaEngine{}
+	require.NoError(t, RegisterCodecs(m))
+
+	c, ok := Lookup("audio/pcmu")
+	require.True(t, ok)
+	assert.Equal(t, PCMU, c)
+	assert.Equal(t, 160, c.FrameSamples())
+	assert.Equal(t, 320, G722.FrameSamples())
+	assert.True(t, Opus.IsOpus())
+	assert.False(t, PCMA.IsOpus())
+
+	_, ok = Lookup("audio/G729")
+	assert.False(t, ok)
+}
+
+func TestTelephoneEventFor(t *testing.T) {
+	te, ok := TelephoneEventFor(Opus)
+	require.True(t, ok)
+	assert.Equal(t, uint32(48000), te.ClockRate)
+
+	// G.722 的 RTP 时钟为 8kHz，事件也使用 8kHz
+	for _, c := range []Codec{G722, PCMU, PCMA} {
+		te, ok := TelephoneEventFor(c)
+		require.True(t, ok)
+		assert.Equal(t, TelephoneEvent8k, te)
+	}
+}
@@ -0,0 +1,323 @@
+package codec
+
+import "fmt"
+
+// G.722 子带 ADPCM（ITU-T G.722，64 kbit/s 模式）
+// 16kHz 输入经 QMF 分为高低两个 8kHz 子带，低带 6 bit、高带 2 bit 量化，每两个输入样本输出一个字节
+
+var (
+	g722QMFCoeffs = [12]int{3, -11, 12, 32, -210, 951, 3876, -805, 362, -156, 53, -11}
+
+	g722Q6   = [32]int{0, 35, 72, 110, 150, 190, 233, 276, 323, 370, 422, 473, 530, 587, 650, 714, 786, 858, 940, 1023, 1121, 1219, 1339, 1458, 1612, 1765, 1980, 2195, 2557, 2919, 0, 0}
+	g722ILN  = [32]int{0, 63, 62, 31, 30, 29, 28, 27, 26, 25, 24, 23, 22, 21, 20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 0}
+	g722ILP  = [32]int{0, 61, 60, 59, 58, 57, 56, 55, 54, 53, 52, 51, 50, 49, 48, 47, 46, 45, 44, 43, 42, 41, 40, 39, 38, 37, 36, 35, 34, 33, 32, 0}
+	g722WL   = [8]int{-60, -30, 58, 172, 334, 538, 1198, 3042}
+	g722RL42 = [16]int{0, 7, 6, 5, 4, 3, 2, 1, 7, 6, 5, 4, 3, 2, 1, 0}
+	g722ILB  = [32]int{2048, 2093, 2139, 2186, 2233, 2282, 2332, 2383, 2435, 2489, 2543, 2599, 2656, 2714, 2774, 2834, 2896, 2960, 3025, 3091, 3158, 3228, 3298, 3371, 3444, 3520, 3597, 3676, 3756, 3838, 3922, 4008}
+	g722QM4  = [16]int{0, -20456, -12896, -8968, -6288, -4240, -2584, -1200, 20456, 12896, 8968, 6288, 4240, 2584, 1200, 0}
+	g722QM6  = [64]int{
+		-136, -136, -136, -136, -24808, -21904, -19008, -16704,
+		-14984, -13512, -12280, -11192, -10232, -9360, -8576, -7856,
+		-7192, -6576, -6000, -5456, -4944, -4464, -4008, -3576,
+		-3168, -2776, -2400, -2032, -1688, -1360, -1040, -728,
+		24808, 21904, 19008, 16704, 14984, 13512, 12280, 11192,
+		10232, 9360, 8576, 7856, 7192, 6576, 6000, 5456,
+		4944, 4464, 4008, 3576, 3168, 2776, 2400, 2032,
+		1688, 1360, 1040, 728, 432, 136, -432, -136,
+	}
+	g722QM2 = [4]int{-7408, -1616, 7408, 1616}
+	g722IHN = [3]int{0, 1, 0}
+	g722IHP = [3]int{0, 3, 2}
+	g722WH  = [3]int{0, -214, 798}
+	g722RH2 = [4]int{2, 1, 2, 1}
+)
+
+// g722Band 一个子带的 ADPCM 预测器状态
+type g722Band struct {
+	s   int
+	sp  int
+	sz  int
+	r   [3]int
+	a   [3]int
+	ap  [3]int
+	p   [3]int
+	d   [7]int
+	b   [7]int
+	bp  [7]int
+	sg  [7]int
+	nb  int
+	det int
+}
+
+// g722State 编解码器共用的状态：两个子带预测器和 QMF 延迟线
+type g722State struct {
+	band [2]g722Band
+	x    [24]int
+}
+
+func newG722State() g722State {
+	var s g722State
+	s.band[0].det = 32
+	s.band[1].det = 8
+	return s
+}
+
+func saturate16(v int) int {
+	if v > 32767 {
+		return 32767
+	}
+	if v < -32768 {
+		return -32768
+	}
+	return v
+}
+
+// g722Scale 根据对数量化步长 nb 计算线性步长 det
+func g722Scale(nb, shift int) int {
+	wd1 := (nb >> 6) & 31
+	wd2 := shift - (nb >> 11)
+	var wd3 int
+	if wd2 < 0 {
+		wd3 = g722ILB[wd1] << -wd2
+	} else {
+		wd3 = g722ILB[wd1] >> wd2
+	}
+	return wd3 << 2
+}
+
+// update 更新子带的自适应预测器（G.722 Block 4）
+func (b *g722Band) update(d int) {
+	// RECONS / PARREC
+	b.d[0] = d
+	b.r[0] = saturate16(b.s + d)
+	b.p[0] = saturate16(b.sz + d)
+
+	// UPPOL2
+	for i := 0; i < 3; i++ {
+		b.sg[i] = b.p[i] >> 15
+	}
+	wd1 := saturate16(b.a[1] << 2)
+	wd2 := wd1
+	if b.sg[0] == b.sg[1] {
+		wd2 = -wd1
+	}
+	if wd2 > 32767 {
+		wd2 = 32767
+	}
+	wd3 := wd2 >> 7
+	if b.sg[0] == b.sg[2] {
+		wd3 += 128
+	} else {
+		wd3 -= 128
+	}
+	wd3 += (b.a[2] * 32512) >> 15
+	if wd3 > 12288 {
+		wd3 = 12288
+	} else if wd3 < -12288 {
+		wd3 = -12288
+	}
+	b.ap[2] = wd3
+
+	// UPPOL1
+	b.sg[0] = b.p[0] >> 15
+	b.sg[1] = b.p[1] >> 15
+	wd1 = -192
+	if b.sg[0] == b.sg[1] {
+		wd1 = 192
+	}
+	wd2 = (b.a[1] * 32640) >> 15
+	b.ap[1] = saturate16(wd1 + wd2)
+	wd3 = saturate16(15360 - b.ap[2])
+	if b.ap[1] > wd3 {
+		b.ap[1] = wd3
+	} else if b.ap[1] < -wd3 {
+		b.ap[1] = -wd3
+	}
+
+	// UPZERO
+	wd1 = 128
+	if d == 0 {
+		wd1 = 0
+	}
+	b.sg[0] = d >> 15
+	for i := 1; i < 7; i++ {
+		b.sg[i] = b.d[i] >> 15
+		wd2 = -wd1
+		if b.sg[i] == b.sg[0] {
+			wd2 = wd1
+		}
+		wd3 = (b.b[i] * 32640) >> 15
+		b.bp[i] = saturate16(wd2 + wd3)
+	}
+
+	// DELAYA
+	for i := 6; i > 0; i-- {
+		b.d[i] = b.d[i-1]
+		b.b[i] = b.bp[i]
+	}
+	for i := 2; i > 0; i-- {
+		b.r[i] = b.r[i-1]
+		b.p[i] = b.p[i-1]
+		b.a[i] = b.ap[i]
+	}
+
+	// FILTEP
+	wd1 = saturate16(b.r[1] + b.r[1])
+	wd1 = (b.a[1] * wd1) >> 15
+	wd2 = saturate16(b.r[2] + b.r[2])
+	wd2 = (b.a[2] * wd2) >> 15
+	b.sp = saturate16(wd1 + wd2)
+
+	// FILTEZ
+	b.sz = 0
+	for i := 6; i > 0; i-- {
+		wd1 = saturate16(b.d[i] + b.d[i])
+		b.sz += (b.b[i] * wd1) >> 15
+	}
+	b.sz = saturate16(b.sz)
+
+	// PREDIC
+	b.s = saturate16(b.sp + b.sz)
+}
+
+// updateLowScale 低带对数步长自适应（LOGSCL/SCALEL）
+func (b *g722Band) updateLowScale(ril int) {
+	nb := (b.nb*127)>>7 + g722WL[g722RL42[ril]]
+	b.nb = min(max(nb, 0), 18432)
+	b.det = g722Scale(b.nb, 8)
+}
+
+// updateHighScale 高带对数步长自适应（LOGSCH/SCALEH）
+func (b *g722Band) updateHighScale(ihigh int) {
+	nb := (b.nb*127)>>7 + g722WH[g722RH2[ihigh]]
+	b.nb = min(max(nb, 0), 22528)
+	b.det = g722Scale(b.nb, 10)
+}
+
+// G722Encoder G.722 64 kbit/s 编码器，输入 16kHz 单声道 PCM
+type G722Encoder struct {
+	g722State
+}
+
+func NewG722Encoder() *G722Encoder {
+	return &G722Encoder{g722State: newG722State()}
+}
+
+// Encode 编码偶数个样本，每两个样本输出一个字节
+func (e *G722Encoder) Encode(pcm []int16) ([]byte, error) {
+	if len(pcm)%2 != 0 {
+		return nil, fmt.Errorf("g722: sample count %d is not even", len(pcm))
+	}
+
+	out := make([]byte, len(pcm)/2)
+	for j := range out {
+		// 发送端 QMF：移入两个样本，只保留抽取后的输出
+		copy(e.x[:22], e.x[2:])
+		e.x[22] = int(pcm[2*j])
+		e.x[23] = int(pcm[2*j+1])
+		var sumEven, sumOdd int
+		for i := 0; i < 12; i++ {
+			sumOdd += e.x[2*i] * g722QMFCoeffs[i]
+			sumEven += e.x[2*i+1] * g722QMFCoeffs[11-i]
+		}
+		xlow := (sumEven + sumOdd) >> 14
+		xhigh := (sumEven - sumOdd) >> 14
+
+		// 低带：SUBTRA / QUANTL
+		low := &e.band[0]
+		el := saturate16(xlow - low.s)
+		wd := el
+		if el < 0 {
+			wd = -(el + 1)
+		}
+		i := 1
+		for ; i < 30; i++ {
+			if wd < (g722Q6[i]*low.det)>>12 {
+				break
+			}
+		}
+		ilow := g722ILP[i]
+		if el < 0 {
+			ilow = g722ILN[i]
+		}
+
+		// INVQAL：预测器只使用 4 bit 的量化值
+		ril := ilow >> 2
+		dlow := (low.det * g722QM4[ril]) >> 15
+		low.updateLowScale(ril)
+		low.update(dlow)
+
+		// 高带：SUBTRA / QUANTH
+		high := &e.band[1]
+		eh := saturate16(xhigh - high.s)
+		wd = eh
+		if eh < 0 {
+			wd = -(eh + 1)
+		}
+		mih := 1
+		if wd >= (564*high.det)>>12 {
+			mih = 2
+		}
+		ihigh := g722IHP[mih]
+		if eh < 0 {
+			ihigh = g722IHN[mih]
+		}
+
+		// INVQAH
+		dhigh := (high.det * g722QM2[ihigh]) >> 15
+		high.updateHighScale(ihigh)
+		high.update(dhigh)
+
+		out[j] = byte(ihigh<<6 | ilow)
+	}
+	return out, nil
+}
+
+// G722Decoder G.722 64 kbit/s 解码器，输出 16kHz 单声道 PCM
+type G722Decoder struct {
+	g722State
+}
+
+func NewG722Decoder() *G722Decoder {
+	return &G722Decoder{g722State: newG722State()}
+}
+
+// Decode 解码一个负载，每个字节输出两个样本
+func (d *G722Decoder) Decode(payload []byte) ([]int16, error) {
+	out := make([]int16, 0, len(payload)*2)
+	for _, code := range payload {
+		ilow := int(code) & 0x3F
+		ihigh := int(code>>6) & 0x03
+
+		// 低带：INVQBL / RECONS / LIMIT
+		low := &d.band[0]
+		rlow := low.s + (low.det*g722QM6[ilow])>>15
+		rlow = min(max(rlow, -16384), 16383)
+
+		// INVQAL
+		ril := ilow >> 2
+		dlow := (low.det * g722QM4[ril]) >> 15
+		low.updateLowScale(ril)
+		low.update(dlow)
+
+		// 高带：INVQAH / RECONS / LIMIT
+		high := &d.band[1]
+		dhigh := (high.det * g722QM2[ihigh]) >> 15
+		rhigh := dhigh + high.s
+		rhigh = min(max(rhigh, -16384), 16383)
+		high.updateHighScale(ihigh)
+		high.update(dhigh)
+
+		// 接收端 QMF
+		copy(d.x[:22], d.x[2:])
+		d.x[22] = rlow + rhigh
+		d.x[23] = rlow - rhigh
+		var xout1, xout2 int
+		for i := 0; i < 12; i++ {
+			xout2 += d.x[2*i] * g722QMFCoeffs[i]
+			xout1 += d.x[2*i+1] * g722QMFCoeffs[11-i]
+		}
+		out = append(out, int16(saturate16(xout1>>11)), int16(saturate16(xout2>>11)))
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package codec
+
+import (
+	"fmt"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/utils"
+)
+
+// Encoder 将 S16 PCM 编码为一个 RTP 负载
+type Encoder interface {
+	Encode(pcm []int16) ([]byte, error)
+}
+
+// Decoder 将一个 RTP 负载解码为 S16 PCM
+type Decoder interface {
+	Decode(payload []byte) ([]int16, error)
+}
+
+// NewEncoder 创建纯 Go 实现的编码器（G.711/G.722），Opus 需使用 libopus
+func NewEncoder(c Codec) (Encoder, error) {
+	switch c.Name {
+	case PCMU.Name:
+		return muLawCodec{}, nil
+	case PCMA.Name:
+		return aLawCodec{}, nil
+	case G722.Name:
+		return NewG722Encoder(), nil
+	default:
+		return nil, fmt.Errorf("no pure-Go encoder for %s", c)
+	}
+}
+
+// NewDecoder 创建纯 Go 实现的解码器（G.711/G.722），Opus 需使用 libopus
+func NewDecoder(c Codec) (Decoder, error) {
+	switch c.Name {
+	case PCMU.Name:
+		return muLawCodec{}, nil
+	case PCMA.Name:
+		return aLawCodec{}, nil
+	case G722.Name:
+		return NewG722Decoder(), nil
+	default:
+		return nil, fmt.Errorf("no pure-Go decoder for %s", c)
+	}
+}
+
+// muLawCodec G.711 µ-law，无状态
+type muLawCodec struct{}
+
+func (muLawCodec) Encode(pcm []int16) ([]byte, error) {
+	return utils.Int16SliceToMuLaw(pcm), nil
+}
+
+func (muLawCodec) Decode(payload []byte) ([]int16, error) {
+	return utils.MuLawToInt16Slice(payload), nil
+}
+
+// aLawCodec G.711 A-law，无状态
+type aLawCodec struct{}
+
+func (aLawCodec) Encode(pcm []int16) ([]byte, error) {
+	return utils.Int16SliceToALaw(pcm), nil
+}
+
+func (aLawCodec) Decode(payload []byte) ([]int16, error) {
+	return utils.ALawToInt16Slice(payload), nil
+}
@@ -3,13 +3,27 @@ package connection
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v4"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/codec"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/dtmf"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/elements"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/recording"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/speech"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
 	"google.golang.org/genai"
 )
 
@@ -19,24 +33,65 @@ const (
 	frameSize     = 960      // 20ms @ 48kHz
 	opusFrameSize = 960      // 20ms @ 48kHz
 	maxDataBytes  = 1000 * 2 // Buffer for Opus encoded data
+
+	// 建立模型会话（含 setup 握手）的超时，服务端不回复时不会一直等待
+	sessionConnectTimeout = 15 * time.Second
 )
 
 type RTCConnectionWrapper struct {
-	id               string
-	genaiSession     *genai.Session
+	id      string
+	model   realtime.Model
+	session realtime.Session
+	// 客户端在 /session 请求中指定的会话参数，工具、转写和恢复句柄由 dialSession 填充
+	sessionOptions realtime.ConnectOptions
+
 	pc               *webrtc.PeerConnection
 	dataChannel      *webrtc.DataChannel
 	remoteAudioTrack *webrtc.TrackRemote
 	localAudioTrack  *webrtc.TrackLocalStaticSample
 
+	// 本会话协商的音频编码，上下行使用同一种
+	codec codec.Codec
+	// 远端 telephone-event 的 payload type，未协商时为 0
+	telephoneEventPT webrtc.PayloadType
+
+	// 模型可调用的函数，在会话 setup 中声明
+	tools *tools.Registry
+	// 模型只输出文本时用于合成语音，为 nil 时文本只经 DataChannel 发送
+	ttsEngine speech.TTSEngine
+	// 非 nil 时使用级联模式（STT → LLM → TTS）代替实时模型
+	cascade *speech.Cascade
+	// 非 nil 时录制整个通话，Stop 时定稿
+	recorder *recording.Recorder
+	// Opus 格式录音时非 nil，远端 RTP 包原样写入
+	opusRecording *recording.OpusRecording
+	// 非 nil 时在其中开启的转储点保存本会话的音频
+	dumpConfig    *audio.DumpConfig
+	playoutDumper *audio.Dumper
+	// 非空时用该文件模拟麦克风，代替远端的上行音频
+	micFile    string
+	micOptions elements.FileSourceOptions
+	micSource  *elements.FileSourceElement
+
 	webrtcSinkElement       *elements.WebRTCSinkElement
-	opusDecodeElement       *elements.OpusDecodeElement
+	jitterBufferElement     *elements.JitterBufferElement
+	decodeElement           pipeline.Element
+	channelMixElement       *elements.ChannelMixElement
+	dataChannelSinkElement  *elements.DataChannelSinkElement
+	dtmfDetectElement       *elements.DTMFDetectElement
+	recordElement           *elements.RecordElement
 	opusEncodeElement       *elements.OpusEncodeElement
 	inAudioResampleElement  *elements.AudioResampleElement
 	outAudioResampleElement *elements.AudioResampleElement
-	geminiElement           *elements.GeminiElement
+	realtimeElement         *elements.RealtimeElement
+	sttElement              *elements.STTElement
+	chatElement             *elements.ChatElement
 
 	pipeline *pipeline.Pipeline
+	bus      *pipeline.EventBus
+
+	// 根据 RTCP 接收报告调整下行编码参数
+	encoderController *audio.EncoderController
 
 	cancel context.CancelFunc
 	ctx    context.Context // 供整个 PeerConnection 生命周期使用
@@ -52,97 +107,513 @@ func NewRTCConnectionWrapper(id string, pc *webrtc.PeerConnection) *RTCConnectio
 		cancel:      cancel,
 		ctx:         ctx,
 		dataChannel: nil,
+		bus:         pipeline.NewEventBus(),
+		codec:       codec.Opus,
+		model:       DefaultGeminiModel(),
+		tools:       tools.NewRegistry(),
+		encoderController: audio.NewEncoderController(
+			audio.DefaultMinBitrate, audio.DefaultMaxBitrate, audio.DefaultStartBitrate,
+			os.Getenv("OPUS_ENABLE_DTX") == "true"),
 	}
 }
 
-func (c *RTCConnectionWrapper) InitAISession(ctx context.Context) error {
-
-	apiKey := os.Getenv("GOOGLE_API_KEY")
-
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey, Backend: genai.BackendGoogleAI})
-	if err != nil {
-		log.Fatal("create client error: ", err)
-		return err
+// geminiClientConfig 读取 GOOGLE_API_KEY 和 GEMINI_BASE_URL（如指向 livetest 模拟服务端）
+func geminiClientConfig() live.ClientConfig {
+	return live.ClientConfig{
+		APIKey:  os.Getenv("GOOGLE_API_KEY"),
+		BaseURL: os.Getenv("GEMINI_BASE_URL"),
 	}
+}
 
-	session, err := client.Live.Connect("gemini-2.0-flash-exp", &genai.LiveConnectConfig{
-		ResponseModalities: []string{"AUDIO"},
+// DefaultGeminiModel 按环境变量创建 Gemini 模型：GOOGLE_API_KEY、GEMINI_BASE_URL，
+// 以及默认开启、GEMINI_CONTEXT_COMPRESSION=false 时关闭的上下文压缩
+func DefaultGeminiModel() *realtime.Gemini {
+	return realtime.NewGemini(realtime.GeminiConfig{
+		Client:             geminiClientConfig(),
+		ContextCompression: os.Getenv("GEMINI_CONTEXT_COMPRESSION") != "false",
 	})
+}
+
+// InitAISession 建立模型会话。失败时返回错误，RealtimeElement 启动后会按重连策略继续尝试
+func (c *RTCConnectionWrapper) InitAISession(ctx context.Context) error {
+	// 级联模式没有模型会话
+	if c.cascade != nil {
+		return nil
+	}
+	session, err := c.dialSession(ctx, elements.DialOptions{})
 	if err != nil {
-		log.Fatal("connect to model error: ", err)
-		return err
+		return fmt.Errorf("connect to %s: %w", c.model.Name(), err)
 	}
 
-	c.genaiSession = session
+	c.session = session
 
 	return nil
 }
 
+// dialSession 建立模型会话，供初次连接、断线重连和长会话切换使用。
+// 开启双向音频转写用于实时字幕，会话恢复和上下文压缩由各模型按能力处理
+func (c *RTCConnectionWrapper) dialSession(ctx context.Context, opts elements.DialOptions) (realtime.Session, error) {
+	connectOpts := c.sessionOptions
+	connectOpts.Tools = c.tools.Declarations()
+	connectOpts.Transcription = true
+	connectOpts.ResumeHandle = opts.Handle
+	if opts.Summary != "" {
+		summary := elements.SummaryInstruction(opts.Summary)
+		if connectOpts.Instructions != "" {
+			summary = connectOpts.Instructions + "\n\n" + summary
+		}
+		connectOpts.Instructions = summary
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sessionConnectTimeout)
+	defer cancel()
+	return c.model.Connect(ctx, connectOpts)
+}
+
+// SetSessionOptions 设置模型、语音、语言、系统指令等会话参数，需在 InitAISession 之前调用
+func (c *RTCConnectionWrapper) SetSessionOptions(opts realtime.ConnectOptions) {
+	c.sessionOptions = opts
+}
+
+// SetModel 设置本会话使用的实时模型，需在 InitAISession 之前调用
+func (c *RTCConnectionWrapper) SetModel(m realtime.Model) {
+	c.model = m
+}
+
+// SetTools 设置模型可调用的函数，需在 InitAISession 之前调用
+func (c *RTCConnectionWrapper) SetTools(r *tools.Registry) {
+	if r == nil {
+		r = tools.NewRegistry()
+	}
+	c.tools = r
+}
+
+// SetTTSEngine 设置只输出文本的会话使用的语音合成引擎，需在 Start 之前调用
+func (c *RTCConnectionWrapper) SetTTSEngine(engine speech.TTSEngine) {
+	c.ttsEngine = engine
+}
+
+// SetCascade 使用级联模式代替实时模型，会话参数中的系统指令和 turn_detection 仍然有效，
+// 需在 InitAISession 之前调用
+func (c *RTCConnectionWrapper) SetCascade(cascade *speech.Cascade) {
+	c.cascade = cascade
+}
+
+// SetRecorder 录制本会话：用户在左声道、助手在右声道，采样率为协商编码的采样率；
+// 录音格式为 Opus 且协商到 Opus 时改为两路 Ogg Opus。需在 Start 之前调用
+func (c *RTCConnectionWrapper) SetRecorder(r *recording.Recorder) {
+	c.recorder = r
+}
+
+// SetDumpConfig 设置调试用的音频转储，需在 Start 之前调用
+func (c *RTCConnectionWrapper) SetDumpConfig(config *audio.DumpConfig) {
+	c.dumpConfig = config
+}
+
+// SetMicrophoneFile 用 WAV 或 Ogg Opus 文件模拟麦克风：文件按 opts 播放并代替远端的上行音频，
+// 远端的 RTP 包仍用于按键检测和 Opus 录音。Ogg Opus 只能用于 Opus 会话。需在 Start 之前调用
+func (c *RTCConnectionWrapper) SetMicrophoneFile(path string, opts elements.FileSourceOptions) {
+	c.micFile = path
+	c.micOptions = opts
+}
+
+// SetAudioCodec 设置本会话使用的音频编码，需在 Start 之前调用
+func (c *RTCConnectionWrapper) SetAudioCodec(ac codec.Codec) {
+	c.codec = ac
+}
+
 func (c *RTCConnectionWrapper) Start(ctx context.Context, pc *webrtc.PeerConnection) error {
 
 	c.pc = pc
 
+	// 模型文本和会话事件经 DataChannel 发给客户端，音频透传给 webrtcSinkElement；
+	// DataChannel 由客户端创建，打开之前的消息由该元素缓存
+	dataChannelSinkElement := elements.NewDataChannelSinkElement(100)
+	c.dataChannelSinkElement = dataChannelSinkElement
+
 	pc.OnDataChannel(func(d *webrtc.DataChannel) {
 		log.Printf("DataChannel created: %s", d.Label())
 
 		c.dataChannel = d
+		dataChannelSinkElement.SetDataChannel(d)
 
 		go c.readDataChannel(ctx)
 	})
 
 	pc.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
 		log.Printf("OnTrack: %v, codec: %v", track.ID(), track.Codec().MimeType)
+		if !strings.EqualFold(track.Codec().MimeType, c.codec.MimeType) {
+			log.Printf("remote track codec %s does not match negotiated %s", track.Codec().MimeType, c.codec)
+		}
 		if track.Kind() == webrtc.RTPCodecTypeAudio {
+			c.telephoneEventPT = telephoneEventPayloadType(receiver)
 			c.remoteAudioTrack = track
 			go c.readRemoteAudio(ctx)
 		}
 	})
 
-	audioTrack, audioTrackErr := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "pion")
+	audioTrack, audioTrackErr := webrtc.NewTrackLocalStaticSample(c.codec.Capability(), "audio", "pion")
 	if audioTrackErr != nil {
 		log.Println("create local audio track error:", audioTrackErr)
 		return audioTrackErr
 	}
 	c.localAudioTrack = audioTrack
 
-	pc.AddTransceiverFromTrack(c.localAudioTrack, webrtc.RTPTransceiverInit{
+	transceiver, err := pc.AddTransceiverFromTrack(c.localAudioTrack, webrtc.RTPTransceiverInit{
 		Direction: webrtc.RTPTransceiverDirectionSendrecv,
 	})
+	if err != nil {
+		log.Println("add transceiver error:", err)
+		return err
+	}
+	// 应答中只保留选定的编码（以及同时钟频率的 telephone-event），保证远端按该编码发送
+	preferences := []webrtc.RTPCodecParameters{c.codec.Parameters()}
+	if te, ok := codec.TelephoneEventFor(c.codec); ok {
+		preferences = append(preferences, te.Parameters())
+	}
+	if err := transceiver.SetCodecPreferences(preferences); err != nil {
+		log.Println("set codec preferences error:", err)
+		return err
+	}
 
-	webrtcSinkElement := elements.NewWebRTCSinkElement(100, c.localAudioTrack)
-	geminiElement := elements.NewGeminiElement()
-	geminiElement.SetSession(c.genaiSession)
+	webrtcSinkElement, err := elements.NewWebRTCSinkElementWithCodec(100, c.localAudioTrack, c.codec)
+	if err != nil {
+		return err
+	}
+
+	// SDP 中 Opus 固定声明为 2 声道，浏览器开启 stereo 时会发送真正的立体声，
+	// 这里按立体声解码，再由 ChannelMixElement 下混为单声道
+	downmix, err := audio.ParseDownmixStrategy(os.Getenv("AUDIO_DOWNMIX"))
+	if err != nil {
+		return err
+	}
+	jitterBufferElement := elements.NewJitterBufferElement(100, int(c.codec.ClockRate), c.codec.Channels)
+	decodeElement, err := elements.NewDecodeElement(100, c.codec)
+	if err != nil {
+		return err
+	}
+	channelMixElement, err := elements.NewChannelMixElement(100, 1, downmix)
+	if err != nil {
+		return err
+	}
+	// 可选的带内按键检测，用于不发送 telephone-event 的终端（如经网关接入的话机）
+	var dtmfDetectElement *elements.DTMFDetectElement
+	if os.Getenv("DTMF_INBAND") == "true" {
+		dtmfDetectElement = elements.NewDTMFDetectElement(100, c.handleDTMF)
+	}
 
-	opusDecodeElement := elements.NewOpusDecodeElement(100, 48000, 1)
-	inAudioResampleElement := elements.NewAudioResampleElement(48000, 16000, 1, 1)
+	// 通话录音：用户取下混后的上行音频，助手取下行实际播放的每一帧，两者都是协商编码的采样率。
+	// Opus 格式直接封装远端 RTP 包和下行编码器的输出，不解码也不重新编码。
+	// 模拟麦克风时远端 RTP 不是模型听到的音频，改用 WAV 录下文件源的音频
+	var recordElement *elements.RecordElement
+	var playoutTaps []func(pcm []byte)
+	if c.recorder != nil && c.recorder.Format == recording.FormatOpus && c.codec.IsOpus() && c.micFile == "" {
+		rec, err := c.recorder.StartOpus(c.id, c.codec.Channels)
+		if err != nil {
+			return fmt.Errorf("start recording: %w", err)
+		}
+		c.opusRecording = rec
+		webrtcSinkElement.SetPacketTap(func(packet []byte) {
+			if err := rec.WriteAssistant(packet); err != nil {
+				log.Printf("record error: %v", err)
+			}
+		})
+	} else if c.recorder != nil {
+		rec, err := c.recorder.Start(c.id, c.codec.SampleRate)
+		if err != nil {
+			return fmt.Errorf("start recording: %w", err)
+		}
+		recordElement = elements.NewRecordElement(rec, audio.RecordUser)
+		playoutTaps = append(playoutTaps, func(pcm []byte) {
+			if err := rec.Write(audio.RecordAssistant, pcm); err != nil {
+				log.Printf("record error: %v", err)
+			}
+		})
+	}
+	if c.dumpConfig.Enabled(audio.DumpPointPlayout) {
+		dumper, err := c.dumpConfig.NewDumper(c.id, audio.DumpPointPlayout, c.codec.SampleRate, 1)
+		if err != nil {
+			log.Println("create playout dumper error:", err)
+		} else {
+			c.playoutDumper = dumper
+			playoutTaps = append(playoutTaps, func(pcm []byte) {
+				if err := dumper.Write(pcm); err != nil {
+					log.Printf("playout dump error: %v", err)
+				}
+			})
+		}
+	}
+	if len(playoutTaps) > 0 {
+		webrtcSinkElement.SetPlayoutTap(func(pcm []byte) {
+			for _, tap := range playoutTaps {
+				tap(pcm)
+			}
+		})
+	}
 
-	elements := []pipeline.Element{
-		opusDecodeElement,
-		inAudioResampleElement,
-		geminiElement,
-		webrtcSinkElement,
+	// 模型部分：实时模型，或级联模式的 STT → LLM → TTS，输入为单声道上行音频，
+	// 输出 24kHz 音频和文本
+	var inputSampleRate int
+	var modelElements []pipeline.Element
+	if c.cascade != nil {
+		inputSampleRate = c.cascade.STT.SampleRate()
+		modelElements, err = c.cascadeElements()
+	} else {
+		inputSampleRate = c.model.InputSampleRate()
+		modelElements, err = c.realtimeElements()
+	}
+	if err != nil {
+		return err
 	}
+	inAudioResampleElement := elements.NewAudioResampleElement(c.codec.SampleRate, inputSampleRate, channelMixElement.OutChannels(), 1)
 
-	pipeline := pipeline.NewPipeline(elements)
-	pipeline.Link(opusDecodeElement, inAudioResampleElement)
-	pipeline.Link(inAudioResampleElement, geminiElement)
-	pipeline.Link(geminiElement, webrtcSinkElement)
+	// 整条管线是一条链：抖动缓冲 → 解码 → 下混 → 录音、带内按键检测（透传）→ 重采样 → 模型
+	// → DataChannel → 下行播放，开启的转储点处插入透传的 DumpElement
+	chain, err := c.uplinkElements(jitterBufferElement, decodeElement)
+	if err != nil {
+		return err
+	}
+	chain = append(chain, c.dumpElements(audio.DumpPointUplink)...)
+	chain = append(chain, channelMixElement)
+	if recordElement != nil {
+		chain = append(chain, recordElement)
+	}
+	if dtmfDetectElement != nil {
+		chain = append(chain, dtmfDetectElement)
+	}
+	chain = append(chain, inAudioResampleElement)
+	chain = append(chain, c.dumpElements(audio.DumpPointModelInput)...)
+	chain = append(chain, modelElements...)
+	chain = append(chain, c.dumpElements(audio.DumpPointModelOutput)...)
+	chain = append(chain, dataChannelSinkElement, webrtcSinkElement)
+
+	pipeline := pipeline.NewPipeline(chain)
+	for i := 1; i < len(chain); i++ {
+		pipeline.Link(chain[i-1], chain[i])
+	}
 
 	c.webrtcSinkElement = webrtcSinkElement
-	c.opusDecodeElement = opusDecodeElement
+	c.jitterBufferElement = jitterBufferElement
+	c.decodeElement = decodeElement
+	c.channelMixElement = channelMixElement
+	c.dtmfDetectElement = dtmfDetectElement
+	c.recordElement = recordElement
 	c.inAudioResampleElement = inAudioResampleElement
-	c.geminiElement = geminiElement
 
 	c.pipeline = pipeline
 
+	if err := c.bus.Start(ctx); err != nil {
+		return err
+	}
+
+	if c.codec.IsOpus() {
+		if err := webrtcSinkElement.ApplyEncoderSettings(c.encoderController.Settings()); err != nil {
+			log.Println("apply initial encoder settings error:", err)
+		}
+	}
+	go c.readSenderRTCP(c.ctx, transceiver.Sender())
+
 	return pipeline.Start(ctx)
 }
 
+// uplinkElements 返回管线开头产生上行音频的元素。模拟麦克风时文件源在最前面：
+// Ogg Opus 与远端 RTP 一样经抖动缓冲和解码，WAV 重采样到协商编码的采样率
+func (c *RTCConnectionWrapper) uplinkElements(jitterBuffer, decode pipeline.Element) ([]pipeline.Element, error) {
+	if c.micFile == "" {
+		return []pipeline.Element{jitterBuffer, decode}, nil
+	}
+	src, err := elements.NewFileSourceElement(c.micFile, c.micOptions)
+	if err != nil {
+		return nil, fmt.Errorf("open microphone file: %w", err)
+	}
+	switch src.MediaType() {
+	case c.codec.MediaType:
+		c.micSource = src
+		return []pipeline.Element{src, jitterBuffer, decode}, nil
+	case "audio/x-raw":
+		c.micSource = src
+		resample := elements.NewAudioResampleElement(src.SampleRate(), c.codec.SampleRate, src.Channels(), src.Channels())
+		return []pipeline.Element{src, resample}, nil
+	}
+	return nil, fmt.Errorf("cannot play %s on a %s call", c.micFile, c.codec)
+}
+
+// dumpElements 转储点开启时返回该处的 DumpElement
+func (c *RTCConnectionWrapper) dumpElements(point audio.DumpPoint) []pipeline.Element {
+	if !c.dumpConfig.Enabled(point) {
+		return nil
+	}
+	return []pipeline.Element{elements.NewDumpElement(c.dumpConfig, c.id, point)}
+}
+
+// realtimeElements 创建实时模型的元素：RealtimeElement，只输出文本且配置了 TTS 时后接 TTSElement
+func (c *RTCConnectionWrapper) realtimeElements() ([]pipeline.Element, error) {
+	// 下行播放缓冲按 24kHz 输入设计
+	if rate := c.model.OutputSampleRate(); rate != audio.InputSampleRate {
+		return nil, fmt.Errorf("model %s output sample rate %d is not supported", c.model.Name(), rate)
+	}
+	realtimeElement := elements.NewRealtimeElement(c.model)
+	realtimeElement.SetSession(c.session)
+	realtimeElement.SetDialer(c.dialSession)
+	// 摘要使用 Gemini generateContent，未配置 GOOGLE_API_KEY 时直接使用对话记录
+	if cfg := geminiClientConfig(); cfg.APIKey != "" {
+		realtimeElement.SetSummarizer(&elements.ModelSummarizer{Config: cfg})
+	}
+	realtimeElement.SetBus(c.bus)
+	realtimeElement.SetTools(c.tools)
+	c.realtimeElement = realtimeElement
+
+	if c.ttsEngine == nil || c.sessionOptions.AudioOutput() {
+		return []pipeline.Element{realtimeElement}, nil
+	}
+	ttsElement, err := elements.NewTTSElement(c.ttsEngine, audio.InputSampleRate)
+	if err != nil {
+		return nil, err
+	}
+	return []pipeline.Element{realtimeElement, ttsElement}, nil
+}
+
+// cascadeElements 创建级联模式的元素，端点检测使用会话参数中的 turn_detection
+func (c *RTCConnectionWrapper) cascadeElements() ([]pipeline.Element, error) {
+	var vad audio.VADConfig
+	var prefixPadding time.Duration
+	if td := c.sessionOptions.TurnDetection; td != nil {
+		vad.Threshold = td.Threshold
+		vad.SilenceDuration = td.SilenceDuration
+		prefixPadding = td.PrefixPadding
+	}
+	sttElement := elements.NewSTTElement(c.cascade.STT, vad, prefixPadding)
+	sttElement.SetBus(c.bus)
+	chatElement := elements.NewChatElement(c.cascade.Chat, c.sessionOptions.Instructions)
+	chatElement.SetBus(c.bus)
+	ttsElement, err := elements.NewTTSElement(c.cascade.TTS, audio.InputSampleRate)
+	if err != nil {
+		return nil, err
+	}
+	c.sttElement = sttElement
+	c.chatElement = chatElement
+	return []pipeline.Element{sttElement, chatElement, ttsElement}, nil
+}
+
 func (c *RTCConnectionWrapper) Stop() error {
-	return c.pipeline.Stop()
+	c.bus.Stop()
+	var err error
+	if c.pipeline != nil {
+		err = c.pipeline.Stop()
+	}
+	if c.recorder != nil {
+		if ferr := c.recorder.Finish(c.id); ferr != nil {
+			log.Printf("finish recording %s error: %v", c.id, ferr)
+		}
+	}
+	if c.playoutDumper != nil {
+		c.playoutDumper.Close()
+	}
+	return err
+}
+
+// Close 取消连接的 context 并关闭 PeerConnection，通常在 Stop 之后调用
+func (c *RTCConnectionWrapper) Close() error {
+	c.cancel()
+	return c.pc.Close()
+}
+
+// Bus 返回该连接的事件总线
+func (c *RTCConnectionWrapper) Bus() pipeline.Bus {
+	return c.bus
+}
+
+// readSenderRTCP 读取下行轨道的 RTCP，根据接收报告调整 Opus 编码参数
+func (c *RTCConnectionWrapper) readSenderRTCP(ctx context.Context, sender *webrtc.RTPSender) {
+	var ssrc uint32
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		packets, _, err := sender.ReadRTCP()
+		if err != nil {
+			// 只有超时可以重试，EOF、io.ErrClosedPipe 等表示发送端已关闭
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) {
+				log.Println("read RTCP error:", err)
+			}
+			return
+		}
+
+		// ReadRTCP 在协商完成、开始发送之后才返回，此时读取参数不会与 SetRemoteDescription 竞争
+		if ssrc == 0 {
+			if encodings := sender.GetParameters().Encodings; len(encodings) > 0 {
+				ssrc = uint32(encodings[0].SSRC)
+			}
+		}
+
+		for _, pkt := range packets {
+			var reports []rtcp.ReceptionReport
+			switch p := pkt.(type) {
+			case *rtcp.ReceiverReport:
+				reports = p.Reports
+			case *rtcp.SenderReport:
+				// 对端同时在发送音频时，接收报告携带在 SR 中
+				reports = p.Reports
+			}
+
+			for _, r := range reports {
+				if ssrc != 0 && r.SSRC != ssrc {
+					continue
+				}
+				c.handleReceptionReport(r)
+			}
+		}
+	}
+}
+
+func (c *RTCConnectionWrapper) handleReceptionReport(r rtcp.ReceptionReport) {
+	// G.711/G.722 没有可调的编码参数
+	if !c.codec.IsOpus() {
+		return
+	}
+
+	report := audio.NetworkReport{
+		FractionLost: float64(r.FractionLost) / 256,
+		TotalLost:    r.TotalLost,
+		Jitter:       time.Duration(r.Jitter) * time.Second / sampleRate,
+	}
+
+	adaptation, changed := c.encoderController.Update(report)
+	if !changed {
+		return
+	}
+
+	log.Printf("encoder adapted: %s -> %s (%s)", adaptation.Previous, adaptation.Current, adaptation.Reason)
+	if err := c.webrtcSinkElement.ApplyEncoderSettings(adaptation.Current); err != nil {
+		log.Println("apply encoder settings error:", err)
+		return
+	}
+
+	c.bus.Publish(pipeline.Event{
+		Type:      pipeline.EventEncoderAdapted,
+		Timestamp: time.Now(),
+		Payload:   adaptation,
+	})
 }
 
 func (c *RTCConnectionWrapper) readRemoteAudio(ctx context.Context) {
+	channels := int(c.remoteAudioTrack.Codec().Channels)
+	if channels == 0 {
+		channels = 1
+	}
+
+	// telephone-event 与音频共用 SSRC，按 payload type 区分
+	var detector *dtmf.RTPDetector
+	if te, ok := codec.TelephoneEventFor(c.codec); ok && c.telephoneEventPT != 0 {
+		detector = dtmf.NewRTPDetector(int(te.ClockRate))
+	}
 
 	for {
 		select {
@@ -155,24 +626,92 @@ func (c *RTCConnectionWrapper) readRemoteAudio(ctx context.Context) {
 				continue
 			}
 
-			// 将拿到的 payload 投递给 pipeline 的“输入 element”
+			if detector != nil && webrtc.PayloadType(rtpPacket.PayloadType) == c.telephoneEventPT {
+				events, err := detector.Push(rtpPacket.Timestamp, rtpPacket.Payload)
+				if err != nil {
+					log.Println("parse telephone-event error:", err)
+					continue
+				}
+				for _, ev := range events {
+					c.handleDTMF(ev)
+				}
+				continue
+			}
+
+			if c.opusRecording != nil {
+				if err := c.opusRecording.WriteUser(rtpPacket.Payload, rtpPacket.Timestamp); err != nil {
+					log.Println("record error:", err)
+				}
+			}
+
+			// 模拟麦克风时上行音频来自文件
+			if c.micSource != nil {
+				continue
+			}
+
+			// 将拿到的 payload 投递给 pipeline 的“输入 element”（抖动缓冲负责重排和丢包检测）
 			msg := pipeline.PipelineMessage{
 				Type: pipeline.MsgTypeAudio,
 				AudioData: &pipeline.AudioData{
-					Data:       rtpPacket.Payload,
-					SampleRate: 48000,
-					Channels:   1,
-					MediaType:  "audio/x-opus",
-					Codec:      "opus",
-					Timestamp:  time.Now(),
+					Data:           rtpPacket.Payload,
+					SampleRate:     c.codec.SampleRate,
+					Channels:       channels,
+					MediaType:      c.codec.MediaType,
+					Codec:          c.codec.Name,
+					Timestamp:      time.Now(),
+					SequenceNumber: rtpPacket.SequenceNumber,
+					RTPTimestamp:   rtpPacket.Timestamp,
 				},
 			}
 
-			c.opusDecodeElement.In() <- msg
+			c.jitterBufferElement.In() <- msg
+		}
+	}
+}
+
+// telephoneEventPayloadType 返回接收端协商到的 telephone-event payload type
+func telephoneEventPayloadType(receiver *webrtc.RTPReceiver) webrtc.PayloadType {
+	for _, p := range receiver.GetParameters().Codecs {
+		if strings.EqualFold(p.MimeType, codec.MimeTypeTelephoneEvent) {
+			return p.PayloadType
+		}
+	}
+	return 0
+}
+
+// handleDTMF 发布按键事件，通过 DataChannel 通知客户端，并按需以文本轮次告知模型
+func (c *RTCConnectionWrapper) handleDTMF(ev dtmf.Event) {
+	log.Printf("DTMF digit %s (%s, %v)", ev.Digit, ev.Source, ev.Duration)
+
+	c.bus.Publish(pipeline.Event{
+		Type:      pipeline.EventDTMF,
+		Timestamp: ev.Timestamp,
+		Payload:   ev,
+	})
+
+	if c.dataChannelSinkElement != nil {
+		err := c.dataChannelSinkElement.Send(elements.DataChannelEvent{
+			Type:       elements.DataChannelEventDTMF,
+			SessionID:  c.id,
+			Timestamp:  ev.Timestamp.UnixMilli(),
+			Digit:      ev.Digit,
+			DurationMS: ev.Duration.Milliseconds(),
+			Source:     string(ev.Source),
+		})
+		if err != nil {
+			log.Println("send dtmf over data channel error:", err)
+		}
+	}
+
+	if os.Getenv("DTMF_INJECT_TEXT") == "true" && c.realtimeElement != nil {
+		if err := c.realtimeElement.SendText(fmt.Sprintf("user pressed %s", ev.Digit)); err != nil {
+			log.Println("send dtmf to model error:", err)
 		}
 	}
 }
 
+// readDataChannel 接收客户端消息。消息沿用 Live API 的 LiveClientMessage 格式，
+// 其中的文本轮次、图像和音频转发给当前模型
 func (c *RTCConnectionWrapper) readDataChannel(ctx context.Context) {
 
 	defer c.dataChannel.Close()
@@ -186,8 +725,51 @@ func (c *RTCConnectionWrapper) readDataChannel(ctx context.Context) {
 			log.Println("unmarshal message error ", string(message), err)
 			return
 		}
-		c.genaiSession.Send(&sendMessage)
+		if c.realtimeElement == nil {
+			return
+		}
+		if err := c.forwardClientMessage(&sendMessage); err != nil {
+			log.Println("send client message error:", err)
+		}
 	})
 
 	<-ctx.Done()
 }
+
+func (c *RTCConnectionWrapper) forwardClientMessage(msg *genai.LiveClientMessage) error {
+	if content := msg.ClientContent; content != nil {
+		var text strings.Builder
+		for _, turn := range content.Turns {
+			if turn == nil {
+				continue
+			}
+			for _, part := range turn.Parts {
+				text.WriteString(part.Text)
+			}
+		}
+		if text.Len() > 0 {
+			if err := c.realtimeElement.SendText(text.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if input := msg.RealtimeInput; input != nil {
+		for _, chunk := range input.MediaChunks {
+			if chunk == nil {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(chunk.MIMEType, "image/"):
+				if err := c.realtimeElement.SendImage(chunk.Data, chunk.MIMEType); err != nil {
+					return err
+				}
+			case strings.HasPrefix(chunk.MIMEType, "audio/pcm"):
+				c.realtimeElement.SendAudio(chunk.Data)
+			default:
+				log.Printf("unsupported media chunk %s", chunk.MIMEType)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,610 @@
+package connection
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/codec"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/elements"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live/livetest"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/recording"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/speech"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPeerConnection 创建只使用本机回环地址的 PeerConnection
+func newPeerConnection(t *testing.T) *webrtc.PeerConnection {
+	settingEngine := webrtc.SettingEngine{}
+	settingEngine.SetIncludeLoopbackCandidate(true)
+	settingEngine.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+
+	mediaEngine := &webrtc.MediaEngine{}
+	require.NoError(t, codec.RegisterCodecs(mediaEngine))
+
+	api := webrtc.NewAPI(webrtc.WithSettingEngine(settingEngine), webrtc.WithMediaEngine(mediaEngine))
+	pc, err := api.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+func gatherLocalDescription(t *testing.T, pc *webrtc.PeerConnection, desc webrtc.SessionDescription) webrtc.SessionDescription {
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	require.NoError(t, pc.SetLocalDescription(desc))
+	<-gatherComplete
+	return *pc.LocalDescription()
+}
+
+// toneFrame 返回第 n 个 20ms 的 8kHz 正弦波，G.711 μ-law 编码，talk 为 false 时为静音
+func toneFrame(enc codec.Encoder, n int, talk bool) ([]byte, error) {
+	pcm := make([]int16, 160)
+	for i := range pcm {
+		if talk {
+			pcm[i] = int16(math.Sin(2*math.Pi*440*float64(n*160+i)/8000) * 8000)
+		}
+	}
+	return enc.Encode(pcm)
+}
+
+// e2eSession 一个连接到 livetest 模拟服务端的完整 WebRTC 会话
+type e2eSession struct {
+	srv     *livetest.Server
+	events  chan elements.DataChannelEvent
+	audible chan struct{}
+}
+
+// startSession 浏览器侧用 PCMU 持续发送正弦波，模型为按 script 回复的 livetest 服务端，
+// configure 在 InitAISession 之前调整 wrapper
+func startSession(t *testing.T, script livetest.Script, configure func(*RTCConnectionWrapper)) *e2eSession {
+	return startTalkingSession(t, script, configure, func(int) bool { return true })
+}
+
+// startTalkingSession 与 startSession 相同，talk 决定第 n 个 20ms 上行帧是正弦波还是静音
+func startTalkingSession(t *testing.T, script livetest.Script, configure func(*RTCConnectionWrapper), talk func(n int) bool) *e2eSession {
+	return startScriptedSession(t, []livetest.Script{script}, configure, talk)
+}
+
+// startScriptedSession 与 startTalkingSession 相同，模型的第 n 个连接执行 scripts 中的第 n 个脚本
+func startScriptedSession(t *testing.T, scripts []livetest.Script, configure func(*RTCConnectionWrapper), talk func(n int) bool) *e2eSession {
+	srv := livetest.NewServer(scripts[0], scripts[1:]...)
+	t.Cleanup(srv.Close)
+	t.Setenv("GOOGLE_API_KEY", "test")
+	t.Setenv("GEMINI_BASE_URL", srv.URL)
+	t.Setenv("AUDIO_RESAMPLER", "sinc")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s := &e2eSession{
+		srv:     srv,
+		events:  make(chan elements.DataChannelEvent, 100),
+		audible: make(chan struct{}),
+	}
+
+	// 客户端
+	client := newPeerConnection(t)
+	uplink, err := webrtc.NewTrackLocalStaticSample(codec.PCMU.Capability(), "audio", "client")
+	require.NoError(t, err)
+	_, err = client.AddTrack(uplink)
+	require.NoError(t, err)
+
+	dc, err := client.CreateDataChannel("events", nil)
+	require.NoError(t, err)
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		var ev elements.DataChannelEvent
+		if json.Unmarshal(msg.Data, &ev) == nil {
+			s.events <- ev
+		}
+	})
+
+	// 下行收到有声音的帧
+	client.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		dec, err := codec.NewDecoder(codec.PCMU)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for {
+			pkt, _, err := track.ReadRTP()
+			if err != nil {
+				return
+			}
+			pcm, err := dec.Decode(pkt.Payload)
+			if err != nil {
+				continue
+			}
+			for _, v := range pcm {
+				if v > 2000 || v < -2000 {
+					close(s.audible)
+					return
+				}
+			}
+		}
+	})
+
+	offer, err := client.CreateOffer(nil)
+	require.NoError(t, err)
+	offer = gatherLocalDescription(t, client, offer)
+
+	// 服务端，与 WebRTCServer.HandleNegotiate 的流程一致
+	server := newPeerConnection(t)
+	wrapper := NewRTCConnectionWrapper("e2e", server)
+	wrapper.SetAudioCodec(codec.PCMU)
+	if configure != nil {
+		configure(wrapper)
+	}
+	require.NoError(t, wrapper.InitAISession(ctx))
+	require.NoError(t, wrapper.Start(ctx, server))
+	t.Cleanup(func() { wrapper.Stop() })
+
+	require.NoError(t, server.SetRemoteDescription(offer))
+	answer, err := server.CreateAnswer(nil)
+	require.NoError(t, err)
+	answer = gatherLocalDescription(t, server, answer)
+	require.NoError(t, client.SetRemoteDescription(answer))
+
+	// 按 20ms 节奏发送上行音频
+	enc, err := codec.NewEncoder(codec.PCMU)
+	require.NoError(t, err)
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for n := 0; ; n++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// 不在测试协程中，不能用 require
+				frame, err := toneFrame(enc, n, talk(n))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				uplink.WriteSample(media.Sample{Data: frame, Duration: 20 * time.Millisecond})
+			}
+		}
+	}()
+	return s
+}
+
+// waitTurn 收集 DataChannel 事件直到 turn_complete，返回各事件的 "type role: text"
+func (s *e2eSession) waitTurn(t *testing.T) []string {
+	var got []string
+	for _, ev := range s.waitEvents(t) {
+		switch ev.Type {
+		case "usage", "session_state":
+		default:
+			got = append(got, ev.Type+" "+ev.Role+": "+ev.Text)
+		}
+	}
+	return got
+}
+
+// waitEvents 收集 DataChannel 事件直到 turn_complete（不含）
+func (s *e2eSession) waitEvents(t *testing.T) []elements.DataChannelEvent {
+	var got []elements.DataChannelEvent
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case ev := <-s.events:
+			if ev.Type == "turn_complete" {
+				return got
+			}
+			got = append(got, ev)
+		case <-deadline:
+			t.Fatalf("no turn_complete over the data channel, got %v", got)
+		}
+	}
+}
+
+// waitFor 收集 DataChannel 事件直到 match 返回 true（含该事件）
+func (s *e2eSession) waitFor(t *testing.T, match func(ev elements.DataChannelEvent) bool) []elements.DataChannelEvent {
+	var got []elements.DataChannelEvent
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case ev := <-s.events:
+			got = append(got, ev)
+			if match(ev) {
+				return got
+			}
+		case <-deadline:
+			t.Fatalf("timeout waiting for data channel event, got %v", got)
+		}
+	}
+}
+
+// sessionStates 返回 events 中的 session_state
+func sessionStates(events []elements.DataChannelEvent) []pipeline.SessionState {
+	var states []pipeline.SessionState
+	for _, ev := range events {
+		if ev.Type == "session_state" && ev.State != nil {
+			states = append(states, *ev.State)
+		}
+	}
+	return states
+}
+
+func sessionStatus(status pipeline.SessionStatus) func(ev elements.DataChannelEvent) bool {
+	return func(ev elements.DataChannelEvent) bool {
+		return ev.Type == "session_state" && ev.State != nil && ev.State.Status == status
+	}
+}
+
+func (s *e2eSession) waitAudible(t *testing.T) {
+	select {
+	case <-s.audible:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no model audio on the downlink track")
+	}
+}
+
+// TestEndToEnd 检查模型回复的音频经 WebRTC 下行到达，转写经 DataChannel 到达
+func TestEndToEnd(t *testing.T) {
+	s := startSession(t, livetest.Script{Steps: []livetest.Step{
+		{When: livetest.InputAudio(300 * time.Millisecond), Do: []livetest.Action{
+			livetest.InputTranscript("hello", true),
+			livetest.OutputTranscript("hi there", true),
+			livetest.Tone(440, 500*time.Millisecond),
+			livetest.TurnComplete(),
+		}},
+	}}, nil)
+
+	assert.Equal(t, []string{"input_transcript user: hello", "output_transcript model: hi there"}, s.waitTurn(t))
+	s.waitAudible(t)
+
+	assert.GreaterOrEqual(t, s.srv.InputAudio(), 300*time.Millisecond)
+	require.Len(t, s.srv.Setups(), 1)
+	assert.NotNil(t, s.srv.Setups()[0].InputAudioTranscription)
+}
+
+// TestEndToEndTTS 只输出文本的会话，文本经 TTSElement 合成后从下行播放
+func TestEndToEndTTS(t *testing.T) {
+	s := startSession(t, livetest.Script{Steps: []livetest.Step{
+		{When: livetest.InputAudio(300 * time.Millisecond), Do: []livetest.Action{
+			livetest.Text("Sure. "),
+			livetest.Text("Here it is"),
+			livetest.TurnComplete(),
+		}},
+	}}, func(w *RTCConnectionWrapper) {
+		w.SetSessionOptions(realtime.ConnectOptions{Modalities: []string{realtime.ModalityText}})
+		w.SetTTSEngine(speech.NewToneEngine(16000))
+	})
+
+	assert.Equal(t, []string{"text model: Sure. ", "text model: Here it is"}, s.waitTurn(t))
+	s.waitAudible(t)
+	assert.Equal(t, []string{"TEXT"}, s.srv.Setups()[0].GenerationConfig.ResponseModalities)
+}
+
+// TestEndToEndCascade 级联模式：上行说两段话，第一段得到合成的回复，
+// 第二段在回复播放时开始说话，打断回复后得到第二次回复
+func TestEndToEndCascade(t *testing.T) {
+	var wrapper *RTCConnectionWrapper
+	s := startTalkingSession(t, livetest.Script{}, func(w *RTCConnectionWrapper) {
+		wrapper = w
+		w.SetSessionOptions(realtime.ConnectOptions{Instructions: "be brief"})
+		w.SetCascade(&speech.Cascade{
+			STT:  speech.NewFakeSTT(16000, "hello there", "stop"),
+			Chat: &speech.FakeChat{Replies: []string{"Sure, let me tell you a rather long story about the sea."}},
+			TTS:  speech.NewToneEngine(16000),
+		})
+	}, func(n int) bool {
+		// 0~0.5s 说第一段，3~3.5s 在回复播放时说第二段
+		return n < 25 || (n >= 150 && n < 175)
+	})
+
+	// 第一段：开始说话、部分转写、最终转写，然后是流式的回复
+	events := s.waitEvents(t)
+	require.GreaterOrEqual(t, len(events), 4, "%v", events)
+	assert.Equal(t, "speech_started", events[0].Type)
+	assert.Equal(t, elements.DataChannelEvent{Type: "input_transcript", Text: "hello", Role: "user", TurnID: "turn-0"}, withoutTimestamp(events[1]))
+	assert.Equal(t, elements.DataChannelEvent{Type: "input_transcript", Text: "hello there", Final: true, Role: "user", TurnID: "turn-0"}, withoutTimestamp(events[2]))
+	var reply string
+	for _, ev := range events[3:] {
+		assert.Equal(t, "text", ev.Type)
+		assert.Equal(t, "turn-0", ev.TurnID)
+		reply += ev.Text
+	}
+	assert.Equal(t, "Sure, let me tell you a rather long story about the sea.", reply)
+	s.waitAudible(t)
+
+	// 第二段打断仍在播放的回复
+	assert.Equal(t, []string{
+		"interrupted model: ",
+		"speech_started user: ",
+		"input_transcript user: stop",
+		"text model: You",
+		"text model:  said:",
+		"text model:  stop",
+	}, s.waitTurn(t))
+
+	assert.Equal(t, []speech.ChatMessage{
+		{Role: speech.ChatRoleSystem, Content: "be brief"},
+		{Role: speech.ChatRoleUser, Content: "hello there"},
+		{Role: speech.ChatRoleAssistant, Content: reply},
+		{Role: speech.ChatRoleUser, Content: "stop"},
+		{Role: speech.ChatRoleAssistant, Content: "You said: stop"},
+	}, wrapper.chatElement.History())
+	assert.Empty(t, s.srv.Setups(), "cascade mode does not connect to a realtime model")
+}
+
+func withoutTimestamp(ev elements.DataChannelEvent) elements.DataChannelEvent {
+	ev.Timestamp = 0
+	ev.SessionID = ""
+	return ev
+}
+
+// TestEndToEndRecording 通话录音：用户的正弦波在左声道，模型回复的音频在右声道，会话结束后定稿
+func TestEndToEndRecording(t *testing.T) {
+	recorder, err := recording.NewRecorder(t.TempDir())
+	require.NoError(t, err)
+
+	var wrapper *RTCConnectionWrapper
+	s := startTalkingSession(t, livetest.Script{Steps: []livetest.Step{
+		{When: livetest.InputAudio(300 * time.Millisecond), Do: []livetest.Action{
+			livetest.Tone(440, 500*time.Millisecond),
+			livetest.TurnComplete(),
+		}},
+	}}, func(w *RTCConnectionWrapper) {
+		wrapper = w
+		w.SetRecorder(recorder)
+	}, func(n int) bool { return n < 25 })
+
+	s.waitTurn(t)
+	s.waitAudible(t)
+	time.Sleep(time.Second)
+
+	_, err = recorder.Path("e2e")
+	assert.ErrorIs(t, err, recording.ErrRecording)
+	require.NoError(t, wrapper.Stop())
+	path, err := recorder.Path("e2e")
+	require.NoError(t, err)
+
+	format, pcm, err := audio.ReadWav(path)
+	require.NoError(t, err)
+	assert.Equal(t, audio.WavFormat{SampleRate: 8000, Channels: 2, BitsPerSample: 16}, format)
+	duration := time.Duration(len(pcm)/4) * time.Second / 8000
+	assert.Greater(t, duration, time.Second)
+
+	// 每 100ms 判断两个声道是否有声音
+	var user, assistant []bool
+	for off := 0; off+3200 <= len(pcm); off += 3200 {
+		var l, r int
+		for i := off; i < off+3200; i += 4 {
+			l = max(l, abs(int(int16(binary.LittleEndian.Uint16(pcm[i:])))))
+			r = max(r, abs(int(int16(binary.LittleEndian.Uint16(pcm[i+2:])))))
+		}
+		user = append(user, l > 2000)
+		assistant = append(assistant, r > 2000)
+	}
+	assert.True(t, user[2], "user talks at the start: %v", user)
+	assert.False(t, user[len(user)-1], "user is silent at the end: %v", user)
+	assert.False(t, assistant[0], "assistant is silent at the start: %v", assistant)
+	firstUser, firstAssistant := slices.Index(user, true), slices.Index(assistant, true)
+	assert.Less(t, firstUser, firstAssistant, "assistant answers after the user")
+}
+
+func TestEndToEndDump(t *testing.T) {
+	dir := t.TempDir()
+	config := &audio.DumpConfig{Dir: dir, Points: map[audio.DumpPoint]bool{}}
+	for _, point := range audio.DumpPoints {
+		config.Points[point] = true
+	}
+
+	var wrapper *RTCConnectionWrapper
+	s := startTalkingSession(t, livetest.Script{Steps: []livetest.Step{
+		{When: livetest.InputAudio(300 * time.Millisecond), Do: []livetest.Action{
+			livetest.Tone(440, 300*time.Millisecond),
+			livetest.TurnComplete(),
+		}},
+	}}, func(w *RTCConnectionWrapper) {
+		wrapper = w
+		w.SetDumpConfig(config)
+	}, func(n int) bool { return n < 25 })
+
+	s.waitTurn(t)
+	s.waitAudible(t)
+	require.NoError(t, wrapper.Stop())
+
+	// 每个转储点一个文件，格式取自经过的音频
+	for point, format := range map[audio.DumpPoint]audio.WavFormat{
+		audio.DumpPointUplink:      {SampleRate: 8000, Channels: 1, BitsPerSample: 16},
+		audio.DumpPointModelInput:  {SampleRate: 16000, Channels: 1, BitsPerSample: 16},
+		audio.DumpPointModelOutput: {SampleRate: 24000, Channels: 1, BitsPerSample: 16},
+		audio.DumpPointPlayout:     {SampleRate: 8000, Channels: 1, BitsPerSample: 16},
+	} {
+		files, err := filepath.Glob(filepath.Join(dir, "e2e", "tag_"+string(point)+"_*.wav"))
+		require.NoError(t, err)
+		require.Len(t, files, 1, point)
+		got, pcm, err := audio.ReadWav(files[0])
+		require.NoError(t, err)
+		assert.Equal(t, format, got, point)
+		assert.NotEmpty(t, pcm, point)
+	}
+}
+
+// TestEndToEndMicrophoneFile 用录音文件模拟麦克风，客户端的上行音频不再送给模型
+func TestEndToEndMicrophoneFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "caller.wav")
+	w, err := audio.NewWavStreamWriter(path, 16000, 1, 16)
+	require.NoError(t, err)
+	_, err = w.Write(livetest.TonePCM(440, 500*time.Millisecond, 16000))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	s := startTalkingSession(t, livetest.Script{Steps: []livetest.Step{
+		{When: livetest.InputAudio(300 * time.Millisecond), Do: []livetest.Action{
+			livetest.OutputTranscript("heard you", true),
+			livetest.TurnComplete(),
+		}},
+	}}, func(w *RTCConnectionWrapper) {
+		w.SetMicrophoneFile(path, elements.FileSourceOptions{})
+	}, func(int) bool { return false })
+
+	assert.Equal(t, []string{"output_transcript model: heard you"}, s.waitTurn(t))
+
+	// 文件只有 500ms，客户端一直在发的静音帧被忽略
+	time.Sleep(500 * time.Millisecond)
+	assert.InDelta(t, 500, s.srv.InputAudio().Milliseconds(), 60)
+}
+
+// reconnectScripts 第一个连接收到 300ms 音频后下发恢复句柄并断开；
+// 第二个连接在 setup 后等待 setupDelay 才完成，收到 800ms 音频后回复
+func reconnectScripts(setupDelay time.Duration) []livetest.Script {
+	return []livetest.Script{
+		{Steps: []livetest.Step{
+			{When: livetest.InputAudio(300 * time.Millisecond), Do: []livetest.Action{
+				livetest.ResumptionHandle("h1"),
+				livetest.Disconnect(),
+			}},
+		}},
+		{
+			OnSetup: func(live.Setup) error {
+				time.Sleep(setupDelay)
+				return nil
+			},
+			Steps: []livetest.Step{
+				{When: livetest.InputAudio(800 * time.Millisecond), Do: []livetest.Action{
+					livetest.OutputTranscript("welcome back", true),
+					livetest.Tone(440, 300*time.Millisecond),
+					livetest.TurnComplete(),
+				}},
+			},
+		},
+	}
+}
+
+// dials 每次连接的第一个 setup：被拒绝的连接会去掉上下文压缩再试一次
+func dials(setups []live.Setup) []live.Setup {
+	var first []live.Setup
+	for _, setup := range setups {
+		if setup.ContextWindowCompression != nil {
+			first = append(first, setup)
+		}
+	}
+	return first
+}
+
+// TestEndToEndReconnect 连接中断后用恢复句柄重连，客户端收到 reconnecting 和 connected，
+// 重连期间缓存的上行音频补发给新连接，因此新连接立即满足 800ms 的输入并回复
+func TestEndToEndReconnect(t *testing.T) {
+	s := startScriptedSession(t, reconnectScripts(time.Second), nil, func(int) bool { return true })
+
+	events := s.waitFor(t, sessionStatus(pipeline.SessionConnected))
+	assert.Equal(t, []pipeline.SessionState{
+		{Status: pipeline.SessionReconnecting, Reason: sessionStates(events)[0].Reason, Attempt: 1},
+		{Status: pipeline.SessionConnected, Attempt: 1, Resumed: true},
+	}, sessionStates(events))
+	connected := events[len(events)-1]
+
+	reply := s.waitFor(t, func(ev elements.DataChannelEvent) bool { return ev.Type == "output_transcript" })
+	assert.Equal(t, "welcome back", reply[len(reply)-1].Text)
+	assert.Less(t, reply[len(reply)-1].Timestamp-connected.Timestamp, int64(500), "buffered audio is replayed on reconnect")
+	s.waitAudible(t)
+
+	setups := s.srv.Setups()
+	require.Len(t, setups, 2)
+	require.NotNil(t, setups[0].SessionResumption)
+	require.NotNil(t, setups[1].SessionResumption)
+	assert.Empty(t, setups[0].SessionResumption.Handle)
+	assert.Equal(t, "h1", setups[1].SessionResumption.Handle)
+}
+
+// TestEndToEndReconnectDropUplink drop 策略下重连期间的音频被丢弃，新连接需要重新收满 800ms
+func TestEndToEndReconnectDropUplink(t *testing.T) {
+	t.Setenv("GEMINI_UPLINK_POLICY", "drop")
+	s := startScriptedSession(t, reconnectScripts(time.Second), nil, func(int) bool { return true })
+
+	events := s.waitFor(t, sessionStatus(pipeline.SessionConnected))
+	connected := events[len(events)-1]
+	reply := s.waitFor(t, func(ev elements.DataChannelEvent) bool { return ev.Type == "output_transcript" })
+	assert.GreaterOrEqual(t, reply[len(reply)-1].Timestamp-connected.Timestamp, int64(600))
+}
+
+// TestEndToEndReconnectGivesUp 新连接都被拒绝，按 GEMINI_RECONNECT_MAX_ATTEMPTS 尝试后放弃，两次尝试之间退避
+func TestEndToEndReconnectGivesUp(t *testing.T) {
+	t.Setenv("GEMINI_RECONNECT_MAX_ATTEMPTS", "2")
+	s := startScriptedSession(t, []livetest.Script{
+		{Steps: []livetest.Step{
+			{When: livetest.InputAudio(300 * time.Millisecond), Do: []livetest.Action{livetest.Disconnect()}},
+		}},
+		{OnSetup: func(live.Setup) error { return errors.New("unavailable") }},
+	}, nil, func(int) bool { return true })
+
+	events := s.waitFor(t, sessionStatus(pipeline.SessionDisconnected))
+	var statuses []string
+	var attempts []int
+	var states []elements.DataChannelEvent
+	for _, ev := range events {
+		if ev.Type == "session_state" {
+			statuses = append(statuses, string(ev.State.Status))
+			attempts = append(attempts, ev.State.Attempt)
+			states = append(states, ev)
+		}
+	}
+	assert.Equal(t, []string{"reconnecting", "reconnecting", "disconnected"}, statuses)
+	assert.Equal(t, []int{1, 2, 0}, attempts)
+	// 第二次尝试前等待 MinBackoff（500ms ±20%）
+	assert.GreaterOrEqual(t, states[2].Timestamp-states[1].Timestamp, int64(400))
+	assert.Len(t, dials(s.srv.Setups()), 3)
+}
+
+// TestEndToEndReconnectExpiredHandle 恢复句柄失效时两次失败后放弃句柄，以对话摘要建立新会话
+func TestEndToEndReconnectExpiredHandle(t *testing.T) {
+	s := startScriptedSession(t, []livetest.Script{
+		{Steps: []livetest.Step{
+			{When: livetest.InputAudio(300 * time.Millisecond), Do: []livetest.Action{
+				livetest.InputTranscript("where is my order", true),
+				livetest.OutputTranscript("it ships today", true),
+				livetest.TurnComplete(),
+				livetest.ResumptionHandle("h1"),
+				livetest.Disconnect(),
+			}},
+		}},
+		{OnSetup: func(setup live.Setup) error {
+			if setup.SessionResumption != nil && setup.SessionResumption.Handle != "" {
+				return errors.New("invalid handle")
+			}
+			return nil
+		}},
+	}, nil, func(int) bool { return true })
+
+	s.waitTurn(t)
+	events := s.waitFor(t, sessionStatus(pipeline.SessionConnected))
+	states := sessionStates(events)
+	assert.Equal(t, pipeline.SessionState{Status: pipeline.SessionConnected, Attempt: 3}, states[len(states)-1])
+
+	setups := dials(s.srv.Setups())
+	require.Len(t, setups, 4)
+	var handles []string
+	for _, setup := range setups[1:] {
+		handles = append(handles, setup.SessionResumption.Handle)
+	}
+	assert.Equal(t, []string{"h1", "h1", ""}, handles)
+	require.NotNil(t, setups[3].SystemInstruction)
+	var instruction strings.Builder
+	for _, part := range setups[3].SystemInstruction.Parts {
+		instruction.WriteString(part.Text)
+	}
+	assert.Contains(t, instruction.String(), livetest.DefaultSummary)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,140 @@
+package dtmf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Source 按键的检测来源
+type Source string
+
+const (
+	// SourceRFC4733 来自 RTP telephone-event
+	SourceRFC4733 Source = "rfc4733"
+	// SourceInBand 来自 PCM 中的双音多频信号
+	SourceInBand Source = "inband"
+)
+
+// Event 一次按键
+type Event struct {
+	Digit     string        `json:"digit"`
+	Duration  time.Duration `json:"duration"`
+	Source    Source        `json:"source"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// RFC 4733 事件码 0-15 对应的按键
+var eventDigits = [16]string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "*", "#", "A", "B", "C", "D"}
+
+// DigitForEvent 返回 telephone-event 事件码对应的按键，非 DTMF 事件返回 false
+func DigitForEvent(code uint8) (string, bool) {
+	if int(code) >= len(eventDigits) {
+		return "", false
+	}
+	return eventDigits[code], true
+}
+
+// TelephoneEvent RFC 4733 telephone-event 负载
+type TelephoneEvent struct {
+	Event    uint8
+	End      bool
+	Volume   uint8  // 0~63，表示 -dBm0
+	Duration uint16 // 以 RTP 时钟为单位
+}
+
+// ParseTelephoneEvent 解析 4 字节的 telephone-event 负载
+func ParseTelephoneEvent(payload []byte) (TelephoneEvent, error) {
+	if len(payload) < 4 {
+		return TelephoneEvent{}, fmt.Errorf("telephone-event payload too short: %d bytes", len(payload))
+	}
+	return TelephoneEvent{
+		Event:    payload[0],
+		End:      payload[1]&0x80 != 0,
+		Volume:   payload[1] & 0x3F,
+		Duration: binary.BigEndian.Uint16(payload[2:4]),
+	}, nil
+}
+
+// Marshal 编码为 4 字节负载
+func (e TelephoneEvent) Marshal() []byte {
+	out := make([]byte, 4)
+	out[0] = e.Event
+	out[1] = e.Volume & 0x3F
+	if e.End {
+		out[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(out[2:], e.Duration)
+	return out
+}
+
+// RTPDetector 将 telephone-event 包序列还原为按键
+// 同一按键的所有包共享 RTP 时间戳，结束包通常重传三次；每个按键只报告一次
+type RTPDetector struct {
+	clockRate int
+
+	active      bool
+	timestamp   uint32
+	event       TelephoneEvent
+	reported    bool
+	hasPrevious bool
+	previousTS  uint32
+}
+
+// NewRTPDetector 创建 telephone-event 检测器，clockRate 为事件的 RTP 时钟频率
+func NewRTPDetector(clockRate int) *RTPDetector {
+	return &RTPDetector{clockRate: clockRate}
+}
+
+// Push 处理一个 telephone-event 包，返回本次完成的按键
+// 按键在收到结束包时报告；若结束包全部丢失，则在下一个按键开始时补报
+func (d *RTPDetector) Push(timestamp uint32, payload []byte) ([]Event, error) {
+	ev, err := ParseTelephoneEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	if !d.active || timestamp != d.timestamp {
+		// 已报告过的按键的重传包
+		if d.hasPrevious && timestamp == d.previousTS {
+			return nil, nil
+		}
+		if d.active && !d.reported {
+			if e, ok := d.complete(); ok {
+				events = append(events, e)
+			}
+		}
+		d.active = true
+		d.timestamp = timestamp
+		d.reported = false
+		d.event = ev
+	} else if ev.Duration >= d.event.Duration {
+		// 时长只会增长，重排的旧包不覆盖
+		d.event = ev
+	}
+	if ev.End && !d.reported {
+		if e, ok := d.complete(); ok {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// complete 将当前按键标记为已报告
+func (d *RTPDetector) complete() (Event, bool) {
+	d.reported = true
+	d.hasPrevious = true
+	d.previousTS = d.timestamp
+
+	digit, ok := DigitForEvent(d.event.Event)
+	if !ok {
+		return Event{}, false
+	}
+	return Event{
+		Digit:     digit,
+		Duration:  time.Duration(d.event.Duration) * time.Second / time.Duration(d.clockRate),
+		Source:    SourceRFC4733,
+		Timestamp: time.Now(),
+	}, true
+}
@@ -0,0 +1,193 @@
+package dtmf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTelephoneEvent(t *testing.T) {
+	ev, err := ParseTelephoneEvent([]byte{11, 0x8a, 0x03, 0x20})
+	require.NoError(t, err)
+	assert.Equal(t, TelephoneEvent{Event: 11, End: true, Volume: 10, Duration: 800}, ev)
+	assert.Equal(t, []byte{11, 0x8a, 0x03, 0x20}, ev.Marshal())
+
+	_, err = ParseTelephoneEvent([]byte{1, 2})
+	assert.Error(t, err)
+
+	digit, ok := DigitForEvent(11)
+	assert.True(t, ok)
+	assert.Equal(t, "#", digit)
+	_, ok = DigitForEvent(16)
+	assert.False(t, ok)
+}
+
+func packet(event uint8, end bool, duration uint16) []byte {
+	return TelephoneEvent{Event: event, End: end, Volume: 10, Duration: duration}.Marshal()
+}
+
+func TestRTPDetector(t *testing.T) {
+	d := NewRTPDetector(8000)
+
+	var got []Event
+	push := func(ts uint32, payload []byte) {
+		events, err := d.Push(ts, payload)
+		require.NoError(t, err)
+		got = append(got, events...)
+	}
+
+	// 按键 3：开始包、续包、三个结束包
+	push(1000, packet(3, false, 160))
+	push(1000, packet(3, false, 320))
+	push(1000, packet(3, true, 800))
+	push(1000, packet(3, true, 800))
+	push(1000, packet(3, true, 800))
+	require.Len(t, got, 1)
+	assert.Equal(t, "3", got[0].Digit)
+	assert.Equal(t, 100*time.Millisecond, got[0].Duration)
+	assert.Equal(t, SourceRFC4733, got[0].Source)
+
+	// 按键 # 的结束包全部丢失，下一个按键开始时补报
+	push(5000, packet(11, false, 160))
+	push(5000, packet(11, false, 480))
+	require.Len(t, got, 1)
+	push(9000, packet(1, false, 160))
+	require.Len(t, got, 2)
+	assert.Equal(t, "#", got[1].Digit)
+	assert.Equal(t, 60*time.Millisecond, got[1].Duration)
+
+	// 迟到的上一个按键的重传结束包被忽略
+	push(5000, packet(11, true, 800))
+	push(9000, packet(1, true, 640))
+	require.Len(t, got, 3)
+	assert.Equal(t, "1", got[2].Digit)
+	assert.Equal(t, 80*time.Millisecond, got[2].Duration)
+}
+
+func TestRTPDetectorIgnoresNonDTMFEvents(t *testing.T) {
+	d := NewRTPDetector(48000)
+	events, err := d.Push(1, packet(16, true, 960))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+// genDigit 生成按键的双音信号，每个单音幅度为 amp
+func genDigit(rate int, low, high, amp float64, d time.Duration) []int16 {
+	n := int(d.Seconds() * float64(rate))
+	out := make([]int16, n)
+	for i := range out {
+		t := float64(i) / float64(rate)
+		v := amp * (math.Sin(2*math.Pi*low*t) + math.Sin(2*math.Pi*high*t))
+		out[i] = int16(v * 32767)
+	}
+	return out
+}
+
+func silence(rate int, d time.Duration) []int16 {
+	return make([]int16, int(d.Seconds()*float64(rate)))
+}
+
+func TestInBandDetector(t *testing.T) {
+	for _, rate := range []int{8000, 16000, 48000} {
+		d := NewInBandDetector(rate)
+
+		var pcm []int16
+		digits := "159#0D"
+		for _, r := range digits {
+			row, col := keyPosition(string(r))
+			pcm = append(pcm, genDigit(rate, rowFreqs[row], colFreqs[col], 0.25, 100*time.Millisecond)...)
+			pcm = append(pcm, silence(rate, 80*time.Millisecond)...)
+		}
+
+		// 分成不规则的小段输入，检测结果应与块边界无关
+		var got string
+		for len(pcm) > 0 {
+			n := min(len(pcm), 317)
+			for _, ev := range d.Process(pcm[:n]) {
+				assert.Equal(t, SourceInBand, ev.Source)
+				assert.InDelta(t, 100, ev.Duration.Milliseconds(), 30)
+				got += ev.Digit
+			}
+			pcm = pcm[n:]
+		}
+		assert.Equal(t, digits, got, "rate %d", rate)
+	}
+}
+
+func TestInBandDetectorRejectsShortAndTwistedTones(t *testing.T) {
+	const rate = 8000
+	d := NewInBandDetector(rate)
+
+	var pcm []int16
+	// 过短的按键
+	pcm = append(pcm, genDigit(rate, 770, 1336, 0.25, 20*time.Millisecond)...)
+	pcm = append(pcm, silence(rate, 100*time.Millisecond)...)
+	// 列频比行频低 12dB，超出正向扭曲限制
+	n := int(0.1 * rate)
+	for i := 0; i < n; i++ {
+		t := float64(i) / rate
+		v := 0.4*math.Sin(2*math.Pi*770*t) + 0.1*math.Sin(2*math.Pi*1336*t)
+		pcm = append(pcm, int16(v*32767))
+	}
+	pcm = append(pcm, silence(rate, 100*time.Millisecond)...)
+	// 单个单音
+	pcm = append(pcm, genDigit(rate, 852, 852, 0.2, 100*time.Millisecond)...)
+	pcm = append(pcm, silence(rate, 100*time.Millisecond)...)
+
+	assert.Empty(t, d.Process(pcm))
+}
+
+func TestInBandDetectorRejectsNoiseAndSpeechLikeSignals(t *testing.T) {
+	const rate = 16000
+	d := NewInBandDetector(rate)
+	rng := rand.New(rand.NewSource(1))
+
+	var pcm []int16
+	// 白噪声
+	for i := 0; i < rate; i++ {
+		pcm = append(pcm, int16(rng.NormFloat64()*3000))
+	}
+	// 类语音的谐波信号：基频 140Hz，含多个谐波，覆盖 DTMF 频段
+	for i := 0; i < rate; i++ {
+		t := float64(i) / rate
+		var v float64
+		for h := 1; h <= 20; h++ {
+			v += math.Sin(2*math.Pi*140*float64(h)*t) / float64(h)
+		}
+		pcm = append(pcm, int16(v*4000))
+	}
+
+	assert.Empty(t, d.Process(pcm))
+}
+
+func TestInBandDetectorToneInNoise(t *testing.T) {
+	const rate = 8000
+	d := NewInBandDetector(rate)
+	rng := rand.New(rand.NewSource(2))
+
+	pcm := genDigit(rate, 941, 1477, 0.2, 120*time.Millisecond)
+	pcm = append(pcm, silence(rate, 100*time.Millisecond)...)
+	// 约 20dB 信噪比
+	for i := range pcm {
+		pcm[i] += int16(rng.NormFloat64() * 650)
+	}
+
+	events := d.Process(pcm)
+	require.Len(t, events, 1)
+	assert.Equal(t, "#", events[0].Digit)
+}
+
+func keyPosition(digit string) (int, int) {
+	for r := range keypad {
+		for c := range keypad[r] {
+			if keypad[r][c] == digit {
+				return r, c
+			}
+		}
+	}
+	panic("unknown digit " + digit)
+}
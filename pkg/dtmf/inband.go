@@ -0,0 +1,191 @@
+package dtmf
+
+import (
+	"math"
+	"time"
+)
+
+// 双音多频的行频和列频（Hz）
+var (
+	rowFreqs = [4]float64{697, 770, 852, 941}
+	colFreqs = [4]float64{1209, 1336, 1477, 1633}
+
+	keypad = [4][4]string{
+		{"1", "2", "3", "A"},
+		{"4", "5", "6", "B"},
+		{"7", "8", "9", "C"},
+		{"*", "0", "#", "D"},
+	}
+)
+
+const (
+	// 8kHz 下的标准 Goertzel 块长（约 25.6ms），其他采样率按比例缩放
+	blockSize8k = 205
+
+	// 连续多少个块检测到同一按键才确认，以及按键结束前需要的静默块数
+	minToneBlocks = 2
+	minGapBlocks  = 2
+
+	// 单音最小幅度（相对满量程，约 -36dBFS）
+	minToneAmplitude = 0.016
+	// 行频与列频的功率比上限：正向扭曲（列弱于行）8dB，反向扭曲 4dB
+	maxNormalTwist  = 6.31 // 8dB
+	maxReverseTwist = 2.51 // 4dB
+	// 同组中第二强的频率至少比最强的低 6dB
+	minPeakRatio = 3.98
+	// 两个单音的能量占块总能量的最小比例，用于排除语音
+	minToneFraction = 0.7
+)
+
+// InBandDetector 用 Goertzel 算法检测 PCM 中的双音多频按键
+type InBandDetector struct {
+	sampleRate int
+	blockSize  int
+	rowCoeffs  [4]float64
+	colCoeffs  [4]float64
+
+	block []float64
+
+	candidate      string
+	candidateCount int
+	current        string
+	currentBlocks  int
+	gapBlocks      int
+}
+
+// NewInBandDetector 创建指定采样率（单声道 S16）的带内检测器
+func NewInBandDetector(sampleRate int) *InBandDetector {
+	d := &InBandDetector{
+		sampleRate: sampleRate,
+		blockSize:  blockSize8k * sampleRate / 8000,
+	}
+	for i := range rowFreqs {
+		d.rowCoeffs[i] = 2 * math.Cos(2*math.Pi*rowFreqs[i]/float64(sampleRate))
+		d.colCoeffs[i] = 2 * math.Cos(2*math.Pi*colFreqs[i]/float64(sampleRate))
+	}
+	d.block = make([]float64, 0, d.blockSize)
+	return d
+}
+
+// Process 处理一段 PCM，返回其中结束的按键
+func (d *InBandDetector) Process(pcm []int16) []Event {
+	var events []Event
+	for _, v := range pcm {
+		d.block = append(d.block, float64(v)/32768)
+		if len(d.block) < d.blockSize {
+			continue
+		}
+		if e, ok := d.update(d.detectBlock(d.block)); ok {
+			events = append(events, e)
+		}
+		d.block = d.block[:0]
+	}
+	return events
+}
+
+// update 对逐块检测结果做去抖，按键结束时返回 true
+func (d *InBandDetector) update(digit string) (Event, bool) {
+	if digit != "" && digit == d.current {
+		d.currentBlocks++
+		d.gapBlocks = 0
+		return Event{}, false
+	}
+
+	// 候选按键需要连续出现 minToneBlocks 次
+	if digit != "" && digit == d.candidate {
+		d.candidateCount++
+	} else {
+		d.candidate = digit
+		d.candidateCount = 1
+	}
+
+	if d.current != "" {
+		d.gapBlocks++
+		if d.gapBlocks < minGapBlocks && digit == "" {
+			return Event{}, false
+		}
+		// 当前按键结束
+		e := Event{
+			Digit:     d.current,
+			Duration:  time.Duration(d.currentBlocks*d.blockSize) * time.Second / time.Duration(d.sampleRate),
+			Source:    SourceInBand,
+			Timestamp: time.Now(),
+		}
+		d.current = ""
+		d.currentBlocks = 0
+		d.gapBlocks = 0
+		d.promote()
+		return e, true
+	}
+
+	d.promote()
+	return Event{}, false
+}
+
+// promote 候选按键达到最短时长后成为当前按键
+func (d *InBandDetector) promote() {
+	if d.candidate != "" && d.candidateCount >= minToneBlocks {
+		d.current = d.candidate
+		d.currentBlocks = d.candidateCount
+		d.candidate = ""
+		d.candidateCount = 0
+	}
+}
+
+// detectBlock 判断一个块中是否存在有效的双音多频信号
+func (d *InBandDetector) detectBlock(block []float64) string {
+	var energy float64
+	for _, v := range block {
+		energy += v * v
+	}
+	n := float64(len(block))
+	// 幅度为 A 的正弦在块内的能量约为 A²N/2
+	minEnergy := minToneAmplitude * minToneAmplitude * n / 2
+	if energy < 2*minEnergy {
+		return ""
+	}
+
+	row, rowEnergy, rowSecond := strongest(block, d.rowCoeffs)
+	col, colEnergy, colSecond := strongest(block, d.colCoeffs)
+
+	if rowEnergy < minEnergy || colEnergy < minEnergy {
+		return ""
+	}
+	if colEnergy*maxNormalTwist < rowEnergy || rowEnergy*maxReverseTwist < colEnergy {
+		return ""
+	}
+	if rowSecond*minPeakRatio > rowEnergy || colSecond*minPeakRatio > colEnergy {
+		return ""
+	}
+	if rowEnergy+colEnergy < minToneFraction*energy {
+		return ""
+	}
+	return keypad[row][col]
+}
+
+// strongest 返回一组频率中能量最大者的下标、能量以及第二大的能量
+func strongest(block []float64, coeffs [4]float64) (int, float64, float64) {
+	best, bestEnergy, second := 0, 0.0, 0.0
+	for i, c := range coeffs {
+		e := goertzelEnergy(block, c)
+		if e > bestEnergy {
+			second = bestEnergy
+			best, bestEnergy = i, e
+		} else if e > second {
+			second = e
+		}
+	}
+	return best, bestEnergy, second
+}
+
+// goertzelEnergy 计算单个频率分量在块内的能量（与时域能量同一量纲）
+func goertzelEnergy(block []float64, coeff float64) float64 {
+	var s1, s2 float64
+	for _, v := range block {
+		s := v + coeff*s1 - s2
+		s2, s1 = s1, s
+	}
+	power := s1*s1 + s2*s2 - coeff*s1*s2
+	// |X(k)|² ≈ A²N²/4，换算为能量 A²N/2
+	return 2 * power / float64(len(block))
+}
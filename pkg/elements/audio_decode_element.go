@@ -0,0 +1,127 @@
+package elements
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/codec"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/utils"
+)
+
+// NewDecodeElement 根据协商的编码选择解码元素：Opus 使用 libopus，G.711/G.722 使用纯 Go 解码器
+func NewDecodeElement(bufferSize int, c codec.Codec) (pipeline.Element, error) {
+	if c.IsOpus() {
+		return NewOpusDecodeElement(bufferSize, c.SampleRate, c.Channels), nil
+	}
+	return NewAudioDecodeElement(bufferSize, c)
+}
+
+// AudioDecodeElement 解码 G.711/G.722 RTP 负载，输出 S16 PCM
+// 丢失的帧以与上一帧等长的静音代替
+type AudioDecodeElement struct {
+	*pipeline.BaseElement
+
+	codec   codec.Codec
+	decoder codec.Decoder
+
+	lastSamples int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewAudioDecodeElement(bufferSize int, c codec.Codec) (*AudioDecodeElement, error) {
+	decoder, err := codec.NewDecoder(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AudioDecodeElement{
+		BaseElement: pipeline.NewBaseElement(bufferSize),
+		codec:       c,
+		decoder:     decoder,
+		lastSamples: c.FrameSamples(),
+	}, nil
+}
+
+func (e *AudioDecodeElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-e.BaseElement.InChan:
+				if msg.Type != pipeline.MsgTypeAudio {
+					continue
+				}
+
+				if msg.AudioData.MediaType != e.codec.MediaType {
+					continue
+				}
+
+				var pcm []int16
+				if msg.AudioData.Lost {
+					pcm = make([]int16, e.lastSamples)
+				} else {
+					if len(msg.AudioData.Data) == 0 {
+						continue
+					}
+
+					var err error
+					pcm, err = e.decoder.Decode(msg.AudioData.Data)
+					if err != nil {
+						log.Printf("%s decode error: %v", e.codec.Name, err)
+						continue
+					}
+					e.lastSamples = len(pcm)
+				}
+
+				outMsg := pipeline.PipelineMessage{
+					Type:      pipeline.MsgTypeAudio,
+					SessionID: msg.SessionID,
+					Timestamp: time.Now(),
+					AudioData: &pipeline.AudioData{
+						Data:       utils.Int16SliceToByteSlice(pcm),
+						MediaType:  "audio/x-raw",
+						SampleRate: e.codec.SampleRate,
+						Channels:   1,
+						Timestamp:  time.Now(),
+					},
+				}
+
+				select {
+				case e.BaseElement.OutChan <- outMsg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (e *AudioDecodeElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *AudioDecodeElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *AudioDecodeElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
@@ -6,7 +6,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/asticode/go-astiav"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
 )
@@ -19,28 +18,14 @@ type AudioResampleElement struct {
 	inChannels  int
 	outChannels int
 
-	resample *audio.Resample
+	resample audio.Resampler
 
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 }
 
 func NewAudioResampleElement(inRate, outRate int, inChannels, outChannels int) *AudioResampleElement {
-	inLayout := astiav.ChannelLayoutMono
-	outLayout := astiav.ChannelLayoutMono
-	if inChannels == 1 {
-		inLayout = astiav.ChannelLayoutMono
-	} else if inChannels == 2 {
-		inLayout = astiav.ChannelLayoutStereo
-	}
-
-	if outChannels == 1 {
-		outLayout = astiav.ChannelLayoutMono
-	} else if outChannels == 2 {
-		outLayout = astiav.ChannelLayoutStereo
-	}
-
-	resample, err := audio.NewResample(inRate, outRate, inLayout, outLayout)
+	resample, err := audio.NewResampler(inRate, outRate, inChannels, outChannels)
 	if err != nil {
 		log.Fatalf("failed to create resample: %v", err)
 	}
@@ -80,12 +65,27 @@ func (e *AudioResampleElement) Start(ctx context.Context) error {
 					continue
 				}
 
+				if msg.AudioData.Format() != pipeline.SampleFormatS16 {
+					log.Printf("resample: unsupported sample format %s", msg.AudioData.Format())
+					continue
+				}
+
+				// 声道数不符时不做隐式转换，应在上游使用 ChannelMixElement
+				if msg.AudioData.Channels != 0 && msg.AudioData.Channels != e.inChannels {
+					log.Printf("resample: unexpected %d-channel input, want %d", msg.AudioData.Channels, e.inChannels)
+					continue
+				}
+
 				// 重采样
 				outData, err := e.resample.Resample(msg.AudioData.Data)
 				if err != nil {
 					log.Printf("Resample error: %v", err)
 					continue
 				}
+				// 重采样器可能暂存输入以等待足够的滤波器上下文
+				if len(outData) == 0 {
+					continue
+				}
 
 				// 创建输出消息
 				outMsg := pipeline.PipelineMessage{
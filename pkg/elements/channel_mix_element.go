@@ -0,0 +1,169 @@
+package elements
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// ChannelMixElement 将 audio/x-raw 转换为固定的声道布局
+// 输入声道数以消息中的 Channels 为准，布局变化时自动重建混音器；
+// 输出始终为 OutChannels() 声明的声道数，下游元素可以直接依赖
+type ChannelMixElement struct {
+	*pipeline.BaseElement
+
+	outChannels int
+	strategy    audio.DownmixStrategy
+	matrix      [][]float64
+
+	mixer *audio.ChannelMixer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewChannelMixElement 创建使用默认矩阵的声道转换元素，strategy 决定下混为单声道的方式
+func NewChannelMixElement(bufferSize int, outChannels int, strategy audio.DownmixStrategy) (*ChannelMixElement, error) {
+	// 提前校验参数，避免到第一条消息时才报错
+	if _, err := audio.ParseDownmixStrategy(string(strategy)); err != nil {
+		return nil, err
+	}
+	if _, err := audio.NewChannelMixer(outChannels, outChannels, strategy); err != nil {
+		return nil, err
+	}
+
+	return &ChannelMixElement{
+		BaseElement: pipeline.NewBaseElement(bufferSize),
+		outChannels: outChannels,
+		strategy:    strategy,
+	}, nil
+}
+
+// NewChannelMixElementWithMatrix 创建使用自定义矩阵的声道转换元素
+// 矩阵维度为 [outChannels][inChannels]，输入声道数与矩阵不符的消息将被丢弃
+func NewChannelMixElementWithMatrix(bufferSize int, matrix [][]float64) (*ChannelMixElement, error) {
+	mixer, err := audio.NewChannelMixerWithMatrix(matrix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChannelMixElement{
+		BaseElement: pipeline.NewBaseElement(bufferSize),
+		outChannels: mixer.OutChannels(),
+		matrix:      matrix,
+		mixer:       mixer,
+	}, nil
+}
+
+// OutChannels 返回输出声道数
+func (e *ChannelMixElement) OutChannels() int {
+	return e.outChannels
+}
+
+func (e *ChannelMixElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-e.BaseElement.InChan:
+				if msg.Type != pipeline.MsgTypeAudio {
+					continue
+				}
+
+				if msg.AudioData.MediaType != "audio/x-raw" {
+					continue
+				}
+
+				if len(msg.AudioData.Data) == 0 {
+					continue
+				}
+
+				if msg.AudioData.Format() != pipeline.SampleFormatS16 {
+					log.Printf("channel mix: unsupported sample format %s", msg.AudioData.Format())
+					continue
+				}
+
+				mixer, err := e.mixerFor(msg.AudioData.Channels)
+				if err != nil {
+					log.Printf("channel mix error: %v", err)
+					continue
+				}
+
+				outData, err := mixer.Mix(msg.AudioData.Data)
+				if err != nil {
+					log.Printf("channel mix error: %v", err)
+					continue
+				}
+
+				outMsg := pipeline.PipelineMessage{
+					Type:      pipeline.MsgTypeAudio,
+					SessionID: msg.SessionID,
+					Timestamp: time.Now(),
+					AudioData: &pipeline.AudioData{
+						Data:       outData,
+						SampleRate: msg.AudioData.SampleRate,
+						Channels:   e.outChannels,
+						MediaType:  "audio/x-raw",
+						Timestamp:  time.Now(),
+					},
+				}
+
+				select {
+				case e.BaseElement.OutChan <- outMsg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// mixerFor 返回处理指定输入声道数的混音器
+func (e *ChannelMixElement) mixerFor(inChannels int) (*audio.ChannelMixer, error) {
+	if e.mixer != nil && e.mixer.InChannels() == inChannels {
+		return e.mixer, nil
+	}
+	if e.matrix != nil {
+		return nil, fmt.Errorf("input has %d channels, mix matrix expects %d", inChannels, e.mixer.InChannels())
+	}
+
+	mixer, err := audio.NewChannelMixer(inChannels, e.outChannels, e.strategy)
+	if err != nil {
+		return nil, err
+	}
+	if e.mixer != nil {
+		log.Printf("channel layout changed: %d -> %d channels", e.mixer.InChannels(), inChannels)
+	}
+	e.mixer = mixer
+	return mixer, nil
+}
+
+func (e *ChannelMixElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *ChannelMixElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *ChannelMixElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
@@ -0,0 +1,200 @@
+package elements
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/speech"
+)
+
+// ChatElement 级联模式的对话：收到用户的最终转写后用 ChatModel 生成回复，
+// 以模型文本（text）流式投递，结束时投递 turn_complete。生成过程中收到 speech_started
+// 时取消生成并投递 interrupted（打断），已生成的部分计入对话历史。所有输入消息原样透传
+type ChatElement struct {
+	*pipeline.BaseElement
+
+	model speech.ChatModel
+	bus   pipeline.Bus
+
+	// mu 保护以下字段，回复在持有 mu 时投递，保证 interrupted 之后不会再投递被打断的回复
+	mu          sync.Mutex
+	history     []speech.ChatMessage
+	generation  int
+	replying    bool
+	replyTurn   string
+	replyText   strings.Builder
+	cancelReply context.CancelFunc
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewChatElement 创建对话元素，instructions 非空时作为系统消息
+func NewChatElement(model speech.ChatModel, instructions string) *ChatElement {
+	e := &ChatElement{
+		BaseElement: pipeline.NewBaseElement(100),
+		model:       model,
+	}
+	if instructions != "" {
+		e.history = append(e.history, speech.ChatMessage{Role: speech.ChatRoleSystem, Content: instructions})
+	}
+	return e
+}
+
+// SetBus 设置事件总线，打断时发布 EventBargeIn
+func (e *ChatElement) SetBus(bus pipeline.Bus) {
+	e.bus = bus
+}
+
+// History 返回对话历史的副本
+func (e *ChatElement) History() []speech.ChatMessage {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]speech.ChatMessage(nil), e.history...)
+}
+
+func (e *ChatElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go e.inputLoop(ctx)
+	return nil
+}
+
+func (e *ChatElement) inputLoop(ctx context.Context) {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-e.BaseElement.InChan:
+			if msg.Type != pipeline.MsgTypeText || msg.TextData == nil {
+				e.emit(ctx, msg)
+				continue
+			}
+			switch t := msg.TextData; {
+			case t.Type == pipeline.TextEventSpeechStarted:
+				e.interrupt(ctx, msg.SessionID)
+				e.emit(ctx, msg)
+			case t.Type == pipeline.TextEventInputTranscript && t.Final:
+				e.emit(ctx, msg)
+				if text := strings.TrimSpace(t.Text); text != "" {
+					e.interrupt(ctx, msg.SessionID)
+					e.reply(ctx, msg.SessionID, t.TurnID, text)
+				}
+			default:
+				e.emit(ctx, msg)
+			}
+		}
+	}
+}
+
+// interrupt 取消正在生成的回复并投递 interrupted，没有时什么都不做
+func (e *ChatElement) interrupt(ctx context.Context, sessionID string) {
+	e.mu.Lock()
+	if !e.replying {
+		e.mu.Unlock()
+		return
+	}
+	turnID := e.replyTurn
+	e.finishReply()
+	e.mu.Unlock()
+
+	if e.bus != nil {
+		e.bus.Publish(pipeline.Event{Type: pipeline.EventBargeIn, Timestamp: time.Now(), Payload: turnID})
+	}
+	e.emitText(ctx, sessionID, pipeline.TextData{Type: pipeline.TextEventInterrupted, Role: pipeline.RoleModel, TurnID: turnID})
+}
+
+// finishReply 结束当前回复并把已生成的文本计入历史，调用方持有 mu
+func (e *ChatElement) finishReply() {
+	if text := e.replyText.String(); text != "" {
+		e.history = append(e.history, speech.ChatMessage{Role: speech.ChatRoleAssistant, Content: text})
+	}
+	e.replyText.Reset()
+	e.replying = false
+	e.generation++
+	if e.cancelReply != nil {
+		e.cancelReply()
+		e.cancelReply = nil
+	}
+}
+
+// reply 把用户的话加入历史，并在新协程中生成回复
+func (e *ChatElement) reply(ctx context.Context, sessionID, turnID, text string) {
+	e.mu.Lock()
+	e.history = append(e.history, speech.ChatMessage{Role: speech.ChatRoleUser, Content: text})
+	messages := append([]speech.ChatMessage(nil), e.history...)
+	replyCtx, cancel := context.WithCancel(ctx)
+	e.generation++
+	generation := e.generation
+	e.replying = true
+	e.replyTurn = turnID
+	e.cancelReply = cancel
+	e.mu.Unlock()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		err := e.model.Chat(replyCtx, messages, func(delta string) error {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			if generation != e.generation {
+				return context.Canceled
+			}
+			e.replyText.WriteString(delta)
+			e.emitText(replyCtx, sessionID, pipeline.TextData{Type: pipeline.TextEventModelText, Text: delta, Role: pipeline.RoleModel, TurnID: turnID})
+			return replyCtx.Err()
+		})
+		if err != nil && replyCtx.Err() == nil {
+			log.Printf("chat error: %v", err)
+		}
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if generation != e.generation {
+			return
+		}
+		e.finishReply()
+		// 出错时同样结束本轮，客户端和 TTSElement 不必等待
+		e.emitText(ctx, sessionID, pipeline.TextData{Type: pipeline.TextEventTurnComplete, Role: pipeline.RoleModel, TurnID: turnID})
+	}()
+}
+
+func (e *ChatElement) emitText(ctx context.Context, sessionID string, data pipeline.TextData) {
+	e.emit(ctx, pipeline.PipelineMessage{
+		Type:      pipeline.MsgTypeText,
+		SessionID: sessionID,
+		Timestamp: time.Now(),
+		TextData:  &data,
+	})
+}
+
+func (e *ChatElement) emit(ctx context.Context, msg pipeline.PipelineMessage) {
+	select {
+	case e.BaseElement.OutChan <- msg:
+	case <-ctx.Done():
+	}
+}
+
+func (e *ChatElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *ChatElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *ChatElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
@@ -0,0 +1,211 @@
+package elements
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// DataChannel 消息中 type 字段的取值，除 pipeline.TextEventType 外的其他事件
+const (
+	DataChannelEventDTMF = "dtmf"
+)
+
+// 通道打开前最多缓存的消息数，超出时丢弃最早的消息
+const maxPendingDataChannelMessages = 256
+
+// DataChannelEvent 通过 DataChannel 发送给客户端的 JSON 消息
+//
+//	{"type":"text","text":"你好","role":"model","turn_id":"turn-0","timestamp":1700000000000}
+//	{"type":"input_transcript","text":"hello","final":true,"role":"user","turn_id":"turn-0","timestamp":...}
+//	{"type":"output_transcript","text":"hi there","role":"model","turn_id":"turn-0","timestamp":...}
+//	{"type":"turn_complete","role":"model","turn_id":"turn-0","timestamp":...}
+//	{"type":"interrupted","role":"model","turn_id":"turn-1","timestamp":...}
+//	{"type":"usage","usage":{"prompt_tokens":12,"response_tokens":34,"total_tokens":46},"turn_id":"turn-1","timestamp":...}
+//	{"type":"tool_call","tool":{"id":"fc-1","name":"get_current_time","status":"started","args":{"timezone":"Asia/Shanghai"}},"role":"model","turn_id":"turn-2","timestamp":...}
+//	{"type":"tool_call","tool":{"id":"fc-1","name":"get_current_time","status":"completed","result":{"time":"..."},"duration_ms":3},"role":"model","turn_id":"turn-2","timestamp":...}
+//	{"type":"session_state","state":{"status":"reconnecting","reason":"server going away","attempt":1},"timestamp":...}
+//	{"type":"session_state","state":{"status":"connected","attempt":1,"resumed":true},"timestamp":...}
+//	{"type":"dtmf","digit":"3","duration_ms":120,"source":"rfc4733","timestamp":...}
+type DataChannelEvent struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id,omitempty"`
+	Timestamp int64  `json:"timestamp"` // Unix 毫秒
+
+	Text   string          `json:"text,omitempty"`
+	Final  bool            `json:"final,omitempty"`
+	Role   string          `json:"role,omitempty"`
+	TurnID string          `json:"turn_id,omitempty"`
+	Usage  *pipeline.Usage `json:"usage,omitempty"`
+
+	Tool  *pipeline.ToolActivity `json:"tool,omitempty"`
+	State *pipeline.SessionState `json:"state,omitempty"`
+
+	Digit      string `json:"digit,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Source     string `json:"source,omitempty"`
+}
+
+// DataChannelSinkElement 将 MsgTypeText 消息序列化为 DataChannelEvent 发送给客户端，
+// 所有消息再原样透传给下游（WebRTCSinkElement 据 interrupted 清空播放缓冲），
+// 因此可以直接串接在 RealtimeElement 与 WebRTCSinkElement 之间。
+// DataChannel 由客户端创建，在 SetDataChannel 且通道打开之前的消息会被缓存
+type DataChannelSinkElement struct {
+	*pipeline.BaseElement
+
+	mu      sync.Mutex
+	dc      *webrtc.DataChannel
+	pending [][]byte
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewDataChannelSinkElement(bufferSize int) *DataChannelSinkElement {
+	return &DataChannelSinkElement{
+		BaseElement: pipeline.NewBaseElement(bufferSize),
+	}
+}
+
+// SetDataChannel 设置发送用的 DataChannel，通道打开后发送缓存的消息
+func (e *DataChannelSinkElement) SetDataChannel(dc *webrtc.DataChannel) {
+	e.mu.Lock()
+	e.dc = dc
+	e.mu.Unlock()
+
+	dc.OnOpen(e.flush)
+	if dc.ReadyState() == webrtc.DataChannelStateOpen {
+		e.flush()
+	}
+}
+
+// Send 发送一条事件，Timestamp 为空时使用当前时间
+func (e *DataChannelSinkElement) Send(ev DataChannelEvent) error {
+	if ev.Timestamp == 0 {
+		ev.Timestamp = time.Now().UnixMilli()
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.dc == nil || e.dc.ReadyState() != webrtc.DataChannelStateOpen {
+		e.enqueue(data)
+		return nil
+	}
+	if len(e.pending) > 0 {
+		// 之前的发送失败留下了缓存：排在缓存之后，先重试缓存再发送，保持顺序
+		e.enqueue(data)
+		return e.flushLocked()
+	}
+	return e.dc.Send(data)
+}
+
+// enqueue 缓存一条消息，调用方需持有 mu
+func (e *DataChannelSinkElement) enqueue(data []byte) {
+	if len(e.pending) >= maxPendingDataChannelMessages {
+		log.Printf("data channel not open, dropping oldest pending message")
+		e.pending = e.pending[1:]
+	}
+	e.pending = append(e.pending, data)
+}
+
+// flush 按顺序发送缓存的消息
+func (e *DataChannelSinkElement) flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.dc == nil || e.dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return
+	}
+	if err := e.flushLocked(); err != nil {
+		log.Printf("data channel send error: %v", err)
+	}
+}
+
+// flushLocked 发送缓存的消息，失败时保留未发送的部分，调用方需持有 mu
+func (e *DataChannelSinkElement) flushLocked() error {
+	for len(e.pending) > 0 {
+		if err := e.dc.Send(e.pending[0]); err != nil {
+			return err
+		}
+		e.pending = e.pending[1:]
+	}
+	e.pending = nil
+	return nil
+}
+
+func (e *DataChannelSinkElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-e.BaseElement.InChan:
+				if msg.Type == pipeline.MsgTypeText && msg.TextData != nil {
+					e.sendText(msg)
+				}
+
+				select {
+				case e.BaseElement.OutChan <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// sendText 将一条 MsgTypeText 消息转换为 DataChannelEvent 发送
+func (e *DataChannelSinkElement) sendText(msg pipeline.PipelineMessage) {
+	ev := DataChannelEvent{
+		Type:      string(msg.TextData.Type),
+		SessionID: msg.SessionID,
+		Timestamp: msg.Timestamp.UnixMilli(),
+		Text:      msg.TextData.Text,
+		Final:     msg.TextData.Final,
+		Role:      msg.TextData.Role,
+		TurnID:    msg.TextData.TurnID,
+		Usage:     msg.TextData.Usage,
+		Tool:      msg.TextData.Tool,
+		State:     msg.TextData.State,
+	}
+	if msg.Timestamp.IsZero() {
+		ev.Timestamp = 0
+	}
+	if err := e.Send(ev); err != nil {
+		log.Printf("data channel send error: %v", err)
+	}
+}
+
+func (e *DataChannelSinkElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *DataChannelSinkElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *DataChannelSinkElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
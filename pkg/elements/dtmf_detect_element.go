@@ -0,0 +1,104 @@
+package elements
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/dtmf"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/utils"
+)
+
+// DTMFDetectElement 在单声道 S16 PCM 中检测带内双音多频按键，音频原样透传
+type DTMFDetectElement struct {
+	*pipeline.BaseElement
+
+	onDigit func(dtmf.Event)
+
+	detector   *dtmf.InBandDetector
+	sampleRate int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDTMFDetectElement 创建带内按键检测元素，检测到的按键通过 onDigit 回调
+func NewDTMFDetectElement(bufferSize int, onDigit func(dtmf.Event)) *DTMFDetectElement {
+	return &DTMFDetectElement{
+		BaseElement: pipeline.NewBaseElement(bufferSize),
+		onDigit:     onDigit,
+	}
+}
+
+func (e *DTMFDetectElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-e.BaseElement.InChan:
+				if msg.Type == pipeline.MsgTypeAudio {
+					e.detect(msg.AudioData)
+				}
+
+				select {
+				case e.BaseElement.OutChan <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// detect 对一条音频消息做检测，不满足单声道 S16 的消息直接跳过
+func (e *DTMFDetectElement) detect(data *pipeline.AudioData) {
+	if data == nil || data.MediaType != "audio/x-raw" || len(data.Data) == 0 {
+		return
+	}
+	if data.Channels != 1 || data.Format() != pipeline.SampleFormatS16 {
+		return
+	}
+
+	if e.detector == nil || e.sampleRate != data.SampleRate {
+		e.detector = dtmf.NewInBandDetector(data.SampleRate)
+		e.sampleRate = data.SampleRate
+	}
+
+	pcm, err := utils.ByteSliceToInt16Slice(data.Data)
+	if err != nil {
+		log.Printf("dtmf detect error: %v", err)
+		return
+	}
+
+	for _, ev := range e.detector.Process(pcm) {
+		if e.onDigit != nil {
+			e.onDigit(ev)
+		}
+	}
+}
+
+func (e *DTMFDetectElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *DTMFDetectElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *DTMFDetectElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
@@ -0,0 +1,134 @@
+package elements
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// DumpElement 把经过的 audio/x-raw 音频转储为 WAV，所有消息原样透传，可插在管线的任意位置。
+// 采样率、声道数和样本格式取自消息，每种采样率和声道数各写一个文件，格式来回切换时接着写原来的文件；
+// 非 S16LE 的样本转换为 S16LE 后写入
+type DumpElement struct {
+	*pipeline.BaseElement
+
+	config    *audio.DumpConfig
+	sessionID string
+	point     audio.DumpPoint
+
+	// dumpers 按 [采样率, 声道数] 索引
+	dumpers map[[2]int]*audio.Dumper
+	// failed 创建文件失败或达到配额后不再转储
+	failed bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDumpElement 创建转储元素，文件写在 config 中该会话的目录下，以 point 命名
+func NewDumpElement(config *audio.DumpConfig, sessionID string, point audio.DumpPoint) *DumpElement {
+	return &DumpElement{
+		BaseElement: pipeline.NewBaseElement(100),
+		config:      config,
+		sessionID:   sessionID,
+		point:       point,
+		dumpers:     make(map[[2]int]*audio.Dumper),
+	}
+}
+
+func (e *DumpElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer e.closeDumpers()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-e.BaseElement.InChan:
+				if !ok {
+					return
+				}
+				if msg.Type == pipeline.MsgTypeAudio && msg.AudioData != nil {
+					e.dump(msg.AudioData)
+				}
+
+				select {
+				case e.BaseElement.OutChan <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// dump 写入一帧音频，出错时记录日志，不影响透传
+func (e *DumpElement) dump(data *pipeline.AudioData) {
+	if e.failed || data.MediaType != "audio/x-raw" || len(data.Data) == 0 {
+		return
+	}
+
+	key := [2]int{data.SampleRate, data.Channels}
+	dumper := e.dumpers[key]
+	if dumper == nil {
+		var err error
+		dumper, err = e.config.NewDumper(e.sessionID, e.point, data.SampleRate, data.Channels)
+		if err != nil {
+			log.Printf("create %s dumper error: %v", e.point, err)
+			e.failed = true
+			return
+		}
+		e.dumpers[key] = dumper
+	}
+
+	pcm := data.Data
+	if format := data.Format(); format != pipeline.SampleFormatS16 {
+		var err error
+		if pcm, err = audio.ConvertSampleFormat(pcm, format, pipeline.SampleFormatS16); err != nil {
+			log.Printf("%s dump: %v", e.point, err)
+			return
+		}
+	}
+	if err := dumper.Write(pcm); err != nil {
+		log.Printf("%s dump error: %v", e.point, err)
+		if errors.Is(err, audio.ErrDiskQuota) {
+			e.failed = true
+			e.closeDumpers()
+		}
+	}
+}
+
+func (e *DumpElement) closeDumpers() {
+	for key, dumper := range e.dumpers {
+		if err := dumper.Close(); err != nil {
+			log.Printf("close %s dumper error: %v", e.point, err)
+		}
+		delete(e.dumpers, key)
+	}
+}
+
+func (e *DumpElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *DumpElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *DumpElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
@@ -0,0 +1,279 @@
+package elements
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/codec"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// FileSourceOptions FileSourceElement 的选项
+type FileSourceOptions struct {
+	// Realtime 为 true 时按音频时长定速输出（模拟麦克风），否则尽快输出（压测、离线评估）
+	Realtime bool
+	// Count 播放次数，0 为一次，负数为无限循环
+	Count int
+}
+
+// FileSourceElement 把录音文件作为音频源，用于没有浏览器时模拟麦克风：
+//   - WAV（16 位 PCM，含 RF64）：整个读入内存，按 20ms 输出 audio/x-raw，采样率和声道数取自文件
+//   - Ogg Opus：逐包输出 audio/x-opus，带连续的序号和 RTP 时间戳（48kHz），
+//     与远端 RTP 轨道的输出相同，可接入抖动缓冲和解码元素
+//
+// 所有遍数播放完后输出一条 MsgTypeEOS 消息。输入的消息被丢弃
+type FileSourceElement struct {
+	*pipeline.BaseElement
+
+	path string
+	opts FileSourceOptions
+
+	// WAV 时有效
+	format audio.WavFormat
+	pcm    []byte
+	// Ogg Opus 时有效
+	opus     bool
+	opusHead audio.OpusHead
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewFileSourceElement 按文件头识别格式并检查文件，Ogg Opus 在播放时逐遍重新打开
+func NewFileSourceElement(path string, opts FileSourceOptions) (*FileSourceElement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(f, magic)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	e := &FileSourceElement{
+		BaseElement: pipeline.NewBaseElement(100),
+		path:        path,
+		opts:        opts,
+	}
+	switch string(magic) {
+	case "RIFF", "RF64":
+		e.format, e.pcm, err = audio.ReadWav(path)
+		if err != nil {
+			return nil, err
+		}
+	case "OggS":
+		reader, err := audio.OpenOggOpus(path)
+		if err != nil {
+			return nil, err
+		}
+		e.opus = true
+		e.opusHead = reader.Head()
+		reader.Close()
+	default:
+		return nil, fmt.Errorf("%s is neither WAV nor Ogg Opus", path)
+	}
+	return e, nil
+}
+
+// SampleRate 输出音频的采样率（Ogg Opus 为 48000）
+func (e *FileSourceElement) SampleRate() int {
+	if e.opus {
+		return codec.Opus.SampleRate
+	}
+	return e.format.SampleRate
+}
+
+// Channels 输出音频的声道数
+func (e *FileSourceElement) Channels() int {
+	if e.opus {
+		return e.opusHead.Channels
+	}
+	return e.format.Channels
+}
+
+// MediaType 输出音频的类型：audio/x-raw 或 audio/x-opus
+func (e *FileSourceElement) MediaType() string {
+	if e.opus {
+		return codec.Opus.MediaType
+	}
+	return "audio/x-raw"
+}
+
+func (e *FileSourceElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(2)
+	go func() {
+		defer e.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-e.BaseElement.InChan:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		defer e.wg.Done()
+		e.run(ctx)
+	}()
+	return nil
+}
+
+// fileSourceClock 各遍共用的时间线
+type fileSourceClock struct {
+	start time.Time
+	// elapsed 之前各遍的总时长
+	elapsed time.Duration
+	// Ogg Opus 的 RTP 序号和时间戳
+	seq     uint16
+	rtpBase uint32
+}
+
+func (e *FileSourceElement) run(ctx context.Context) {
+	clock := &fileSourceClock{
+		start:   time.Now(),
+		seq:     uint16(rand.Uint32()),
+		rtpBase: rand.Uint32(),
+	}
+
+	for n := 0; e.opts.Count < 0 || n < max(e.opts.Count, 1); n++ {
+		var played time.Duration
+		var err error
+		if e.opus {
+			played, err = e.playOpus(ctx, clock)
+		} else {
+			played, err = e.playWav(ctx, clock)
+		}
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Printf("file source %s error: %v", e.path, err)
+			}
+			return
+		}
+		clock.elapsed += played
+		// 空文件不循环
+		if played == 0 {
+			break
+		}
+	}
+
+	select {
+	case e.BaseElement.OutChan <- pipeline.PipelineMessage{Type: pipeline.MsgTypeEOS, Timestamp: time.Now()}:
+	case <-ctx.Done():
+	}
+}
+
+// playWav 播放一遍 WAV，返回这一遍的时长
+func (e *FileSourceElement) playWav(ctx context.Context, clock *fileSourceClock) (time.Duration, error) {
+	bytesPerFrame := e.format.Channels * 2
+	frameBytes := e.format.SampleRate / 50 * bytesPerFrame // 20ms
+	toDuration := func(n int) time.Duration {
+		return time.Duration(n/bytesPerFrame) * time.Second / time.Duration(e.format.SampleRate)
+	}
+
+	for off := 0; off < len(e.pcm); off += frameBytes {
+		chunk := e.pcm[off:min(off+frameBytes, len(e.pcm))]
+		data := &pipeline.AudioData{
+			Data:       bytes.Clone(chunk),
+			SampleRate: e.format.SampleRate,
+			Channels:   e.format.Channels,
+			MediaType:  "audio/x-raw",
+		}
+		if err := e.emit(ctx, clock, clock.elapsed+toDuration(off), data); err != nil {
+			return 0, err
+		}
+	}
+	return toDuration(len(e.pcm)), nil
+}
+
+// playOpus 播放一遍 Ogg Opus，返回这一遍的时长
+func (e *FileSourceElement) playOpus(ctx context.Context, clock *fileSourceClock) (time.Duration, error) {
+	reader, err := audio.OpenOggOpus(e.path)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	toDuration := func(samples int64) time.Duration {
+		return time.Duration(samples) * time.Second / audio.OpusClockRate
+	}
+	offset := uint32(clock.elapsed * audio.OpusClockRate / time.Second)
+	var end int64
+	for {
+		pkt, err := reader.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			return toDuration(end), nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		data := &pipeline.AudioData{
+			Data:           pkt.Data,
+			SampleRate:     codec.Opus.SampleRate,
+			Channels:       e.opusHead.Channels,
+			MediaType:      codec.Opus.MediaType,
+			Codec:          codec.Opus.Name,
+			SequenceNumber: clock.seq,
+			RTPTimestamp:   clock.rtpBase + offset + uint32(pkt.Position),
+		}
+		clock.seq++
+		if err := e.emit(ctx, clock, clock.elapsed+toDuration(pkt.Position), data); err != nil {
+			return 0, err
+		}
+		end = pkt.Position + int64(pkt.Samples)
+	}
+}
+
+// emit 实时模式下等到 at（相对开始播放的时刻）再输出
+func (e *FileSourceElement) emit(ctx context.Context, clock *fileSourceClock, at time.Duration, data *pipeline.AudioData) error {
+	if e.opts.Realtime {
+		select {
+		case <-time.After(time.Until(clock.start.Add(at))):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	now := time.Now()
+	data.Timestamp = now
+	select {
+	case e.BaseElement.OutChan <- pipeline.PipelineMessage{Type: pipeline.MsgTypeAudio, Timestamp: now, AudioData: data}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *FileSourceElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *FileSourceElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *FileSourceElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
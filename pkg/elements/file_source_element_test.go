@@ -0,0 +1,156 @@
+package elements
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestWav 写一个 16 位 PCM WAV，样本值为递增的字节
+func writeTestWav(t *testing.T, sampleRate, channels int, d time.Duration) (string, []byte) {
+	path := filepath.Join(t.TempDir(), "caller.wav")
+	pcm := make([]byte, int(d.Seconds()*float64(sampleRate))*channels*2)
+	for i := range pcm {
+		pcm[i] = byte(i)
+	}
+	w, err := audio.NewWavStreamWriter(path, uint32(sampleRate), uint16(channels), 16)
+	require.NoError(t, err)
+	_, err = w.Write(pcm)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return path, pcm
+}
+
+// writeTestOpus 写一个包含 n 个 20ms CELT 包的立体声 Ogg Opus 文件
+func writeTestOpus(t *testing.T, n int) (string, [][]byte) {
+	path := filepath.Join(t.TempDir(), "caller.opus")
+	w, err := audio.NewOggOpusFileWriter(path, audio.OpusHead{Channels: 2})
+	require.NoError(t, err)
+	var packets [][]byte
+	for i := 0; i < n; i++ {
+		packet := append([]byte{31 << 3}, bytes.Repeat([]byte{byte(i)}, 10)...)
+		require.NoError(t, w.WritePacket(packet))
+		packets = append(packets, packet)
+	}
+	require.NoError(t, w.Close())
+	return path, packets
+}
+
+// collect 启动 e 并收集音频消息直到 EOS，检查 EOS 之后没有其他消息
+func collect(t *testing.T, e *FileSourceElement) []*pipeline.AudioData {
+	require.NoError(t, e.Start(context.Background()))
+	defer e.Stop()
+
+	var got []*pipeline.AudioData
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-e.Out():
+			if msg.Type == pipeline.MsgTypeEOS {
+				select {
+				case extra := <-e.Out():
+					t.Fatalf("message after EOS: %+v", extra)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return got
+			}
+			require.Equal(t, pipeline.MsgTypeAudio, msg.Type)
+			got = append(got, msg.AudioData)
+		case <-deadline:
+			t.Fatalf("no EOS after %d messages", len(got))
+		}
+	}
+}
+
+func TestFileSourceWav(t *testing.T) {
+	path, pcm := writeTestWav(t, 8000, 2, 50*time.Millisecond)
+	e, err := NewFileSourceElement(path, FileSourceOptions{Count: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 8000, e.SampleRate())
+	assert.Equal(t, 2, e.Channels())
+	assert.Equal(t, "audio/x-raw", e.MediaType())
+
+	got := collect(t, e)
+	// 每遍 20ms、20ms、10ms，共两遍
+	var sizes []int
+	var played []byte
+	for _, data := range got {
+		sizes = append(sizes, len(data.Data))
+		played = append(played, data.Data...)
+		assert.Equal(t, 8000, data.SampleRate)
+		assert.Equal(t, 2, data.Channels)
+		assert.Equal(t, "audio/x-raw", data.MediaType)
+		assert.Equal(t, pipeline.SampleFormatS16, data.Format())
+		assert.False(t, data.Timestamp.IsZero())
+	}
+	assert.Equal(t, []int{640, 640, 320, 640, 640, 320}, sizes)
+	assert.Equal(t, append(append([]byte(nil), pcm...), pcm...), played)
+}
+
+func TestFileSourceOggOpusLoop(t *testing.T) {
+	path, packets := writeTestOpus(t, 3)
+	e, err := NewFileSourceElement(path, FileSourceOptions{Count: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 48000, e.SampleRate())
+	assert.Equal(t, 2, e.Channels())
+	assert.Equal(t, "audio/x-opus", e.MediaType())
+
+	got := collect(t, e)
+	require.Len(t, got, 9)
+	for i, data := range got {
+		assert.Equal(t, packets[i%3], data.Data)
+		assert.Equal(t, "audio/x-opus", data.MediaType)
+		assert.Equal(t, "opus", data.Codec)
+		assert.Equal(t, 48000, data.SampleRate)
+		assert.Equal(t, 2, data.Channels)
+		if i > 0 {
+			// 跨遍连续递增，与实时轨道一样
+			assert.Equal(t, got[i-1].SequenceNumber+1, data.SequenceNumber, i)
+			assert.Equal(t, got[i-1].RTPTimestamp+960, data.RTPTimestamp, i)
+		}
+	}
+}
+
+func TestFileSourceRealtime(t *testing.T) {
+	path, _ := writeTestWav(t, 16000, 1, 100*time.Millisecond)
+
+	// 尽快输出
+	e, err := NewFileSourceElement(path, FileSourceOptions{Count: 2})
+	require.NoError(t, err)
+	start := time.Now()
+	collect(t, e)
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+
+	// 按时长定速：两遍共 200ms，最后一帧在 180ms 输出
+	e, err = NewFileSourceElement(path, FileSourceOptions{Realtime: true, Count: 2})
+	require.NoError(t, err)
+	start = time.Now()
+	require.NoError(t, e.Start(context.Background()))
+	defer e.Stop()
+	var at []time.Duration
+	for msg := range e.Out() {
+		if msg.Type == pipeline.MsgTypeEOS {
+			break
+		}
+		at = append(at, time.Since(start))
+	}
+	require.Len(t, at, 10)
+	assert.GreaterOrEqual(t, at[9], 180*time.Millisecond)
+	assert.Less(t, at[0], 20*time.Millisecond)
+	assert.GreaterOrEqual(t, at[5], 100*time.Millisecond, "second pass starts after the first")
+}
+
+func TestFileSourceUnknownFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello world"), 0o644))
+	_, err := NewFileSourceElement(path, FileSourceOptions{})
+	assert.Error(t, err)
+}
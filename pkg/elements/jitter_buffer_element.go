@@ -0,0 +1,141 @@
+package elements
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// JitterBufferElement 对 RTP 编码音频做重排和丢包检测
+// 输入为带 SequenceNumber/RTPTimestamp 的编码音频消息（audio/x-opus、audio/x-mulaw 等），输出按序排列的帧；
+// 丢失的帧以 Lost=true 输出，由下游解码元素做丢包隐藏（Opus 还可以用 FEC 恢复）
+type JitterBufferElement struct {
+	*pipeline.BaseElement
+
+	jitter     *audio.JitterBuffer
+	sampleRate int
+	channels   int
+	sessionID  string
+	mediaType  string
+	codec      string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewJitterBufferElement(bufferSize int, clockRate int, channels int) *JitterBufferElement {
+	return &JitterBufferElement{
+		BaseElement: pipeline.NewBaseElement(bufferSize),
+		jitter:      audio.NewJitterBuffer(clockRate, audio.DefaultJitterMinDelay, audio.DefaultJitterMaxDelay),
+		sampleRate:  clockRate,
+		channels:    channels,
+	}
+}
+
+func (e *JitterBufferElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		statsTicker := time.NewTicker(10 * time.Second)
+		defer statsTicker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-e.BaseElement.InChan:
+				if msg.Type != pipeline.MsgTypeAudio {
+					continue
+				}
+
+				if msg.AudioData.MediaType == "audio/x-raw" {
+					continue
+				}
+
+				e.sessionID = msg.SessionID
+				e.mediaType = msg.AudioData.MediaType
+				e.codec = msg.AudioData.Codec
+				e.jitter.Push(audio.JitterPacket{
+					SequenceNumber: msg.AudioData.SequenceNumber,
+					Timestamp:      msg.AudioData.RTPTimestamp,
+					Payload:        msg.AudioData.Data,
+				}, time.Now())
+
+				if !e.drain(ctx) {
+					return
+				}
+			case <-ticker.C:
+				if !e.drain(ctx) {
+					return
+				}
+			case <-statsTicker.C:
+				stats := e.jitter.Stats()
+				log.Printf("jitter buffer stats: %+v", stats)
+			}
+		}
+	}()
+	return nil
+}
+
+// drain 输出所有已到播放时间的帧，ctx 结束时返回 false
+func (e *JitterBufferElement) drain(ctx context.Context) bool {
+	for {
+		frame, ok := e.jitter.Pop(time.Now())
+		if !ok {
+			return true
+		}
+
+		outMsg := pipeline.PipelineMessage{
+			Type:      pipeline.MsgTypeAudio,
+			SessionID: e.sessionID,
+			Timestamp: time.Now(),
+			AudioData: &pipeline.AudioData{
+				Data:           frame.Payload,
+				SampleRate:     e.sampleRate,
+				Channels:       e.channels,
+				MediaType:      e.mediaType,
+				Codec:          e.codec,
+				Timestamp:      time.Now(),
+				SequenceNumber: frame.SequenceNumber,
+				RTPTimestamp:   frame.Timestamp,
+				Lost:           frame.Lost,
+			},
+		}
+
+		select {
+		case e.BaseElement.OutChan <- outMsg:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (e *JitterBufferElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+
+	e.jitter.Reset()
+	return nil
+}
+
+func (e *JitterBufferElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *JitterBufferElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
@@ -0,0 +1,126 @@
+package elements
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/codec"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// OggOpusSourceElement 回放 Ogg Opus 录音：按包输出 audio/x-opus 消息，带连续的序号和 RTP 时间戳，
+// 与远端 RTP 轨道的输出相同，可直接接入抖动缓冲和解码元素。输入的消息被丢弃
+type OggOpusSourceElement struct {
+	*pipeline.BaseElement
+
+	reader *audio.OggOpusReader
+	// realtime 为 true 时按包的时长定速输出，否则尽快输出
+	realtime bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewOggOpusSourceElement 创建回放元素，reader 在读完或 Stop 时由调用方关闭
+func NewOggOpusSourceElement(reader *audio.OggOpusReader, realtime bool) *OggOpusSourceElement {
+	return &OggOpusSourceElement{
+		BaseElement: pipeline.NewBaseElement(100),
+		reader:      reader,
+		realtime:    realtime,
+	}
+}
+
+func (e *OggOpusSourceElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(2)
+	go func() {
+		defer e.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.BaseElement.InChan:
+			}
+		}
+	}()
+	go func() {
+		defer e.wg.Done()
+		e.run(ctx)
+	}()
+	return nil
+}
+
+func (e *OggOpusSourceElement) run(ctx context.Context) {
+	channels := e.reader.Head().Channels
+	seq := uint16(rand.Uint32())
+	base := rand.Uint32()
+	start := time.Now()
+
+	for {
+		pkt, err := e.reader.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			log.Printf("read ogg opus error: %v", err)
+			return
+		}
+
+		// 包的起始时刻到达前等待
+		if e.realtime {
+			due := start.Add(time.Duration(pkt.Position) * time.Second / audio.OpusClockRate)
+			select {
+			case <-time.After(time.Until(due)):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		msg := pipeline.PipelineMessage{
+			Type:      pipeline.MsgTypeAudio,
+			Timestamp: time.Now(),
+			AudioData: &pipeline.AudioData{
+				Data:           pkt.Data,
+				SampleRate:     codec.Opus.SampleRate,
+				Channels:       channels,
+				MediaType:      codec.Opus.MediaType,
+				Codec:          codec.Opus.Name,
+				Timestamp:      time.Now(),
+				SequenceNumber: seq,
+				RTPTimestamp:   base + uint32(pkt.Position),
+			},
+		}
+		seq++
+
+		select {
+		case e.BaseElement.OutChan <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *OggOpusSourceElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *OggOpusSourceElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *OggOpusSourceElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
@@ -3,12 +3,10 @@ package elements
 import (
 	"context"
 	"log"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/hraban/opus"
-	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/utils"
 )
@@ -19,7 +17,6 @@ type OpusDecodeElement struct {
 	decoder    *opus.Decoder
 	sampleRate int
 	channels   int
-	dumper     *audio.Dumper
 
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
@@ -31,20 +28,11 @@ func NewOpusDecodeElement(bufferSize int, sampleRate int, channels int) *OpusDec
 		log.Fatalf("failed to create opus decoder: %v", err)
 	}
 
-	var dumper *audio.Dumper
-	if os.Getenv("DUMP_OPUS_DECODED") == "true" {
-		dumper, err = audio.NewDumper("opus_decoded", sampleRate, channels)
-		if err != nil {
-			log.Printf("create audio dumper error: %v", err)
-		}
-	}
-
 	return &OpusDecodeElement{
 		BaseElement: pipeline.NewBaseElement(bufferSize),
 		decoder:     decoder,
 		sampleRate:  sampleRate,
 		channels:    channels,
-		dumper:      dumper,
 	}
 }
 
@@ -70,26 +58,30 @@ func (e *OpusDecodeElement) Start(ctx context.Context) error {
 					continue
 				}
 
-				if len(msg.AudioData.Data) == 0 {
-					continue
-				}
+				var n int
+				var err error
+				if msg.AudioData.Lost {
+					// 丢包：有后一个包时用带内 FEC 恢复，否则做 PLC
+					n, err = e.conceal(msg.AudioData.Data, pcmBuf)
+					if err != nil {
+						log.Println("Opus concealment error:", err)
+						continue
+					}
+				} else {
+					if len(msg.AudioData.Data) == 0 {
+						continue
+					}
 
-				// 解码
-				n, err := e.decoder.Decode(msg.AudioData.Data, pcmBuf)
-				if err != nil {
-					log.Println("Opus decode error:", err)
-					continue
+					// 解码
+					n, err = e.decoder.Decode(msg.AudioData.Data, pcmBuf)
+					if err != nil {
+						log.Println("Opus decode error:", err)
+						continue
+					}
 				}
 
 				audioData := utils.Int16SliceToByteSlice(pcmBuf[:n])
 
-				// dump 音频数据
-				if e.dumper != nil {
-					if err := e.dumper.Write(audioData); err != nil {
-						log.Printf("Failed to dump audio: %v", err)
-					}
-				}
-
 				// 创建输出消息
 				outMsg := pipeline.PipelineMessage{
 					Type:      pipeline.MsgTypeAudio,
@@ -116,6 +108,29 @@ func (e *OpusDecodeElement) Start(ctx context.Context) error {
 	return nil
 }
 
+// conceal 为丢失的帧生成音频，返回每声道的采样点数
+func (e *OpusDecodeElement) conceal(fecData []byte, pcmBuf []int16) (int, error) {
+	// 丢失帧的时长与上一帧相同
+	n, err := e.decoder.LastPacketDuration()
+	if err != nil || n <= 0 {
+		n = e.sampleRate / 50
+	}
+	if n*e.channels > len(pcmBuf) {
+		n = len(pcmBuf) / e.channels
+	}
+	pcm := pcmBuf[:n*e.channels]
+
+	if len(fecData) > 0 {
+		if err := e.decoder.DecodeFEC(fecData, pcm); err == nil {
+			return n, nil
+		}
+	}
+	if err := e.decoder.DecodePLC(pcm); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
 func (e *OpusDecodeElement) Stop() error {
 	if e.cancel != nil {
 		e.cancel()
@@ -123,11 +138,6 @@ func (e *OpusDecodeElement) Stop() error {
 		e.cancel = nil
 	}
 
-	if e.dumper != nil {
-		e.dumper.Close()
-		e.dumper = nil
-	}
-
 	// 清空解码器引用
 	e.decoder = nil
 	return nil
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/hraban/opus"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/utils"
 )
@@ -15,6 +16,7 @@ type OpusEncodeElement struct {
 	*pipeline.BaseElement
 
 	encoder    *opus.Encoder
+	encoderMu  sync.Mutex
 	sampleRate int
 	channels   int
 
@@ -68,10 +70,21 @@ func (e *OpusEncodeElement) Start(ctx context.Context) error {
 					continue
 				}
 
-				pcmData := utils.ByteSliceToInt16Slice(msg.AudioData.Data)
+				if msg.AudioData.Format() != pipeline.SampleFormatS16 {
+					log.Printf("Opus encode: unsupported sample format %s", msg.AudioData.Format())
+					continue
+				}
+
+				pcmData, err := utils.ByteSliceToInt16Slice(msg.AudioData.Data)
+				if err != nil {
+					log.Println("Opus encode error:", err)
+					continue
+				}
 
 				// 编码
+				e.encoderMu.Lock()
 				n, err := e.encoder.Encode(pcmData, opusBuf)
+				e.encoderMu.Unlock()
 				if err != nil {
 					log.Println("Opus encode error:", err)
 					continue
@@ -105,6 +118,13 @@ func (e *OpusEncodeElement) Start(ctx context.Context) error {
 	return nil
 }
 
+// ApplyEncoderSettings 动态调整编码参数，替代默认的固定 64kbps
+func (e *OpusEncodeElement) ApplyEncoderSettings(s audio.EncoderSettings) error {
+	e.encoderMu.Lock()
+	defer e.encoderMu.Unlock()
+	return applyOpusSettings(e.encoder, s)
+}
+
 func (e *OpusEncodeElement) Stop() error {
 	if e.cancel != nil {
 		e.cancel()
@@ -113,7 +133,9 @@ func (e *OpusEncodeElement) Stop() error {
 	}
 
 	// 清空编码器引用
+	e.encoderMu.Lock()
 	e.encoder = nil
+	e.encoderMu.Unlock()
 	return nil
 }
 
@@ -0,0 +1,28 @@
+package elements
+
+import (
+	"fmt"
+
+	"github.com/hraban/opus"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+)
+
+// applyOpusSettings 将动态编码参数应用到 Opus 编码器
+func applyOpusSettings(encoder *opus.Encoder, s audio.EncoderSettings) error {
+	if encoder == nil {
+		return fmt.Errorf("opus encoder not initialized")
+	}
+	if err := encoder.SetBitrate(s.Bitrate); err != nil {
+		return fmt.Errorf("set bitrate %d: %w", s.Bitrate, err)
+	}
+	if err := encoder.SetInBandFEC(s.InBandFEC); err != nil {
+		return fmt.Errorf("set in-band FEC: %w", err)
+	}
+	if err := encoder.SetPacketLossPerc(s.PacketLossPerc); err != nil {
+		return fmt.Errorf("set packet loss %d%%: %w", s.PacketLossPerc, err)
+	}
+	if err := encoder.SetDTX(s.DTX); err != nil {
+		return fmt.Errorf("set DTX: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package elements
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
+)
+
+// RealtimeElement 将上行 PCM 发给实时模型（realtime.Session），
+// 模型音频按 outputSampleRate 投递给下游，文本、转写和会话事件作为 MsgTypeText 投递
+type RealtimeElement struct {
+	*pipeline.BaseElement
+
+	// 模型的上下行采样率，上游需重采样到 inputSampleRate
+	inputSampleRate  int
+	outputSampleRate int
+
+	sessionMu sync.RWMutex
+	session   realtime.Session
+	closed    bool // 已停止，不再接受新会话
+
+	// 断线重连，resumeHandle 仅在接收协程中访问
+	dial            SessionDialer
+	reconnectConfig ReconnectConfig
+	resumeHandle    string
+
+	// 会话不可用或切换期间缓存的上行音频
+	uplinkMu    sync.Mutex
+	uplink      [][]byte
+	uplinkBytes int
+
+	// 长会话切换，仅在接收协程中访问
+	rolloverConfig  RolloverConfig
+	summarizer      Summarizer
+	history         []ConversationTurn
+	sessionStart    time.Time
+	sessionTokens   int
+	rolloverRetryAt time.Time
+
+	// 输入协程写入、接收协程读取
+	sessionID atomic.Value
+	bus       pipeline.Bus
+
+	// 仅在接收协程中访问
+	transcripts transcriptTracker
+
+	tools     *tools.Registry
+	toolMu    sync.Mutex
+	toolCalls map[string]context.CancelFunc // 执行中的函数调用，key 为调用 ID
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRealtimeElement 创建 element，采样率取自 model，会话通过 SetSession / SetDialer 提供
+func NewRealtimeElement(model realtime.Model) *RealtimeElement {
+	return &RealtimeElement{
+		BaseElement:      pipeline.NewBaseElement(100),
+		inputSampleRate:  model.InputSampleRate(),
+		outputSampleRate: model.OutputSampleRate(),
+		reconnectConfig:  reconnectConfigFromEnv(),
+		rolloverConfig:   rolloverConfigFromEnv(),
+		tools:            tools.NewRegistry(),
+		toolCalls:        make(map[string]context.CancelFunc),
+	}
+}
+
+func (e *RealtimeElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.sessionMu.Lock()
+	e.closed = false
+	e.sessionMu.Unlock()
+	e.resetSessionLimits()
+
+	// 启动音频输入处理协程
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-e.BaseElement.InChan:
+				if msg.Type != pipeline.MsgTypeAudio {
+					continue
+				}
+
+				if msg.AudioData.MediaType != "audio/x-raw" {
+					continue
+				}
+
+				if len(msg.AudioData.Data) == 0 {
+					continue
+				}
+
+				// 保存会话ID
+				e.sessionID.Store(msg.SessionID)
+
+				// 将 PCM data 发送给 AI，会话中断期间按策略缓存
+				e.sendAudio(msg.AudioData.Data)
+			}
+		}
+	}()
+
+	// 从 AI session 接收，断开后自动重连
+	if e.currentSession() != nil || e.dial != nil {
+		e.wg.Add(1)
+		go e.receiveLoop(ctx)
+	}
+
+	return nil
+}
+
+func (e *RealtimeElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		// 关闭会话使接收协程退出
+		e.detachSession(true)
+		e.wg.Wait()
+		e.cancel = nil
+	}
+
+	// 清理 session
+	e.detachSession(true)
+	e.sessionID.Store("")
+	return nil
+}
+
+func (e *RealtimeElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *RealtimeElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
+
+// handleEvent 将模型音频投递给下游，文本、转写和轮次事件作为 MsgTypeText 投递
+func (e *RealtimeElement) handleEvent(ctx context.Context, ev *realtime.Event) {
+	switch ev.Type {
+	case realtime.EventToolCall:
+		e.handleToolCall(ctx, ev.ToolCalls)
+
+	case realtime.EventToolCancel:
+		e.handleToolCallCancellation(ev.CancelledCalls)
+
+	case realtime.EventUsage:
+		e.sessionTokens = ev.Usage.Total
+		e.emitText(ctx, pipeline.TextData{
+			Type:   pipeline.TextEventUsage,
+			TurnID: e.transcripts.turnID(),
+			Usage: &pipeline.Usage{
+				PromptTokens:   ev.Usage.Prompt,
+				ResponseTokens: ev.Usage.Response,
+				TotalTokens:    ev.Usage.Total,
+			},
+		})
+
+	case realtime.EventInputTranscript:
+		e.emitTranscripts(ctx, e.transcripts.addInput(ev.Text, ev.Final))
+
+	case realtime.EventOutputTranscript:
+		e.emitTranscripts(ctx, e.transcripts.addOutput(ev.Text, ev.Final))
+
+	case realtime.EventText:
+		// 模型开始回复，用户输入的转写定稿
+		e.emitTranscripts(ctx, e.transcripts.finishInput())
+		e.emitText(ctx, pipeline.TextData{
+			Type:   pipeline.TextEventModelText,
+			Text:   ev.Text,
+			Role:   pipeline.RoleModel,
+			TurnID: e.transcripts.turnID(),
+		})
+
+	case realtime.EventAudio:
+		e.emitTranscripts(ctx, e.transcripts.finishInput())
+		e.emit(ctx, pipeline.PipelineMessage{
+			Type:      pipeline.MsgTypeAudio,
+			SessionID: e.currentSessionID(),
+			Timestamp: time.Now(),
+			AudioData: &pipeline.AudioData{
+				Data:       ev.Audio,
+				MediaType:  "audio/x-raw",
+				SampleRate: e.outputSampleRate,
+				Channels:   1,
+				Timestamp:  time.Now(),
+			},
+		})
+
+	case realtime.EventInterrupted, realtime.EventTurnComplete:
+		turnID := e.transcripts.turnID()
+		e.emitTranscripts(ctx, e.transcripts.endTurn())
+
+		typ := pipeline.TextEventTurnComplete
+		if ev.Type == realtime.EventInterrupted {
+			typ = pipeline.TextEventInterrupted
+		}
+		e.emitText(ctx, pipeline.TextData{Type: typ, Role: pipeline.RoleModel, TurnID: turnID})
+	}
+}
+
+// emitTranscripts 投递转写并发布到事件总线（EventPartialResult / EventFinalResult）
+func (e *RealtimeElement) emitTranscripts(ctx context.Context, transcripts []pipeline.TextData) {
+	for _, t := range transcripts {
+		if t.Final {
+			e.recordTurn(t.Role, t.TurnID, t.Text)
+		}
+		if e.bus != nil {
+			eventType := pipeline.EventPartialResult
+			if t.Final {
+				eventType = pipeline.EventFinalResult
+			}
+			e.bus.Publish(pipeline.Event{Type: eventType, Timestamp: time.Now(), Payload: t})
+		}
+		e.emitText(ctx, t)
+	}
+}
+
+// emit 向下游投递消息，element 停止时放弃
+func (e *RealtimeElement) emit(ctx context.Context, msg pipeline.PipelineMessage) {
+	select {
+	case e.BaseElement.OutChan <- msg:
+	case <-ctx.Done():
+	}
+}
+
+func (e *RealtimeElement) currentSessionID() string {
+	id, _ := e.sessionID.Load().(string)
+	return id
+}
+
+// emitText 向下游投递文本或会话事件
+func (e *RealtimeElement) emitText(ctx context.Context, data pipeline.TextData) {
+	e.emit(ctx, pipeline.PipelineMessage{
+		Type:      pipeline.MsgTypeText,
+		SessionID: e.currentSessionID(),
+		Timestamp: time.Now(),
+		TextData:  &data,
+	})
+}
+
+// SetSession 设置初始会话，element 停止时会关闭当前会话
+func (e *RealtimeElement) SetSession(session realtime.Session) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+	e.session = session
+}
+
+// SetBus 设置发布转写事件的事件总线
+func (e *RealtimeElement) SetBus(bus pipeline.Bus) {
+	e.bus = bus
+}
+
+// InputSampleRate 模型要求的上行采样率
+func (e *RealtimeElement) InputSampleRate() int {
+	return e.inputSampleRate
+}
+
+// SendText 以用户轮次向会话发送一段文本，例如“user pressed 3”
+func (e *RealtimeElement) SendText(text string) error {
+	session := e.currentSession()
+	if session == nil {
+		return errSessionUnavailable
+	}
+	return session.SendText(text)
+}
+
+// SendImage 向会话发送一帧图像，重连期间返回错误
+func (e *RealtimeElement) SendImage(data []byte, mimeType string) error {
+	session := e.currentSession()
+	if session == nil {
+		return errSessionUnavailable
+	}
+	return session.SendImage(data, mimeType)
+}
+
+// SendAudio 向会话发送客户端经 DataChannel 上传的 PCM，按 inputSampleRate 解释
+func (e *RealtimeElement) SendAudio(data []byte) {
+	e.sendAudio(data)
+}
@@ -0,0 +1,231 @@
+package elements
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+)
+
+// DialOptions 建立模型会话的参数
+type DialOptions struct {
+	Handle  string // 非空时恢复之前的会话
+	Summary string // 非空时为切换后的新会话，需通过 SummaryInstruction 加入系统指令
+}
+
+// RolloverConfig 未开启上下文压缩时，会话接近时长或上下文上限后切换到新会话的条件
+type RolloverConfig struct {
+	MaxDuration    time.Duration // 会话时长达到该值后切换，0 表示不限
+	MaxTokens      int           // 上下文 token 数达到该值后切换，0 表示不限
+	SummaryTimeout time.Duration // 生成摘要的超时，超时后使用对话记录代替
+}
+
+// DefaultRolloverConfig 音频会话上限为 15 分钟、32k token，预留余量
+func DefaultRolloverConfig() RolloverConfig {
+	return RolloverConfig{
+		MaxDuration:    13 * time.Minute,
+		MaxTokens:      28000,
+		SummaryTimeout: 10 * time.Second,
+	}
+}
+
+// rolloverConfigFromEnv 读取 GEMINI_ROLLOVER_AFTER（如 13m）和 GEMINI_ROLLOVER_TOKENS
+func rolloverConfigFromEnv() RolloverConfig {
+	cfg := DefaultRolloverConfig()
+	if v := os.Getenv("GEMINI_ROLLOVER_AFTER"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.MaxDuration = d
+		} else {
+			log.Printf("invalid GEMINI_ROLLOVER_AFTER %q", v)
+		}
+	}
+	if v := os.Getenv("GEMINI_ROLLOVER_TOKENS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.MaxTokens = n
+		} else {
+			log.Printf("invalid GEMINI_ROLLOVER_TOKENS %q", v)
+		}
+	}
+	return cfg
+}
+
+// 对话记录最多保留的条数
+const maxConversationHistory = 200
+
+// 不调用模型时摘要的最大长度
+const maxTranscriptSummaryChars = 8000
+
+// 切换失败后再次尝试的间隔
+const rolloverRetryInterval = time.Minute
+
+// RoleSummary 对话记录中由之前的会话生成的摘要
+const RoleSummary = "summary"
+
+// ConversationTurn 对话记录中的一条定稿转写
+type ConversationTurn struct {
+	Role   string // pipeline.RoleUser、pipeline.RoleModel 或 RoleSummary
+	TurnID string // 所属的轮次，摘要为空
+	Text   string
+}
+
+// Summarizer 将对话记录压缩为摘要，用于切换后的新会话
+type Summarizer interface {
+	Summarize(ctx context.Context, history []ConversationTurn) (string, error)
+}
+
+// TranscriptSummary 不调用模型，按时间顺序拼接对话记录，超出 maxChars 时丢弃最早的部分
+func TranscriptSummary(history []ConversationTurn, maxChars int) string {
+	lines := make([]string, 0, len(history))
+	size := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		line := fmt.Sprintf("%s: %s", history[i].Role, history[i].Text)
+		if size+len(line) > maxChars && len(lines) > 0 {
+			break
+		}
+		lines = append(lines, line)
+		size += len(line) + 1
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+const summarizerInstruction = "You summarize an ongoing voice conversation between a user and an assistant " +
+	"so that a new assistant session can continue it seamlessly. Keep facts, names, numbers, " +
+	"decisions, open questions and the user's goal. Write in the conversation's language, at most 300 words."
+
+// ModelSummarizer 调用 generateContent 生成摘要
+type ModelSummarizer struct {
+	Config live.ClientConfig
+	Model  string // 为空时使用 live.DefaultTextModel
+}
+
+func (s *ModelSummarizer) Summarize(ctx context.Context, history []ConversationTurn) (string, error) {
+	model := s.Model
+	if model == "" {
+		model = live.DefaultTextModel
+	}
+	prompt := "Conversation so far:\n\n" + TranscriptSummary(history, 4*maxTranscriptSummaryChars)
+	return live.GenerateText(ctx, s.Config, model, summarizerInstruction, prompt)
+}
+
+// SummaryInstruction 返回切换后新会话的系统指令中描述之前对话的部分
+func SummaryInstruction(summary string) string {
+	return "You are continuing a voice conversation that is already in progress. " +
+		"Do not greet the user again or mention this note. Summary of the conversation so far:\n\n" + summary
+}
+
+// SetRolloverConfig 设置切换新会话的条件，需在 Start 之前调用
+func (e *RealtimeElement) SetRolloverConfig(cfg RolloverConfig) {
+	e.rolloverConfig = cfg
+}
+
+// SetSummarizer 设置生成摘要的方式，未设置时直接使用对话记录
+func (e *RealtimeElement) SetSummarizer(s Summarizer) {
+	e.summarizer = s
+}
+
+// recordTurn 记录一条定稿转写，仅在接收协程中调用。
+// 转写按轮累积，用户定稿后继续说话时同一轮会再次定稿，此时替换之前的记录
+func (e *RealtimeElement) recordTurn(role, turnID, text string) {
+	if text == "" {
+		return
+	}
+	for i := len(e.history) - 1; turnID != "" && i >= 0 && e.history[i].TurnID == turnID; i-- {
+		if e.history[i].Role == role {
+			e.history[i].Text = text
+			return
+		}
+	}
+	e.history = append(e.history, ConversationTurn{Role: role, TurnID: turnID, Text: text})
+	if len(e.history) > maxConversationHistory {
+		e.history = e.history[len(e.history)-maxConversationHistory:]
+	}
+}
+
+// resetSessionLimits 新会话（非恢复）开始，重新计算时长和上下文
+func (e *RealtimeElement) resetSessionLimits() {
+	e.sessionStart = time.Now()
+	e.sessionTokens = 0
+	e.rolloverRetryAt = time.Time{}
+}
+
+// rolloverReason 返回需要切换新会话的原因，不受限制的会话（如开启了上下文压缩）无需切换
+func (e *RealtimeElement) rolloverReason(session realtime.Session) string {
+	if session.Info().Unbounded || e.dial == nil {
+		return ""
+	}
+	if time.Now().Before(e.rolloverRetryAt) {
+		return ""
+	}
+	cfg := e.rolloverConfig
+	if cfg.MaxDuration > 0 && time.Since(e.sessionStart) >= cfg.MaxDuration {
+		return "session duration limit"
+	}
+	if cfg.MaxTokens > 0 && e.sessionTokens >= cfg.MaxTokens {
+		return "context window limit"
+	}
+	return ""
+}
+
+// summarize 生成对话摘要，失败时退化为对话记录
+func (e *RealtimeElement) summarize(ctx context.Context) string {
+	if len(e.history) == 0 {
+		return ""
+	}
+	if e.summarizer != nil {
+		sctx, cancel := context.WithTimeout(ctx, e.rolloverConfig.SummaryTimeout)
+		summary, err := e.summarizer.Summarize(sctx, e.history)
+		cancel()
+		if err == nil && summary != "" {
+			return summary
+		}
+		log.Printf("summarize conversation error: %v", err)
+	}
+	return TranscriptSummary(e.history, maxTranscriptSummaryChars)
+}
+
+// rollover 在两轮之间切换到以摘要开头的新会话。
+// 生成摘要和连接新会话期间上行音频继续发给当前会话，失败时继续使用当前会话
+func (e *RealtimeElement) rollover(ctx context.Context, reason string) {
+	log.Printf("AI session rollover: %s", reason)
+
+	summary := e.summarize(ctx)
+	session, err := e.dial(ctx, DialOptions{Summary: summary})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("AI session rollover error: %v", err)
+			e.rolloverRetryAt = time.Now().Add(rolloverRetryInterval)
+		}
+		return
+	}
+
+	// 持有 uplinkMu 切换，sendAudio 不会再把音频发给旧会话
+	e.uplinkMu.Lock()
+	old, ok := e.setSession(session)
+	if ok {
+		e.flushUplink(session)
+	}
+	e.uplinkMu.Unlock()
+	if !ok {
+		return
+	}
+	if old != nil {
+		old.Close()
+	}
+
+	e.resumeHandle = ""
+	e.resetSessionLimits()
+	e.history = nil
+	e.recordTurn(RoleSummary, "", summary)
+
+	e.emitSessionState(ctx, pipeline.SessionState{Status: pipeline.SessionRolledOver, Reason: reason})
+}
@@ -0,0 +1,37 @@
+package elements
+
+import (
+	"context"
+	"testing"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHistoryUserKeepsTalking 模型开始回复后用户继续说话，对话记录中本轮只有一条完整的用户转写
+func TestHistoryUserKeepsTalking(t *testing.T) {
+	e := NewRealtimeElement(realtime.NewGemini(realtime.GeminiConfig{}))
+	ctx := context.Background()
+	go func() {
+		for range e.Out() {
+		}
+	}()
+
+	for _, ev := range []*realtime.Event{
+		{Type: realtime.EventInputTranscript, Text: "book a table"},
+		{Type: realtime.EventOutputTranscript, Text: "Sure,"},
+		{Type: realtime.EventInputTranscript, Text: " for two", Final: true},
+		{Type: realtime.EventOutputTranscript, Text: " for how many?"},
+		{Type: realtime.EventTurnComplete},
+		{Type: realtime.EventInputTranscript, Text: "two", Final: true},
+	} {
+		e.handleEvent(ctx, ev)
+	}
+
+	assert.Equal(t, []ConversationTurn{
+		{Role: pipeline.RoleUser, TurnID: "turn-0", Text: "book a table for two"},
+		{Role: pipeline.RoleModel, TurnID: "turn-0", Text: "Sure, for how many?"},
+		{Role: pipeline.RoleUser, TurnID: "turn-1", Text: "two"},
+	}, e.history)
+}
@@ -0,0 +1,317 @@
+package elements
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+)
+
+// SessionDialer 建立新的模型会话，用于初次连接、断线重连和切换新会话
+type SessionDialer func(ctx context.Context, opts DialOptions) (realtime.Session, error)
+
+// UplinkPolicy 会话中断期间上行音频的处理方式
+type UplinkPolicy string
+
+const (
+	UplinkBuffer UplinkPolicy = "buffer" // 缓存最近的音频，重连后补发
+	UplinkDrop   UplinkPolicy = "drop"   // 直接丢弃
+)
+
+var errSessionUnavailable = errors.New("realtime session unavailable")
+
+// ReconnectConfig 模型会话断开后的重连参数
+type ReconnectConfig struct {
+	MaxAttempts  int           // 连续失败多少次后放弃，0 表示不限
+	MinBackoff   time.Duration // 第二次尝试前的等待时间，之后每次翻倍
+	MaxBackoff   time.Duration
+	UplinkPolicy UplinkPolicy
+	UplinkBuffer time.Duration // UplinkBuffer 策略下最多缓存的音频时长，超出时丢弃最早的音频
+}
+
+func DefaultReconnectConfig() ReconnectConfig {
+	return ReconnectConfig{
+		MaxAttempts:  10,
+		MinBackoff:   500 * time.Millisecond,
+		MaxBackoff:   10 * time.Second,
+		UplinkPolicy: UplinkBuffer,
+		UplinkBuffer: 3 * time.Second,
+	}
+}
+
+// reconnectConfigFromEnv 读取 GEMINI_RECONNECT_MAX_ATTEMPTS、GEMINI_UPLINK_POLICY、GEMINI_UPLINK_BUFFER_MS
+func reconnectConfigFromEnv() ReconnectConfig {
+	cfg := DefaultReconnectConfig()
+	if v := os.Getenv("GEMINI_RECONNECT_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.MaxAttempts = n
+		} else {
+			log.Printf("invalid GEMINI_RECONNECT_MAX_ATTEMPTS %q", v)
+		}
+	}
+	switch p := UplinkPolicy(os.Getenv("GEMINI_UPLINK_POLICY")); p {
+	case "":
+	case UplinkBuffer, UplinkDrop:
+		cfg.UplinkPolicy = p
+	default:
+		log.Printf("invalid GEMINI_UPLINK_POLICY %q, using %s", p, cfg.UplinkPolicy)
+	}
+	if v := os.Getenv("GEMINI_UPLINK_BUFFER_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+			cfg.UplinkBuffer = time.Duration(ms) * time.Millisecond
+		} else {
+			log.Printf("invalid GEMINI_UPLINK_BUFFER_MS %q", v)
+		}
+	}
+	return cfg
+}
+
+// backoff 返回第 n 次重试前的等待时间（n 从 1 开始），带 ±20% 抖动
+func (c ReconnectConfig) backoff(n int) time.Duration {
+	d := c.MinBackoff
+	for i := 1; i < n && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	return time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+}
+
+// SetDialer 设置重连使用的 SessionDialer，未设置时会话断开后不再重连
+func (e *RealtimeElement) SetDialer(dial SessionDialer) {
+	e.dial = dial
+}
+
+// SetReconnectConfig 设置重连参数，需在 Start 之前调用
+func (e *RealtimeElement) SetReconnectConfig(cfg ReconnectConfig) {
+	e.reconnectConfig = cfg
+}
+
+func (e *RealtimeElement) currentSession() realtime.Session {
+	e.sessionMu.RLock()
+	defer e.sessionMu.RUnlock()
+	return e.session
+}
+
+// setSession 切换到新会话并返回之前的会话，element 已停止时关闭新会话并返回 false
+func (e *RealtimeElement) setSession(session realtime.Session) (realtime.Session, bool) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+	if e.closed {
+		session.Close()
+		return nil, false
+	}
+	old := e.session
+	e.session = session
+	return old, true
+}
+
+// detachSession 取下并关闭当前会话，closed 为 true 时之后不再接受新会话
+func (e *RealtimeElement) detachSession(closed bool) {
+	e.sessionMu.Lock()
+	session := e.session
+	e.session = nil
+	if closed {
+		e.closed = true
+	}
+	e.sessionMu.Unlock()
+
+	if session != nil {
+		session.Close()
+	}
+}
+
+// receiveLoop 接收模型消息，连接中断或收到 GoAway 时重连
+func (e *RealtimeElement) receiveLoop(ctx context.Context) {
+	defer e.wg.Done()
+
+	for {
+		session := e.currentSession()
+		if session == nil {
+			if !e.reconnect(ctx, "not connected") {
+				return
+			}
+			continue
+		}
+
+		ev, err := session.Receive()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("AI session receive error:", err)
+			if !e.reconnect(ctx, err.Error()) {
+				return
+			}
+			continue
+		}
+
+		switch ev.Type {
+		case realtime.EventResumption:
+			e.resumeHandle = ev.ResumeHandle
+			continue
+
+		case realtime.EventGoAway:
+			// 不等服务端断开，立即用最新的句柄切换到新会话
+			log.Printf("AI session going away in %v, reconnecting", ev.TimeLeft)
+			if !e.reconnect(ctx, "server going away") {
+				return
+			}
+			continue
+		}
+
+		e.handleEvent(ctx, ev)
+
+		// 在两轮之间检查会话是否接近上限
+		if ev.Type == realtime.EventTurnComplete {
+			if reason := e.rolloverReason(session); reason != "" {
+				e.rollover(ctx, reason)
+			}
+		}
+	}
+}
+
+// reconnect 关闭当前会话并按退避策略重连，成功后补发缓存的上行音频。
+// 返回 false 表示放弃重连或 element 已停止
+func (e *RealtimeElement) reconnect(ctx context.Context, reason string) bool {
+	e.detachSession(false)
+
+	// 中断的本轮输出不会再继续，转写定稿
+	e.emitTranscripts(ctx, e.transcripts.endTurn())
+
+	if e.dial == nil {
+		e.emitSessionState(ctx, pipeline.SessionState{Status: pipeline.SessionDisconnected, Reason: reason})
+		return false
+	}
+
+	cfg := e.reconnectConfig
+	summary, summarized := "", false
+	for attempt := 1; cfg.MaxAttempts == 0 || attempt <= cfg.MaxAttempts; attempt++ {
+		e.emitSessionState(ctx, pipeline.SessionState{Status: pipeline.SessionReconnecting, Reason: reason, Attempt: attempt})
+
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(cfg.backoff(attempt - 1)):
+			}
+		}
+
+		// 无法恢复时建立新会话，以摘要延续之前的对话
+		opts := DialOptions{Handle: e.resumeHandle}
+		if opts.Handle == "" {
+			if !summarized {
+				summary, summarized = e.summarize(ctx), true
+			}
+			opts.Summary = summary
+		}
+		handle := opts.Handle
+		session, err := e.dial(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			log.Printf("AI session reconnect attempt %d error: %v", attempt, err)
+			reason = err.Error()
+			// 句柄可能已过期，之后改为建立新会话
+			if handle != "" && attempt >= 2 {
+				log.Printf("dropping session resumption handle")
+				e.resumeHandle = ""
+			}
+			continue
+		}
+
+		e.uplinkMu.Lock()
+		_, ok := e.setSession(session)
+		if ok {
+			e.flushUplink(session)
+		}
+		e.uplinkMu.Unlock()
+		if !ok {
+			return false
+		}
+		if handle == "" {
+			e.resetSessionLimits()
+			e.history = nil
+			e.recordTurn(RoleSummary, "", summary)
+		}
+
+		log.Printf("AI session reconnected (resumed: %v)", handle != "")
+		e.emitSessionState(ctx, pipeline.SessionState{Status: pipeline.SessionConnected, Attempt: attempt, Resumed: handle != ""})
+		return true
+	}
+
+	log.Printf("AI session reconnect gave up after %d attempts", cfg.MaxAttempts)
+	e.emitSessionState(ctx, pipeline.SessionState{Status: pipeline.SessionDisconnected, Reason: reason})
+	return false
+}
+
+// sendAudio 发送上行音频，会话不可用时按策略缓存
+func (e *RealtimeElement) sendAudio(data []byte) {
+	e.uplinkMu.Lock()
+	defer e.uplinkMu.Unlock()
+
+	session := e.currentSession()
+	if session != nil {
+		// 先补发之前发送失败的音频，保证顺序
+		e.flushUplink(session)
+	}
+	if session == nil || len(e.uplink) > 0 {
+		e.bufferUplink(data)
+		return
+	}
+	if err := session.SendAudio(data); err != nil {
+		log.Println("AI session send error:", err)
+		e.bufferUplink(data)
+	}
+}
+
+// bufferUplink 缓存一块上行音频，调用方需持有 uplinkMu
+func (e *RealtimeElement) bufferUplink(data []byte) {
+	if e.reconnectConfig.UplinkPolicy != UplinkBuffer {
+		return
+	}
+	e.uplink = append(e.uplink, append([]byte(nil), data...))
+	e.uplinkBytes += len(data)
+
+	limit := int(e.reconnectConfig.UplinkBuffer.Seconds() * float64(e.inputBytesPerSecond()))
+	for e.uplinkBytes > limit && len(e.uplink) > 0 {
+		e.uplinkBytes -= len(e.uplink[0])
+		e.uplink = e.uplink[1:]
+	}
+}
+
+// flushUplink 按顺序补发缓存的音频，调用方需持有 uplinkMu
+func (e *RealtimeElement) flushUplink(session realtime.Session) {
+	if len(e.uplink) > 0 {
+		log.Printf("AI session flushing %d ms of buffered audio", e.uplinkBytes*1000/e.inputBytesPerSecond())
+	}
+	for len(e.uplink) > 0 {
+		if err := session.SendAudio(e.uplink[0]); err != nil {
+			log.Println("AI session send error:", err)
+			return
+		}
+		e.uplinkBytes -= len(e.uplink[0])
+		e.uplink = e.uplink[1:]
+	}
+}
+
+// inputBytesPerSecond 发给模型的音频为单声道 S16
+func (e *RealtimeElement) inputBytesPerSecond() int {
+	return e.inputSampleRate * 2
+}
+
+// emitSessionState 通知客户端会话状态并发布到事件总线（EventSessionState）
+func (e *RealtimeElement) emitSessionState(ctx context.Context, state pipeline.SessionState) {
+	if e.bus != nil {
+		e.bus.Publish(pipeline.Event{Type: pipeline.EventSessionState, Timestamp: time.Now(), Payload: state})
+	}
+	e.emitText(ctx, pipeline.TextData{Type: pipeline.TextEventSessionState, State: &state})
+}
@@ -0,0 +1,40 @@
+package elements
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakySession 前 failures 次 SendAudio 失败，之后记录发送的音频
+type flakySession struct {
+	realtime.Session
+
+	failures int
+	sent     [][]byte
+}
+
+func (s *flakySession) SendAudio(pcm []byte) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("write: broken pipe")
+	}
+	s.sent = append(s.sent, pcm)
+	return nil
+}
+
+// TestSendAudioAfterFailure 一次发送失败后，会话恢复正常时先补发缓存的音频，之后的音频不再滞留在缓存中
+func TestSendAudioAfterFailure(t *testing.T) {
+	session := &flakySession{failures: 1}
+	e := NewRealtimeElement(realtime.NewGemini(realtime.GeminiConfig{}))
+	e.SetSession(session)
+
+	for _, chunk := range []string{"a", "b", "c"} {
+		e.sendAudio([]byte(chunk))
+	}
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")}, session.sent)
+	assert.Empty(t, e.uplink)
+	assert.Zero(t, e.uplinkBytes)
+}
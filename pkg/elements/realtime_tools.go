@@ -0,0 +1,122 @@
+package elements
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
+	"google.golang.org/genai"
+)
+
+// SetTools 设置处理模型函数调用的工具注册表，需与会话 setup 中声明的工具一致
+func (e *RealtimeElement) SetTools(r *tools.Registry) {
+	if r == nil {
+		r = tools.NewRegistry()
+	}
+	e.tools = r
+}
+
+// handleToolCall 并发执行模型下发的函数调用，每个调用完成后单独回复
+func (e *RealtimeElement) handleToolCall(ctx context.Context, calls []realtime.ToolCall) {
+	turnID := e.transcripts.turnID()
+	for _, call := range calls {
+		if ctx.Err() != nil {
+			continue
+		}
+		fc := &genai.FunctionCall{ID: call.ID, Name: call.Name, Args: call.Args}
+
+		callCtx, cancel := context.WithCancel(ctx)
+		e.toolMu.Lock()
+		e.toolCalls[fc.ID] = cancel
+		e.toolMu.Unlock()
+
+		e.wg.Add(1)
+		go func(fc *genai.FunctionCall) {
+			defer e.wg.Done()
+			defer func() {
+				e.toolMu.Lock()
+				delete(e.toolCalls, fc.ID)
+				e.toolMu.Unlock()
+				cancel()
+			}()
+			e.runTool(ctx, callCtx, fc, turnID)
+		}(fc)
+	}
+}
+
+func (e *RealtimeElement) runTool(ctx, callCtx context.Context, fc *genai.FunctionCall, turnID string) {
+	e.emitToolActivity(ctx, turnID, pipeline.ToolActivity{
+		ID:     fc.ID,
+		Name:   fc.Name,
+		Status: pipeline.ToolStatusStarted,
+		Args:   fc.Args,
+	})
+
+	start := time.Now()
+	resp, err := e.tools.Call(callCtx, fc)
+
+	activity := pipeline.ToolActivity{
+		ID:         fc.ID,
+		Name:       fc.Name,
+		Status:     pipeline.ToolStatusCompleted,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+
+	// 被服务端取消或 element 停止，不再回复
+	if callCtx.Err() == context.Canceled {
+		if ctx.Err() != nil {
+			return
+		}
+		activity.Status = pipeline.ToolStatusCancelled
+		e.emitToolActivity(ctx, turnID, activity)
+		return
+	}
+
+	if err != nil {
+		log.Printf("tool %s (%s) error: %v", fc.Name, fc.ID, err)
+		activity.Status = pipeline.ToolStatusFailed
+		activity.Error = err.Error()
+	} else {
+		activity.Result = resp.Response
+	}
+
+	if err := e.sendToolResult(realtime.ToolResult{ID: fc.ID, Name: fc.Name, Response: resp.Response}); err != nil {
+		log.Printf("send tool response error: %v", err)
+	}
+	e.emitToolActivity(ctx, turnID, activity)
+}
+
+func (e *RealtimeElement) sendToolResult(result realtime.ToolResult) error {
+	session := e.currentSession()
+	if session == nil {
+		return errSessionUnavailable
+	}
+	return session.SendToolResults([]realtime.ToolResult{result})
+}
+
+// handleToolCallCancellation 取消仍在执行的函数调用，通常发生在用户打断时
+func (e *RealtimeElement) handleToolCallCancellation(ids []string) {
+	e.toolMu.Lock()
+	defer e.toolMu.Unlock()
+	for _, id := range ids {
+		if cancel, ok := e.toolCalls[id]; ok {
+			cancel()
+		}
+	}
+}
+
+// emitToolActivity 投递函数调用状态并发布到事件总线（EventToolCall）
+func (e *RealtimeElement) emitToolActivity(ctx context.Context, turnID string, activity pipeline.ToolActivity) {
+	if e.bus != nil {
+		e.bus.Publish(pipeline.Event{Type: pipeline.EventToolCall, Timestamp: time.Now(), Payload: activity})
+	}
+	e.emitText(ctx, pipeline.TextData{
+		Type:   pipeline.TextEventToolCall,
+		Role:   pipeline.RoleModel,
+		TurnID: turnID,
+		Tool:   &activity,
+	})
+}
@@ -0,0 +1,89 @@
+package elements
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// transcriptTracker 将服务端的增量转写累积为每轮的完整文本，并为每轮分配 ID
+// 用户输入的转写在模型开始回复时定稿，模型输出的转写在本轮结束或被打断时定稿
+type transcriptTracker struct {
+	turn int
+
+	input       strings.Builder
+	inputFinal  bool
+	output      strings.Builder
+	outputFinal bool
+}
+
+func (t *transcriptTracker) turnID() string {
+	return fmt.Sprintf("turn-%d", t.turn)
+}
+
+// addInput 追加用户语音的增量转写，finished 表示该段已结束
+func (t *transcriptTracker) addInput(text string, finished bool) []pipeline.TextData {
+	if text == "" && !finished {
+		return nil
+	}
+	// 定稿后用户继续说话，仍归入本轮
+	t.inputFinal = false
+	t.input.WriteString(text)
+	if finished {
+		return t.finishInput()
+	}
+	return []pipeline.TextData{t.data(pipeline.TextEventInputTranscript, pipeline.RoleUser, t.input.String(), false)}
+}
+
+// addOutput 追加模型语音的转写，同时意味着用户输入已经结束
+func (t *transcriptTracker) addOutput(text string, finished bool) []pipeline.TextData {
+	if text == "" && !finished {
+		return nil
+	}
+	out := t.finishInput()
+	t.outputFinal = false
+	t.output.WriteString(text)
+	if finished {
+		return append(out, t.finishOutput()...)
+	}
+	return append(out, t.data(pipeline.TextEventOutputTranscript, pipeline.RoleModel, t.output.String(), false))
+}
+
+// finishInput 用户输入定稿，已定稿或没有转写时返回空
+func (t *transcriptTracker) finishInput() []pipeline.TextData {
+	if t.inputFinal || t.input.Len() == 0 {
+		return nil
+	}
+	t.inputFinal = true
+	return []pipeline.TextData{t.data(pipeline.TextEventInputTranscript, pipeline.RoleUser, t.input.String(), true)}
+}
+
+func (t *transcriptTracker) finishOutput() []pipeline.TextData {
+	if t.outputFinal || t.output.Len() == 0 {
+		return nil
+	}
+	t.outputFinal = true
+	return []pipeline.TextData{t.data(pipeline.TextEventOutputTranscript, pipeline.RoleModel, t.output.String(), true)}
+}
+
+// endTurn 本轮结束（完成或被打断），定稿剩余的转写并进入下一轮
+func (t *transcriptTracker) endTurn() []pipeline.TextData {
+	out := append(t.finishInput(), t.finishOutput()...)
+	t.turn++
+	t.input.Reset()
+	t.output.Reset()
+	t.inputFinal = false
+	t.outputFinal = false
+	return out
+}
+
+func (t *transcriptTracker) data(typ pipeline.TextEventType, role, text string, final bool) pipeline.TextData {
+	return pipeline.TextData{
+		Type:   typ,
+		Text:   text,
+		Final:  final,
+		Role:   role,
+		TurnID: t.turnID(),
+	}
+}
@@ -0,0 +1,78 @@
+package elements
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// RecordElement 将经过的单声道音频写入通话录音的一路，所有消息原样透传
+type RecordElement struct {
+	*pipeline.BaseElement
+
+	recorder *audio.StereoRecorder
+	channel  audio.RecordChannel
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRecordElement 创建录音元素，输入音频的采样率须与 recorder 相同
+func NewRecordElement(recorder *audio.StereoRecorder, channel audio.RecordChannel) *RecordElement {
+	return &RecordElement{
+		BaseElement: pipeline.NewBaseElement(100),
+		recorder:    recorder,
+		channel:     channel,
+	}
+}
+
+func (e *RecordElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-e.BaseElement.InChan:
+				if msg.Type == pipeline.MsgTypeAudio && msg.AudioData != nil && len(msg.AudioData.Data) > 0 {
+					if msg.AudioData.SampleRate != e.recorder.SampleRate() || msg.AudioData.Channels != 1 {
+						log.Printf("record: skipping %dHz %dch audio", msg.AudioData.SampleRate, msg.AudioData.Channels)
+					} else if err := e.recorder.Write(e.channel, msg.AudioData.Data); err != nil {
+						log.Printf("record error: %v", err)
+					}
+				}
+
+				select {
+				case e.BaseElement.OutChan <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (e *RecordElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *RecordElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *RecordElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
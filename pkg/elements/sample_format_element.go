@@ -0,0 +1,112 @@
+package elements
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+)
+
+// SampleFormatElement 将 audio/x-raw 转换为指定的样本格式
+// 输入格式以消息中的 SampleFormat 为准（为空时视为 S16LE），采样率和声道数保持不变
+type SampleFormatElement struct {
+	*pipeline.BaseElement
+
+	format pipeline.SampleFormat
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewSampleFormatElement(bufferSize int, format pipeline.SampleFormat) (*SampleFormatElement, error) {
+	if format.BytesPerSample() == 0 {
+		return nil, fmt.Errorf("unsupported sample format: %q", format)
+	}
+
+	return &SampleFormatElement{
+		BaseElement: pipeline.NewBaseElement(bufferSize),
+		format:      format,
+	}, nil
+}
+
+// Format 返回输出样本格式
+func (e *SampleFormatElement) Format() pipeline.SampleFormat {
+	return e.format
+}
+
+func (e *SampleFormatElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-e.BaseElement.InChan:
+				if msg.Type != pipeline.MsgTypeAudio {
+					continue
+				}
+
+				if msg.AudioData.MediaType != "audio/x-raw" {
+					continue
+				}
+
+				if len(msg.AudioData.Data) == 0 {
+					continue
+				}
+
+				outData, err := audio.ConvertSampleFormat(msg.AudioData.Data, msg.AudioData.Format(), e.format)
+				if err != nil {
+					log.Printf("sample format conversion error: %v", err)
+					continue
+				}
+
+				outMsg := pipeline.PipelineMessage{
+					Type:      pipeline.MsgTypeAudio,
+					SessionID: msg.SessionID,
+					Timestamp: time.Now(),
+					AudioData: &pipeline.AudioData{
+						Data:         outData,
+						SampleRate:   msg.AudioData.SampleRate,
+						Channels:     msg.AudioData.Channels,
+						MediaType:    "audio/x-raw",
+						SampleFormat: e.format,
+						Timestamp:    time.Now(),
+					},
+				}
+
+				select {
+				case e.BaseElement.OutChan <- outMsg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (e *SampleFormatElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *SampleFormatElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *SampleFormatElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
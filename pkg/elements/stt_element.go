@@ -0,0 +1,184 @@
+package elements
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/speech"
+)
+
+// DefaultPrefixPadding 检测到说话时一并送去识别的之前的音频，避免丢掉第一个字
+const DefaultPrefixPadding = 300 * time.Millisecond
+
+// STTElement 级联模式的语音识别：用能量 VAD 做端点检测，每段语音交给 SpeechToText 流式识别。
+// 开始说话时投递 speech_started（用于打断），识别结果作为 input_transcript 投递，
+// 非音频消息原样透传，用户音频不再向下游传递
+type STTElement struct {
+	*pipeline.BaseElement
+
+	engine      speech.SpeechToText
+	vad         *audio.VAD
+	prefixBytes int
+	bus         pipeline.Bus
+
+	// 仅在输入协程中访问
+	prefix    []byte
+	utterance chan []byte
+	turn      int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSTTElement 创建识别元素，输入音频的采样率须与 engine.SampleRate() 相同
+func NewSTTElement(engine speech.SpeechToText, vad audio.VADConfig, prefixPadding time.Duration) *STTElement {
+	if prefixPadding <= 0 {
+		prefixPadding = DefaultPrefixPadding
+	}
+	return &STTElement{
+		BaseElement: pipeline.NewBaseElement(100),
+		engine:      engine,
+		vad:         audio.NewVAD(engine.SampleRate(), vad),
+		prefixBytes: int(prefixPadding.Seconds()*float64(engine.SampleRate())) * 2,
+	}
+}
+
+// SetBus 设置事件总线，识别结果发布为 EventPartialResult / EventFinalResult
+func (e *STTElement) SetBus(bus pipeline.Bus) {
+	e.bus = bus
+}
+
+func (e *STTElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go e.inputLoop(ctx)
+	return nil
+}
+
+func (e *STTElement) inputLoop(ctx context.Context) {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			if e.utterance != nil {
+				close(e.utterance)
+				e.utterance = nil
+			}
+			return
+		case msg := <-e.BaseElement.InChan:
+			if msg.Type == pipeline.MsgTypeAudio && msg.AudioData != nil {
+				e.processAudio(ctx, msg.SessionID, msg.AudioData.Data)
+				continue
+			}
+			e.emit(ctx, msg)
+		}
+	}
+}
+
+// processAudio 做端点检测，并把说话期间的音频交给当前语音段的识别
+func (e *STTElement) processAudio(ctx context.Context, sessionID string, pcm []byte) {
+	e.prefix = append(e.prefix, pcm...)
+	if extra := len(e.prefix) - e.prefixBytes; extra > 0 {
+		e.prefix = append(e.prefix[:0], e.prefix[extra:]...)
+	}
+
+	sent := false
+	for _, ev := range e.vad.Process(pcm) {
+		switch ev {
+		case audio.VADSpeechStart:
+			turnID := fmt.Sprintf("turn-%d", e.turn)
+			e.turn++
+			e.emitText(ctx, sessionID, pipeline.TextData{Type: pipeline.TextEventSpeechStarted, Role: pipeline.RoleUser, TurnID: turnID})
+
+			e.utterance = make(chan []byte, 500)
+			e.wg.Add(1)
+			go e.transcribe(ctx, e.utterance, sessionID, turnID)
+			// prefix 已包含本次的音频
+			e.send(append([]byte(nil), e.prefix...))
+			sent = true
+		case audio.VADSpeechEnd:
+			if e.utterance != nil {
+				close(e.utterance)
+				e.utterance = nil
+			}
+		}
+	}
+	if e.utterance != nil && !sent {
+		e.send(pcm)
+	}
+}
+
+// send 把音频交给识别协程，识别跟不上时丢弃
+func (e *STTElement) send(pcm []byte) {
+	select {
+	case e.utterance <- pcm:
+	default:
+		log.Println("stt: recognizer is too slow, dropping audio")
+	}
+}
+
+// transcribe 识别一段语音，直到 utterance 被关闭（端点）后给出最终结果
+func (e *STTElement) transcribe(ctx context.Context, utterance <-chan []byte, sessionID, turnID string) {
+	defer e.wg.Done()
+	err := e.engine.Transcribe(ctx, utterance, func(t speech.Transcript) error {
+		data := pipeline.TextData{
+			Type:   pipeline.TextEventInputTranscript,
+			Text:   t.Text,
+			Final:  t.Final,
+			Role:   pipeline.RoleUser,
+			TurnID: turnID,
+		}
+		if e.bus != nil {
+			eventType := pipeline.EventPartialResult
+			if t.Final {
+				eventType = pipeline.EventFinalResult
+			}
+			e.bus.Publish(pipeline.Event{Type: eventType, Timestamp: time.Now(), Payload: data})
+		}
+		e.emitText(ctx, sessionID, data)
+		return ctx.Err()
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("stt transcribe error: %v", err)
+	}
+}
+
+func (e *STTElement) emitText(ctx context.Context, sessionID string, data pipeline.TextData) {
+	e.emit(ctx, pipeline.PipelineMessage{
+		Type:      pipeline.MsgTypeText,
+		SessionID: sessionID,
+		Timestamp: time.Now(),
+		TextData:  &data,
+	})
+}
+
+func (e *STTElement) emit(ctx context.Context, msg pipeline.PipelineMessage) {
+	select {
+	case e.BaseElement.OutChan <- msg:
+	case <-ctx.Done():
+	}
+}
+
+func (e *STTElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	return nil
+}
+
+func (e *STTElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *STTElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
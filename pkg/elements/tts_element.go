@@ -0,0 +1,265 @@
+package elements
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/speech"
+)
+
+// ttsJob 一个待合成的句子，generation 用于丢弃被打断的轮次
+type ttsJob struct {
+	text       string
+	sessionID  string
+	generation int
+}
+
+// TTSElement 将模型的流式文本按句切分后用 TTSEngine 合成为 PCM，用于只输出文本的模型会话。
+// 所有输入消息原样透传，合成的音频按 outputSampleRate 投递，可以串接在 RealtimeElement
+// 与 DataChannelSinkElement 之间；收到 interrupted 时取消正在进行的合成并丢弃未合成的文本。
+// 级联模式下收到 speech_started 时，如果还有未播完的音频，同样打断并投递 interrupted
+type TTSElement struct {
+	*pipeline.BaseElement
+
+	engine           speech.TTSEngine
+	outputSampleRate int
+	resample         audio.Resampler
+
+	// 仅在输入协程中访问
+	splitter speech.SentenceSplitter
+	turnID   string
+
+	jobs chan ttsJob
+
+	// mu 保护以下字段，合成的音频在持有 mu 时投递，
+	// 保证 interrupted 之后不会再投递被打断轮次的音频
+	mu          sync.Mutex
+	generation  int
+	cancelSynth context.CancelFunc
+	// pending 已入队但未合成完的句子数，playingUntil 按实时播放估计的已投递音频的结束时间
+	pending      int
+	playingUntil time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTTSElement 创建合成元素，engine 的采样率与 outputSampleRate 不同时自动重采样
+func NewTTSElement(engine speech.TTSEngine, outputSampleRate int) (*TTSElement, error) {
+	e := &TTSElement{
+		BaseElement:      pipeline.NewBaseElement(100),
+		engine:           engine,
+		outputSampleRate: outputSampleRate,
+		jobs:             make(chan ttsJob, 100),
+	}
+	if rate := engine.SampleRate(); rate != outputSampleRate {
+		resample, err := audio.NewResampler(rate, outputSampleRate, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		e.resample = resample
+	}
+	return e, nil
+}
+
+func (e *TTSElement) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	e.wg.Add(2)
+	go e.inputLoop(ctx)
+	go e.synthLoop(ctx)
+	return nil
+}
+
+// inputLoop 透传输入消息，将模型文本切分为句子交给合成协程
+func (e *TTSElement) inputLoop(ctx context.Context) {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-e.BaseElement.InChan:
+			if msg.Type == pipeline.MsgTypeText && msg.TextData != nil {
+				switch msg.TextData.Type {
+				case pipeline.TextEventModelText:
+					e.turnID = msg.TextData.TurnID
+					for _, sentence := range e.splitter.Write(msg.TextData.Text) {
+						e.enqueue(ctx, sentence, msg.SessionID)
+					}
+				case pipeline.TextEventTurnComplete:
+					if rest := e.splitter.Flush(); rest != "" {
+						e.enqueue(ctx, rest, msg.SessionID)
+					}
+				case pipeline.TextEventInterrupted:
+					e.interrupt()
+				case pipeline.TextEventSpeechStarted:
+					if e.speaking() {
+						e.interrupt()
+						e.emitText(ctx, msg.SessionID, pipeline.TextData{Type: pipeline.TextEventInterrupted, Role: pipeline.RoleModel, TurnID: e.turnID})
+					}
+				}
+			}
+
+			select {
+			case e.BaseElement.OutChan <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (e *TTSElement) enqueue(ctx context.Context, text, sessionID string) {
+	e.mu.Lock()
+	job := ttsJob{text: text, sessionID: sessionID, generation: e.generation}
+	e.pending++
+	e.mu.Unlock()
+
+	select {
+	case e.jobs <- job:
+	case <-ctx.Done():
+	}
+}
+
+// speaking 是否还有未合成的文本或未播完的音频
+func (e *TTSElement) speaking() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pending > 0 || time.Now().Before(e.playingUntil)
+}
+
+func (e *TTSElement) emitText(ctx context.Context, sessionID string, data pipeline.TextData) {
+	select {
+	case e.BaseElement.OutChan <- pipeline.PipelineMessage{
+		Type:      pipeline.MsgTypeText,
+		SessionID: sessionID,
+		Timestamp: time.Now(),
+		TextData:  &data,
+	}:
+	case <-ctx.Done():
+	}
+}
+
+// interrupt 丢弃当前轮次：缓冲的文本、排队的句子和正在进行的合成
+func (e *TTSElement) interrupt() {
+	e.splitter.Reset()
+
+	e.mu.Lock()
+	e.generation++
+	e.pending = 0
+	e.playingUntil = time.Time{}
+	if e.cancelSynth != nil {
+		e.cancelSynth()
+	}
+	e.mu.Unlock()
+}
+
+// synthLoop 按顺序合成句子
+func (e *TTSElement) synthLoop(ctx context.Context) {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-e.jobs:
+			e.mu.Lock()
+			if job.generation != e.generation {
+				e.mu.Unlock()
+				continue
+			}
+			synthCtx, cancel := context.WithCancel(ctx)
+			e.cancelSynth = cancel
+			e.mu.Unlock()
+
+			err := e.engine.Synthesize(synthCtx, job.text, func(pcm []byte) error {
+				return e.emit(synthCtx, job, pcm)
+			})
+			if err != nil && synthCtx.Err() == nil {
+				log.Printf("tts synthesize error: %v", err)
+			}
+			// 被打断时丢弃重采样器中残留的音频
+			if synthCtx.Err() != nil && e.resample != nil {
+				if _, err := e.resample.Flush(); err != nil {
+					log.Printf("tts resample flush error: %v", err)
+				}
+			}
+
+			e.mu.Lock()
+			e.cancelSynth = nil
+			if job.generation == e.generation {
+				e.pending--
+			}
+			e.mu.Unlock()
+			cancel()
+		}
+	}
+}
+
+// emit 重采样并投递一块合成的音频，轮次已被打断时返回 context.Canceled
+func (e *TTSElement) emit(ctx context.Context, job ttsJob, pcm []byte) error {
+	if e.resample != nil {
+		var err error
+		if pcm, err = e.resample.Resample(pcm); err != nil {
+			return err
+		}
+	}
+	if len(pcm) == 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if job.generation != e.generation {
+		return context.Canceled
+	}
+	start := time.Now()
+	if e.playingUntil.After(start) {
+		start = e.playingUntil
+	}
+	e.playingUntil = start.Add(time.Duration(len(pcm)/2) * time.Second / time.Duration(e.outputSampleRate))
+
+	msg := pipeline.PipelineMessage{
+		Type:      pipeline.MsgTypeAudio,
+		SessionID: job.sessionID,
+		Timestamp: time.Now(),
+		AudioData: &pipeline.AudioData{
+			Data:       pcm,
+			SampleRate: e.outputSampleRate,
+			Channels:   1,
+			MediaType:  "audio/x-raw",
+			Timestamp:  time.Now(),
+		},
+	}
+	select {
+	case e.BaseElement.OutChan <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *TTSElement) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+		e.cancel = nil
+	}
+	if e.resample != nil {
+		e.resample.Free()
+		e.resample = nil
+	}
+	return nil
+}
+
+func (e *TTSElement) In() chan<- pipeline.PipelineMessage {
+	return e.BaseElement.InChan
+}
+
+func (e *TTSElement) Out() <-chan pipeline.PipelineMessage {
+	return e.BaseElement.OutChan
+}
@@ -2,8 +2,10 @@ package elements
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -11,57 +13,78 @@ import (
 	"github.com/pion/webrtc/v4"
 	"github.com/pion/webrtc/v4/pkg/media"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/codec"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/pipeline"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/utils"
 )
 
 // WebRTCSinkElement 将音频数据写入 WebRTC 轨道, todo 支持视频/文本
+// 输入为 24kHz PCM，按协商的编码（Opus、G.722、G.711）重采样并编码后写入轨道
 type WebRTCSinkElement struct {
 	*pipeline.BaseElement
 
 	track *webrtc.TrackLocalStaticSample
+	codec codec.Codec
 
 	playout *audio.PlayoutBuffer
-	dumper  *audio.Dumper
+	// playoutTap 非 nil 时收到每一帧实际发出的 PCM（协商编码的采样率，单声道），用于通话录音
+	playoutTap func(pcm []byte)
+	// packetTap 非 nil 时收到每一个发出的编码包（仅 Opus），用于不重新编码的 Ogg Opus 录音
+	packetTap func(packet []byte)
 
-	encoder    *opus.Encoder
-	opusFile   *os.File
-	opusEnable bool
+	encoder    *opus.Encoder // 仅 Opus
+	pcmEncoder codec.Encoder // G.711/G.722
+	encoderMu  sync.Mutex
 
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 }
 
 func NewWebRTCSinkElement(bufferSize int, track *webrtc.TrackLocalStaticSample) *WebRTCSinkElement {
-	playout, err := audio.NewPlayoutBuffer()
+	e, err := NewWebRTCSinkElementWithCodec(bufferSize, track, codec.Opus)
 	if err != nil {
-		log.Fatal("create audio buffer error: ", err)
-	}
-
-	var dumper *audio.Dumper
-	if os.Getenv("DUMP_LOCAL_AUDIO") == "true" {
-		dumper, err = audio.NewDumper("local", 24000, 1)
-		if err != nil {
-			log.Printf("create audio dumper error: %v", err)
-		}
+		log.Fatal("create webrtc sink error: ", err)
 	}
+	return e
+}
 
-	encoder, err := opus.NewEncoder(48000, 1, opus.AppVoIP)
+// NewWebRTCSinkElementWithCodec 创建使用指定编码的 WebRTC 输出元素
+func NewWebRTCSinkElementWithCodec(bufferSize int, track *webrtc.TrackLocalStaticSample, c codec.Codec) (*WebRTCSinkElement, error) {
+	playout, err := audio.NewPlayoutBufferWithRate(c.SampleRate)
 	if err != nil {
-		log.Fatal("create opus encoder error: ", err)
+		return nil, fmt.Errorf("create audio buffer: %w", err)
+	}
+	if err := configureComfortNoise(playout); err != nil {
+		playout.Close()
+		return nil, err
 	}
 
-	// // 设置编码参数
-	// encoder.SetBitrate(50000) // 64 kbps
-	// encoder.SetComplexity(10) // 最高质量
-
-	return &WebRTCSinkElement{
+	e := &WebRTCSinkElement{
 		BaseElement: pipeline.NewBaseElement(bufferSize),
 		track:       track,
+		codec:       c,
 		playout:     playout,
-		dumper:      dumper,
-		encoder:     encoder,
 	}
+
+	if c.IsOpus() {
+		e.encoder, err = opus.NewEncoder(c.SampleRate, 1, opus.AppVoIP)
+		if err != nil {
+			playout.Close()
+			return nil, fmt.Errorf("create opus encoder: %w", err)
+		}
+
+		// // 设置编码参数
+		// encoder.SetBitrate(50000) // 64 kbps
+		// encoder.SetComplexity(10) // 最高质量
+	} else {
+		e.pcmEncoder, err = codec.NewEncoder(c)
+		if err != nil {
+			playout.Close()
+			return nil, err
+		}
+	}
+
+	return e, nil
 }
 
 func (e *WebRTCSinkElement) Start(ctx context.Context) error {
@@ -86,12 +109,43 @@ func (e *WebRTCSinkElement) Stop() error {
 		e.playout = nil
 	}
 
-	if e.dumper != nil {
-		e.dumper.Close()
-		e.dumper = nil
+	return nil
+}
+
+// SetPlayoutTap 设置接收每一帧实际发出的音频（含静音）的回调，需在 Start 之前调用
+func (e *WebRTCSinkElement) SetPlayoutTap(tap func(pcm []byte)) {
+	e.playoutTap = tap
+}
+
+// SetPacketTap 设置接收每一个发出的 Opus 包的回调，需在 Start 之前调用，非 Opus 编码时不会被调用。
+// packet 在回调返回后会被复用
+func (e *WebRTCSinkElement) SetPacketTap(tap func(packet []byte)) {
+	e.packetTap = tap
+}
+
+// ApplyEncoderSettings 动态调整下行 Opus 编码参数（码率、FEC、预期丢包率、DTX）
+func (e *WebRTCSinkElement) ApplyEncoderSettings(s audio.EncoderSettings) error {
+	if !e.codec.IsOpus() {
+		return fmt.Errorf("encoder settings only apply to opus, track uses %s", e.codec)
 	}
+	e.encoderMu.Lock()
+	defer e.encoderMu.Unlock()
+	return applyOpusSettings(e.encoder, s)
+}
 
-	return nil
+// encode 将一帧 PCM 编码为协商的编码格式，Opus 的输出复用 opusBuf
+func (e *WebRTCSinkElement) encode(pcm []int16, opusBuf []byte) ([]byte, error) {
+	e.encoderMu.Lock()
+	defer e.encoderMu.Unlock()
+
+	if e.encoder != nil {
+		n, err := e.encoder.Encode(pcm, opusBuf)
+		if err != nil {
+			return nil, err
+		}
+		return opusBuf[:n], nil
+	}
+	return e.pcmEncoder.Encode(pcm)
 }
 
 func (e *WebRTCSinkElement) In() chan<- pipeline.PipelineMessage {
@@ -111,6 +165,12 @@ func (e *WebRTCSinkElement) run(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case msg := <-e.BaseElement.InChan:
+				// 模型输出被打断时丢弃尚未播放的音频
+				if msg.Type == pipeline.MsgTypeText && msg.TextData != nil && msg.TextData.Type == pipeline.TextEventInterrupted {
+					e.playout.Clear()
+					continue
+				}
+
 				if msg.Type != pipeline.MsgTypeAudio {
 					continue
 				}
@@ -123,13 +183,6 @@ func (e *WebRTCSinkElement) run(ctx context.Context) {
 					continue
 				}
 
-				// dump 音频数据
-				if e.dumper != nil {
-					if err := e.dumper.Write(msg.AudioData.Data); err != nil {
-						log.Printf("Failed to dump audio: %v", err)
-					}
-				}
-
 				// 写入播放缓冲区
 				if err := e.playout.Write(msg.AudioData.Data); err != nil {
 					log.Printf("Failed to write to playout buffer: %v", err)
@@ -158,18 +211,28 @@ func (e *WebRTCSinkElement) run(ctx context.Context) {
 				if time.Since(lastSendTime) >= 20*time.Millisecond {
 
 					audioData := e.playout.ReadFrame()
+					if e.playoutTap != nil {
+						e.playoutTap(audioData)
+					}
 
-					pcmData := utils.ByteSliceToInt16Slice(audioData)
+					pcmData, err := utils.ByteSliceToInt16Slice(audioData)
+					if err != nil {
+						log.Printf("%s encode error: %v", e.codec.Name, err)
+						continue
+					}
 
-					n, err := e.encoder.Encode(pcmData, opusBuf)
+					payload, err := e.encode(pcmData, opusBuf)
 					if err != nil {
-						log.Println("Opus encode error:", err)
+						log.Printf("%s encode error: %v", e.codec.Name, err)
 						continue
 					}
+					if e.packetTap != nil && e.encoder != nil {
+						e.packetTap(payload)
+					}
 
 					// 创建音频样本
 					sample := media.Sample{
-						Data:     opusBuf[:n],
+						Data:     payload,
 						Duration: 20 * time.Millisecond,
 					}
 
@@ -185,3 +248,25 @@ func (e *WebRTCSinkElement) run(ctx context.Context) {
 		}
 	}()
 }
+
+// configureComfortNoise 按环境变量 PLAYOUT_COMFORT_NOISE（none|white|pink|shaped）
+// 和 PLAYOUT_COMFORT_NOISE_LEVEL（dBov）配置播放缓冲区的舒适噪声
+func configureComfortNoise(playout *audio.PlayoutBuffer) error {
+	cfg := audio.DefaultComfortNoiseConfig()
+
+	noiseType, err := audio.ParseNoiseType(os.Getenv("PLAYOUT_COMFORT_NOISE"))
+	if err != nil {
+		return err
+	}
+	cfg.Type = noiseType
+
+	if v := os.Getenv("PLAYOUT_COMFORT_NOISE_LEVEL"); v != "" {
+		level, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid PLAYOUT_COMFORT_NOISE_LEVEL %q: %w", v, err)
+		}
+		cfg.Level = level
+	}
+
+	return playout.SetComfortNoise(cfg)
+}
@@ -56,11 +56,14 @@ func (e *WebRTCSourceElement) Start(ctx context.Context) error {
 					Type:      pipeline.MsgTypeAudio,
 					Timestamp: time.Now(),
 					AudioData: &pipeline.AudioData{
-						Data:       rtp.Payload,
-						MediaType:  "audio/x-raw",
-						SampleRate: 48000, // WebRTC 默认采样率
-						Channels:   1,     // WebRTC 默认单声道
-						Timestamp:  time.Now(),
+						Data:           rtp.Payload,
+						MediaType:      "audio/x-opus",
+						Codec:          "opus",
+						SampleRate:     48000, // WebRTC 默认采样率
+						Channels:       1,     // WebRTC 默认单声道
+						Timestamp:      time.Now(),
+						SequenceNumber: rtp.SequenceNumber,
+						RTPTimestamp:   rtp.Timestamp,
 					},
 				}
 
@@ -11,7 +11,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/asticode/go-astiav"
 	"github.com/google/uuid"
 	"github.com/hraban/opus"
 	"github.com/pion/webrtc/v4"
@@ -33,6 +32,8 @@ const (
 type WebRTCServer struct {
 	sync.RWMutex
 	peers map[string]*PeerConnection
+	// dumpConfig 非 nil 时按其中的转储点保存会话音频
+	dumpConfig *audio.DumpConfig
 }
 
 // 创建一个PeerConnection 封装
@@ -56,6 +57,24 @@ func NewWebRTCServer() *WebRTCServer {
 	}
 }
 
+// SetDumpConfig 设置调试用的音频转储
+func (s *WebRTCServer) SetDumpConfig(c *audio.DumpConfig) {
+	s.dumpConfig = c
+}
+
+// newDumper 按配置为会话的一个转储点创建 dumper，未开启或失败时返回 nil
+func (s *WebRTCServer) newDumper(peer *PeerConnection, point audio.DumpPoint, sampleRate, channels int) *audio.Dumper {
+	if !s.dumpConfig.Enabled(point) {
+		return nil
+	}
+	dumper, err := s.dumpConfig.NewDumper(peer.id, point, sampleRate, channels)
+	if err != nil {
+		log.Printf("创建 %s dumper 失败: %v\n", point, err)
+		return nil
+	}
+	return dumper
+}
+
 // HandleNegotiate handles the WebRTC negotiation endpoint
 func (s *WebRTCServer) HandleNegotiate(w http.ResponseWriter, r *http.Request) {
 	// 添加 CORS 头
@@ -170,7 +189,7 @@ func (s *WebRTCServer) HandleNegotiate(w http.ResponseWriter, r *http.Request) {
 
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey, Backend: genai.BackendGoogleAI})
 	if err != nil {
-		log.Fatal("create client error: ", err)
+		log.Println("create client error: ", err)
 		http.Error(w, "Failed to create client", http.StatusInternalServerError)
 		return
 	}
@@ -179,7 +198,7 @@ func (s *WebRTCServer) HandleNegotiate(w http.ResponseWriter, r *http.Request) {
 		ResponseModalities: []string{"AUDIO"},
 	})
 	if err != nil {
-		log.Fatal("connect to model error: ", err)
+		log.Println("connect to model error: ", err)
 		http.Error(w, "Failed to connect to model", http.StatusInternalServerError)
 		return
 	}
@@ -213,21 +232,17 @@ func (s *WebRTCServer) HandleNegotiate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WebRTCServer) HandleSession(ctx context.Context, peer *PeerConnection) {
-	var dumper *audio.Dumper
-	if os.Getenv("DUMP_SESSION_AUDIO") == "true" {
-		var err error
-		dumper, err = audio.NewDumper("session", 24000, 1)
-		if err != nil {
-			log.Printf("创建 session dumper 失败: %v\n", err)
-		} else {
-			defer dumper.Close()
-		}
+	dumper := s.newDumper(peer, audio.DumpPointModelOutput, 24000, 1)
+	if dumper != nil {
+		defer dumper.Close()
 	}
 
 	for {
 		message, err := peer.genaiSession.Receive()
 		if err != nil {
-			log.Fatal("receive model response error: ", err)
+			// 模型连接断开只结束本会话，不影响其他会话
+			log.Println("receive model response error: ", err)
+			return
 		}
 
 		log.Printf("Received message: %+v\n", message)
@@ -274,15 +289,9 @@ func (s *WebRTCServer) HandleSession(ctx context.Context, peer *PeerConnection)
 }
 
 func (s *WebRTCServer) HandleRemoteAudio(ctx context.Context, peer *PeerConnection) {
-	var dumper *audio.Dumper
-	if os.Getenv("DUMP_REMOTE_AUDIO") == "true" {
-		var err error
-		dumper, err = audio.NewDumper("remoteaudio", 48000, 1)
-		if err != nil {
-			log.Printf("创建 remote dumper 失败: %v\n", err)
-		} else {
-			defer dumper.Close()
-		}
+	dumper := s.newDumper(peer, audio.DumpPointUplink, 48000, 1)
+	if dumper != nil {
+		defer dumper.Close()
 	}
 
 	decoder, err := opus.NewDecoder(48000, 1)
@@ -291,7 +300,7 @@ func (s *WebRTCServer) HandleRemoteAudio(ctx context.Context, peer *PeerConnecti
 		return
 	}
 
-	resample, err := audio.NewResample(48000, 16000, astiav.ChannelLayoutMono, astiav.ChannelLayoutMono)
+	resample, err := audio.NewResampler(48000, 16000, 1, 1)
 	if err != nil {
 		log.Printf("创建 resample 失败: %v\n", err)
 		return
@@ -351,15 +360,9 @@ func (s *WebRTCServer) HandleLocalAudio(ctx context.Context, peer *PeerConnectio
 	ticker := time.NewTicker(10 * time.Millisecond)
 	defer ticker.Stop()
 
-	var dumper *audio.Dumper
-	if os.Getenv("DUMP_LOCAL_AUDIO") == "true" {
-		var err error
-		dumper, err = audio.NewDumper("localaudio", 48000, 1)
-		if err != nil {
-			log.Printf("创建 local dumper 失败: %v\n", err)
-		} else {
-			defer dumper.Close()
-		}
+	dumper := s.newDumper(peer, audio.DumpPointPlayout, 48000, 1)
+	if dumper != nil {
+		defer dumper.Close()
 	}
 
 	// 创建 Opus 编码器 (48kHz, mono)
@@ -449,6 +452,14 @@ func (s *WebRTCServer) HandleDataChannel(ctx context.Context, peer *PeerConnecti
 // StartServer starts the WebRTC server on the specified port
 func StartServer(addr string) error {
 	server := NewWebRTCServer()
+
+	// 调试用的音频转储，由 DUMP_* 环境变量配置
+	dumpConfig, err := audio.DumpConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	server.SetDumpConfig(dumpConfig)
+
 	http.HandleFunc("/session", server.HandleNegotiate)
 	log.Printf("WebRTC server starting on %s", addr)
 	return http.ListenAndServe(addr, nil)
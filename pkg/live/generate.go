@@ -0,0 +1,112 @@
+package live
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// DefaultTextModel 生成摘要等非实时任务使用的模型
+const DefaultTextModel = "gemini-2.0-flash"
+
+// GenerateEndpoint 返回 generateContent 的 HTTP 地址，BaseURL 的 ws/wss 映射为 http/https
+func GenerateEndpoint(cfg ClientConfig, model string) (string, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	version := cfg.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL: %w", err)
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss", "":
+		u.Scheme = "https"
+	}
+	if !strings.HasPrefix(model, "models/") {
+		model = "models/" + model
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + fmt.Sprintf("/%s/%s:generateContent", version, model)
+	if cfg.APIKey != "" {
+		q := u.Query()
+		q.Set("key", cfg.APIKey)
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
+
+type generateRequest struct {
+	Contents          []*genai.Content `json:"contents"`
+	SystemInstruction *genai.Content   `json:"systemInstruction,omitempty"`
+}
+
+type generateResponse struct {
+	Candidates []struct {
+		Content *genai.Content `json:"content"`
+	} `json:"candidates"`
+}
+
+// GenerateText 单轮调用 generateContent，返回第一个候选的文本
+func GenerateText(ctx context.Context, cfg ClientConfig, model, systemInstruction, prompt string) (string, error) {
+	endpoint, err := GenerateEndpoint(cfg, model)
+	if err != nil {
+		return "", err
+	}
+
+	req := generateRequest{
+		Contents: []*genai.Content{{Role: "user", Parts: []*genai.Part{{Text: prompt}}}},
+	}
+	if systemInstruction != "" {
+		req.SystemInstruction = &genai.Content{Parts: []*genai.Part{{Text: systemInstruction}}}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("generate content: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("generate content: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var out generateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode generate content response: %w", err)
+	}
+	var text strings.Builder
+	if len(out.Candidates) > 0 && out.Candidates[0].Content != nil {
+		for _, p := range out.Candidates[0].Content.Parts {
+			text.WriteString(p.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("generate content: empty response")
+	}
+	return text.String(), nil
+}
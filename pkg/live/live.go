@@ -0,0 +1,293 @@
+// Package live 是 Gemini Live API（BidiGenerateContent）的 websocket 客户端
+// genai v0.0.1 的 Live 类型缺少转写、用量等字段，这里补齐连接配置和服务端消息，
+// 内容类（Content、Part、Tool 等）仍复用 genai 的定义
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/genai"
+)
+
+const (
+	// DefaultBaseURL Gemini API 地址
+	DefaultBaseURL = "wss://generativelanguage.googleapis.com"
+	// DefaultAPIVersion Live API 版本
+	DefaultAPIVersion = "v1beta"
+	// DefaultModel 默认使用的实时模型
+	DefaultModel = "gemini-2.0-flash-exp"
+)
+
+// ClientConfig 连接参数
+type ClientConfig struct {
+	APIKey     string
+	BaseURL    string // 为空时使用 DefaultBaseURL，支持 ws/wss/http/https
+	APIVersion string // 为空时使用 DefaultAPIVersion
+}
+
+// GenerationConfig 生成参数
+type GenerationConfig struct {
+	ResponseModalities []string      `json:"responseModalities,omitempty"`
+	SpeechConfig       *SpeechConfig `json:"speechConfig,omitempty"`
+	Temperature        *float64      `json:"temperature,omitempty"`
+}
+
+// SpeechConfig 语音输出配置，genai v0.0.1 缺少 LanguageCode
+type SpeechConfig struct {
+	VoiceConfig  *genai.VoiceConfig `json:"voiceConfig,omitempty"`
+	LanguageCode string             `json:"languageCode,omitempty"`
+}
+
+// 语音活动检测的灵敏度
+const (
+	StartSensitivityHigh = "START_SENSITIVITY_HIGH"
+	StartSensitivityLow  = "START_SENSITIVITY_LOW"
+	EndSensitivityHigh   = "END_SENSITIVITY_HIGH"
+	EndSensitivityLow    = "END_SENSITIVITY_LOW"
+)
+
+// AutomaticActivityDetection 服务端语音活动检测参数，未设置的字段使用服务端默认值
+type AutomaticActivityDetection struct {
+	Disabled                 bool   `json:"disabled,omitempty"`
+	StartOfSpeechSensitivity string `json:"startOfSpeechSensitivity,omitempty"`
+	EndOfSpeechSensitivity   string `json:"endOfSpeechSensitivity,omitempty"`
+	PrefixPaddingMs          int    `json:"prefixPaddingMs,omitempty"`
+	SilenceDurationMs        int    `json:"silenceDurationMs,omitempty"`
+}
+
+// RealtimeInputConfig 实时输入的处理方式
+type RealtimeInputConfig struct {
+	AutomaticActivityDetection *AutomaticActivityDetection `json:"automaticActivityDetection,omitempty"`
+}
+
+// AudioTranscriptionConfig 开启音频转写，目前没有可配置项
+type AudioTranscriptionConfig struct{}
+
+// SessionResumptionConfig 开启会话恢复，Handle 为上一个会话最后收到的句柄，
+// 为空表示新会话，服务端会通过 SessionResumptionUpdate 下发句柄
+type SessionResumptionConfig struct {
+	Handle string `json:"handle,omitempty"`
+}
+
+// SlidingWindow 压缩时从最早的轮次开始丢弃，直到上下文降到 TargetTokens
+type SlidingWindow struct {
+	TargetTokens int64 `json:"targetTokens,omitempty"`
+}
+
+// ContextWindowCompressionConfig 上下文达到 TriggerTokens 时由服务端压缩，
+// 开启后会话不再受时长和上下文长度的限制
+type ContextWindowCompressionConfig struct {
+	TriggerTokens int64          `json:"triggerTokens,omitempty"`
+	SlidingWindow *SlidingWindow `json:"slidingWindow,omitempty"`
+}
+
+// Setup 连接建立后发送的第一条消息
+type Setup struct {
+	Model                    string                          `json:"model"`
+	GenerationConfig         *GenerationConfig               `json:"generationConfig,omitempty"`
+	SystemInstruction        *genai.Content                  `json:"systemInstruction,omitempty"`
+	Tools                    []*genai.Tool                   `json:"tools,omitempty"`
+	RealtimeInputConfig      *RealtimeInputConfig            `json:"realtimeInputConfig,omitempty"`
+	InputAudioTranscription  *AudioTranscriptionConfig       `json:"inputAudioTranscription,omitempty"`
+	OutputAudioTranscription *AudioTranscriptionConfig       `json:"outputAudioTranscription,omitempty"`
+	SessionResumption        *SessionResumptionConfig        `json:"sessionResumption,omitempty"`
+	ContextWindowCompression *ContextWindowCompressionConfig `json:"contextWindowCompression,omitempty"`
+}
+
+// Transcription 一段音频转写，Text 为增量文本
+type Transcription struct {
+	Text     string `json:"text,omitempty"`
+	Finished bool   `json:"finished,omitempty"`
+}
+
+// ServerContent 模型输出及轮次状态
+type ServerContent struct {
+	ModelTurn           *genai.Content `json:"modelTurn,omitempty"`
+	TurnComplete        bool           `json:"turnComplete,omitempty"`
+	Interrupted         bool           `json:"interrupted,omitempty"`
+	GenerationComplete  bool           `json:"generationComplete,omitempty"`
+	InputTranscription  *Transcription `json:"inputTranscription,omitempty"`
+	OutputTranscription *Transcription `json:"outputTranscription,omitempty"`
+}
+
+// UsageMetadata token 用量
+type UsageMetadata struct {
+	PromptTokenCount   int `json:"promptTokenCount,omitempty"`
+	ResponseTokenCount int `json:"responseTokenCount,omitempty"`
+	TotalTokenCount    int `json:"totalTokenCount,omitempty"`
+}
+
+// ToolCallCancellation 取消此前下发的函数调用
+// genai v0.0.1 中 IDs 声明为 []int64，与服务端实际下发的字符串 ID 不符
+type ToolCallCancellation struct {
+	IDs []string `json:"ids,omitempty"`
+}
+
+// GoAway 服务端即将断开连接，客户端应在 TimeLeft 内用恢复句柄重新连接
+type GoAway struct {
+	TimeLeft string `json:"timeLeft,omitempty"` // protobuf Duration，如 "10s"
+}
+
+// TimeLeftDuration 解析 TimeLeft，无法解析时返回 0
+func (g *GoAway) TimeLeftDuration() time.Duration {
+	d, err := time.ParseDuration(g.TimeLeft)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// SessionResumptionUpdate 新的会话恢复句柄，Resumable 为 false 时当前位置不能恢复
+type SessionResumptionUpdate struct {
+	NewHandle string `json:"newHandle,omitempty"`
+	Resumable bool   `json:"resumable,omitempty"`
+}
+
+// ServerMessage 服务端消息，usageMetadata 之外每条消息只有一个字段非空
+type ServerMessage struct {
+	SetupComplete           *struct{}                 `json:"setupComplete,omitempty"`
+	ServerContent           *ServerContent            `json:"serverContent,omitempty"`
+	ToolCall                *genai.LiveServerToolCall `json:"toolCall,omitempty"`
+	ToolCallCancellation    *ToolCallCancellation     `json:"toolCallCancellation,omitempty"`
+	GoAway                  *GoAway                   `json:"goAway,omitempty"`
+	SessionResumptionUpdate *SessionResumptionUpdate  `json:"sessionResumptionUpdate,omitempty"`
+	UsageMetadata           *UsageMetadata            `json:"usageMetadata,omitempty"`
+}
+
+// Session 一个 Live 会话，Send 可以并发调用
+type Session struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	setup   Setup
+}
+
+// Endpoint 返回 BidiGenerateContent 的 websocket 地址
+func Endpoint(cfg ClientConfig) (string, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	version := cfg.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https", "":
+		u.Scheme = "wss"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") +
+		fmt.Sprintf("/ws/google.ai.generativelanguage.%s.GenerativeService.BidiGenerateContent", version)
+	if cfg.APIKey != "" {
+		q := u.Query()
+		q.Set("key", cfg.APIKey)
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
+
+// Connect 建立会话并完成 setup 握手
+func Connect(ctx context.Context, cfg ClientConfig, setup *Setup) (*Session, error) {
+	endpoint, err := Endpoint(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint, http.Header{})
+	if err != nil {
+		return nil, fmt.Errorf("connect to live api: %w", err)
+	}
+	s := &Session{conn: conn, setup: *setup}
+	if !strings.HasPrefix(s.setup.Model, "models/") {
+		s.setup.Model = "models/" + s.setup.Model
+	}
+	if err := s.write(map[string]any{"setup": &s.setup}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send setup: %w", err)
+	}
+
+	// 握手期间读操作跟随 ctx 取消，服务端不回复 setupComplete 时不会一直阻塞
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	resp, err := s.Receive()
+	if !stop() && ctx.Err() != nil {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("setup: %w", err)
+	}
+	if resp.SetupComplete == nil {
+		conn.Close()
+		return nil, fmt.Errorf("setup: unexpected first message")
+	}
+	return s, nil
+}
+
+// Setup 返回建立会话时使用的配置
+func (s *Session) Setup() Setup {
+	return s.setup
+}
+
+// Send 发送一条客户端消息（实时输入、文本轮次或工具结果）
+func (s *Session) Send(msg *genai.LiveClientMessage) error {
+	if msg.Setup != nil {
+		return fmt.Errorf("setup can only be sent by Connect")
+	}
+	return s.write(msg)
+}
+
+func (s *Session) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal client message: %w", err)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Receive 读取下一条服务端消息，只能在一个协程中调用
+func (s *Session) Receive() (*ServerMessage, error) {
+	_, data, err := s.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid server message: %w", err)
+	}
+	if e, ok := raw["error"]; ok {
+		return nil, fmt.Errorf("server error: %s", e)
+	}
+
+	msg := &ServerMessage{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("invalid server message: %w", err)
+	}
+	return msg, nil
+}
+
+// Close 关闭会话
+func (s *Session) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return s.conn.Close()
+}
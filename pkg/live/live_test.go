@@ -0,0 +1,219 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// newServer 启动一个测试用的 Live 服务端，handler 在 setup 完成后处理连接
+func newServer(t *testing.T, handler func(conn *websocket.Conn, setup map[string]any)) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, ".GenerativeService.BidiGenerateContent"))
+		assert.Equal(t, "test-key", r.URL.Query().Get("key"))
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var msg map[string]map[string]any
+		require.NoError(t, conn.ReadJSON(&msg))
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"setupComplete":{}}`)))
+		handler(conn, msg["setup"])
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEndpoint(t *testing.T) {
+	u, err := Endpoint(ClientConfig{APIKey: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, "wss://generativelanguage.googleapis.com/ws/google.ai.generativelanguage.v1beta.GenerativeService.BidiGenerateContent?key=k", u)
+
+	u, err = Endpoint(ClientConfig{BaseURL: "http://127.0.0.1:8080/proxy/", APIVersion: "v1alpha"})
+	require.NoError(t, err)
+	assert.Equal(t, "ws://127.0.0.1:8080/proxy/ws/google.ai.generativelanguage.v1alpha.GenerativeService.BidiGenerateContent", u)
+}
+
+func TestSessionTranscription(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	srv := newServer(t, func(conn *websocket.Conn, setup map[string]any) {
+		assert.Equal(t, "models/"+DefaultModel, setup["model"])
+		assert.Contains(t, setup, "inputAudioTranscription")
+		assert.Contains(t, setup, "outputAudioTranscription")
+
+		var msg map[string]any
+		require.NoError(t, conn.ReadJSON(&msg))
+		received <- msg
+
+		for _, m := range []string{
+			`{"serverContent":{"inputTranscription":{"text":"hello"}}}`,
+			`{"serverContent":{"outputTranscription":{"text":"hi","finished":true}}}`,
+			`{"serverContent":{"turnComplete":true},"usageMetadata":{"promptTokenCount":3,"responseTokenCount":4,"totalTokenCount":7}}`,
+			`{"error":{"code":500}}`,
+		} {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(m)))
+		}
+	})
+
+	s, err := Connect(context.Background(), ClientConfig{APIKey: "test-key", BaseURL: srv.URL}, &Setup{
+		Model:                    DefaultModel,
+		InputAudioTranscription:  &AudioTranscriptionConfig{},
+		OutputAudioTranscription: &AudioTranscriptionConfig{},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Send(&genai.LiveClientMessage{
+		RealtimeInput: &genai.LiveClientRealtimeInput{
+			MediaChunks: []*genai.Blob{{Data: []byte{1, 2}, MIMEType: "audio/pcm"}},
+		},
+	}))
+	msg := <-received
+	data, _ := json.Marshal(msg)
+	assert.JSONEq(t, `{"realtimeInput":{"mediaChunks":[{"data":"AQI=","mimeType":"audio/pcm"}]}}`, string(data))
+
+	m, err := s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, &Transcription{Text: "hello"}, m.ServerContent.InputTranscription)
+
+	m, err = s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, &Transcription{Text: "hi", Finished: true}, m.ServerContent.OutputTranscription)
+
+	m, err = s.Receive()
+	require.NoError(t, err)
+	assert.True(t, m.ServerContent.TurnComplete)
+	assert.Equal(t, 7, m.UsageMetadata.TotalTokenCount)
+
+	_, err = s.Receive()
+	assert.Error(t, err)
+
+	assert.Error(t, s.Send(&genai.LiveClientMessage{Setup: &genai.LiveClientSetup{}}))
+}
+
+func TestSessionToolCall(t *testing.T) {
+	srv := newServer(t, func(conn *websocket.Conn, setup map[string]any) {
+		tools, _ := json.Marshal(setup["tools"])
+		assert.JSONEq(t, `[{"functionDeclarations":[{"name":"lookup","description":"d"}]}]`, string(tools))
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"toolCall":{"functionCalls":[{"id":"fc-1","name":"lookup","args":{"q":"x"}}]}}`)))
+
+		var msg map[string]any
+		require.NoError(t, conn.ReadJSON(&msg))
+		data, _ := json.Marshal(msg)
+		assert.JSONEq(t, `{"toolResponse":{"functionResponses":[{"id":"fc-1","name":"lookup","response":{"ok":true}}]}}`, string(data))
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"toolCallCancellation":{"ids":["fc-2"]}}`)))
+	})
+
+	s, err := Connect(context.Background(), ClientConfig{APIKey: "test-key", BaseURL: srv.URL}, &Setup{
+		Model: DefaultModel,
+		Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "lookup", Description: "d"}}}},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	m, err := s.Receive()
+	require.NoError(t, err)
+	require.Len(t, m.ToolCall.FunctionCalls, 1)
+	fc := m.ToolCall.FunctionCalls[0]
+	assert.Equal(t, map[string]any{"q": "x"}, fc.Args)
+
+	require.NoError(t, s.Send(&genai.LiveClientMessage{ToolResponse: &genai.LiveClientToolResponse{
+		FunctionResponses: []*genai.FunctionResponse{{ID: fc.ID, Name: fc.Name, Response: map[string]any{"ok": true}}},
+	}}))
+
+	m, err = s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fc-2"}, m.ToolCallCancellation.IDs)
+}
+
+func TestSessionResumption(t *testing.T) {
+	srv := newServer(t, func(conn *websocket.Conn, setup map[string]any) {
+		assert.Equal(t, map[string]any{"handle": "h-1"}, setup["sessionResumption"])
+
+		for _, m := range []string{
+			`{"sessionResumptionUpdate":{"newHandle":"h-2","resumable":true}}`,
+			`{"goAway":{"timeLeft":"1.500s"}}`,
+		} {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(m)))
+		}
+	})
+
+	s, err := Connect(context.Background(), ClientConfig{APIKey: "test-key", BaseURL: srv.URL}, &Setup{
+		Model:             DefaultModel,
+		SessionResumption: &SessionResumptionConfig{Handle: "h-1"},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	m, err := s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, &SessionResumptionUpdate{NewHandle: "h-2", Resumable: true}, m.SessionResumptionUpdate)
+
+	m, err = s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, m.GoAway.TimeLeftDuration())
+
+	assert.Zero(t, (&GoAway{}).TimeLeftDuration())
+}
+
+func TestContextWindowCompressionSetup(t *testing.T) {
+	srv := newServer(t, func(conn *websocket.Conn, setup map[string]any) {
+		assert.Equal(t, map[string]any{"triggerTokens": 25000.0, "slidingWindow": map[string]any{"targetTokens": 12000.0}}, setup["contextWindowCompression"])
+	})
+
+	setup := &Setup{
+		Model: DefaultModel,
+		ContextWindowCompression: &ContextWindowCompressionConfig{
+			TriggerTokens: 25000,
+			SlidingWindow: &SlidingWindow{TargetTokens: 12000},
+		},
+	}
+	s, err := Connect(context.Background(), ClientConfig{APIKey: "test-key", BaseURL: srv.URL}, setup)
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, "models/"+DefaultModel, s.Setup().Model)
+	assert.NotNil(t, s.Setup().ContextWindowCompression)
+	assert.Equal(t, DefaultModel, setup.Model, "caller's setup is not modified")
+}
+
+func TestGenerateText(t *testing.T) {
+	u, err := GenerateEndpoint(ClientConfig{APIKey: "k"}, DefaultTextModel)
+	require.NoError(t, err)
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key=k", u)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/m:generateContent", r.URL.Path)
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		data, _ := json.Marshal(req)
+		assert.JSONEq(t, `{"contents":[{"role":"user","parts":[{"text":"hello"}]}],"systemInstruction":{"parts":[{"text":"be brief"}]}}`, string(data))
+
+		if r.URL.Query().Get("key") != "test-key" {
+			http.Error(w, "bad key", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hi "},{"text":"there"}]}}]}`))
+	}))
+	defer srv.Close()
+
+	text, err := GenerateText(context.Background(), ClientConfig{APIKey: "test-key", BaseURL: srv.URL}, "m", "be brief", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hi there", text)
+
+	_, err = GenerateText(context.Background(), ClientConfig{APIKey: "wrong", BaseURL: srv.URL}, "m", "be brief", "hello")
+	assert.ErrorContains(t, err, "status 403")
+}
@@ -0,0 +1,318 @@
+// Package livetest 提供脚本驱动的 Gemini Live 模拟服务端，用于不依赖 GOOGLE_API_KEY 的端到端测试。
+//
+// 服务端实现 BidiGenerateContent 的 websocket 协议（setup、realtimeInput、clientContent、
+// toolResponse 以及服务端的 serverContent、toolCall 等消息）和 generateContent。
+// 每个连接从头执行一个 Script（默认都是同一个，测试重连时可为之后的连接指定不同的脚本）：
+// 按顺序等待每一步的触发条件，然后执行该步的动作，例如
+//
+//	srv := livetest.NewServer(livetest.Script{Steps: []livetest.Step{
+//		{When: livetest.InputAudio(time.Second), Do: []livetest.Action{
+//			livetest.WAV("testdata/reply.wav"),
+//			livetest.TurnComplete(),
+//		}},
+//	}})
+//	defer srv.Close()
+//	cfg := live.ClientConfig{APIKey: "test", BaseURL: srv.URL}
+//
+// 下一步触发时上一步未执行完的动作被取消，因此在模型音频播放期间触发的一步可以模拟用户打断
+package livetest
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live"
+	"google.golang.org/genai"
+)
+
+// OutputSampleRate 模型音频的采样率，与 Live API 一致
+const OutputSampleRate = 24000
+
+// 未在 MIME 类型中声明采样率时，输入音频按 16kHz 计算时长
+const defaultInputSampleRate = 16000
+
+// DefaultSummary generateContent 默认返回的文本
+const DefaultSummary = "Summary of the conversation so far."
+
+// Script 每个连接执行的脚本
+type Script struct {
+	Steps []Step
+	// Realtime 按实际时长逐块发送模型音频，否则一次发完；测试打断时需要开启
+	Realtime bool
+	// ChunkDuration 模型音频的分块时长，默认 40ms
+	ChunkDuration time.Duration
+	// OnSetup 非 nil 时检查每个连接的 setup，返回错误则拒绝连接
+	OnSetup func(setup live.Setup) error
+	// Summary generateContent 返回的文本，为空时使用 DefaultSummary
+	Summary string
+}
+
+// Step 脚本中的一步，When 满足后按顺序执行 Do
+type Step struct {
+	When Trigger
+	Do   []Action
+}
+
+// Server 模拟服务端，URL 可直接用作 live.ClientConfig.BaseURL
+type Server struct {
+	*httptest.Server
+
+	script Script
+	// next 第 2、3…个连接的脚本，连接数更多时使用最后一个
+	next []Script
+
+	mu       sync.Mutex
+	setups   []live.Setup
+	received []*genai.LiveClientMessage
+	input    time.Duration
+	conns    map[*websocket.Conn]struct{}
+}
+
+// NewServer 启动模拟服务端。第一个连接执行 script，之后的连接依次执行 next 中的脚本，
+// 未指定时与前一个连接相同；generateContent 的返回取自 script.Summary
+func NewServer(script Script, next ...Script) *Server {
+	if script.Summary == "" {
+		script.Summary = DefaultSummary
+	}
+	s := &Server{script: withDefaults(script), conns: make(map[*websocket.Conn]struct{})}
+	for _, sc := range next {
+		s.next = append(s.next, withDefaults(sc))
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func withDefaults(script Script) Script {
+	if script.ChunkDuration <= 0 {
+		script.ChunkDuration = 40 * time.Millisecond
+	}
+	return script
+}
+
+// scriptFor 第 n 个连接（从 0 开始）执行的脚本
+func (s *Server) scriptFor(n int) *Script {
+	if n == 0 || len(s.next) == 0 {
+		return &s.script
+	}
+	return &s.next[min(n, len(s.next))-1]
+}
+
+// Close 断开所有会话并关闭服务端
+func (s *Server) Close() {
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.Server.Close()
+}
+
+// Setups 返回每个连接收到的 setup，按连接顺序
+func (s *Server) Setups() []live.Setup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]live.Setup(nil), s.setups...)
+}
+
+// Received 返回 setup 之后收到的所有客户端消息
+func (s *Server) Received() []*genai.LiveClientMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*genai.LiveClientMessage(nil), s.received...)
+}
+
+// InputAudio 返回所有连接收到的输入音频总时长
+func (s *Server) InputAudio() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.input
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, ".BidiGenerateContent"):
+		s.serveLive(w, r)
+	case strings.HasSuffix(r.URL.Path, ":generateContent") && r.Method == http.MethodPost:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"candidates": []map[string]any{{
+				"content": genai.Content{Role: "model", Parts: []*genai.Part{{Text: s.script.Summary}}},
+			}},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveLive(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.conns[ws] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, ws)
+		s.mu.Unlock()
+		ws.Close()
+	}()
+
+	var first struct {
+		Setup *live.Setup `json:"setup"`
+	}
+	if err := ws.ReadJSON(&first); err != nil || first.Setup == nil {
+		ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseProtocolError, "expected setup"))
+		return
+	}
+	s.mu.Lock()
+	script := s.scriptFor(len(s.setups))
+	s.setups = append(s.setups, *first.Setup)
+	s.mu.Unlock()
+
+	if script.OnSetup != nil {
+		if err := script.OnSetup(*first.Setup); err != nil {
+			ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseUnsupportedData, err.Error()))
+			return
+		}
+	}
+
+	c := &conn{ws: ws, script: script}
+	if err := c.send(&live.ServerMessage{SetupComplete: &struct{}{}}); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	inputs := make(chan clientInput, 64)
+	go func() {
+		defer cancel()
+		defer close(inputs)
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			msg := &genai.LiveClientMessage{}
+			if err := json.Unmarshal(data, msg); err != nil {
+				log.Printf("livetest: invalid client message: %v", err)
+				continue
+			}
+			in := inputOf(msg)
+			s.mu.Lock()
+			s.received = append(s.received, msg)
+			s.input += in.audio
+			s.mu.Unlock()
+			select {
+			case inputs <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	c.run(ctx, inputs)
+	// 脚本结束后继续接收，直到客户端断开
+	for range inputs {
+	}
+}
+
+// conn 一个会话连接
+type conn struct {
+	ws     *websocket.Conn
+	script *Script
+
+	writeMu sync.Mutex
+}
+
+func (c *conn) send(msg *live.ServerMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.ws.WriteJSON(msg)
+}
+
+// run 按顺序执行脚本。每一步的动作在单独的协程中执行，下一步触发时取消
+func (c *conn) run(ctx context.Context, inputs <-chan clientInput) {
+	var cancelActions context.CancelFunc
+	var actionsDone chan struct{}
+	stopActions := func() {
+		if cancelActions != nil {
+			cancelActions()
+			<-actionsDone
+		}
+	}
+	defer stopActions()
+
+	for _, step := range c.script.Steps {
+		if !step.When.wait(ctx, inputs) {
+			return
+		}
+		stopActions()
+
+		actx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		cancelActions, actionsDone = cancel, done
+		go func(actions []Action) {
+			defer close(done)
+			for _, a := range actions {
+				if actx.Err() != nil {
+					return
+				}
+				if err := a.run(actx, c); err != nil {
+					if actx.Err() == nil {
+						log.Printf("livetest: action %s error: %v", a.name, err)
+						c.ws.Close()
+					}
+					return
+				}
+			}
+		}(step.Do)
+	}
+
+	// 最后一步的动作执行完再返回
+	select {
+	case <-actionsDone:
+	case <-ctx.Done():
+	}
+}
+
+// clientInput 一条客户端消息中与触发条件相关的内容
+type clientInput struct {
+	audio        time.Duration
+	text         bool
+	toolResponse bool
+}
+
+func inputOf(msg *genai.LiveClientMessage) clientInput {
+	var in clientInput
+	if msg.RealtimeInput != nil {
+		for _, chunk := range msg.RealtimeInput.MediaChunks {
+			if chunk == nil || !strings.HasPrefix(chunk.MIMEType, "audio/pcm") {
+				continue
+			}
+			rate := defaultInputSampleRate
+			if i := strings.Index(chunk.MIMEType, "rate="); i >= 0 {
+				if n, err := parsePositive(chunk.MIMEType[i+len("rate="):]); err == nil {
+					rate = n
+				}
+			}
+			in.audio += time.Duration(len(chunk.Data)/2) * time.Second / time.Duration(rate)
+		}
+	}
+	if msg.ClientContent != nil && len(msg.ClientContent.Turns) > 0 {
+		in.text = true
+	}
+	if msg.ToolResponse != nil {
+		in.toolResponse = true
+	}
+	return in
+}
@@ -0,0 +1,202 @@
+package livetest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func connect(t *testing.T, srv *Server) *live.Session {
+	s, err := live.Connect(context.Background(), live.ClientConfig{APIKey: "test", BaseURL: srv.URL}, &live.Setup{Model: live.DefaultModel})
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func sendAudio(t *testing.T, s *live.Session, d time.Duration) {
+	require.NoError(t, s.Send(&genai.LiveClientMessage{
+		RealtimeInput: &genai.LiveClientRealtimeInput{
+			MediaChunks: []*genai.Blob{{Data: make([]byte, int(d.Seconds()*16000)*2), MIMEType: "audio/pcm;rate=16000"}},
+		},
+	}))
+}
+
+func TestScriptReplyWithWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reply.wav")
+	w, err := audio.NewWavStreamWriter(path, OutputSampleRate, 1, 16)
+	require.NoError(t, err)
+	_, err = w.Write(TonePCM(440, 100*time.Millisecond, OutputSampleRate))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	srv := NewServer(Script{Steps: []Step{
+		{When: InputAudio(time.Second), Do: []Action{
+			InputTranscript("hello", true),
+			WAV(path),
+			OutputTranscript("hi there", true),
+			TurnComplete(),
+		}},
+		{When: ClientText(), Do: []Action{ToolCall("c1", "get_time", map[string]any{"tz": "UTC"})}},
+		{When: ToolResponse(), Do: []Action{Text("done"), TurnComplete()}},
+	}})
+	defer srv.Close()
+	s := connect(t, srv)
+
+	// 不足 1s 时不回复
+	sendAudio(t, s, 600*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	sendAudio(t, s, 400*time.Millisecond)
+
+	msg, err := s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", msg.ServerContent.InputTranscription.Text)
+	var audioBytes int
+	for {
+		msg, err = s.Receive()
+		require.NoError(t, err)
+		if msg.ServerContent.ModelTurn == nil {
+			break
+		}
+		audioBytes += len(msg.ServerContent.ModelTurn.Parts[0].InlineData.Data)
+	}
+	assert.Equal(t, 4800, audioBytes)
+	assert.Equal(t, "hi there", msg.ServerContent.OutputTranscription.Text)
+	msg, err = s.Receive()
+	require.NoError(t, err)
+	assert.True(t, msg.ServerContent.TurnComplete)
+
+	require.NoError(t, s.Send(&genai.LiveClientMessage{ClientContent: &genai.LiveClientContent{
+		Turns: []*genai.Content{{Role: "user", Parts: []*genai.Part{{Text: "what time is it"}}}}, TurnComplete: true,
+	}}))
+	msg, err = s.Receive()
+	require.NoError(t, err)
+	require.NotNil(t, msg.ToolCall)
+	assert.Equal(t, "get_time", msg.ToolCall.FunctionCalls[0].Name)
+
+	require.NoError(t, s.Send(&genai.LiveClientMessage{ToolResponse: &genai.LiveClientToolResponse{
+		FunctionResponses: []*genai.FunctionResponse{{ID: "c1", Name: "get_time", Response: map[string]any{"time": "12:00"}}},
+	}}))
+	msg, err = s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, "done", msg.ServerContent.ModelTurn.Parts[0].Text)
+
+	assert.Equal(t, time.Second, srv.InputAudio())
+	assert.Len(t, srv.Received(), 4)
+	assert.Equal(t, "models/"+live.DefaultModel, srv.Setups()[0].Model)
+}
+
+func TestScriptInterrupt(t *testing.T) {
+	srv := NewServer(Script{Realtime: true, Steps: []Step{
+		{When: InputAudio(100 * time.Millisecond), Do: []Action{Tone(440, 2*time.Second), TurnComplete()}},
+		{When: InputAudio(100 * time.Millisecond), Do: []Action{Interrupted()}},
+	}})
+	defer srv.Close()
+	s := connect(t, srv)
+
+	sendAudio(t, s, 100*time.Millisecond)
+	msg, err := s.Receive()
+	require.NoError(t, err)
+	require.NotNil(t, msg.ServerContent.ModelTurn)
+
+	// 播放中的回复被下一步打断，不再发送剩余音频和 turnComplete
+	sendAudio(t, s, 100*time.Millisecond)
+	chunks := 1
+	for {
+		msg, err = s.Receive()
+		require.NoError(t, err)
+		if msg.ServerContent.ModelTurn == nil {
+			break
+		}
+		chunks++
+	}
+	assert.True(t, msg.ServerContent.Interrupted)
+	assert.Less(t, chunks, 10)
+}
+
+func TestScriptSessionControl(t *testing.T) {
+	srv := NewServer(Script{
+		Steps: []Step{
+			{Do: []Action{ResumptionHandle("h1"), Usage(10, 5)}},
+			{When: Delay(20 * time.Millisecond), Do: []Action{GoAway(5 * time.Second), Disconnect()}},
+		},
+		OnSetup: func(setup live.Setup) error {
+			if setup.SessionResumption != nil && setup.SessionResumption.Handle == "expired" {
+				return errors.New("invalid handle")
+			}
+			return nil
+		},
+	})
+	defer srv.Close()
+	s := connect(t, srv)
+
+	msg, err := s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, "h1", msg.SessionResumptionUpdate.NewHandle)
+	msg, err = s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, 15, msg.UsageMetadata.TotalTokenCount)
+	msg, err = s.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, msg.GoAway.TimeLeftDuration())
+	_, err = s.Receive()
+	assert.Error(t, err)
+
+	_, err = live.Connect(context.Background(), live.ClientConfig{BaseURL: srv.URL}, &live.Setup{
+		Model:             live.DefaultModel,
+		SessionResumption: &live.SessionResumptionConfig{Handle: "expired"},
+	})
+	assert.Error(t, err)
+	assert.Len(t, srv.Setups(), 2)
+}
+
+// TestConnectWithoutSetupComplete 服务端收到 setup 后不回复时，Connect 随 ctx 结束
+func TestConnectWithoutSetupComplete(t *testing.T) {
+	release := make(chan struct{})
+	srv := NewServer(Script{OnSetup: func(live.Setup) error {
+		<-release
+		return nil
+	}})
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := live.Connect(ctx, live.ClientConfig{BaseURL: srv.URL}, &live.Setup{Model: live.DefaultModel})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestScriptPerConnection(t *testing.T) {
+	srv := NewServer(
+		Script{Steps: []Step{{Do: []Action{Text("first")}}}},
+		Script{Steps: []Step{{Do: []Action{Text("second")}}}},
+	)
+	defer srv.Close()
+
+	// 第三个连接重复最后一个脚本
+	for _, want := range []string{"first", "second", "second"} {
+		s := connect(t, srv)
+		msg, err := s.Receive()
+		require.NoError(t, err)
+		assert.Equal(t, want, msg.ServerContent.ModelTurn.Parts[0].Text)
+	}
+	assert.Len(t, srv.Setups(), 3)
+}
+
+func TestGenerateContent(t *testing.T) {
+	srv := NewServer(Script{Summary: "they talked about orders"})
+	defer srv.Close()
+
+	text, err := live.GenerateText(context.Background(), live.ClientConfig{BaseURL: srv.URL}, live.DefaultTextModel, "", "summarize")
+	require.NoError(t, err)
+	assert.Equal(t, "they talked about orders", text)
+}
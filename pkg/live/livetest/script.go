@@ -0,0 +1,266 @@
+package livetest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live"
+	"google.golang.org/genai"
+)
+
+type triggerKind int
+
+const (
+	triggerNow triggerKind = iota
+	triggerInputAudio
+	triggerClientText
+	triggerToolResponse
+	triggerDelay
+)
+
+// Trigger 一步的触发条件，零值表示上一步触发后立即执行
+type Trigger struct {
+	kind     triggerKind
+	duration time.Duration
+}
+
+// InputAudio 自上一步触发起累计收到 d 的输入音频后触发
+func InputAudio(d time.Duration) Trigger {
+	return Trigger{kind: triggerInputAudio, duration: d}
+}
+
+// ClientText 收到客户端的文本轮次（clientContent）后触发
+func ClientText() Trigger {
+	return Trigger{kind: triggerClientText}
+}
+
+// ToolResponse 收到函数调用结果后触发
+func ToolResponse() Trigger {
+	return Trigger{kind: triggerToolResponse}
+}
+
+// Delay 上一步触发 d 之后触发
+func Delay(d time.Duration) Trigger {
+	return Trigger{kind: triggerDelay, duration: d}
+}
+
+// wait 消费客户端输入直到条件满足，连接断开时返回 false
+func (t Trigger) wait(ctx context.Context, inputs <-chan clientInput) bool {
+	var timeout <-chan time.Time
+	switch t.kind {
+	case triggerNow:
+		return ctx.Err() == nil
+	case triggerDelay:
+		timer := time.NewTimer(t.duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var audioReceived time.Duration
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timeout:
+			return true
+		case in, ok := <-inputs:
+			if !ok {
+				return false
+			}
+			switch t.kind {
+			case triggerInputAudio:
+				audioReceived += in.audio
+				if audioReceived >= t.duration {
+					return true
+				}
+			case triggerClientText:
+				if in.text {
+					return true
+				}
+			case triggerToolResponse:
+				if in.toolResponse {
+					return true
+				}
+			}
+		}
+	}
+}
+
+// Action 脚本中的一个动作
+type Action struct {
+	name string
+	run  func(ctx context.Context, c *conn) error
+}
+
+// Send 发送一条任意的服务端消息
+func Send(msg *live.ServerMessage) Action {
+	return Action{name: "send", run: func(ctx context.Context, c *conn) error {
+		return c.send(msg)
+	}}
+}
+
+func content(sc *live.ServerContent) Action {
+	return Send(&live.ServerMessage{ServerContent: sc})
+}
+
+// Audio 以模型轮次发送 24kHz 单声道 S16LE 音频，按 Script.ChunkDuration 分块
+func Audio(pcm []byte) Action {
+	return Action{name: "audio", run: func(ctx context.Context, c *conn) error {
+		return c.sendAudio(ctx, pcm)
+	}}
+}
+
+// WAV 以模型轮次发送 WAV 文件中的音频，文件需为 24kHz 单声道 16 位 PCM
+func WAV(path string) Action {
+	return Action{name: "wav " + path, run: func(ctx context.Context, c *conn) error {
+		format, pcm, err := audio.ReadWav(path)
+		if err != nil {
+			return err
+		}
+		if format.SampleRate != OutputSampleRate || format.Channels != 1 {
+			return fmt.Errorf("%s: want %d Hz mono, got %d Hz %d channels", path, OutputSampleRate, format.SampleRate, format.Channels)
+		}
+		return c.sendAudio(ctx, pcm)
+	}}
+}
+
+// Tone 以模型轮次发送一段正弦波，用于不需要真实语音的测试
+func Tone(freq float64, d time.Duration) Action {
+	return Audio(TonePCM(freq, d, OutputSampleRate))
+}
+
+// TonePCM 生成单声道 S16LE 正弦波，幅度为满量程的一半
+func TonePCM(freq float64, d time.Duration, sampleRate int) []byte {
+	n := int(d.Seconds() * float64(sampleRate))
+	pcm := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		v := int16(math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)) * math.MaxInt16 / 2)
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(v))
+	}
+	return pcm
+}
+
+// Text 以模型轮次发送文本
+func Text(text string) Action {
+	return content(&live.ServerContent{
+		ModelTurn: &genai.Content{Role: "model", Parts: []*genai.Part{{Text: text}}},
+	})
+}
+
+// InputTranscript 发送用户语音的转写，text 为增量
+func InputTranscript(text string, finished bool) Action {
+	return content(&live.ServerContent{InputTranscription: &live.Transcription{Text: text, Finished: finished}})
+}
+
+// OutputTranscript 发送模型语音的转写，text 为增量
+func OutputTranscript(text string, finished bool) Action {
+	return content(&live.ServerContent{OutputTranscription: &live.Transcription{Text: text, Finished: finished}})
+}
+
+// Interrupted 通知客户端模型输出被打断
+func Interrupted() Action {
+	return content(&live.ServerContent{Interrupted: true})
+}
+
+// TurnComplete 结束本轮模型输出
+func TurnComplete() Action {
+	return content(&live.ServerContent{TurnComplete: true})
+}
+
+// ToolCall 下发一个函数调用
+func ToolCall(id, name string, args map[string]any) Action {
+	return Send(&live.ServerMessage{ToolCall: &genai.LiveServerToolCall{
+		FunctionCalls: []*genai.FunctionCall{{ID: id, Name: name, Args: args}},
+	}})
+}
+
+// ToolCallCancellation 取消之前下发的函数调用
+func ToolCallCancellation(ids ...string) Action {
+	return Send(&live.ServerMessage{ToolCallCancellation: &live.ToolCallCancellation{IDs: ids}})
+}
+
+// Usage 发送 token 用量
+func Usage(prompt, response int) Action {
+	return Send(&live.ServerMessage{UsageMetadata: &live.UsageMetadata{
+		PromptTokenCount:   prompt,
+		ResponseTokenCount: response,
+		TotalTokenCount:    prompt + response,
+	}})
+}
+
+// ResumptionHandle 下发新的会话恢复句柄
+func ResumptionHandle(handle string) Action {
+	return Send(&live.ServerMessage{SessionResumptionUpdate: &live.SessionResumptionUpdate{NewHandle: handle, Resumable: true}})
+}
+
+// GoAway 通知客户端连接将在 timeLeft 后断开
+func GoAway(timeLeft time.Duration) Action {
+	return Send(&live.ServerMessage{GoAway: &live.GoAway{TimeLeft: timeLeft.String()}})
+}
+
+// Pause 等待 d，期间可以被下一步打断
+func Pause(d time.Duration) Action {
+	return Action{name: "pause", run: func(ctx context.Context, c *conn) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+			return nil
+		}
+	}}
+}
+
+// Disconnect 直接断开连接，模拟网络中断
+func Disconnect() Action {
+	return Action{name: "disconnect", run: func(ctx context.Context, c *conn) error {
+		return c.ws.Close()
+	}}
+}
+
+// sendAudio 分块发送模型音频，Realtime 时按音频时长控制发送速度
+func (c *conn) sendAudio(ctx context.Context, pcm []byte) error {
+	chunkBytes := int(c.script.ChunkDuration.Seconds()*OutputSampleRate) * 2
+	start := time.Now()
+	var sent time.Duration
+	for len(pcm) > 0 {
+		if c.script.Realtime {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(start.Add(sent))):
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		n := min(chunkBytes, len(pcm))
+		err := c.send(&live.ServerMessage{ServerContent: &live.ServerContent{
+			ModelTurn: &genai.Content{Role: "model", Parts: []*genai.Part{{
+				InlineData: &genai.Blob{Data: pcm[:n], MIMEType: fmt.Sprintf("audio/pcm;rate=%d", OutputSampleRate)},
+			}}},
+		}})
+		if err != nil {
+			return err
+		}
+		sent += time.Duration(n/2) * time.Second / OutputSampleRate
+		pcm = pcm[n:]
+	}
+	return nil
+}
+
+func parsePositive(s string) (int, error) {
+	if i := strings.IndexAny(s, ";,"); i >= 0 {
+		s = s[:i]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ServerConfig 一个 MCP 服务端的配置，Command 与 URL 二选一。
+// Env 和 Headers 的值支持 $VAR 形式引用环境变量，避免将密钥写入配置文件
+type ServerConfig struct {
+	Name string `json:"-"`
+
+	// stdio
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+
+	// Streamable HTTP
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// LoadConfig 读取常见的 mcpServers 格式配置文件，按名称排序返回
+//
+//	{
+//	  "mcpServers": {
+//	    "files": {"command": "npx", "args": ["-y", "@modelcontextprotocol/server-filesystem", "/tmp"]},
+//	    "orders": {"url": "http://localhost:3000/mcp", "headers": {"Authorization": "Bearer $ORDERS_TOKEN"}}
+//	  }
+//	}
+func LoadConfig(path string) ([]ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		MCPServers map[string]ServerConfig `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse mcp config %s: %w", path, err)
+	}
+
+	servers := make([]ServerConfig, 0, len(file.MCPServers))
+	for name, cfg := range file.MCPServers {
+		if cfg.Command == "" && cfg.URL == "" {
+			return nil, fmt.Errorf("mcp server %s: command or url is required", name)
+		}
+		cfg.Name = name
+		servers = append(servers, cfg)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	return servers, nil
+}
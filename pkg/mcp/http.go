@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 会话 ID 头，由服务端在 initialize 响应中下发，之后的请求都需携带
+const sessionIDHeader = "Mcp-Session-Id"
+
+// httpTransport Streamable HTTP 传输：每条消息单独 POST，
+// 响应为 application/json 或包含响应的 text/event-stream
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newHTTPTransport(cfg ServerConfig) *httpTransport {
+	headers := make(map[string]string, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	return &httpTransport{
+		url:     cfg.URL,
+		headers: headers,
+		client:  &http.Client{},
+	}
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(sessionIDHeader, t.sessionID)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+func (t *httpTransport) post(ctx context.Context, data []byte) (*http.Response, error) {
+	req, err := t.newRequest(ctx, http.MethodPost, data)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get(sessionIDHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("mcp http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, id int64, data []byte) (*message, error) {
+	resp, err := t.post(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return t.readStream(ctx, resp.Body, strconv.FormatInt(id, 10))
+	}
+
+	var m message
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode mcp response: %w", err)
+	}
+	return &m, nil
+}
+
+// readStream 从 SSE 流中读取消息，直到收到 ID 匹配的响应
+func (t *httpTransport) readStream(ctx context.Context, r io.Reader, id string) (*message, error) {
+	reader := bufio.NewReader(r)
+	var data strings.Builder
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			// 空行表示一个事件结束
+			var m message
+			if jerr := json.Unmarshal([]byte(data.String()), &m); jerr == nil {
+				if m.isResponse() && string(m.ID) == id {
+					return &m, nil
+				}
+				if !m.isResponse() && len(m.ID) > 0 {
+					t.reply(ctx, &m)
+				}
+			}
+			data.Reset()
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("mcp stream closed before response")
+			}
+			return nil, err
+		}
+	}
+}
+
+// reply 回复服务端在流中发来的请求
+func (t *httpTransport) reply(ctx context.Context, m *message) {
+	data, _ := json.Marshal(replyTo(m))
+	if err := t.notify(ctx, data); err != nil {
+		log.Printf("mcp http: reply to %s error: %v", m.Method, err)
+	}
+}
+
+func (t *httpTransport) notify(ctx context.Context, data []byte) error {
+	resp, err := t.post(ctx, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close 结束服务端会话，服务端不支持时（405）忽略
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	req, err := t.newRequest(context.Background(), http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const jsonRPCVersion = "2.0"
+
+// JSON-RPC 错误码
+const (
+	codeMethodNotFound = -32601
+)
+
+// request 客户端发出的请求或通知，通知没有 ID
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// message 收到的任意 JSON-RPC 消息：响应、服务端请求或通知
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// isResponse 是否为对客户端请求的响应
+func (m *message) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// response 回复服务端发来的请求
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError 服务端返回的 JSON-RPC 错误
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// replyTo 生成对服务端请求的回复，目前只支持 ping
+func replyTo(m *message) response {
+	resp := response{JSONRPC: jsonRPCVersion, ID: m.ID}
+	if m.Method == "ping" {
+		resp.Result = struct{}{}
+	} else {
+		resp.Error = &RPCError{Code: codeMethodNotFound, Message: "method not found: " + m.Method}
+	}
+	return resp
+}
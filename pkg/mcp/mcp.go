@@ -0,0 +1,206 @@
+// Package mcp 实现 Model Context Protocol 客户端，支持 stdio 和 Streamable HTTP 传输，
+// 用于将 MCP 服务端的工具提供给模型调用
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ProtocolVersion 客户端使用的协议版本
+const ProtocolVersion = "2025-03-26"
+
+// 请求被取消后发送 notifications/cancelled 的超时
+const cancelNotifyTimeout = time.Second
+
+// Implementation 客户端或服务端的名称和版本
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ClientInfo initialize 时上报的客户端信息
+var ClientInfo = Implementation{Name: "gemini-realtime-webrtc", Version: "0.1.0"}
+
+// Tool MCP 服务端声明的工具
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"` // JSON Schema
+}
+
+// Content 工具结果中的一段内容
+type Content struct {
+	Type     string `json:"type"` // text、image、audio、resource 等
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"` // base64，image/audio 时有效
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// CallToolResult tools/call 的结果
+type CallToolResult struct {
+	Content           []Content      `json:"content"`
+	StructuredContent map[string]any `json:"structuredContent,omitempty"`
+	IsError           bool           `json:"isError,omitempty"`
+}
+
+// Text 合并结果中的文本内容，非文本内容以占位符表示
+func (r *CallToolResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		} else {
+			parts = append(parts, fmt.Sprintf("[%s %s]", c.Type, c.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+type initializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	ServerInfo      Implementation `json:"serverInfo"`
+}
+
+type listToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// transport 发送 JSON-RPC 消息的传输层
+type transport interface {
+	// roundTrip 发送请求并等待 ID 对应的响应
+	roundTrip(ctx context.Context, id int64, data []byte) (*message, error)
+	notify(ctx context.Context, data []byte) error
+	close() error
+}
+
+// Client 与一个 MCP 服务端的连接，可并发使用
+type Client struct {
+	name       string
+	t          transport
+	nextID     atomic.Int64
+	serverInfo Implementation
+}
+
+// Connect 连接 MCP 服务端并完成 initialize 握手。
+// 配置了 URL 时使用 Streamable HTTP，否则启动 Command 子进程使用 stdio
+func Connect(ctx context.Context, cfg ServerConfig) (*Client, error) {
+	var t transport
+	switch {
+	case cfg.URL != "":
+		t = newHTTPTransport(cfg)
+	case cfg.Command != "":
+		st, err := newStdioTransport(cfg)
+		if err != nil {
+			return nil, err
+		}
+		t = st
+	default:
+		return nil, fmt.Errorf("mcp server %s: command or url is required", cfg.Name)
+	}
+
+	c := &Client{name: cfg.Name, t: t}
+
+	var result initializeResult
+	err := c.call(ctx, "initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      ClientInfo,
+	}, &result)
+	if err == nil {
+		err = c.notify(ctx, "notifications/initialized", nil)
+	}
+	if err != nil {
+		t.close()
+		return nil, fmt.Errorf("initialize mcp server %s: %w", cfg.Name, err)
+	}
+	c.serverInfo = result.ServerInfo
+	return c, nil
+}
+
+// Name 配置中的服务端名称
+func (c *Client) Name() string {
+	return c.name
+}
+
+// ServerInfo 服务端在 initialize 时上报的信息
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+// ListTools 列出服务端的全部工具
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var params map[string]any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool 调用工具。工具执行失败时返回 IsError 的结果而非 error
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close 关闭连接，stdio 服务端进程随之退出
+func (c *Client) Close() error {
+	return c.t.close()
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	id := c.nextID.Add(1)
+	data, err := json.Marshal(request{JSONRPC: jsonRPCVersion, ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	m, err := c.t.roundTrip(ctx, id, data)
+	if err != nil {
+		if ctx.Err() != nil {
+			// 通知服务端放弃该请求
+			cctx, cancel := context.WithTimeout(context.Background(), cancelNotifyTimeout)
+			c.notify(cctx, "notifications/cancelled", map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+			cancel()
+		}
+		return err
+	}
+	if m.Error != nil {
+		return m.Error
+	}
+	if result == nil || len(m.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.Result, result)
+}
+
+func (c *Client) notify(ctx context.Context, method string, params any) error {
+	data, err := json.Marshal(request{JSONRPC: jsonRPCVersion, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	return c.t.notify(ctx, data)
+}
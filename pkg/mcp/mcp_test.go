@@ -0,0 +1,334 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// 设置该环境变量时测试二进制作为 stdio 的 stub MCP 服务端运行
+const stubServerEnv = "MCP_STUB_SERVER"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(stubServerEnv) {
+	case "1":
+		runStubServer()
+		os.Exit(0)
+	case "silent":
+		// 启动后读取请求但从不回复
+		io.Copy(io.Discard, os.Stdin)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// stubHandle 处理一条请求，返回 result 或 error
+func stubHandle(ctx context.Context, method string, params json.RawMessage) (any, *RPCError) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "stub", "version": "1.0"},
+		}, nil
+	case "tools/list":
+		var p struct{ Cursor string }
+		json.Unmarshal(params, &p)
+		if p.Cursor == "" {
+			return map[string]any{
+				"tools": []map[string]any{
+					{"name": "echo", "description": "Echo text", "inputSchema": map[string]any{
+						"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}, "required": []string{"text"},
+					}},
+					{"name": "add", "inputSchema": map[string]any{
+						"type": "object", "properties": map[string]any{"a": map[string]any{"type": "number"}, "b": map[string]any{"type": "number"}},
+					}},
+				},
+				"nextCursor": "page2",
+			}, nil
+		}
+		return map[string]any{
+			"tools": []map[string]any{
+				{"name": "fail", "inputSchema": map[string]any{"type": "object"}},
+				{"name": "slow", "inputSchema": map[string]any{"type": "object"}},
+				{"name": "bad-schema", "inputSchema": map[string]any{"type": "tuple"}},
+			},
+		}, nil
+	case "tools/call":
+		var p struct {
+			Name      string
+			Arguments map[string]any
+		}
+		json.Unmarshal(params, &p)
+		switch p.Name {
+		case "echo":
+			return map[string]any{"content": []map[string]any{{"type": "text", "text": p.Arguments["text"]}}}, nil
+		case "add":
+			a, _ := p.Arguments["a"].(float64)
+			b, _ := p.Arguments["b"].(float64)
+			return map[string]any{
+				"content":           []map[string]any{{"type": "text", "text": fmt.Sprint(a + b)}},
+				"structuredContent": map[string]any{"sum": a + b},
+			}, nil
+		case "fail":
+			return map[string]any{"content": []map[string]any{{"type": "text", "text": "order not found"}}, "isError": true}, nil
+		case "slow":
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			return map[string]any{"content": []map[string]any{}}, nil
+		}
+		return nil, &RPCError{Code: -32602, Message: "unknown tool " + p.Name}
+	}
+	return nil, &RPCError{Code: codeMethodNotFound, Message: "method not found"}
+}
+
+// runStubServer 以 stdio 方式服务，每个请求在单独的协程中处理
+func runStubServer() {
+	var mu sync.Mutex
+	out := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var m message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil || len(m.ID) == 0 {
+			continue
+		}
+		go func(m message) {
+			result, rpcErr := stubHandle(context.Background(), m.Method, m.Params)
+			mu.Lock()
+			defer mu.Unlock()
+			out.Encode(response{JSONRPC: jsonRPCVersion, ID: m.ID, Result: result, Error: rpcErr})
+		}(m)
+	}
+}
+
+func stdioConfig() ServerConfig {
+	return ServerConfig{
+		Name:    "stub",
+		Command: os.Args[0],
+		Env:     map[string]string{stubServerEnv: "1"},
+	}
+}
+
+// newHTTPStub 启动 Streamable HTTP 的 stub 服务端，tools/call 以 SSE 返回
+func newHTTPStub(t *testing.T) (*httptest.Server, *sync.Map) {
+	var seen sync.Map // 收到的请求方法及会话删除
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		if r.Method == http.MethodDelete {
+			seen.Store("DELETE "+r.Header.Get(sessionIDHeader), true)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		var m message
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		seen.Store(m.Method, true)
+
+		if m.Method == "initialize" {
+			w.Header().Set(sessionIDHeader, "session-1")
+		} else if r.Header.Get(sessionIDHeader) != "session-1" {
+			http.Error(w, "missing session", http.StatusBadRequest)
+			return
+		}
+		if len(m.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		result, rpcErr := stubHandle(r.Context(), m.Method, m.Params)
+		data, _ := json.Marshal(response{JSONRPC: jsonRPCVersion, ID: m.ID, Result: result, Error: rpcErr})
+		if m.Method != "tools/call" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{}}\n\n")
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &seen
+}
+
+func testClient(t *testing.T, c *Client) {
+	ctx := context.Background()
+
+	list, err := c.ListTools(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range list {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"echo", "add", "fail", "slow", "bad-schema"}, names)
+
+	res, err := c.CallTool(ctx, "echo", map[string]any{"text": "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", res.Text())
+	assert.False(t, res.IsError)
+
+	res, err = c.CallTool(ctx, "fail", nil)
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+
+	_, err = c.CallTool(ctx, "missing", nil)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32602, rpcErr.Code)
+
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = c.CallTool(tctx, "slow", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 超时后连接仍然可用
+	res, err = c.CallTool(ctx, "echo", map[string]any{"text": "again"})
+	require.NoError(t, err)
+	assert.Equal(t, "again", res.Text())
+}
+
+func TestStdioClient(t *testing.T) {
+	c, err := Connect(context.Background(), stdioConfig())
+	require.NoError(t, err)
+	assert.Equal(t, Implementation{Name: "stub", Version: "1.0"}, c.ServerInfo())
+
+	testClient(t, c)
+
+	// 并发调用按 ID 匹配响应
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := c.CallTool(context.Background(), "echo", map[string]any{"text": fmt.Sprint(i)})
+			if assert.NoError(t, err) {
+				assert.Equal(t, fmt.Sprint(i), res.Text())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.NoError(t, c.Close())
+	_, err = c.ListTools(context.Background())
+	assert.Error(t, err)
+}
+
+func TestHTTPClient(t *testing.T) {
+	srv, seen := newHTTPStub(t)
+	t.Setenv("STUB_TOKEN", "secret")
+
+	c, err := Connect(context.Background(), ServerConfig{
+		Name:    "stub",
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer $STUB_TOKEN"},
+	})
+	require.NoError(t, err)
+
+	testClient(t, c)
+
+	require.NoError(t, c.Close())
+	_, ok := seen.Load("notifications/initialized")
+	assert.True(t, ok)
+	_, ok = seen.Load("notifications/cancelled")
+	assert.True(t, ok)
+	_, ok = seen.Load("DELETE session-1")
+	assert.True(t, ok)
+}
+
+func TestRegisterTools(t *testing.T) {
+	r := tools.NewRegistry()
+	clients := ConnectAll(context.Background(), r, []ServerConfig{
+		stdioConfig(),
+		{Name: "missing", Command: filepath.Join(t.TempDir(), "no-such-server")},
+	})
+	require.Len(t, clients, 1)
+	defer clients[0].Close()
+
+	// bad-schema 被跳过
+	var names []string
+	for _, d := range r.Declarations() {
+		names = append(names, d.Name)
+	}
+	assert.Equal(t, []string{"stub_echo", "stub_add", "stub_fail", "stub_slow"}, names)
+	decls := r.Declarations()
+	assert.Equal(t, []string{"text"}, decls[0].Parameters.Required)
+	assert.Nil(t, decls[2].Parameters)
+
+	ctx := context.Background()
+	resp, err := r.Call(ctx, &genai.FunctionCall{ID: "1", Name: "stub_echo", Args: map[string]any{"text": "hi"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"result": "hi"}, resp.Response)
+
+	resp, err = r.Call(ctx, &genai.FunctionCall{ID: "2", Name: "stub_add", Args: map[string]any{"a": 1.0, "b": 2.5}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"sum": 3.5}, resp.Response)
+
+	resp, err = r.Call(ctx, &genai.FunctionCall{ID: "3", Name: "stub_fail"})
+	assert.Error(t, err)
+	assert.Equal(t, map[string]any{"error": "order not found"}, resp.Response)
+}
+
+func TestConnectAllTimeout(t *testing.T) {
+	timeout := ConnectTimeout
+	ConnectTimeout = 200 * time.Millisecond
+	defer func() { ConnectTimeout = timeout }()
+
+	silent := stdioConfig()
+	silent.Name = "silent"
+	silent.Env = map[string]string{stubServerEnv: "silent"}
+
+	r := tools.NewRegistry()
+	start := time.Now()
+	clients := ConnectAll(context.Background(), r, []ServerConfig{silent, stdioConfig()})
+	// 不回复的服务端超时后跳过，不影响后面的服务端
+	require.Len(t, clients, 1)
+	defer clients[0].Close()
+	assert.Equal(t, "stub", clients[0].Name())
+	assert.Less(t, time.Since(start), stdioShutdownTimeout)
+	assert.Len(t, r.Declarations(), 4)
+}
+
+func TestToolName(t *testing.T) {
+	assert.Equal(t, "files_read_file", ToolName("files", "read_file"))
+	assert.Equal(t, "my_server_get_user", ToolName("my server", "get/user"))
+	assert.Equal(t, "_1x_y", ToolName("1x", "y"))
+	assert.Len(t, ToolName("s", string(make([]byte, 100))), 64)
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"mcpServers": {
+			"orders": {"url": "http://localhost:3000/mcp", "headers": {"Authorization": "Bearer $TOKEN"}},
+			"files": {"command": "npx", "args": ["server-filesystem", "/tmp"], "env": {"DEBUG": "1"}}
+		}
+	}`), 0o644))
+
+	servers, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, servers, 2)
+	assert.Equal(t, ServerConfig{Name: "files", Command: "npx", Args: []string{"server-filesystem", "/tmp"}, Env: map[string]string{"DEBUG": "1"}}, servers[0])
+	assert.Equal(t, "orders", servers[1].Name)
+	assert.Equal(t, "http://localhost:3000/mcp", servers[1].URL)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"mcpServers": {"empty": {}}}`), 0o644))
+	_, err = LoadConfig(path)
+	assert.Error(t, err)
+}
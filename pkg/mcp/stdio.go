@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 单条消息的最大长度
+const maxStdioMessageSize = 16 << 20
+
+// 关闭 stdin 后等待子进程退出的时间，超时则强制结束
+const stdioShutdownTimeout = 2 * time.Second
+
+// stdioTransport 启动子进程，通过 stdin/stdout 交换以换行分隔的 JSON-RPC 消息
+type stdioTransport struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *message
+	err     error // 读协程退出的原因
+
+	done chan struct{}
+}
+
+func newStdioTransport(cfg ServerConfig) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", cfg.Command, err)
+	}
+
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+func (t *stdioTransport) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessageSize)
+
+	for scanner.Scan() {
+		var m message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			log.Printf("mcp stdio: invalid message: %v", err)
+			continue
+		}
+
+		switch {
+		case m.isResponse():
+			t.mu.Lock()
+			ch, ok := t.pending[string(m.ID)]
+			delete(t.pending, string(m.ID))
+			t.mu.Unlock()
+			if ok {
+				ch <- &m
+			}
+		case len(m.ID) > 0:
+			// 服务端发来的请求
+			data, _ := json.Marshal(replyTo(&m))
+			if err := t.write(data); err != nil {
+				log.Printf("mcp stdio: reply to %s error: %v", m.Method, err)
+			}
+		}
+		// 通知（日志、进度等）直接忽略
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.mu.Lock()
+	t.err = fmt.Errorf("mcp server exited: %w", err)
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) write(data []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err := t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) roundTrip(ctx context.Context, id int64, data []byte) (*message, error) {
+	key := strconv.FormatInt(id, 10)
+	ch := make(chan *message, 1)
+
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[key] = ch
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	if err := t.writeContext(ctx, data); err != nil {
+		return nil, err
+	}
+
+	select {
+	case m := <-ch:
+		return m, nil
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return nil, t.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, data []byte) error {
+	return t.writeContext(ctx, data)
+}
+
+// writeContext 写入时跟随 ctx 取消。服务端不读 stdin 时管道写满会一直阻塞，
+// 此时关闭 stdin 使写入返回，该连接随之不可用
+func (t *stdioTransport) writeContext(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { t.stdin.Close() })
+	err := t.write(data)
+	if !stop() && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// close 关闭 stdin 通知子进程退出，超时后强制结束
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+
+	exited := make(chan error, 1)
+	go func() { exited <- t.cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil
+		}
+		return err
+	case <-time.After(stdioShutdownTimeout):
+		t.cmd.Process.Kill()
+		<-exited
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
+	"google.golang.org/genai"
+)
+
+// 函数名中不允许的字符
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// ToolName 返回注册到模型时使用的函数名 <server>_<tool>，避免不同服务端的同名工具冲突
+func ToolName(server, tool string) string {
+	name := invalidNameChars.ReplaceAllString(server+"_"+tool, "_")
+	if name[0] >= '0' && name[0] <= '9' || name[0] == '.' || name[0] == '-' {
+		name = "_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// RegisterTools 列出服务端的工具并注册到 registry，调用时转发给服务端。
+// 参数声明无法转换的工具会被跳过，返回注册成功的数量
+func RegisterTools(ctx context.Context, r *tools.Registry, c *Client) (int, error) {
+	list, err := c.ListTools(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list tools of mcp server %s: %w", c.name, err)
+	}
+
+	n := 0
+	for _, t := range list {
+		var params *genai.Schema
+		if len(t.InputSchema) > 0 {
+			params, err = tools.ParseSchema(t.InputSchema)
+			if err != nil {
+				log.Printf("mcp server %s: skip tool %s: %v", c.name, t.Name, err)
+				continue
+			}
+			// 没有参数的对象不声明 parameters
+			if params.Type == genai.TypeObject && len(params.Properties) == 0 {
+				params = nil
+			}
+		}
+
+		if err := r.Register(tools.Tool{
+			Name:        ToolName(c.name, t.Name),
+			Description: t.Description,
+			Parameters:  params,
+			Handler:     c.handler(t.Name),
+		}); err != nil {
+			log.Printf("mcp server %s: skip tool %s: %v", c.name, t.Name, err)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+// handler 将函数调用转发为 tools/call，结构化结果原样返回，否则返回合并后的文本
+func (c *Client) handler(name string) tools.Handler {
+	return func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		res, err := c.CallTool(ctx, name, args)
+		if err != nil {
+			return nil, err
+		}
+		if res.IsError {
+			return nil, errors.New(res.Text())
+		}
+		if res.StructuredContent != nil {
+			return res.StructuredContent, nil
+		}
+		return map[string]any{"result": res.Text()}, nil
+	}
+}
+
+// ConnectTimeout ConnectAll 中每个服务端完成握手并列出工具的时限
+var ConnectTimeout = 10 * time.Second
+
+// ConnectAll 连接所有服务端并注册其工具，连接失败或超时的服务端记录日志后跳过
+func ConnectAll(ctx context.Context, r *tools.Registry, servers []ServerConfig) []*Client {
+	var clients []*Client
+	for _, cfg := range servers {
+		c, n, err := connectServer(ctx, r, cfg)
+		if err != nil {
+			log.Printf("connect mcp server %s error: %v", cfg.Name, err)
+			continue
+		}
+		log.Printf("mcp server %s (%s %s): %d tools", cfg.Name, c.serverInfo.Name, c.serverInfo.Version, n)
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// connectServer 在 ConnectTimeout 内连接一个服务端并注册其工具
+func connectServer(ctx context.Context, r *tools.Registry, cfg ServerConfig) (*Client, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, ConnectTimeout)
+	defer cancel()
+
+	c, err := Connect(ctx, cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := RegisterTools(ctx, r, c)
+	if err != nil {
+		c.Close()
+		return nil, 0, err
+	}
+	return c, n, nil
+}
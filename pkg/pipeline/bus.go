@@ -17,6 +17,14 @@ const (
 	EventPartialResult EventType = "PartialResult"
 	EventFinalResult   EventType = "FinalResult"
 	EventBargeIn       EventType = "BargeIn"
+	// EventEncoderAdapted 下行编码参数根据网络状况调整，Payload 为 audio.EncoderAdaptation
+	EventEncoderAdapted EventType = "EncoderAdapted"
+	// EventDTMF 收到用户按键，Payload 为 dtmf.Event
+	EventDTMF EventType = "DTMF"
+	// EventToolCall 函数调用的状态变化，Payload 为 ToolActivity
+	EventToolCall EventType = "ToolCall"
+	// EventSessionState 模型会话断开、重连或恢复，Payload 为 SessionState
+	EventSessionState EventType = "SessionState"
 	// 可继续扩展更多事件类型...
 )
 
@@ -49,7 +57,7 @@ type EventBus struct {
 	// key: EventType, value: 订阅该事件类型的通道列表
 	subscribers map[EventType][]chan<- Event
 
-	// 保护 subscribers 的互斥锁
+	// 保护 subscribers、running 和 done 的互斥锁
 	lock sync.RWMutex
 
 	// 是否需要支持异步缓冲队列或后台处理，可以加一个 channel
@@ -57,6 +65,7 @@ type EventBus struct {
 
 	running bool
 	cancel  context.CancelFunc // 新增：存储 context 取消函数
+	done    <-chan struct{}    // 后台协程退出后关闭，Publish 不再等待写入 eventChan
 }
 
 func NewEventBus() *EventBus {
@@ -93,9 +102,16 @@ func (b *EventBus) Unsubscribe(eventType EventType, ch chan<- Event) {
 
 // Publish 直接发布事件。如果需要异步处理，可以向 b.eventChan 写入
 func (b *EventBus) Publish(evt Event) {
-	if b.running {
-		// 若有后台协程在处理，就写入 eventChan
-		b.eventChan <- evt
+	b.lock.RLock()
+	running, done := b.running, b.done
+	b.lock.RUnlock()
+
+	if running {
+		// 若有后台协程在处理，就写入 eventChan；总线已停止时丢弃
+		select {
+		case b.eventChan <- evt:
+		case <-done:
+		}
 	} else {
 		// 若不使用后台协程，则直接分发
 		b.lock.RLock()
@@ -115,6 +131,8 @@ func (b *EventBus) Publish(evt Event) {
 
 // Start 启动后台协程，异步分发事件
 func (b *EventBus) Start(ctx context.Context) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
 	if b.running {
 		return nil
 	}
@@ -122,6 +140,7 @@ func (b *EventBus) Start(ctx context.Context) error {
 	// 创建一个新的 context 和取消函数
 	ctx, cancel := context.WithCancel(ctx) // ignore error
 	b.cancel = cancel
+	b.done = ctx.Done()
 	b.running = true
 
 	go func() {
@@ -156,6 +175,8 @@ func (b *EventBus) Start(ctx context.Context) error {
 
 // Stop 停止后台协程
 func (b *EventBus) Stop() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
 	if !b.running {
 		return
 	}
@@ -12,6 +12,32 @@ import (
 // 	elements []Element
 // }
 
+// SampleFormat audio/x-raw 的样本格式，多声道时样本交错排列，多字节格式均为小端序
+type SampleFormat string
+
+const (
+	SampleFormatS16   SampleFormat = "s16le"
+	SampleFormatS32   SampleFormat = "s32le"
+	SampleFormatF32   SampleFormat = "f32le"
+	SampleFormatU8    SampleFormat = "u8"
+	SampleFormatMuLaw SampleFormat = "mulaw" // G.711 µ-law
+	SampleFormatALaw  SampleFormat = "alaw"  // G.711 A-law
+)
+
+// BytesPerSample 返回单个样本的字节数，未知格式返回 0
+func (f SampleFormat) BytesPerSample() int {
+	switch f {
+	case SampleFormatS16:
+		return 2
+	case SampleFormatS32, SampleFormatF32:
+		return 4
+	case SampleFormatU8, SampleFormatMuLaw, SampleFormatALaw:
+		return 1
+	default:
+		return 0
+	}
+}
+
 type AudioData struct {
 	Data       []byte
 	SampleRate int
@@ -19,6 +45,23 @@ type AudioData struct {
 	MediaType  string // "audio/x-raw", "audio/x-opus", etc.
 	Codec      string
 	Timestamp  time.Time
+
+	// SampleFormat 仅对 audio/x-raw 有效，为空时表示 S16LE
+	SampleFormat SampleFormat
+
+	// SequenceNumber/RTPTimestamp 仅对来自 RTP 的编码数据有效
+	SequenceNumber uint16
+	RTPTimestamp   uint32
+	// Lost 表示该帧在传输中丢失，Data 为空或为后一个包（可用于 FEC），解码器需做丢包隐藏
+	Lost bool
+}
+
+// Format 返回 audio/x-raw 数据的样本格式，未设置时为 S16LE
+func (a *AudioData) Format() SampleFormat {
+	if a.SampleFormat == "" {
+		return SampleFormatS16
+	}
+	return a.SampleFormat
 }
 
 type VideoData struct {
@@ -33,12 +76,93 @@ type VideoData struct {
 	Timestamp      time.Time
 }
 
+// TextEventType MsgTypeText 消息的类型，取值即发送给客户端的 JSON 中的 type
+type TextEventType string
+
+const (
+	TextEventModelText        TextEventType = "text"              // 模型输出的文本
+	TextEventInputTranscript  TextEventType = "input_transcript"  // 用户语音的转写
+	TextEventOutputTranscript TextEventType = "output_transcript" // 模型语音的转写
+	TextEventTurnComplete     TextEventType = "turn_complete"     // 模型本轮输出结束
+	TextEventInterrupted      TextEventType = "interrupted"       // 模型输出被用户打断
+	TextEventUsage            TextEventType = "usage"             // token 用量
+	TextEventToolCall         TextEventType = "tool_call"         // 函数调用的状态变化
+	TextEventSessionState     TextEventType = "session_state"     // 模型会话断开、重连或恢复
+	TextEventSpeechStarted    TextEventType = "speech_started"    // 级联模式检测到用户开始说话
+)
+
+// Usage token 用量
+type Usage struct {
+	PromptTokens   int `json:"prompt_tokens"`
+	ResponseTokens int `json:"response_tokens"`
+	TotalTokens    int `json:"total_tokens"`
+}
+
+// ToolStatus 函数调用的状态
+type ToolStatus string
+
+const (
+	ToolStatusStarted   ToolStatus = "started"
+	ToolStatusCompleted ToolStatus = "completed"
+	ToolStatusFailed    ToolStatus = "failed"
+	ToolStatusCancelled ToolStatus = "cancelled"
+)
+
+// ToolActivity 一次函数调用的状态，started 时带 Args，completed 时带 Result，failed 时带 Error
+type ToolActivity struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Status     ToolStatus     `json:"status"`
+	Args       map[string]any `json:"args,omitempty"`
+	Result     map[string]any `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	DurationMS int64          `json:"duration_ms,omitempty"`
+}
+
+// SessionStatus 模型会话的连接状态
+type SessionStatus string
+
+const (
+	SessionConnected    SessionStatus = "connected"    // 已连接（含重连成功）
+	SessionReconnecting SessionStatus = "reconnecting" // 连接中断，正在重连，上行音频按策略缓存或丢弃
+	SessionDisconnected SessionStatus = "disconnected" // 重连失败，放弃重连
+	SessionRolledOver   SessionStatus = "rolled_over"  // 接近会话上限，已切换到以摘要开头的新会话
+)
+
+// SessionState 模型会话状态变化，通话本身不受影响
+type SessionState struct {
+	Status  SessionStatus `json:"status"`
+	Reason  string        `json:"reason,omitempty"`
+	Attempt int           `json:"attempt,omitempty"` // 重连的第几次尝试
+	Resumed bool          `json:"resumed,omitempty"` // 重连后是否恢复了之前的上下文
+}
+
+// 说话方
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
+// TextData 模型文本、转写以及会话事件
+type TextData struct {
+	Type   TextEventType
+	Text   string        // 转写为本轮截至目前的完整文本，而非增量
+	Final  bool          // 仅对转写有效，表示该段转写不会再被修正
+	Role   string        // 说话方，RoleUser 或 RoleModel
+	TurnID string        // 同一轮对话（用户输入及模型回复）共享的 ID
+	Usage  *Usage        // 仅对 TextEventUsage 有效
+	Tool   *ToolActivity // 仅对 TextEventToolCall 有效
+	State  *SessionState // 仅对 TextEventSessionState 有效
+}
+
 type PipelineMessageType int
 
 const (
 	MsgTypeAudio PipelineMessageType = iota
 	MsgTypeVideo
 	MsgTypeText
+	// MsgTypeEOS 流结束：源元素没有更多数据，之后不再输出消息
+	MsgTypeEOS
 )
 
 type PipelineMessage struct {
@@ -52,6 +176,9 @@ type PipelineMessage struct {
 	// AudioData 音频数据块
 	AudioData *AudioData
 
+	// TextData 文本和会话事件，MsgTypeText 时有效
+	TextData *TextData
+
 	// Metadata 元数据
 	Metadata interface{}
 }
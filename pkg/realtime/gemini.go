@@ -0,0 +1,254 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live"
+	"google.golang.org/genai"
+)
+
+// GeminiConfig Gemini Live 的连接参数
+type GeminiConfig struct {
+	Client live.ClientConfig
+	Model  string // 为空时使用 live.DefaultModel
+	// ContextCompression 开启上下文压缩，服务端不支持时自动去掉后重试
+	ContextCompression bool
+}
+
+// Gemini 基于 Live API 的实时模型，输入 16kHz、输出 24kHz
+type Gemini struct {
+	cfg GeminiConfig
+	// 服务端不接受上下文压缩配置，之后的会话不再开启
+	compressionUnsupported atomic.Bool
+}
+
+func NewGemini(cfg GeminiConfig) *Gemini {
+	if cfg.Model == "" {
+		cfg.Model = live.DefaultModel
+	}
+	return &Gemini{cfg: cfg}
+}
+
+func (g *Gemini) Name() string          { return "gemini" }
+func (g *Gemini) InputSampleRate() int  { return 16000 }
+func (g *Gemini) OutputSampleRate() int { return 24000 }
+
+// Connect 建立 Live 会话，始终开启会话恢复
+func (g *Gemini) Connect(ctx context.Context, opts ConnectOptions) (Session, error) {
+	model := opts.Model
+	if model == "" {
+		model = g.cfg.Model
+	}
+	// Live API 每个会话只支持一种输出模态
+	modality := "AUDIO"
+	if !hasModality(opts.Modalities, ModalityAudio) {
+		modality = "TEXT"
+	}
+	setup := &live.Setup{
+		Model: model,
+		GenerationConfig: &live.GenerationConfig{
+			ResponseModalities: []string{modality},
+			Temperature:        opts.Temperature,
+		},
+		SessionResumption: &live.SessionResumptionConfig{Handle: opts.ResumeHandle},
+	}
+	if opts.Voice != "" || opts.Language != "" {
+		setup.GenerationConfig.SpeechConfig = &live.SpeechConfig{LanguageCode: opts.Language}
+		if opts.Voice != "" {
+			setup.GenerationConfig.SpeechConfig.VoiceConfig = &genai.VoiceConfig{
+				PrebuiltVoiceConfig: &genai.PrebuiltVoiceConfig{VoiceName: opts.Voice},
+			}
+		}
+	}
+	if td := opts.TurnDetection; td != nil {
+		setup.RealtimeInputConfig = &live.RealtimeInputConfig{
+			AutomaticActivityDetection: geminiActivityDetection(td),
+		}
+	}
+	if opts.Instructions != "" {
+		setup.SystemInstruction = &genai.Content{Parts: []*genai.Part{{Text: opts.Instructions}}}
+	}
+	if len(opts.Tools) > 0 {
+		setup.Tools = []*genai.Tool{{FunctionDeclarations: opts.Tools}}
+	}
+	if opts.Transcription {
+		setup.InputAudioTranscription = &live.AudioTranscriptionConfig{}
+		if modality == "AUDIO" {
+			setup.OutputAudioTranscription = &live.AudioTranscriptionConfig{}
+		}
+	}
+
+	if g.cfg.ContextCompression && !g.compressionUnsupported.Load() {
+		setup.ContextWindowCompression = &live.ContextWindowCompressionConfig{
+			SlidingWindow: &live.SlidingWindow{},
+		}
+		session, err := live.Connect(ctx, g.cfg.Client, setup)
+		if err == nil {
+			return newGeminiSession(session), nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("connect with context window compression error, retrying without: %v", err)
+		setup.ContextWindowCompression = nil
+		session, err = live.Connect(ctx, g.cfg.Client, setup)
+		if err != nil {
+			return nil, err
+		}
+		g.compressionUnsupported.Store(true)
+		return newGeminiSession(session), nil
+	}
+
+	session, err := live.Connect(ctx, g.cfg.Client, setup)
+	if err != nil {
+		return nil, err
+	}
+	return newGeminiSession(session), nil
+}
+
+// geminiActivityDetection 将 Threshold 映射为开始说话的灵敏度，Live API 没有连续的阈值
+func geminiActivityDetection(td *TurnDetection) *live.AutomaticActivityDetection {
+	aad := &live.AutomaticActivityDetection{
+		PrefixPaddingMs:   int(td.PrefixPadding.Milliseconds()),
+		SilenceDurationMs: int(td.SilenceDuration.Milliseconds()),
+	}
+	switch {
+	case td.Threshold >= 0.5:
+		aad.StartOfSpeechSensitivity = live.StartSensitivityLow
+	case td.Threshold > 0:
+		aad.StartOfSpeechSensitivity = live.StartSensitivityHigh
+	}
+	return aad
+}
+
+type geminiSession struct {
+	session *live.Session
+	// 一条服务端消息可能对应多个事件，仅在接收协程中访问
+	pending []*Event
+}
+
+func newGeminiSession(session *live.Session) *geminiSession {
+	return &geminiSession{session: session}
+}
+
+func (s *geminiSession) SendAudio(pcm []byte) error {
+	return s.session.Send(&genai.LiveClientMessage{
+		RealtimeInput: &genai.LiveClientRealtimeInput{
+			MediaChunks: []*genai.Blob{{Data: pcm, MIMEType: "audio/pcm"}},
+		},
+	})
+}
+
+func (s *geminiSession) SendText(text string) error {
+	return s.session.Send(&genai.LiveClientMessage{
+		ClientContent: &genai.LiveClientContent{
+			Turns: []*genai.Content{
+				{Role: "user", Parts: []*genai.Part{{Text: text}}},
+			},
+			TurnComplete: true,
+		},
+	})
+}
+
+func (s *geminiSession) SendImage(data []byte, mimeType string) error {
+	return s.session.Send(&genai.LiveClientMessage{
+		RealtimeInput: &genai.LiveClientRealtimeInput{
+			MediaChunks: []*genai.Blob{{Data: data, MIMEType: mimeType}},
+		},
+	})
+}
+
+func (s *geminiSession) SendToolResults(results []ToolResult) error {
+	responses := make([]*genai.FunctionResponse, 0, len(results))
+	for _, r := range results {
+		responses = append(responses, &genai.FunctionResponse{ID: r.ID, Name: r.Name, Response: r.Response})
+	}
+	return s.session.Send(&genai.LiveClientMessage{
+		ToolResponse: &genai.LiveClientToolResponse{FunctionResponses: responses},
+	})
+}
+
+func (s *geminiSession) Receive() (*Event, error) {
+	for len(s.pending) == 0 {
+		msg, err := s.session.Receive()
+		if err != nil {
+			return nil, err
+		}
+		s.pending = geminiEvents(msg)
+	}
+	ev := s.pending[0]
+	s.pending = s.pending[1:]
+	return ev, nil
+}
+
+func (s *geminiSession) Info() SessionInfo {
+	setup := s.session.Setup()
+	return SessionInfo{
+		Unbounded: setup.ContextWindowCompression != nil,
+		Resumable: setup.SessionResumption != nil,
+	}
+}
+
+func (s *geminiSession) Close() error {
+	return s.session.Close()
+}
+
+// geminiEvents 将一条服务端消息转换为事件，顺序与消息内字段的处理顺序一致
+func geminiEvents(msg *live.ServerMessage) []*Event {
+	var events []*Event
+
+	if u := msg.SessionResumptionUpdate; u != nil && u.Resumable && u.NewHandle != "" {
+		events = append(events, &Event{Type: EventResumption, ResumeHandle: u.NewHandle})
+	}
+	if msg.GoAway != nil {
+		events = append(events, &Event{Type: EventGoAway, TimeLeft: msg.GoAway.TimeLeftDuration()})
+	}
+	if msg.ToolCall != nil {
+		ev := &Event{Type: EventToolCall}
+		for _, fc := range msg.ToolCall.FunctionCalls {
+			if fc != nil {
+				ev.ToolCalls = append(ev.ToolCalls, ToolCall{ID: fc.ID, Name: fc.Name, Args: fc.Args})
+			}
+		}
+		events = append(events, ev)
+	}
+	if msg.ToolCallCancellation != nil {
+		events = append(events, &Event{Type: EventToolCancel, CancelledCalls: msg.ToolCallCancellation.IDs})
+	}
+	if u := msg.UsageMetadata; u != nil {
+		events = append(events, &Event{Type: EventUsage, Usage: &Usage{
+			Prompt:   u.PromptTokenCount,
+			Response: u.ResponseTokenCount,
+			Total:    u.TotalTokenCount,
+		}})
+	}
+
+	content := msg.ServerContent
+	if content == nil {
+		return events
+	}
+	if tr := content.InputTranscription; tr != nil {
+		events = append(events, &Event{Type: EventInputTranscript, Text: tr.Text, Final: tr.Finished})
+	}
+	if tr := content.OutputTranscription; tr != nil {
+		events = append(events, &Event{Type: EventOutputTranscript, Text: tr.Text, Final: tr.Finished})
+	}
+	if content.ModelTurn != nil {
+		for _, part := range content.ModelTurn.Parts {
+			if part.Text != "" {
+				events = append(events, &Event{Type: EventText, Text: part.Text})
+			}
+			if part.InlineData != nil {
+				events = append(events, &Event{Type: EventAudio, Audio: part.InlineData.Data})
+			}
+		}
+	}
+	if content.Interrupted {
+		events = append(events, &Event{Type: EventInterrupted})
+	} else if content.TurnComplete {
+		events = append(events, &Event{Type: EventTurnComplete})
+	}
+	return events
+}
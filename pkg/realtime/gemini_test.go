@@ -0,0 +1,168 @@
+package realtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// newGeminiServer 启动一个测试用的 Live 服务端，rejectCompression 时拒绝带上下文压缩的 setup
+func newGeminiServer(t *testing.T, rejectCompression bool, handler func(conn *websocket.Conn, setup map[string]any)) (*httptest.Server, *atomic.Int32) {
+	var setups atomic.Int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var msg map[string]map[string]any
+		require.NoError(t, conn.ReadJSON(&msg))
+		setups.Add(1)
+		setup := msg["setup"]
+		if _, ok := setup["contextWindowCompression"]; ok && rejectCompression {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseUnsupportedData, "unknown field"))
+			return
+		}
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"setupComplete":{}}`)))
+		handler(conn, setup)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &setups
+}
+
+func TestGeminiSession(t *testing.T) {
+	received := make(chan map[string]any, 4)
+	srv, _ := newGeminiServer(t, false, func(conn *websocket.Conn, setup map[string]any) {
+		assert.Equal(t, "be helpful", setup["systemInstruction"].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"])
+		assert.Contains(t, setup, "inputAudioTranscription")
+		assert.Contains(t, setup, "sessionResumption")
+		assert.Equal(t, "Puck", setup["generationConfig"].(map[string]any)["speechConfig"].(map[string]any)["voiceConfig"].(map[string]any)["prebuiltVoiceConfig"].(map[string]any)["voiceName"])
+		assert.Len(t, setup["tools"], 1)
+
+		for i := 0; i < 4; i++ {
+			var msg map[string]any
+			require.NoError(t, conn.ReadJSON(&msg))
+			received <- msg
+		}
+
+		for _, m := range []string{
+			`{"sessionResumptionUpdate":{"newHandle":"h1","resumable":true}}`,
+			`{"serverContent":{"inputTranscription":{"text":"hi","finished":true}}}`,
+			`{"serverContent":{"modelTurn":{"parts":[{"text":"hello"},{"inlineData":{"mimeType":"audio/pcm","data":"AQI="}}]}}}`,
+			`{"toolCall":{"functionCalls":[{"id":"c1","name":"get_order","args":{"id":"42"}}]}}`,
+			`{"toolCallCancellation":{"ids":["c1"]}}`,
+			`{"serverContent":{"interrupted":true}}`,
+			`{"serverContent":{"outputTranscription":{"text":"ok"},"turnComplete":true},"usageMetadata":{"totalTokenCount":7}}`,
+			`{"goAway":{"timeLeft":"10s"}}`,
+		} {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(m)))
+		}
+	})
+
+	s, err := NewGemini(GeminiConfig{Client: live.ClientConfig{BaseURL: srv.URL}}).Connect(context.Background(), ConnectOptions{
+		Instructions:  "be helpful",
+		Voice:         "Puck",
+		Transcription: true,
+		Tools:         []*genai.FunctionDeclaration{{Name: "get_order"}},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, SessionInfo{Resumable: true}, s.Info())
+
+	require.NoError(t, s.SendAudio([]byte{1}))
+	require.NoError(t, s.SendText("hello"))
+	require.NoError(t, s.SendImage([]byte{2}, "image/jpeg"))
+	require.NoError(t, s.SendToolResults([]ToolResult{{ID: "c1", Name: "get_order", Response: map[string]any{"ok": true}}}))
+	assert.Contains(t, <-received, "realtimeInput")
+	assert.Contains(t, <-received, "clientContent")
+	assert.Equal(t, "image/jpeg", (<-received)["realtimeInput"].(map[string]any)["mediaChunks"].([]any)[0].(map[string]any)["mimeType"])
+	assert.Contains(t, <-received, "toolResponse")
+
+	events := receiveAll(t, s)
+	var types []EventType
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+	assert.Equal(t, []EventType{
+		EventResumption,
+		EventInputTranscript,
+		EventText,
+		EventAudio,
+		EventToolCall,
+		EventToolCancel,
+		EventInterrupted,
+		EventUsage,
+		EventOutputTranscript,
+		EventTurnComplete,
+		EventGoAway,
+	}, types)
+	assert.Equal(t, "h1", events[0].ResumeHandle)
+	assert.True(t, events[1].Final)
+	assert.Equal(t, []byte{1, 2}, events[3].Audio)
+	assert.Equal(t, []ToolCall{{ID: "c1", Name: "get_order", Args: map[string]any{"id": "42"}}}, events[4].ToolCalls)
+	assert.Equal(t, []string{"c1"}, events[5].CancelledCalls)
+	assert.Equal(t, 7, events[7].Usage.Total)
+	assert.Equal(t, "10s", events[10].TimeLeft.String())
+}
+
+func TestGeminiCompressionFallback(t *testing.T) {
+	srv, setups := newGeminiServer(t, true, func(conn *websocket.Conn, setup map[string]any) {
+		conn.ReadMessage()
+	})
+	g := NewGemini(GeminiConfig{Client: live.ClientConfig{BaseURL: srv.URL}, ContextCompression: true})
+
+	s, err := g.Connect(context.Background(), ConnectOptions{})
+	require.NoError(t, err)
+	assert.False(t, s.Info().Unbounded)
+	s.Close()
+	assert.Equal(t, int32(2), setups.Load())
+
+	// 之后的会话不再尝试压缩
+	s, err = g.Connect(context.Background(), ConnectOptions{})
+	require.NoError(t, err)
+	s.Close()
+	assert.Equal(t, int32(3), setups.Load())
+}
+
+func TestGeminiSessionOptions(t *testing.T) {
+	setups := make(chan map[string]any, 1)
+	srv, _ := newGeminiServer(t, false, func(conn *websocket.Conn, setup map[string]any) {
+		setups <- setup
+		conn.ReadMessage()
+	})
+
+	temperature := 0.5
+	s, err := NewGemini(GeminiConfig{Client: live.ClientConfig{BaseURL: srv.URL}}).Connect(context.Background(), ConnectOptions{
+		Model:         "gemini-2.0-flash-live-001",
+		Language:      "zh-CN",
+		Temperature:   &temperature,
+		Modalities:    []string{ModalityText},
+		Transcription: true,
+		TurnDetection: &TurnDetection{Threshold: 0.8, PrefixPadding: 300 * time.Millisecond, SilenceDuration: time.Second},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	setup := <-setups
+	assert.Equal(t, "models/gemini-2.0-flash-live-001", setup["model"])
+	gen := setup["generationConfig"].(map[string]any)
+	assert.Equal(t, []any{"TEXT"}, gen["responseModalities"])
+	assert.Equal(t, 0.5, gen["temperature"])
+	assert.Equal(t, map[string]any{"languageCode": "zh-CN"}, gen["speechConfig"])
+	assert.Contains(t, setup, "inputAudioTranscription")
+	assert.NotContains(t, setup, "outputAudioTranscription")
+	assert.Equal(t, map[string]any{
+		"startOfSpeechSensitivity": live.StartSensitivityLow,
+		"prefixPaddingMs":          float64(300),
+		"silenceDurationMs":        float64(1000),
+	}, setup["realtimeInputConfig"].(map[string]any)["automaticActivityDetection"])
+}
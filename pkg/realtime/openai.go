@@ -0,0 +1,483 @@
+package realtime
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
+)
+
+const (
+	// DefaultOpenAIBaseURL OpenAI Realtime API 地址
+	DefaultOpenAIBaseURL = "wss://api.openai.com/v1/realtime"
+	// DefaultOpenAIModel 默认使用的实时模型
+	DefaultOpenAIModel = "gpt-4o-realtime-preview"
+	// DefaultOpenAITranscriptionModel 用户语音转写使用的模型
+	DefaultOpenAITranscriptionModel = "whisper-1"
+)
+
+// OpenAIConfig OpenAI Realtime（及兼容服务）的连接参数
+type OpenAIConfig struct {
+	APIKey             string
+	BaseURL            string // 为空时使用 DefaultOpenAIBaseURL，支持 ws/wss/http/https
+	Model              string // 为空时使用 DefaultOpenAIModel
+	Voice              string // 为空时使用服务端默认语音，ConnectOptions.Voice 优先
+	TranscriptionModel string // 为空时使用 DefaultOpenAITranscriptionModel
+}
+
+// OpenAI 基于 OpenAI Realtime websocket 协议的实时模型，输入输出均为 24kHz pcm16。
+// 使用服务端 VAD 检测轮次，不支持会话恢复
+type OpenAI struct {
+	cfg OpenAIConfig
+}
+
+func NewOpenAI(cfg OpenAIConfig) *OpenAI {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOpenAIBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultOpenAIModel
+	}
+	if cfg.TranscriptionModel == "" {
+		cfg.TranscriptionModel = DefaultOpenAITranscriptionModel
+	}
+	return &OpenAI{cfg: cfg}
+}
+
+func (o *OpenAI) Name() string          { return "openai" }
+func (o *OpenAI) InputSampleRate() int  { return 24000 }
+func (o *OpenAI) OutputSampleRate() int { return 24000 }
+
+// Endpoint 返回 websocket 地址
+func (o *OpenAI) Endpoint() (string, error) {
+	return o.endpoint(o.cfg.Model)
+}
+
+func (o *OpenAI) endpoint(model string) (string, error) {
+	u, err := url.Parse(o.cfg.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https", "":
+		u.Scheme = "wss"
+	}
+	q := u.Query()
+	q.Set("model", model)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Connect 建立会话，发送 session.update 并等待服务端确认
+func (o *OpenAI) Connect(ctx context.Context, opts ConnectOptions) (Session, error) {
+	model := opts.Model
+	if model == "" {
+		model = o.cfg.Model
+	}
+	endpoint, err := o.endpoint(model)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+o.cfg.APIKey)
+	header.Set("OpenAI-Beta", "realtime=v1")
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint, header)
+	if err != nil {
+		return nil, fmt.Errorf("connect to openai realtime: %w", err)
+	}
+
+	s := &openAISession{conn: conn, transcribed: make(map[string]bool), calls: make(map[string]*openAICallBatch)}
+	if err := s.write(map[string]any{"type": "session.update", "session": o.sessionConfig(opts)}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send session.update: %w", err)
+	}
+
+	// 握手期间读操作跟随 ctx 取消
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	for {
+		ev, err := s.read()
+		if err != nil {
+			conn.Close()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("session.update: %w", err)
+		}
+		switch ev.Type {
+		case "session.updated":
+			return s, nil
+		case "error":
+			conn.Close()
+			return nil, fmt.Errorf("session.update: %s", ev.Error)
+		}
+	}
+}
+
+func (o *OpenAI) sessionConfig(opts ConnectOptions) map[string]any {
+	modalities := []string{ModalityAudio, ModalityText}
+	if !hasModality(opts.Modalities, ModalityAudio) {
+		modalities = []string{ModalityText}
+	}
+	turnDetection := map[string]any{"type": "server_vad"}
+	if td := opts.TurnDetection; td != nil {
+		if td.Threshold > 0 {
+			turnDetection["threshold"] = td.Threshold
+		}
+		if td.PrefixPadding > 0 {
+			turnDetection["prefix_padding_ms"] = td.PrefixPadding.Milliseconds()
+		}
+		if td.SilenceDuration > 0 {
+			turnDetection["silence_duration_ms"] = td.SilenceDuration.Milliseconds()
+		}
+	}
+	session := map[string]any{
+		"modalities":          modalities,
+		"input_audio_format":  "pcm16",
+		"output_audio_format": "pcm16",
+		"turn_detection":      turnDetection,
+	}
+	if opts.Temperature != nil {
+		session["temperature"] = *opts.Temperature
+	}
+	if opts.Instructions != "" {
+		session["instructions"] = opts.Instructions
+	}
+	voice := opts.Voice
+	if voice == "" {
+		voice = o.cfg.Voice
+	}
+	if voice != "" {
+		session["voice"] = voice
+	}
+	if opts.Transcription {
+		transcription := map[string]any{"model": o.cfg.TranscriptionModel}
+		// 输出语言没有单独的配置，由系统指令约束；这里只用于提高转写准确率
+		if opts.Language != "" {
+			transcription["language"] = strings.SplitN(opts.Language, "-", 2)[0]
+		}
+		session["input_audio_transcription"] = transcription
+	}
+	if len(opts.Tools) > 0 {
+		defs := make([]map[string]any, 0, len(opts.Tools))
+		for _, d := range opts.Tools {
+			defs = append(defs, map[string]any{
+				"type":        "function",
+				"name":        d.Name,
+				"description": d.Description,
+				"parameters":  tools.JSONSchema(d.Parameters),
+			})
+		}
+		session["tools"] = defs
+		session["tool_choice"] = "auto"
+	}
+	return session
+}
+
+// openAIError 服务端 error 事件
+type openAIError struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *openAIError) String() string {
+	if e == nil {
+		return "unknown error"
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Type, e.Code, e.Message)
+}
+
+// openAIEvent 服务端事件中用到的字段
+type openAIEvent struct {
+	Type       string       `json:"type"`
+	Delta      string       `json:"delta"`
+	Transcript string       `json:"transcript"`
+	ItemID     string       `json:"item_id"`
+	CallID     string       `json:"call_id"`
+	Name       string       `json:"name"`
+	Arguments  string       `json:"arguments"`
+	Error      *openAIError `json:"error"`
+	Response   *struct {
+		Status string `json:"status"`
+		Output []struct {
+			Type string `json:"type"`
+		} `json:"output"`
+		Usage *struct {
+			TotalTokens  int `json:"total_tokens"`
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	} `json:"response"`
+}
+
+type openAISession struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	// 一次回复中的多个函数调用的结果都提交后才发 response.create，由 SendToolResults 与接收协程共用
+	callMu    sync.Mutex
+	calls     map[string]*openAICallBatch // call_id 到所属回复
+	callBatch *openAICallBatch            // 正在进行的回复中的函数调用
+
+	// 以下仅在接收协程中访问
+	responding  bool            // 模型正在回复，用户开始说话时视为打断
+	transcribed map[string]bool // 已收到增量转写的用户语音条目
+	pending     []*Event
+}
+
+func (s *openAISession) SendAudio(pcm []byte) error {
+	return s.write(map[string]any{
+		"type":  "input_audio_buffer.append",
+		"audio": base64.StdEncoding.EncodeToString(pcm),
+	})
+}
+
+func (s *openAISession) SendText(text string) error {
+	if err := s.createItem(map[string]any{
+		"type":    "message",
+		"role":    "user",
+		"content": []map[string]any{{"type": "input_text", "text": text}},
+	}); err != nil {
+		return err
+	}
+	return s.write(map[string]any{"type": "response.create"})
+}
+
+// SendImage 图像作为用户消息加入对话，不触发回复
+func (s *openAISession) SendImage(data []byte, mimeType string) error {
+	return s.createItem(map[string]any{
+		"type": "message",
+		"role": "user",
+		"content": []map[string]any{{
+			"type":      "input_image",
+			"image_url": "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data),
+		}},
+	})
+}
+
+// openAICallBatch 同一次回复中的函数调用
+type openAICallBatch struct {
+	pending   map[string]bool // 尚未提交结果的 call_id
+	done      bool            // 回复已结束，不会再有新的调用
+	cancelled bool            // 回复被取消，提交结果后不再请求回复
+}
+
+// ready 所有结果都已提交，可以请求模型继续回复
+func (b *openAICallBatch) ready() bool {
+	return b.done && !b.cancelled && len(b.pending) == 0
+}
+
+// SendToolResults 加入函数调用结果，同一次回复中的调用全部提交后才请求模型继续回复，
+// 并行调用只触发一次 response.create。不属于任何回复的结果立即请求回复
+func (s *openAISession) SendToolResults(results []ToolResult) error {
+	for _, r := range results {
+		output, err := json.Marshal(r.Response)
+		if err != nil {
+			return fmt.Errorf("marshal tool result: %w", err)
+		}
+		if err := s.createItem(map[string]any{
+			"type":    "function_call_output",
+			"call_id": r.ID,
+			"output":  string(output),
+		}); err != nil {
+			return err
+		}
+	}
+
+	s.callMu.Lock()
+	respond := false
+	for _, r := range results {
+		batch, ok := s.calls[r.ID]
+		if !ok {
+			respond = true
+			continue
+		}
+		delete(s.calls, r.ID)
+		delete(batch.pending, r.ID)
+		respond = respond || batch.ready()
+	}
+	s.callMu.Unlock()
+
+	if !respond {
+		return nil
+	}
+	return s.write(map[string]any{"type": "response.create"})
+}
+
+// addCall 记录当前回复中的一个函数调用
+func (s *openAISession) addCall(id string) {
+	s.callMu.Lock()
+	defer s.callMu.Unlock()
+	if s.callBatch == nil {
+		s.callBatch = &openAICallBatch{pending: make(map[string]bool)}
+	}
+	s.callBatch.pending[id] = true
+	s.calls[id] = s.callBatch
+}
+
+// finishCalls 回复结束，返回是否所有调用的结果都已提交（此时由调用方请求回复）
+func (s *openAISession) finishCalls(cancelled bool) bool {
+	s.callMu.Lock()
+	defer s.callMu.Unlock()
+	batch := s.callBatch
+	if batch == nil {
+		return false
+	}
+	s.callBatch = nil
+	batch.done = true
+	batch.cancelled = cancelled
+	return batch.ready()
+}
+
+func (s *openAISession) createItem(item map[string]any) error {
+	return s.write(map[string]any{"type": "conversation.item.create", "item": item})
+}
+
+func (s *openAISession) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal client event: %w", err)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (s *openAISession) read() (*openAIEvent, error) {
+	_, data, err := s.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	ev := &openAIEvent{}
+	if err := json.Unmarshal(data, ev); err != nil {
+		return nil, fmt.Errorf("invalid server event: %w", err)
+	}
+	return ev, nil
+}
+
+func (s *openAISession) Receive() (*Event, error) {
+	for len(s.pending) == 0 {
+		ev, err := s.read()
+		if err != nil {
+			return nil, err
+		}
+		s.pending = s.events(ev)
+	}
+	ev := s.pending[0]
+	s.pending = s.pending[1:]
+	return ev, nil
+}
+
+// events 将一条服务端事件转换为事件，未用到的事件返回空
+func (s *openAISession) events(ev *openAIEvent) []*Event {
+	switch ev.Type {
+	case "response.created":
+		s.responding = true
+
+	case "response.audio.delta":
+		audio, err := base64.StdEncoding.DecodeString(ev.Delta)
+		if err != nil {
+			log.Printf("openai realtime: invalid audio delta: %v", err)
+			return nil
+		}
+		return []*Event{{Type: EventAudio, Audio: audio}}
+
+	case "response.text.delta":
+		return []*Event{{Type: EventText, Text: ev.Delta}}
+
+	case "response.audio_transcript.delta":
+		return []*Event{{Type: EventOutputTranscript, Text: ev.Delta}}
+
+	case "response.audio_transcript.done":
+		return []*Event{{Type: EventOutputTranscript, Final: true}}
+
+	case "conversation.item.input_audio_transcription.delta":
+		s.transcribed[ev.ItemID] = true
+		return []*Event{{Type: EventInputTranscript, Text: ev.Delta}}
+
+	case "conversation.item.input_audio_transcription.completed":
+		// 未收到增量时一次性给出整段转写
+		text := ev.Transcript
+		if s.transcribed[ev.ItemID] {
+			text = ""
+			delete(s.transcribed, ev.ItemID)
+		}
+		return []*Event{{Type: EventInputTranscript, Text: text, Final: true}}
+
+	case "input_audio_buffer.speech_started":
+		// 服务端 VAD 会自动取消进行中的回复
+		if s.responding {
+			s.responding = false
+			return []*Event{{Type: EventInterrupted}}
+		}
+
+	case "response.function_call_arguments.done":
+		args := map[string]any{}
+		if ev.Arguments != "" {
+			if err := json.Unmarshal([]byte(ev.Arguments), &args); err != nil {
+				log.Printf("openai realtime: invalid arguments for %s: %v", ev.Name, err)
+			}
+		}
+		s.addCall(ev.CallID)
+		return []*Event{{Type: EventToolCall, ToolCalls: []ToolCall{{ID: ev.CallID, Name: ev.Name, Args: args}}}}
+
+	case "response.done":
+		// 结果在回复结束前已全部提交，由这里请求继续回复
+		if s.finishCalls(ev.Response != nil && ev.Response.Status == "cancelled") {
+			if err := s.write(map[string]any{"type": "response.create"}); err != nil {
+				log.Printf("openai realtime: request response error: %v", err)
+			}
+		}
+		if ev.Response == nil {
+			return nil
+		}
+		var events []*Event
+		if u := ev.Response.Usage; u != nil {
+			events = append(events, &Event{Type: EventUsage, Usage: &Usage{
+				Prompt:   u.InputTokens,
+				Response: u.OutputTokens,
+				Total:    u.TotalTokens,
+			}})
+		}
+		// 被打断的回复已经通知过；只有函数调用的回复在提交结果后继续
+		wasResponding := s.responding
+		s.responding = false
+		if ev.Response.Status == "cancelled" || !wasResponding {
+			return events
+		}
+		for _, item := range ev.Response.Output {
+			if item.Type == "function_call" {
+				return events
+			}
+		}
+		return append(events, &Event{Type: EventTurnComplete})
+
+	case "error":
+		log.Printf("openai realtime error: %s", ev.Error)
+	}
+	return nil
+}
+
+func (s *openAISession) Info() SessionInfo {
+	return SessionInfo{}
+}
+
+func (s *openAISession) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return s.conn.Close()
+}
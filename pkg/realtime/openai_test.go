@@ -0,0 +1,274 @@
+package realtime
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// newOpenAIServer 启动一个测试用的 OpenAI Realtime 服务端，handler 在 session.updated 之后处理连接
+func newOpenAIServer(t *testing.T, handler func(conn *websocket.Conn, session map[string]any)) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/realtime", r.URL.Path)
+		assert.Equal(t, "test-model", r.URL.Query().Get("model"))
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "realtime=v1", r.Header.Get("OpenAI-Beta"))
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.WriteJSON(map[string]any{"type": "session.created"}))
+		var msg map[string]any
+		require.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, "session.update", msg["type"])
+		session, _ := msg["session"].(map[string]any)
+		if session["voice"] == "invalid" {
+			conn.WriteJSON(map[string]any{"type": "error", "error": map[string]any{"type": "invalid_request_error", "message": "bad voice"}})
+			return
+		}
+		require.NoError(t, conn.WriteJSON(map[string]any{"type": "session.updated"}))
+		handler(conn, session)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testOpenAI(srv *httptest.Server) *OpenAI {
+	return NewOpenAI(OpenAIConfig{APIKey: "test-key", BaseURL: srv.URL + "/v1/realtime", Model: "test-model"})
+}
+
+func receiveAll(t *testing.T, s Session) []*Event {
+	var events []*Event
+	for {
+		ev, err := s.Receive()
+		if err != nil {
+			return events
+		}
+		events = append(events, ev)
+	}
+}
+
+func TestOpenAISession(t *testing.T) {
+	received := make(chan map[string]any, 10)
+	srv := newOpenAIServer(t, func(conn *websocket.Conn, session map[string]any) {
+		assert.Equal(t, "be helpful", session["instructions"])
+		assert.Equal(t, "pcm16", session["input_audio_format"])
+		assert.Equal(t, map[string]any{"model": DefaultOpenAITranscriptionModel}, session["input_audio_transcription"])
+		tl, _ := session["tools"].([]any)
+		require.Len(t, tl, 1)
+		tool := tl[0].(map[string]any)
+		assert.Equal(t, "get_order", tool["name"])
+		assert.Equal(t, "function", tool["type"])
+		assert.Equal(t, []any{"id"}, tool["parameters"].(map[string]any)["required"])
+
+		// 上行音频、文本和函数调用结果
+		for i := 0; i < 6; i++ {
+			var msg map[string]any
+			require.NoError(t, conn.ReadJSON(&msg))
+			received <- msg
+		}
+
+		audio := base64.StdEncoding.EncodeToString([]byte{1, 2, 3, 4})
+		for _, m := range []string{
+			`{"type":"input_audio_buffer.speech_started"}`,
+			`{"type":"conversation.item.input_audio_transcription.completed","item_id":"i1","transcript":"where is my order"}`,
+			`{"type":"response.created"}`,
+			`{"type":"response.function_call_arguments.done","call_id":"c1","name":"get_order","arguments":"{\"id\":\"42\"}"}`,
+			`{"type":"response.done","response":{"status":"completed","output":[{"type":"function_call"}],"usage":{"total_tokens":10,"input_tokens":8,"output_tokens":2}}}`,
+			`{"type":"response.created"}`,
+			`{"type":"response.audio.delta","delta":"` + audio + `"}`,
+			`{"type":"response.audio_transcript.delta","delta":"it ships "}`,
+			`{"type":"response.audio_transcript.delta","delta":"today"}`,
+			`{"type":"response.audio_transcript.done","transcript":"it ships today"}`,
+			`{"type":"response.done","response":{"status":"completed","output":[{"type":"message"}]}}`,
+			`{"type":"response.created"}`,
+			`{"type":"error","error":{"type":"invalid_request_error","message":"ignored"}}`,
+			`{"type":"input_audio_buffer.speech_started"}`,
+			`{"type":"response.done","response":{"status":"cancelled"}}`,
+		} {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(m)))
+		}
+	})
+
+	s, err := testOpenAI(srv).Connect(context.Background(), ConnectOptions{
+		Instructions:  "be helpful",
+		Transcription: true,
+		Tools: []*genai.FunctionDeclaration{{
+			Name:       "get_order",
+			Parameters: tools.MustParseSchema(`{"type":"object","properties":{"id":{"type":"string"}},"required":["id"]}`),
+		}},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, SessionInfo{}, s.Info())
+
+	require.NoError(t, s.SendAudio([]byte{1, 2}))
+	require.NoError(t, s.SendText("hello"))
+	require.NoError(t, s.SendImage([]byte{0xff}, "image/jpeg"))
+	require.NoError(t, s.SendToolResults([]ToolResult{{ID: "c1", Name: "get_order", Response: map[string]any{"status": "shipped"}}}))
+
+	msg := <-received
+	assert.Equal(t, "input_audio_buffer.append", msg["type"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{1, 2}), msg["audio"])
+	msg = <-received
+	assert.Equal(t, "conversation.item.create", msg["type"])
+	assert.Equal(t, "hello", msg["item"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"])
+	assert.Equal(t, "response.create", (<-received)["type"])
+	msg = <-received
+	assert.Equal(t, "data:image/jpeg;base64,/w==", msg["item"].(map[string]any)["content"].([]any)[0].(map[string]any)["image_url"])
+	msg = <-received
+	assert.Equal(t, map[string]any{"type": "function_call_output", "call_id": "c1", "output": `{"status":"shipped"}`}, msg["item"])
+	assert.Equal(t, "response.create", (<-received)["type"])
+
+	events := receiveAll(t, s)
+	var types []EventType
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+	assert.Equal(t, []EventType{
+		EventInputTranscript, // 未在回复中，speech_started 不算打断
+		EventToolCall,
+		EventUsage, // 只有函数调用的回复不结束本轮
+		EventAudio,
+		EventOutputTranscript,
+		EventOutputTranscript,
+		EventOutputTranscript,
+		EventTurnComplete,
+		EventInterrupted, // 被取消的回复不再结束本轮
+	}, types)
+
+	assert.Equal(t, "where is my order", events[0].Text)
+	assert.True(t, events[0].Final)
+	assert.Equal(t, []ToolCall{{ID: "c1", Name: "get_order", Args: map[string]any{"id": "42"}}}, events[1].ToolCalls)
+	assert.Equal(t, &Usage{Prompt: 8, Response: 2, Total: 10}, events[2].Usage)
+	assert.Equal(t, []byte{1, 2, 3, 4}, events[3].Audio)
+	assert.Equal(t, "today", events[5].Text)
+	assert.True(t, events[6].Final)
+	assert.Empty(t, events[6].Text)
+}
+
+func TestOpenAIParallelToolCalls(t *testing.T) {
+	received := make(chan map[string]any, 10)
+	srv := newOpenAIServer(t, func(conn *websocket.Conn, session map[string]any) {
+		for _, m := range []string{
+			`{"type":"response.created"}`,
+			`{"type":"response.function_call_arguments.done","call_id":"c1","name":"get_order","arguments":"{\"id\":\"1\"}"}`,
+			`{"type":"response.function_call_arguments.done","call_id":"c2","name":"get_order","arguments":"{\"id\":\"2\"}"}`,
+			`{"type":"response.done","response":{"status":"completed","output":[{"type":"function_call"},{"type":"function_call"}],"usage":{"total_tokens":3}}}`,
+			`{"type":"response.created"}`,
+			`{"type":"response.function_call_arguments.done","call_id":"c3","name":"get_order","arguments":"{\"id\":\"3\"}"}`,
+			`{"type":"response.function_call_arguments.done","call_id":"c4","name":"get_order","arguments":"{\"id\":\"4\"}"}`,
+			`{"type":"response.done","response":{"status":"completed","output":[{"type":"function_call"},{"type":"function_call"}],"usage":{"total_tokens":3}}}`,
+		} {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(m)))
+		}
+		for {
+			var msg map[string]any
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			received <- msg
+		}
+	})
+
+	s, err := testOpenAI(srv).Connect(context.Background(), ConnectOptions{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	next := func() *Event {
+		ev, err := s.Receive()
+		require.NoError(t, err)
+		return ev
+	}
+	result := func(id string) ToolResult {
+		return ToolResult{ID: id, Name: "get_order", Response: map[string]any{"id": id}}
+	}
+	// 收到下一条消息，返回 item 的 call_id 或消息类型
+	read := func() string {
+		select {
+		case msg := <-received:
+			if item, ok := msg["item"].(map[string]any); ok {
+				return item["call_id"].(string)
+			}
+			return msg["type"].(string)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for client event")
+			return ""
+		}
+	}
+
+	// 第一个调用的结果在回复结束前提交：等第二个调用的结果提交后才请求回复
+	assert.Equal(t, EventToolCall, next().Type)
+	require.NoError(t, s.SendToolResults([]ToolResult{result("c1")}))
+	assert.Equal(t, "c2", next().ToolCalls[0].ID)
+	assert.Equal(t, EventUsage, next().Type)
+	require.NoError(t, s.SendToolResults([]ToolResult{result("c2")}))
+	assert.Equal(t, []string{"c1", "c2", "response.create"}, []string{read(), read(), read()})
+
+	// 两个结果都在回复结束前提交：由 response.done 请求回复
+	assert.Equal(t, "c3", next().ToolCalls[0].ID)
+	assert.Equal(t, "c4", next().ToolCalls[0].ID)
+	require.NoError(t, s.SendToolResults([]ToolResult{result("c4"), result("c3")}))
+	assert.Equal(t, []string{"c4", "c3"}, []string{read(), read()})
+	assert.Equal(t, EventUsage, next().Type)
+	assert.Equal(t, "response.create", read())
+
+	// 之后没有多余的 response.create
+	require.NoError(t, s.SendAudio([]byte{1, 2}))
+	assert.Equal(t, "input_audio_buffer.append", read())
+}
+
+func TestOpenAIConnectError(t *testing.T) {
+	srv := newOpenAIServer(t, func(conn *websocket.Conn, session map[string]any) {})
+
+	_, err := testOpenAI(srv).Connect(context.Background(), ConnectOptions{Voice: "invalid"})
+	assert.ErrorContains(t, err, "bad voice")
+}
+
+func TestOpenAIEndpoint(t *testing.T) {
+	u, err := NewOpenAI(OpenAIConfig{}).Endpoint()
+	require.NoError(t, err)
+	assert.Equal(t, "wss://api.openai.com/v1/realtime?model="+DefaultOpenAIModel, u)
+
+	u, err = NewOpenAI(OpenAIConfig{BaseURL: "http://127.0.0.1:8080/v1/realtime", Model: "m"}).Endpoint()
+	require.NoError(t, err)
+	assert.Equal(t, "ws://127.0.0.1:8080/v1/realtime?model=m", u)
+}
+
+func TestOpenAISessionOptions(t *testing.T) {
+	o := NewOpenAI(OpenAIConfig{Voice: "alloy"})
+	u, err := o.endpoint("gpt-4o-mini-realtime-preview")
+	require.NoError(t, err)
+	assert.Contains(t, u, "model=gpt-4o-mini-realtime-preview")
+
+	temperature := 0.8
+	session := o.sessionConfig(ConnectOptions{
+		Voice:         "verse",
+		Language:      "zh-CN",
+		Temperature:   &temperature,
+		Modalities:    []string{ModalityText},
+		Transcription: true,
+		TurnDetection: &TurnDetection{Threshold: 0.6, SilenceDuration: 800 * time.Millisecond},
+	})
+	assert.Equal(t, "verse", session["voice"])
+	assert.Equal(t, 0.8, session["temperature"])
+	assert.Equal(t, []string{"text"}, session["modalities"])
+	assert.Equal(t, map[string]any{"model": DefaultOpenAITranscriptionModel, "language": "zh"}, session["input_audio_transcription"])
+	assert.Equal(t, map[string]any{"type": "server_vad", "threshold": 0.6, "silence_duration_ms": int64(800)}, session["turn_detection"])
+
+	session = o.sessionConfig(ConnectOptions{})
+	assert.Equal(t, "alloy", session["voice"])
+	assert.Equal(t, []string{"audio", "text"}, session["modalities"])
+	assert.NotContains(t, session, "temperature")
+}
@@ -0,0 +1,158 @@
+// Package realtime 定义与具体厂商无关的实时语音模型接口，
+// pipeline 通过 Model/Session 与模型交互，Gemini Live 与 OpenAI Realtime 为两种实现
+package realtime
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// ErrUnsupported 会话不支持该类输入
+var ErrUnsupported = errors.New("not supported by this realtime model")
+
+// Model 一种实时模型，每次 Connect 建立一个新会话
+type Model interface {
+	// Name 厂商名称，如 "gemini"、"openai"
+	Name() string
+	// InputSampleRate 上行音频的采样率，音频均为单声道 S16LE PCM
+	InputSampleRate() int
+	// OutputSampleRate 模型输出音频的采样率
+	OutputSampleRate() int
+	Connect(ctx context.Context, opts ConnectOptions) (Session, error)
+}
+
+// ConnectOptions 建立会话的参数，为空的字段使用厂商或模型配置的默认值
+type ConnectOptions struct {
+	Model        string                       // 覆盖模型配置中的模型名
+	Instructions string                       // 系统指令
+	Voice        string                       // 输出语音
+	Language     string                       // 语音的语言，BCP-47 代码，如 "zh-CN"
+	Temperature  *float64                     // 采样温度
+	Tools        []*genai.FunctionDeclaration // 可调用的函数
+	// Modalities 输出模态，ModalityAudio 和/或 ModalityText，为空时输出音频
+	Modalities []string
+	// TurnDetection 服务端语音活动检测参数
+	TurnDetection *TurnDetection
+	// Transcription 开启用户及模型语音的转写
+	Transcription bool
+	// ResumeHandle 恢复之前的会话，仅支持会话恢复的模型有效
+	ResumeHandle string
+}
+
+// AudioOutput 模型是否输出音频，只输出文本时需要由 TTS 合成语音
+func (o ConnectOptions) AudioOutput() bool {
+	return hasModality(o.Modalities, ModalityAudio)
+}
+
+// 输出模态
+const (
+	ModalityAudio = "audio"
+	ModalityText  = "text"
+)
+
+// TurnDetection 服务端语音活动检测（VAD）参数，零值字段使用厂商默认值
+type TurnDetection struct {
+	// Threshold 0~1，越大越不容易判定用户开始说话
+	Threshold float64
+	// PrefixPadding 判定开始说话时向前保留的音频
+	PrefixPadding time.Duration
+	// SilenceDuration 判定用户说完所需的静音时长
+	SilenceDuration time.Duration
+}
+
+// hasModality 判断 modalities 是否包含 m，为空时视为只有音频
+func hasModality(modalities []string, m string) bool {
+	if len(modalities) == 0 {
+		return m == ModalityAudio
+	}
+	for _, v := range modalities {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
+// Session 一个实时会话。Send* 可以并发调用，Receive 只能在一个协程中调用
+type Session interface {
+	// SendAudio 发送一块上行 PCM 音频
+	SendAudio(pcm []byte) error
+	// SendText 以用户轮次发送文本，模型随后回复
+	SendText(text string) error
+	// SendImage 发送一帧图像（如摄像头画面），作为之后对话的上下文
+	SendImage(data []byte, mimeType string) error
+	// SendToolResults 回复函数调用的结果
+	SendToolResults(results []ToolResult) error
+	// Receive 读取下一个事件，连接断开时返回错误
+	Receive() (*Event, error)
+	// Info 会话的能力
+	Info() SessionInfo
+	Close() error
+}
+
+// SessionInfo 会话的能力
+type SessionInfo struct {
+	// Unbounded 会话不受时长和上下文长度限制（如开启了上下文压缩），无需切换新会话
+	Unbounded bool
+	// Resumable 支持通过 ResumeHandle 恢复会话
+	Resumable bool
+}
+
+// EventType 模型事件类型
+type EventType string
+
+const (
+	EventAudio            EventType = "audio"             // Audio 为模型输出的 PCM
+	EventText             EventType = "text"              // Text 为模型输出的文本
+	EventInputTranscript  EventType = "input_transcript"  // Text 为用户语音转写的增量
+	EventOutputTranscript EventType = "output_transcript" // Text 为模型语音转写的增量
+	EventToolCall         EventType = "tool_call"         // ToolCalls 为需要执行的函数调用
+	EventToolCancel       EventType = "tool_cancel"       // CancelledCalls 为取消的函数调用 ID
+	EventInterrupted      EventType = "interrupted"       // 模型输出被用户打断
+	EventTurnComplete     EventType = "turn_complete"     // 模型本轮输出结束
+	EventUsage            EventType = "usage"             // Usage 为 token 用量
+	EventGoAway           EventType = "go_away"           // 服务端将在 TimeLeft 后断开
+	EventResumption       EventType = "resumption"        // ResumeHandle 为最新的会话恢复句柄
+)
+
+// Event 模型事件，按 Type 使用对应字段
+type Event struct {
+	Type EventType
+
+	Audio []byte
+	Text  string
+	// Final 仅对转写有效，表示该段转写已结束
+	Final bool
+
+	ToolCalls      []ToolCall
+	CancelledCalls []string
+
+	Usage *Usage
+
+	TimeLeft     time.Duration
+	ResumeHandle string
+}
+
+// ToolCall 模型发起的函数调用
+type ToolCall struct {
+	ID   string
+	Name string
+	Args map[string]any
+}
+
+// ToolResult 函数调用的结果
+type ToolResult struct {
+	ID       string
+	Name     string
+	Response map[string]any
+}
+
+// Usage token 用量，Total 近似为当前上下文大小
+type Usage struct {
+	Prompt   int
+	Response int
+	Total    int
+}
@@ -0,0 +1,292 @@
+// Package recording 为每个会话保存一份完整的通话录音（立体声 WAV，用户在左，助手在右；
+// 或不经解码、重新编码的两路 Ogg Opus），会话结束时定稿，并提供按会话 ID 下载录音的 HTTP 接口
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+)
+
+// 录音中的文件带 partialSuffix，定稿后去掉
+const partialSuffix = ".part"
+
+// 录音文件名为 <session id> 加以下后缀
+const (
+	// SuffixWav 立体声 WAV 录音
+	SuffixWav = ".wav"
+	// SuffixUserOpus Opus 录音中用户一侧（远端 RTP 包原样封装）
+	SuffixUserOpus = ".user.opus"
+	// SuffixAssistantOpus Opus 录音中助手一侧（下行编码器的输出）
+	SuffixAssistantOpus = ".assistant.opus"
+)
+
+// Format 录音格式
+type Format string
+
+const (
+	// FormatWav 解码后的立体声 WAV，适用于所有编码
+	FormatWav Format = "wav"
+	// FormatOpus 两路 Ogg Opus，仅 Opus 会话，其他编码的会话仍录为 WAV
+	FormatOpus Format = "opus"
+)
+
+// ParseFormat 解析 RECORDING_FORMAT，空字符串为 FormatWav
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatWav:
+		return FormatWav, nil
+	case FormatOpus:
+		return FormatOpus, nil
+	}
+	return "", fmt.Errorf("unknown recording format %q", s)
+}
+
+var (
+	// ErrRecording 会话仍在录音
+	ErrRecording = errors.New("session is still being recorded")
+
+	sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+)
+
+// Recorder 管理 Dir 下各会话的录音：录音中写为 <id>.wav.part，Finish 后重命名为 <id>.wav
+type Recorder struct {
+	dir string
+	// Token 非空时下载需要 Authorization: Bearer <Token>
+	Token string
+	// Format Opus 会话使用的录音格式，默认 FormatWav
+	Format Format
+
+	mu     sync.Mutex
+	active map[string]io.Closer
+}
+
+// OpusRecording 一个会话的 Ogg Opus 录音，两路各一个文件，时间线都从各自的第一个包开始
+type OpusRecording struct {
+	mu        sync.Mutex
+	user      *audio.OggOpusWriter
+	assistant *audio.OggOpusWriter
+	closed    bool
+}
+
+// WriteUser 写入一个远端 RTP 包的 Opus 负载，按 RTP 时间戳填补丢包和 DTX 的空档
+func (o *OpusRecording) WriteUser(payload []byte, timestamp uint32) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return nil
+	}
+	return o.user.WriteRTP(payload, timestamp)
+}
+
+// WriteAssistant 写入一个下行发出的 Opus 包
+func (o *OpusRecording) WriteAssistant(packet []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return nil
+	}
+	return o.assistant.WritePacket(packet)
+}
+
+// Close 写出最后一页并关闭两个文件
+func (o *OpusRecording) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	return errors.Join(o.user.Close(), o.assistant.Close())
+}
+
+// NewRecorder 创建录音管理器，dir 不存在时自动创建。
+// 上次进程异常退出留下的 .part 文件会被修复并定稿
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create recording dir: %w", err)
+	}
+	r := &Recorder{dir: dir, Format: FormatWav, active: make(map[string]io.Closer)}
+	r.recoverPartial()
+	return r, nil
+}
+
+// recoverPartial 定稿未完成的录音：WAV 按文件长度回填头部；
+// Ogg Opus 只丢失最后一页，读取时截断处视为结尾，直接重命名
+func (r *Recorder) recoverPartial() {
+	parts, err := filepath.Glob(filepath.Join(r.dir, "*"+partialSuffix))
+	if err != nil {
+		return
+	}
+	for _, part := range parts {
+		name := strings.TrimSuffix(part, partialSuffix)
+		if strings.HasSuffix(name, SuffixWav) {
+			if _, err := audio.RecoverWav(part); err != nil {
+				log.Printf("recover recording %s error: %v", part, err)
+				continue
+			}
+		}
+		if err := os.Rename(part, name); err != nil {
+			log.Printf("recover recording %s error: %v", part, err)
+			continue
+		}
+		log.Printf("recovered unfinished recording %s", name)
+	}
+}
+
+// Dir 录音目录
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// Start 开始录制一个会话，两路音频的采样率都须为 sampleRate
+func (r *Recorder) Start(sessionID string, sampleRate int) (*audio.StereoRecorder, error) {
+	var rec *audio.StereoRecorder
+	err := r.start(sessionID, func() (io.Closer, error) {
+		var err error
+		rec, err = audio.NewStereoRecorder(r.filename(sessionID, SuffixWav)+partialSuffix, sampleRate)
+		return rec, err
+	})
+	return rec, err
+}
+
+// StartOpus 开始以 Ogg Opus 录制一个会话，userChannels 为远端发送的声道数
+func (r *Recorder) StartOpus(sessionID string, userChannels int) (*OpusRecording, error) {
+	rec := &OpusRecording{}
+	err := r.start(sessionID, func() (io.Closer, error) {
+		var err error
+		rec.user, err = audio.NewOggOpusFileWriter(r.filename(sessionID, SuffixUserOpus)+partialSuffix,
+			audio.OpusHead{Channels: userChannels, PreSkip: audio.DefaultOpusPreSkip})
+		if err != nil {
+			return nil, err
+		}
+		rec.assistant, err = audio.NewOggOpusFileWriter(r.filename(sessionID, SuffixAssistantOpus)+partialSuffix,
+			audio.OpusHead{Channels: 1, PreSkip: audio.DefaultOpusPreSkip})
+		if err != nil {
+			rec.user.Close()
+			return nil, err
+		}
+		return rec, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (r *Recorder) start(sessionID string, create func() (io.Closer, error)) error {
+	if !sessionIDPattern.MatchString(sessionID) {
+		return fmt.Errorf("invalid session id %q", sessionID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.active[sessionID]; ok {
+		return fmt.Errorf("session %s is already being recorded", sessionID)
+	}
+
+	rec, err := create()
+	if err != nil {
+		return err
+	}
+	r.active[sessionID] = rec
+	return nil
+}
+
+// Finish 会话结束，关闭录音并定稿
+func (r *Recorder) Finish(sessionID string) error {
+	r.mu.Lock()
+	rec, ok := r.active[sessionID]
+	delete(r.active, sessionID)
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if err := rec.Close(); err != nil {
+		log.Printf("close recording %s error: %v", sessionID, err)
+	}
+	suffixes := []string{SuffixWav}
+	if _, ok := rec.(*OpusRecording); ok {
+		suffixes = []string{SuffixUserOpus, SuffixAssistantOpus}
+	}
+	var errs []error
+	for _, suffix := range suffixes {
+		name := r.filename(sessionID, suffix)
+		errs = append(errs, os.Rename(name+partialSuffix, name))
+	}
+	return errors.Join(errs...)
+}
+
+// Path 返回已定稿的 WAV 录音文件，仍在录音时返回 ErrRecording，没有录音时返回 os.ErrNotExist
+func (r *Recorder) Path(sessionID string) (string, error) {
+	return r.File(sessionID, SuffixWav)
+}
+
+// File 返回已定稿的录音文件 <session id><suffix>，错误同 Path
+func (r *Recorder) File(sessionID, suffix string) (string, error) {
+	if !sessionIDPattern.MatchString(sessionID) {
+		return "", os.ErrNotExist
+	}
+	r.mu.Lock()
+	_, recording := r.active[sessionID]
+	r.mu.Unlock()
+	if recording {
+		return "", ErrRecording
+	}
+
+	path := r.filename(sessionID, suffix)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (r *Recorder) filename(sessionID, suffix string) string {
+	return filepath.Join(r.dir, sessionID+suffix)
+}
+
+// ServeHTTP 处理 GET <prefix>/<session id>[.wav]，返回该会话的 WAV 录音；
+// Opus 录音为 GET <prefix>/<session id>.user.opus 和 <session id>.assistant.opus
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Token != "" && req.Header.Get("Authorization") != "Bearer "+r.Token {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	sessionID, suffix, contentType := strings.TrimSuffix(name, SuffixWav), SuffixWav, "audio/wav"
+	for _, s := range []string{SuffixUserOpus, SuffixAssistantOpus} {
+		if strings.HasSuffix(name, s) {
+			sessionID, suffix, contentType = strings.TrimSuffix(name, s), s, "audio/ogg"
+		}
+	}
+	path, err := r.File(sessionID, suffix)
+	switch {
+	case errors.Is(err, ErrRecording):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, os.ErrNotExist):
+		http.NotFound(w, req)
+		return
+	case err != nil:
+		http.Error(w, "Failed to read recording", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, sessionID, suffix))
+	http.ServeFile(w, req, path)
+}
@@ -0,0 +1,158 @@
+package recording
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recordings")
+	r, err := NewRecorder(dir)
+	require.NoError(t, err)
+
+	rec, err := r.Start("session-1", 8000)
+	require.NoError(t, err)
+	require.NoError(t, rec.Write(audio.RecordUser, make([]byte, 320)))
+	require.NoError(t, rec.Write(audio.RecordAssistant, make([]byte, 320)))
+
+	_, err = r.Start("session-1", 8000)
+	assert.Error(t, err, "already recording")
+	_, err = r.Start("../escape", 8000)
+	assert.Error(t, err)
+
+	_, err = r.Path("session-1")
+	assert.ErrorIs(t, err, ErrRecording)
+	assert.FileExists(t, filepath.Join(dir, "session-1.wav.part"))
+
+	require.NoError(t, r.Finish("session-1"))
+	require.NoError(t, r.Finish("session-1"), "finishing twice is a no-op")
+	path, err := r.Path("session-1")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "session-1.wav"), path)
+
+	format, _, err := audio.ReadWav(path)
+	require.NoError(t, err)
+	assert.Equal(t, audio.WavFormat{SampleRate: 8000, Channels: 2, BitsPerSample: 16}, format)
+
+	_, err = r.Path("unknown")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRecorderHTTP(t *testing.T) {
+	r, err := NewRecorder(t.TempDir())
+	require.NoError(t, err)
+	r.Token = "secret"
+
+	_, err = r.Start("done", 8000)
+	require.NoError(t, err)
+	require.NoError(t, r.Finish("done"))
+	_, err = r.Start("live", 8000)
+	require.NoError(t, err)
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/recordings/done", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "audio/wav", w.Header().Get("Content-Type"))
+	assert.Equal(t, "RIFF", w.Body.String()[:4])
+
+	assert.Equal(t, http.StatusOK, get("/recordings/done.wav", "secret").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("/recordings/done", "").Code)
+	assert.Equal(t, http.StatusConflict, get("/recordings/live", "secret").Code)
+	assert.Equal(t, http.StatusNotFound, get("/recordings/missing", "secret").Code)
+	assert.Equal(t, http.StatusNotFound, get("/recordings/..%2Fetc", "secret").Code)
+}
+
+func TestRecorderOpus(t *testing.T) {
+	r, err := NewRecorder(t.TempDir())
+	require.NoError(t, err)
+
+	rec, err := r.StartOpus("call", 2)
+	require.NoError(t, err)
+	frame := []byte{31 << 3, 1, 2, 3}
+	require.NoError(t, rec.WriteUser(frame, 1000))
+	require.NoError(t, rec.WriteUser(frame, 1000+3*960)) // 丢了两个包
+	require.NoError(t, rec.WriteAssistant(frame))
+	assert.FileExists(t, filepath.Join(r.Dir(), "call.user.opus.part"))
+
+	require.NoError(t, r.Finish("call"))
+	require.NoError(t, rec.WriteUser(frame, 1000+4*960), "writes after finish are ignored")
+
+	path, err := r.File("call", SuffixUserOpus)
+	require.NoError(t, err)
+	user, err := audio.OpenOggOpus(path)
+	require.NoError(t, err)
+	defer user.Close()
+	assert.Equal(t, 2, user.Head().Channels)
+	n := 0
+	for ; ; n++ {
+		if _, err := user.ReadPacket(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, 4, n)
+
+	req := httptest.NewRequest(http.MethodGet, "/recordings/call.assistant.opus", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "audio/ogg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "OggS", w.Body.String()[:4])
+
+	_, err = r.Path("call")
+	assert.ErrorIs(t, err, os.ErrNotExist, "opus sessions have no wav")
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatWav, "wav": FormatWav, "opus": FormatOpus} {
+		got, err := ParseFormat(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseFormat("mp3")
+	assert.Error(t, err)
+}
+
+func TestRecorderRecoversPartial(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder(dir)
+	require.NoError(t, err)
+	rec, err := r.Start("crashed", 8000)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, rec.Write(audio.RecordUser, make([]byte, 320)))
+		require.NoError(t, rec.Write(audio.RecordAssistant, make([]byte, 320)))
+	}
+	// 进程异常退出：不调用 Finish，缓冲中的数据已写入文件但头部未回填
+	require.NoError(t, rec.Close())
+	raw, err := os.ReadFile(filepath.Join(dir, "crashed.wav.part"))
+	require.NoError(t, err)
+	copy(raw[4:8], []byte{0, 0, 0, 0})
+	copy(raw[76:80], []byte{0, 0, 0, 0})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crashed.wav.part"), raw, 0o644))
+
+	r, err = NewRecorder(dir)
+	require.NoError(t, err)
+	path, err := r.Path("crashed")
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "crashed.wav.part"))
+	raw, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(len(raw)-80), binary.LittleEndian.Uint32(raw[76:80]))
+}
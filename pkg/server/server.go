@@ -8,11 +8,20 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"sync"
 
 	"github.com/google/uuid"
+	"github.com/pion/interceptor"
 	"github.com/pion/webrtc/v4"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/audio"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/codec"
 	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/connection"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/elements"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/recording"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/speech"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/tools"
 )
 
 type WebRTCServer struct {
@@ -20,6 +29,18 @@ type WebRTCServer struct {
 	peers      map[string]*connection.RTCConnectionWrapper
 	rtcUDPPort int
 	api        *webrtc.API
+	tools      *tools.Registry
+	policy     *SessionPolicy
+	ttsEngine  speech.TTSEngine
+	cascade    *speech.Cascade
+	recorder   *recording.Recorder
+	dumpConfig *audio.DumpConfig
+	micFile    string
+	micOptions elements.FileSourceOptions
+
+	// 可选的实时模型，key 为 Model.Name()
+	models       map[string]realtime.Model
+	defaultModel string
 }
 
 func NewWebRTCServer(rtcUDPPort int) *WebRTCServer {
@@ -27,7 +48,76 @@ func NewWebRTCServer(rtcUDPPort int) *WebRTCServer {
 	return &WebRTCServer{
 		rtcUDPPort: rtcUDPPort,
 		peers:      make(map[string]*connection.RTCConnectionWrapper),
+		tools:      tools.NewRegistry(),
+		policy:     &SessionPolicy{},
+		models:     make(map[string]realtime.Model),
+	}
+}
+
+// AddModel 注册一个可选的实时模型，客户端通过 /session?provider=<name> 选择。
+// 未指定时使用 REALTIME_PROVIDER，未设置时使用第一个注册的模型
+func (s *WebRTCServer) AddModel(m realtime.Model) {
+	s.models[m.Name()] = m
+	if s.defaultModel == "" {
+		s.defaultModel = m.Name()
+	}
+}
+
+// model 返回名为 provider 的模型，provider 为空时返回默认模型
+func (s *WebRTCServer) model(provider string) (realtime.Model, error) {
+	if provider == "" {
+		provider = os.Getenv("REALTIME_PROVIDER")
+	}
+	if provider == "" {
+		provider = s.defaultModel
 	}
+	if provider == "" {
+		return connection.DefaultGeminiModel(), nil
+	}
+	m, ok := s.models[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", provider)
+	}
+	return m, nil
+}
+
+// SetSessionPolicy 设置客户端会话配置的允许范围和默认值
+func (s *WebRTCServer) SetSessionPolicy(p *SessionPolicy) {
+	if p == nil {
+		p = &SessionPolicy{}
+	}
+	s.policy = p
+}
+
+// SetTTSEngine 设置只输出文本（modalities 为 ["text"]）的会话使用的语音合成引擎
+func (s *WebRTCServer) SetTTSEngine(engine speech.TTSEngine) {
+	s.ttsEngine = engine
+}
+
+// SetCascade 设置级联模式（mode 为 "cascade"）使用的引擎，未设置时不接受级联模式的会话
+func (s *WebRTCServer) SetCascade(cascade *speech.Cascade) {
+	s.cascade = cascade
+}
+
+// SetRecorder 录制所有会话，录音在会话结束时定稿
+func (s *WebRTCServer) SetRecorder(r *recording.Recorder) {
+	s.recorder = r
+}
+
+// SetDumpConfig 设置调试用的音频转储，每个会话写在各自的目录下
+func (s *WebRTCServer) SetDumpConfig(c *audio.DumpConfig) {
+	s.dumpConfig = c
+}
+
+// SetMicrophoneFile 所有会话用该文件模拟麦克风，代替浏览器的上行音频，用于测试和压测
+func (s *WebRTCServer) SetMicrophoneFile(path string, opts elements.FileSourceOptions) {
+	s.micFile = path
+	s.micOptions = opts
+}
+
+// SetTools 设置所有会话共享的工具注册表
+func (s *WebRTCServer) SetTools(r *tools.Registry) {
+	s.tools = r
 }
 
 func (s *WebRTCServer) Start() error {
@@ -53,7 +143,23 @@ func (s *WebRTCServer) Start() error {
 	udpMux := webrtc.NewICEUDPMux(nil, udpListener)
 	settingEngine.SetICEUDPMux(udpMux)
 
-	api := webrtc.NewAPI(webrtc.WithSettingEngine(settingEngine))
+	// 只注册服务端能处理的音频编码（Opus、G.722、G.711）
+	mediaEngine := &webrtc.MediaEngine{}
+	if err := codec.RegisterCodecs(mediaEngine); err != nil {
+		return err
+	}
+
+	// 使用自定义 MediaEngine 时需要显式注册默认拦截器（NACK、RTCP 报告等）
+	interceptorRegistry := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(mediaEngine, interceptorRegistry); err != nil {
+		return err
+	}
+
+	api := webrtc.NewAPI(
+		webrtc.WithSettingEngine(settingEngine),
+		webrtc.WithMediaEngine(mediaEngine),
+		webrtc.WithInterceptorRegistry(interceptorRegistry),
+	)
 
 	s.api = api
 
@@ -66,6 +172,7 @@ func (s *WebRTCServer) HandleNegotiate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Session-Id")
 
 	// 处理 OPTIONS 请求
 	if r.Method == http.MethodOptions {
@@ -83,11 +190,61 @@ func (s *WebRTCServer) HandleNegotiate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var offer webrtc.SessionDescription
-	if err := json.Unmarshal(body, &offer); err != nil {
+	var req sessionRequest
+	if err := json.Unmarshal(body, &req); err != nil {
 		http.Error(w, "Failed to parse offer", http.StatusBadRequest)
 		return
 	}
+	offer := req.SessionDescription
+
+	// 会话配置，provider 也可以通过 ?provider= 指定
+	var sessionConfig SessionConfig
+	if req.Session != nil {
+		sessionConfig = *req.Session
+	}
+	if sessionConfig.Provider == "" {
+		sessionConfig.Provider = r.URL.Query().Get("provider")
+	}
+	sessionConfig, err = s.policy.Resolve(sessionConfig)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid session config: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var model realtime.Model
+	if sessionConfig.Mode == ModeCascade {
+		if s.cascade == nil {
+			http.Error(w, "Invalid session config: cascade mode is not configured", http.StatusBadRequest)
+			return
+		}
+	} else {
+		model, err = s.model(sessionConfig.Provider)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	sessionTools := s.tools
+	if sessionConfig.Tools != nil {
+		sessionTools, err = s.tools.Subset(sessionConfig.Tools)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid session config: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// 按服务端偏好从 offer 中选择音频编码
+	audioCodec, err := codec.SelectFromOffer(offer.SDP)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to negotiate audio codec: %v", err), http.StatusNotAcceptable)
+		return
+	}
+	if model != nil {
+		log.Printf("negotiated audio codec: %s, model: %s", audioCodec, model.Name())
+	} else {
+		log.Printf("negotiated audio codec: %s, cascade mode", audioCodec)
+	}
 
 	ctx := context.Background()
 
@@ -102,11 +259,32 @@ func (s *WebRTCServer) HandleNegotiate(w http.ResponseWriter, r *http.Request) {
 
 	peerID := uuid.New().String()
 	wrapper := connection.NewRTCConnectionWrapper(peerID, pc)
+	wrapper.SetAudioCodec(audioCodec)
+	wrapper.SetTools(sessionTools)
+	if model != nil {
+		wrapper.SetModel(model)
+	} else {
+		wrapper.SetCascade(s.cascade)
+	}
+	wrapper.SetSessionOptions(sessionConfig.ConnectOptions())
+	wrapper.SetTTSEngine(s.ttsEngine)
+	if s.recorder != nil {
+		wrapper.SetRecorder(s.recorder)
+	}
+	wrapper.SetDumpConfig(s.dumpConfig)
+	if s.micFile != "" {
+		wrapper.SetMicrophoneFile(s.micFile, s.micOptions)
+	}
 
-	// 将 wrapper 加入 server 管理
+	// 将 wrapper 加入 server 管理，连接失败或关闭时结束会话
 	s.Lock()
 	s.peers[peerID] = wrapper
 	s.Unlock()
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			s.removePeer(peerID)
+		}
+	})
 
 	// 在此处启动或初始化 AI Session
 	if err := wrapper.InitAISession(ctx); err != nil {
@@ -141,6 +319,27 @@ func (s *WebRTCServer) HandleNegotiate(w http.ResponseWriter, r *http.Request) {
 	<-gatherComplete
 
 	w.Header().Set("Content-Type", "application/sdp")
+	// 会话 ID，用于下载录音等
+	w.Header().Set("X-Session-Id", peerID)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(pc.LocalDescription())
 }
+
+// removePeer 停止会话（定稿录音等）并关闭 PeerConnection，重复调用时什么都不做
+func (s *WebRTCServer) removePeer(peerID string) {
+	s.Lock()
+	wrapper, ok := s.peers[peerID]
+	delete(s.peers, peerID)
+	s.Unlock()
+	if !ok {
+		return
+	}
+
+	log.Printf("session %s ended", peerID)
+	if err := wrapper.Stop(); err != nil {
+		log.Printf("stop session %s error: %v", peerID, err)
+	}
+	if err := wrapper.Close(); err != nil {
+		log.Printf("close peer connection %s error: %v", peerID, err)
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/realtime-ai/gemini-realtime-webrtc/pkg/realtime"
+)
+
+// SessionConfig 客户端在 /session 请求中携带的会话配置，为空的字段使用 SessionPolicy.Defaults
+type SessionConfig struct {
+	// Mode 会话模式，ModeRealtime 或 ModeCascade，为空时使用实时模型
+	Mode         string   `json:"mode,omitempty"`
+	Provider     string   `json:"provider,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	Voice        string   `json:"voice,omitempty"`
+	Language     string   `json:"language,omitempty"`
+	Instructions string   `json:"instructions,omitempty"`
+	Temperature  *float64 `json:"temperature,omitempty"`
+	// Modalities 输出模态，"audio" 和/或 "text"
+	Modalities []string `json:"modalities,omitempty"`
+	// Tools 启用的工具名，必须已在服务端注册；nil 表示全部，空数组表示不使用工具
+	Tools         []string       `json:"tools"`
+	TurnDetection *TurnDetection `json:"turn_detection,omitempty"`
+}
+
+// 会话模式
+const (
+	// ModeRealtime 使用实时模型（Gemini Live、OpenAI Realtime 等）
+	ModeRealtime = "realtime"
+	// ModeCascade 使用服务端配置的 STT → LLM → TTS 级联，provider、model、voice 等不生效
+	ModeCascade = "cascade"
+)
+
+// TurnDetection 服务端 VAD 参数，0 表示使用模型默认值
+type TurnDetection struct {
+	Threshold         float64 `json:"threshold,omitempty"`
+	PrefixPaddingMs   int     `json:"prefix_padding_ms,omitempty"`
+	SilenceDurationMs int     `json:"silence_duration_ms,omitempty"`
+}
+
+// ProviderPolicy 一个 provider 允许客户端选择的模型和语音，默认值总是允许的
+type ProviderPolicy struct {
+	Models []string `json:"models,omitempty"`
+	Voices []string `json:"voices,omitempty"`
+}
+
+// SessionPolicy 客户端可以选择的会话配置范围及默认值，可由 SESSION_POLICY 指定的 JSON 文件加载
+//
+//	{
+//	  "defaults": {"provider": "gemini", "voice": "Puck", "instructions": "You are a helpful assistant."},
+//	  "providers": {"gemini": {"voices": ["Puck", "Kore"]}, "openai": {"voices": ["alloy"]}},
+//	  "languages": ["en-US", "zh-CN"],
+//	  "allow_instructions": true
+//	}
+type SessionPolicy struct {
+	Defaults  SessionConfig             `json:"defaults"`
+	Providers map[string]ProviderPolicy `json:"providers,omitempty"`
+	// Languages 允许的语言，为空时接受任意 BCP-47 代码
+	Languages []string `json:"languages,omitempty"`
+	// AllowInstructions 允许客户端替换系统指令
+	AllowInstructions bool `json:"allow_instructions,omitempty"`
+	// MaxInstructionsLength 客户端系统指令的最大字节数，0 表示 DefaultMaxInstructionsLength
+	MaxInstructionsLength int `json:"max_instructions_length,omitempty"`
+	// MaxTemperature 允许的最大温度，0 表示 DefaultMaxTemperature
+	MaxTemperature float64 `json:"max_temperature,omitempty"`
+}
+
+const (
+	DefaultMaxInstructionsLength = 16 * 1024
+	DefaultMaxTemperature        = 2.0
+
+	maxPrefixPaddingMs   = 2000
+	maxSilenceDurationMs = 10000
+)
+
+var languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// LoadSessionPolicy 读取 JSON 格式的会话策略
+func LoadSessionPolicy(path string) (*SessionPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy := &SessionPolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("parse session policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Resolve 用默认值补全客户端配置并按策略校验，返回实际使用的配置。
+// Provider 在补全默认值后仍可能为空，由服务端按 REALTIME_PROVIDER 等决定
+func (p *SessionPolicy) Resolve(req SessionConfig) (SessionConfig, error) {
+	d := p.Defaults
+	cfg := req
+	if cfg.Mode == "" {
+		cfg.Mode = d.Mode
+	}
+	if cfg.Mode != "" && cfg.Mode != ModeRealtime && cfg.Mode != ModeCascade {
+		return cfg, fmt.Errorf("unknown mode %q", cfg.Mode)
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = d.Provider
+	}
+	if cfg.Language == "" {
+		cfg.Language = d.Language
+	}
+	if cfg.Temperature == nil {
+		cfg.Temperature = d.Temperature
+	}
+	if cfg.Modalities == nil {
+		cfg.Modalities = d.Modalities
+	}
+	if cfg.Tools == nil {
+		cfg.Tools = d.Tools
+	}
+	if cfg.TurnDetection == nil {
+		cfg.TurnDetection = d.TurnDetection
+	}
+
+	// 模型和语音的默认值属于 Defaults.Provider（为空时适用于所有 provider），
+	// 换了 provider 时使用该模型自己的默认值
+	allowed := p.Providers[cfg.Provider]
+	sameProvider := d.Provider == "" || cfg.Provider == d.Provider
+	if cfg.Model == "" && sameProvider {
+		cfg.Model = d.Model
+	}
+	if cfg.Model != "" && !(sameProvider && cfg.Model == d.Model) && !contains(allowed.Models, cfg.Model) {
+		return cfg, fmt.Errorf("model %q is not allowed", cfg.Model)
+	}
+	if cfg.Voice == "" && sameProvider {
+		cfg.Voice = d.Voice
+	}
+	if cfg.Voice != "" && !(sameProvider && cfg.Voice == d.Voice) && !contains(allowed.Voices, cfg.Voice) {
+		return cfg, fmt.Errorf("voice %q is not allowed", cfg.Voice)
+	}
+
+	if req.Instructions != "" {
+		if !p.AllowInstructions {
+			return cfg, fmt.Errorf("instructions are not allowed")
+		}
+		maxLen := p.MaxInstructionsLength
+		if maxLen <= 0 {
+			maxLen = DefaultMaxInstructionsLength
+		}
+		if len(req.Instructions) > maxLen {
+			return cfg, fmt.Errorf("instructions longer than %d bytes", maxLen)
+		}
+	} else {
+		cfg.Instructions = d.Instructions
+	}
+
+	if cfg.Language != "" {
+		if len(p.Languages) > 0 && !contains(p.Languages, cfg.Language) {
+			return cfg, fmt.Errorf("language %q is not allowed", cfg.Language)
+		}
+		if !languagePattern.MatchString(cfg.Language) {
+			return cfg, fmt.Errorf("invalid language %q", cfg.Language)
+		}
+	}
+
+	if t := cfg.Temperature; t != nil {
+		maxTemperature := p.MaxTemperature
+		if maxTemperature <= 0 {
+			maxTemperature = DefaultMaxTemperature
+		}
+		if *t < 0 || *t > maxTemperature {
+			return cfg, fmt.Errorf("temperature must be between 0 and %g", maxTemperature)
+		}
+	}
+
+	for _, m := range cfg.Modalities {
+		if m != realtime.ModalityAudio && m != realtime.ModalityText {
+			return cfg, fmt.Errorf("unknown modality %q", m)
+		}
+	}
+	if cfg.Modalities != nil && len(cfg.Modalities) == 0 {
+		return cfg, fmt.Errorf("at least one modality is required")
+	}
+
+	if td := cfg.TurnDetection; td != nil {
+		if td.Threshold < 0 || td.Threshold > 1 {
+			return cfg, fmt.Errorf("turn_detection.threshold must be between 0 and 1")
+		}
+		if td.PrefixPaddingMs < 0 || td.PrefixPaddingMs > maxPrefixPaddingMs {
+			return cfg, fmt.Errorf("turn_detection.prefix_padding_ms must be between 0 and %d", maxPrefixPaddingMs)
+		}
+		if td.SilenceDurationMs < 0 || td.SilenceDurationMs > maxSilenceDurationMs {
+			return cfg, fmt.Errorf("turn_detection.silence_duration_ms must be between 0 and %d", maxSilenceDurationMs)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ConnectOptions 转换为模型的会话参数，工具由注册表单独提供
+func (c SessionConfig) ConnectOptions() realtime.ConnectOptions {
+	opts := realtime.ConnectOptions{
+		Model:        c.Model,
+		Instructions: c.Instructions,
+		Voice:        c.Voice,
+		Language:     c.Language,
+		Temperature:  c.Temperature,
+		Modalities:   c.Modalities,
+	}
+	if td := c.TurnDetection; td != nil {
+		opts.TurnDetection = &realtime.TurnDetection{
+			Threshold:       td.Threshold,
+			PrefixPadding:   time.Duration(td.PrefixPaddingMs) * time.Millisecond,
+			SilenceDuration: time.Duration(td.SilenceDurationMs) * time.Millisecond,
+		}
+	}
+	return opts
+}
+
+// sessionRequest /session 的请求体：SDP offer，以及可选的会话配置
+//
+//	{"type": "offer", "sdp": "...", "session": {"voice": "Kore", "language": "zh-CN"}}
+type sessionRequest struct {
+	webrtc.SessionDescription
+	Session *SessionConfig `json:"session,omitempty"`
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
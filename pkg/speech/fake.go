@@ -0,0 +1,110 @@
+package speech
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeSTT 测试用的识别引擎，按顺序把 Transcripts 作为每段语音的识别结果：
+// 每收到 PartialEvery 时长的音频给出一次逐词增长的部分结果，音频结束时给出完整文本。
+// Transcripts 用完后识别结果为空
+type FakeSTT struct {
+	Rate         int
+	Transcripts  []string
+	PartialEvery time.Duration
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewFakeSTT 创建每 200ms 给出一次部分结果的测试引擎
+func NewFakeSTT(sampleRate int, transcripts ...string) *FakeSTT {
+	return &FakeSTT{Rate: sampleRate, Transcripts: transcripts, PartialEvery: 200 * time.Millisecond}
+}
+
+func (s *FakeSTT) SampleRate() int {
+	return s.Rate
+}
+
+func (s *FakeSTT) Transcribe(ctx context.Context, audio <-chan []byte, emit func(Transcript) error) error {
+	s.mu.Lock()
+	var text string
+	if s.next < len(s.Transcripts) {
+		text = s.Transcripts[s.next]
+		s.next++
+	}
+	s.mu.Unlock()
+
+	words := strings.Fields(text)
+	partialBytes := int(s.PartialEvery.Seconds()*float64(s.Rate)) * 2
+	var received, partials int
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case pcm, ok := <-audio:
+			if !ok {
+				return emit(Transcript{Text: text, Final: true})
+			}
+			received += len(pcm)
+			if partialBytes <= 0 || received < (partials+1)*partialBytes || partials+1 >= len(words) {
+				continue
+			}
+			partials++
+			if err := emit(Transcript{Text: strings.Join(words[:partials], " ")}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// FakeChat 测试用的对话模型，按顺序返回 Replies，用完后复述用户的最后一句。
+// 回复逐词输出，每个词之前等待 WordDelay
+type FakeChat struct {
+	Replies   []string
+	WordDelay time.Duration
+
+	mu   sync.Mutex
+	next int
+}
+
+func (c *FakeChat) Chat(ctx context.Context, messages []ChatMessage, emit func(delta string) error) error {
+	c.mu.Lock()
+	var reply string
+	if c.next < len(c.Replies) {
+		reply = c.Replies[c.next]
+		c.next++
+	}
+	c.mu.Unlock()
+
+	if reply == "" {
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == ChatRoleUser {
+				reply = "You said: " + messages[i].Content
+				break
+			}
+		}
+	}
+
+	for i, word := range strings.Fields(reply) {
+		if c.WordDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.WordDelay):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			word = " " + word
+		}
+		if err := emit(word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
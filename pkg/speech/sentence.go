@@ -0,0 +1,92 @@
+package speech
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultMaxSentenceLength 没有句末标点时强制切分的长度（字符数）
+const DefaultMaxSentenceLength = 200
+
+// SentenceSplitter 将流式文本按句子切分，使合成可以在整段回复结束前开始。
+// 中文句末标点直接切分；英文的 . ! ? ; 需要后面跟空白，避免切开 3.14 这样的数字
+type SentenceSplitter struct {
+	// MaxLength 超过该长度仍没有句末标点时，在最后一个逗号或空白处切分，0 表示 DefaultMaxSentenceLength
+	MaxLength int
+
+	buf []rune
+}
+
+// Write 追加一段文本，返回已完整的句子
+func (s *SentenceSplitter) Write(text string) []string {
+	s.buf = append(s.buf, []rune(text)...)
+
+	var sentences []string
+	for {
+		end := s.boundary()
+		if end < 0 {
+			end = s.forcedBoundary()
+		}
+		if end < 0 {
+			return sentences
+		}
+		if sentence := strings.TrimSpace(string(s.buf[:end])); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		s.buf = s.buf[end:]
+	}
+}
+
+// Flush 返回剩余的文本（轮次结束时调用），并清空缓冲
+func (s *SentenceSplitter) Flush() string {
+	text := strings.TrimSpace(string(s.buf))
+	s.buf = s.buf[:0]
+	return text
+}
+
+// Reset 丢弃缓冲的文本
+func (s *SentenceSplitter) Reset() {
+	s.buf = s.buf[:0]
+}
+
+// boundary 返回第一个句子结束的位置（不含），没有时返回 -1
+func (s *SentenceSplitter) boundary() int {
+	for i, r := range s.buf {
+		switch {
+		case strings.ContainsRune("。！？；…\n", r):
+			return closingEnd(s.buf, i+1)
+		case strings.ContainsRune(".!?;", r):
+			// 末尾的英文标点要等下一个字符才能确定是否为句末
+			end := closingEnd(s.buf, i+1)
+			if end < len(s.buf) && unicode.IsSpace(s.buf[end]) {
+				return end
+			}
+		}
+	}
+	return -1
+}
+
+// forcedBoundary 缓冲超过 MaxLength 时返回切分位置，否则返回 -1
+func (s *SentenceSplitter) forcedBoundary() int {
+	maxLength := s.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxSentenceLength
+	}
+	if len(s.buf) <= maxLength {
+		return -1
+	}
+	for i := maxLength; i > 0; i-- {
+		if strings.ContainsRune(",，、:：", s.buf[i]) || unicode.IsSpace(s.buf[i]) {
+			return i + 1
+		}
+	}
+	return maxLength
+}
+
+// closingEnd 跳过句末标点后的右引号和右括号
+func closingEnd(buf []rune, i int) int {
+	for i < len(buf) && strings.ContainsRune(`"')]}”’」』）】`, buf[i]) {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,59 @@
+// Package speech 定义与厂商无关的语音识别、对话模型和语音合成接口，用于只输出文本的模型会话
+// 以及级联（STT → LLM → TTS）模式，并提供按句切分流式文本、离线测试用的引擎等辅助实现
+package speech
+
+import "context"
+
+// TTSEngine 文本转语音引擎，输出单声道 S16LE PCM
+type TTSEngine interface {
+	// SampleRate 输出音频的采样率
+	SampleRate() int
+	// Synthesize 合成 text，合成过程中按块调用 emit。
+	// ctx 取消或 emit 返回错误时应尽快返回
+	Synthesize(ctx context.Context, text string, emit func(pcm []byte) error) error
+}
+
+// TextToSpeech 级联模式中的语音合成，与只输出文本的会话共用 TTSEngine
+type TextToSpeech = TTSEngine
+
+// Transcript 一段语音的识别结果，Text 为截至目前的完整文本而非增量
+type Transcript struct {
+	Text  string
+	Final bool
+}
+
+// SpeechToText 流式语音识别引擎，输入单声道 S16LE PCM
+type SpeechToText interface {
+	// SampleRate 输入音频的采样率
+	SampleRate() int
+	// Transcribe 识别一段语音：audio 关闭表示用户说完（端点），识别过程中可多次 emit
+	// 非最终结果，最后 emit 一次 Final 结果后返回。ctx 取消或 emit 返回错误时应尽快返回
+	Transcribe(ctx context.Context, audio <-chan []byte, emit func(Transcript) error) error
+}
+
+// 对话消息的角色
+const (
+	ChatRoleSystem    = "system"
+	ChatRoleUser      = "user"
+	ChatRoleAssistant = "assistant"
+)
+
+// ChatMessage 一条对话消息
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// ChatModel 文本对话模型
+type ChatModel interface {
+	// Chat 根据对话历史生成回复，按增量调用 emit。
+	// 用户打断时 ctx 被取消，应尽快返回
+	Chat(ctx context.Context, messages []ChatMessage, emit func(delta string) error) error
+}
+
+// Cascade 级联模式使用的三个引擎
+type Cascade struct {
+	STT  SpeechToText
+	Chat ChatModel
+	TTS  TextToSpeech
+}
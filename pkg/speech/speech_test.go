@@ -0,0 +1,112 @@
+package speech
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSentenceSplitter(t *testing.T) {
+	var s SentenceSplitter
+	var got []string
+	for _, delta := range []string{"Hello", " there. It costs 3", ".14 dollars", "! Really", "?", " 你好", "。今天", "天气不错！", "“好的。”", "Bye"} {
+		got = append(got, s.Write(delta)...)
+	}
+	assert.Equal(t, []string{"Hello there.", "It costs 3.14 dollars!", "Really?", "你好。", "今天天气不错！", "“好的。”"}, got)
+	assert.Equal(t, "Bye", s.Flush())
+	assert.Empty(t, s.Flush())
+
+	s.Write("dropped")
+	s.Reset()
+	assert.Empty(t, s.Flush())
+}
+
+func TestSentenceSplitterMaxLength(t *testing.T) {
+	s := SentenceSplitter{MaxLength: 10}
+	assert.Equal(t, []string{"one two", "three four"}, s.Write("one two three four five"))
+	assert.Equal(t, "five", s.Flush())
+
+	assert.Equal(t, []string{"abcdefghij"}, s.Write("abcdefghijkl"))
+	assert.Equal(t, "kl", s.Flush())
+}
+
+func TestToneEngine(t *testing.T) {
+	e := NewToneEngine(16000)
+	var pcm []byte
+	var chunks int
+	require.NoError(t, e.Synthesize(context.Background(), "hi, yo", func(b []byte) error {
+		pcm = append(pcm, b...)
+		chunks++
+		return nil
+	}))
+
+	// 6 个字符，每个 60ms
+	assert.Equal(t, 6*960*2, len(pcm))
+	assert.Equal(t, 4, chunks)
+	assert.NotEqual(t, make([]byte, 1920), pcm[:1920], "letters are voiced")
+	assert.Equal(t, make([]byte, 2*1920), pcm[2*1920:4*1920], "comma and space are silent")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := e.Synthesize(ctx, strings.Repeat("a", 10), func([]byte) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+
+	stop := errors.New("stop")
+	err = e.Synthesize(context.Background(), "abc", func([]byte) error { return stop })
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 60*time.Millisecond, e.PerChar)
+}
+
+func TestFakeSTT(t *testing.T) {
+	s := NewFakeSTT(16000, "one two three")
+	audio := make(chan []byte, 10)
+	for i := 0; i < 5; i++ {
+		audio <- make([]byte, 6400) // 200ms
+	}
+	close(audio)
+
+	var got []Transcript
+	require.NoError(t, s.Transcribe(context.Background(), audio, func(tr Transcript) error {
+		got = append(got, tr)
+		return nil
+	}))
+	assert.Equal(t, []Transcript{{Text: "one"}, {Text: "one two"}, {Text: "one two three", Final: true}}, got)
+
+	// Transcripts 用完后结果为空
+	empty := make(chan []byte)
+	close(empty)
+	got = nil
+	require.NoError(t, s.Transcribe(context.Background(), empty, func(tr Transcript) error {
+		got = append(got, tr)
+		return nil
+	}))
+	assert.Equal(t, []Transcript{{Final: true}}, got)
+}
+
+func TestFakeChat(t *testing.T) {
+	c := &FakeChat{Replies: []string{"Hi there, friend."}}
+	history := []ChatMessage{{Role: ChatRoleSystem, Content: "be brief"}, {Role: ChatRoleUser, Content: "hello"}}
+
+	var deltas []string
+	collect := func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	}
+	require.NoError(t, c.Chat(context.Background(), history, collect))
+	assert.Equal(t, []string{"Hi", " there,", " friend."}, deltas)
+
+	deltas = nil
+	require.NoError(t, c.Chat(context.Background(), history, collect))
+	assert.Equal(t, "You said: hello", strings.Join(deltas, ""))
+
+	// 打断时尽快返回
+	slow := &FakeChat{Replies: []string{"a b c"}, WordDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, slow.Chat(ctx, history, collect), context.DeadlineExceeded)
+}
@@ -0,0 +1,70 @@
+package speech
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"time"
+	"unicode"
+)
+
+// ToneEngine 离线测试用的合成引擎：每个字符合成一段正弦波，空白和标点合成静音，
+// 输出时长与文本长度成正比，便于在没有真实 TTS 服务时验证链路
+type ToneEngine struct {
+	Rate      int
+	Frequency float64
+	// PerChar 每个字符的时长
+	PerChar time.Duration
+	// ChunkDuration 每次 emit 的音频时长
+	ChunkDuration time.Duration
+}
+
+// NewToneEngine 创建 440Hz、每字符 60ms 的测试引擎
+func NewToneEngine(sampleRate int) *ToneEngine {
+	return &ToneEngine{
+		Rate:          sampleRate,
+		Frequency:     440,
+		PerChar:       60 * time.Millisecond,
+		ChunkDuration: 100 * time.Millisecond,
+	}
+}
+
+func (e *ToneEngine) SampleRate() int {
+	return e.Rate
+}
+
+func (e *ToneEngine) Synthesize(ctx context.Context, text string, emit func(pcm []byte) error) error {
+	perChar := int(e.PerChar.Seconds() * float64(e.Rate))
+	chunkBytes := int(e.ChunkDuration.Seconds()*float64(e.Rate)) * 2
+
+	var chunk []byte
+	var n int // 已生成的采样数，保证正弦波相位连续
+	for _, r := range text {
+		voiced := !unicode.IsSpace(r) && !unicode.IsPunct(r)
+		for i := 0; i < perChar; i++ {
+			var v int16
+			if voiced {
+				v = int16(math.Sin(2*math.Pi*e.Frequency*float64(n)/float64(e.Rate)) * math.MaxInt16 / 4)
+			}
+			chunk = binary.LittleEndian.AppendUint16(chunk, uint16(v))
+			n++
+
+			if len(chunk) >= chunkBytes {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := emit(chunk); err != nil {
+					return err
+				}
+				chunk = nil
+			}
+		}
+	}
+	if len(chunk) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return emit(chunk)
+	}
+	return nil
+}
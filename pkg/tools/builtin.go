@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CurrentTime 返回指定时区当前时间的示例工具
+var CurrentTime = Tool{
+	Name:        "get_current_time",
+	Description: "Returns the current date and time, optionally in the given IANA time zone.",
+	Parameters: MustParseSchema(`{
+		"type": "object",
+		"properties": {
+			"timezone": {"type": "string", "description": "IANA time zone such as Asia/Shanghai, defaults to the server time zone"}
+		}
+	}`),
+	Handler: currentTime,
+}
+
+func currentTime(ctx context.Context, args map[string]any) (map[string]any, error) {
+	loc := time.Local
+	if tz, _ := args["timezone"].(string); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", tz)
+		}
+		loc = l
+	}
+	now := time.Now().In(loc)
+	return map[string]any{
+		"time":     now.Format(time.RFC3339),
+		"timezone": loc.String(),
+		"weekday":  now.Weekday().String(),
+	}, nil
+}
+
+// RegisterBuiltins 注册内置工具
+func RegisterBuiltins(r *Registry) error {
+	return r.Register(CurrentTime)
+}
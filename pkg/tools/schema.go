@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// jsonSchema 支持的 JSON Schema 子集
+type jsonSchema struct {
+	Type        any                    `json:"type"` // 字符串，或形如 ["string","null"] 的数组
+	Description string                 `json:"description"`
+	Title       string                 `json:"title"`
+	Format      string                 `json:"format"`
+	Enum        []any                  `json:"enum"`
+	Properties  map[string]*jsonSchema `json:"properties"`
+	Required    []string               `json:"required"`
+	Items       *jsonSchema            `json:"items"`
+	AnyOf       []*jsonSchema          `json:"anyOf"`
+	Minimum     *float64               `json:"minimum"`
+	Maximum     *float64               `json:"maximum"`
+	MinItems    *int64                 `json:"minItems"`
+	MaxItems    *int64                 `json:"maxItems"`
+	MinLength   *int64                 `json:"minLength"`
+	MaxLength   *int64                 `json:"maxLength"`
+	Pattern     string                 `json:"pattern"`
+	Default     any                    `json:"default"`
+	Nullable    bool                   `json:"nullable"`
+}
+
+// ParseSchema 将 JSON Schema 转换为 Gemini 函数声明使用的 OpenAPI 子集
+// 不支持的关键字（$ref、oneOf、additionalProperties 等）会被忽略
+func ParseSchema(data []byte) (*genai.Schema, error) {
+	var s jsonSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse json schema: %w", err)
+	}
+	return convertSchema(&s)
+}
+
+// MustParseSchema 同 ParseSchema，出错时 panic，用于注册内置工具
+func MustParseSchema(schema string) *genai.Schema {
+	s, err := ParseSchema([]byte(schema))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func convertSchema(s *jsonSchema) (*genai.Schema, error) {
+	out := &genai.Schema{
+		Description: s.Description,
+		Title:       s.Title,
+		Format:      s.Format,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		Pattern:     s.Pattern,
+		Default:     s.Default,
+	}
+	if s.MinItems != nil {
+		out.MinItems = *s.MinItems
+	}
+	if s.MaxItems != nil {
+		out.MaxItems = *s.MaxItems
+	}
+	if s.MinLength != nil {
+		out.MinLength = *s.MinLength
+	}
+	if s.MaxLength != nil {
+		out.MaxLength = *s.MaxLength
+	}
+
+	nullable := s.Nullable
+	switch t := s.Type.(type) {
+	case nil:
+	case string:
+		typ, err := convertType(t)
+		if err != nil {
+			return nil, err
+		}
+		out.Type = typ
+	case []any:
+		// ["string", "null"] 转换为可空类型
+		for _, v := range t {
+			name, _ := v.(string)
+			if name == "null" {
+				nullable = true
+				continue
+			}
+			if out.Type != "" {
+				return nil, fmt.Errorf("union type %v is not supported", t)
+			}
+			typ, err := convertType(name)
+			if err != nil {
+				return nil, err
+			}
+			out.Type = typ
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %v", t)
+	}
+	if nullable {
+		out.Nullable = &nullable
+	}
+
+	for _, v := range s.Enum {
+		out.Enum = append(out.Enum, fmt.Sprint(v))
+	}
+
+	if len(s.Properties) > 0 {
+		if out.Type == "" {
+			out.Type = genai.TypeObject
+		}
+		out.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, p := range s.Properties {
+			ps, err := convertSchema(p)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", name, err)
+			}
+			out.Properties[name] = ps
+		}
+		out.Required = s.Required
+	}
+
+	if s.Items != nil {
+		items, err := convertSchema(s.Items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		out.Items = items
+	}
+
+	for _, a := range s.AnyOf {
+		as, err := convertSchema(a)
+		if err != nil {
+			return nil, fmt.Errorf("anyOf: %w", err)
+		}
+		out.AnyOf = append(out.AnyOf, as)
+	}
+	return out, nil
+}
+
+func convertType(t string) (genai.Type, error) {
+	switch strings.ToLower(t) {
+	case "string":
+		return genai.TypeString, nil
+	case "number":
+		return genai.TypeNumber, nil
+	case "integer":
+		return genai.TypeInteger, nil
+	case "boolean":
+		return genai.TypeBoolean, nil
+	case "array":
+		return genai.TypeArray, nil
+	case "object":
+		return genai.TypeObject, nil
+	default:
+		return "", fmt.Errorf("unsupported type %q", t)
+	}
+}
+
+// JSONSchema 将函数声明的参数转换回 JSON Schema，供 OpenAI 等使用 JSON Schema 的模型使用。
+// nil 转换为不带属性的 object
+func JSONSchema(s *genai.Schema) map[string]any {
+	if s == nil {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	out := map[string]any{}
+	if s.Type != "" {
+		typ := strings.ToLower(string(s.Type))
+		if s.Nullable != nil && *s.Nullable {
+			out["type"] = []string{typ, "null"}
+		} else {
+			out["type"] = typ
+		}
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if s.Title != "" {
+		out["title"] = s.Title
+	}
+	if s.Format != "" && s.Format != "enum" {
+		out["format"] = s.Format
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if s.Type == genai.TypeObject {
+		props := make(map[string]any, len(s.Properties))
+		for name, p := range s.Properties {
+			props[name] = JSONSchema(p)
+		}
+		out["properties"] = props
+		if len(s.Required) > 0 {
+			out["required"] = s.Required
+		}
+	}
+	if s.Items != nil {
+		out["items"] = JSONSchema(s.Items)
+	}
+	if len(s.AnyOf) > 0 {
+		anyOf := make([]any, 0, len(s.AnyOf))
+		for _, a := range s.AnyOf {
+			anyOf = append(anyOf, JSONSchema(a))
+		}
+		out["anyOf"] = anyOf
+	}
+	if s.Minimum != nil {
+		out["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		out["maximum"] = *s.Maximum
+	}
+	if s.MinItems > 0 {
+		out["minItems"] = s.MinItems
+	}
+	if s.MaxItems > 0 {
+		out["maxItems"] = s.MaxItems
+	}
+	if s.MinLength > 0 {
+		out["minLength"] = s.MinLength
+	}
+	if s.MaxLength > 0 {
+		out["maxLength"] = s.MaxLength
+	}
+	if s.Pattern != "" {
+		out["pattern"] = s.Pattern
+	}
+	if s.Default != nil {
+		out["default"] = s.Default
+	}
+	return out
+}
@@ -0,0 +1,189 @@
+// Package tools 管理可供模型调用的 Go 函数
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// DefaultTimeout 单次调用的默认超时
+const DefaultTimeout = 10 * time.Second
+
+// Handler 工具实现，args 为模型给出的参数，返回值作为 response 发回模型
+type Handler func(ctx context.Context, args map[string]any) (map[string]any, error)
+
+// Tool 一个可调用的工具
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  *genai.Schema // 参数声明，可由 ParseSchema 从 JSON Schema 转换
+	Timeout     time.Duration // 0 表示使用 Registry 的默认超时
+	Handler     Handler
+}
+
+// 函数名需符合 Gemini 的要求
+var namePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]{0,63}$`)
+
+// Registry 工具注册表，可并发使用
+type Registry struct {
+	mu      sync.RWMutex
+	tools   map[string]*Tool
+	order   []string
+	timeout time.Duration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		tools:   make(map[string]*Tool),
+		timeout: DefaultTimeout,
+	}
+}
+
+// SetDefaultTimeout 设置未指定 Timeout 的工具的超时
+func (r *Registry) SetDefaultTimeout(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeout = d
+}
+
+// Register 注册工具，名称不合法或重复时返回错误
+func (r *Registry) Register(t Tool) error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("invalid tool name %q", t.Name)
+	}
+	if t.Handler == nil {
+		return fmt.Errorf("tool %s has no handler", t.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[t.Name]; ok {
+		return fmt.Errorf("tool %s already registered", t.Name)
+	}
+	r.tools[t.Name] = &t
+	r.order = append(r.order, t.Name)
+	return nil
+}
+
+// Len 返回已注册的工具数
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.order)
+}
+
+// Subset 返回只包含 names 中工具的注册表，顺序与 r 一致，工具名不存在时返回错误
+func (r *Registry) Subset(names []string) (*Registry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	want := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := r.tools[name]; !ok {
+			return nil, fmt.Errorf("unknown tool %q", name)
+		}
+		want[name] = true
+	}
+
+	sub := NewRegistry()
+	sub.timeout = r.timeout
+	for _, name := range r.order {
+		if want[name] {
+			sub.tools[name] = r.tools[name]
+			sub.order = append(sub.order, name)
+		}
+	}
+	return sub, nil
+}
+
+// Declarations 按注册顺序返回函数声明
+func (r *Registry) Declarations() []*genai.FunctionDeclaration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	decls := make([]*genai.FunctionDeclaration, 0, len(r.order))
+	for _, name := range r.order {
+		t := r.tools[name]
+		decls = append(decls, &genai.FunctionDeclaration{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  t.Parameters,
+		})
+	}
+	return decls
+}
+
+// LiveTools 返回用于 Live 会话 setup 的工具列表，没有工具时返回 nil
+func (r *Registry) LiveTools() []*genai.Tool {
+	decls := r.Declarations()
+	if len(decls) == 0 {
+		return nil
+	}
+	return []*genai.Tool{{FunctionDeclarations: decls}}
+}
+
+// Call 执行一次函数调用，错误和超时也以 response 的形式返回给模型
+func (r *Registry) Call(ctx context.Context, call *genai.FunctionCall) (*genai.FunctionResponse, error) {
+	r.mu.RLock()
+	t, ok := r.tools[call.Name]
+	timeout := r.timeout
+	r.mu.RUnlock()
+
+	resp := &genai.FunctionResponse{ID: call.ID, Name: call.Name}
+	if !ok {
+		err := fmt.Errorf("unknown tool %s", call.Name)
+		resp.Response = errorResponse(err)
+		return resp, err
+	}
+	if t.Timeout > 0 {
+		timeout = t.Timeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		out map[string]any
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{err: fmt.Errorf("tool %s panicked: %v", call.Name, p)}
+			}
+		}()
+		out, err := t.Handler(ctx, call.Args)
+		done <- result{out, err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		res.err = ctx.Err()
+	}
+
+	if res.err != nil {
+		if errors.Is(res.err, context.DeadlineExceeded) {
+			res.err = fmt.Errorf("tool %s timed out after %v", call.Name, timeout)
+		}
+		resp.Response = errorResponse(res.err)
+		return resp, res.err
+	}
+	if res.out == nil {
+		res.out = map[string]any{}
+	}
+	resp.Response = res.out
+	return resp, nil
+}
+
+func errorResponse(err error) map[string]any {
+	return map[string]any{"error": err.Error()}
+}
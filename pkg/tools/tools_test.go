@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func echo(ctx context.Context, args map[string]any) (map[string]any, error) {
+	return args, nil
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register(Tool{Name: "echo", Handler: echo}))
+	require.NoError(t, r.Register(Tool{Name: "lookup_order", Description: "d", Handler: echo}))
+
+	assert.Error(t, r.Register(Tool{Name: "echo", Handler: echo}), "duplicate")
+	assert.Error(t, r.Register(Tool{Name: "1bad", Handler: echo}), "invalid name")
+	assert.Error(t, r.Register(Tool{Name: "has space", Handler: echo}), "invalid name")
+	assert.Error(t, r.Register(Tool{Name: "nohandler"}), "nil handler")
+
+	assert.Equal(t, 2, r.Len())
+	decls := r.Declarations()
+	require.Len(t, decls, 2)
+	assert.Equal(t, "echo", decls[0].Name)
+	assert.Equal(t, "lookup_order", decls[1].Name)
+
+	lt := r.LiveTools()
+	require.Len(t, lt, 1)
+	assert.Len(t, lt[0].FunctionDeclarations, 2)
+
+	assert.Nil(t, NewRegistry().LiveTools())
+}
+
+func TestSubset(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, r.Register(Tool{Name: name, Handler: echo}))
+	}
+
+	sub, err := r.Subset([]string{"c", "a"})
+	require.NoError(t, err)
+	decls := sub.Declarations()
+	require.Len(t, decls, 2)
+	assert.Equal(t, "a", decls[0].Name)
+	assert.Equal(t, "c", decls[1].Name)
+
+	resp, err := sub.Call(context.Background(), &genai.FunctionCall{Name: "b"})
+	assert.Error(t, err, "not in the subset")
+	assert.Contains(t, resp.Response, "error")
+
+	empty, err := r.Subset(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, empty.Len())
+
+	_, err = r.Subset([]string{"missing"})
+	assert.Error(t, err)
+}
+
+func TestCall(t *testing.T) {
+	r := NewRegistry()
+	r.SetDefaultTimeout(50 * time.Millisecond)
+	require.NoError(t, r.Register(Tool{Name: "echo", Handler: echo}))
+	require.NoError(t, r.Register(Tool{Name: "fail", Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		return nil, errors.New("boom")
+	}}))
+	require.NoError(t, r.Register(Tool{Name: "slow", Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}))
+	require.NoError(t, r.Register(Tool{Name: "stuck", Timeout: 20 * time.Millisecond, Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		time.Sleep(200 * time.Millisecond) // 不响应 ctx 也不能阻塞调用方
+		return nil, nil
+	}}))
+	require.NoError(t, r.Register(Tool{Name: "panic", Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		panic("oops")
+	}}))
+	require.NoError(t, r.Register(Tool{Name: "empty", Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		return nil, nil
+	}}))
+
+	ctx := context.Background()
+
+	resp, err := r.Call(ctx, &genai.FunctionCall{ID: "1", Name: "echo", Args: map[string]any{"a": 1.0}})
+	require.NoError(t, err)
+	assert.Equal(t, &genai.FunctionResponse{ID: "1", Name: "echo", Response: map[string]any{"a": 1.0}}, resp)
+
+	resp, err = r.Call(ctx, &genai.FunctionCall{ID: "2", Name: "empty"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{}, resp.Response)
+
+	for name, want := range map[string]string{
+		"fail":    "boom",
+		"slow":    "timed out",
+		"stuck":   "timed out after 20ms",
+		"panic":   "panicked: oops",
+		"missing": "unknown tool",
+	} {
+		start := time.Now()
+		resp, err := r.Call(ctx, &genai.FunctionCall{ID: name, Name: name})
+		require.Error(t, err, name)
+		assert.Less(t, time.Since(start), 150*time.Millisecond, name)
+		assert.Equal(t, name, resp.ID)
+		assert.Contains(t, resp.Response["error"], want, name)
+	}
+
+	// 调用方取消
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = r.Call(cctx, &genai.FunctionCall{ID: "3", Name: "slow"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCallConcurrent(t *testing.T) {
+	r := NewRegistry()
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(3)
+	require.NoError(t, r.Register(Tool{Name: "wait", Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		started.Done()
+		<-release
+		return map[string]any{"ok": true}, nil
+	}}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.Call(context.Background(), &genai.FunctionCall{Name: "wait"})
+			assert.NoError(t, err)
+		}()
+	}
+	// 三个调用同时进入 handler
+	started.Wait()
+	close(release)
+	wg.Wait()
+}
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema([]byte(`{
+		"type": "object",
+		"description": "order query",
+		"properties": {
+			"order_id": {"type": "string", "pattern": "^[0-9]+$"},
+			"limit": {"type": "integer", "minimum": 1, "maximum": 10},
+			"status": {"type": "string", "enum": ["open", "closed"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 5},
+			"note": {"type": ["string", "null"]}
+		},
+		"required": ["order_id"],
+		"additionalProperties": false
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, genai.TypeObject, s.Type)
+	assert.Equal(t, "order query", s.Description)
+	assert.Equal(t, []string{"order_id"}, s.Required)
+	assert.Equal(t, genai.TypeString, s.Properties["order_id"].Type)
+	assert.Equal(t, "^[0-9]+$", s.Properties["order_id"].Pattern)
+	assert.Equal(t, genai.TypeInteger, s.Properties["limit"].Type)
+	assert.Equal(t, 10.0, *s.Properties["limit"].Maximum)
+	assert.Equal(t, []string{"open", "closed"}, s.Properties["status"].Enum)
+	assert.Equal(t, genai.TypeArray, s.Properties["tags"].Type)
+	assert.Equal(t, genai.TypeString, s.Properties["tags"].Items.Type)
+	assert.Equal(t, int64(5), s.Properties["tags"].MaxItems)
+	assert.Equal(t, genai.TypeString, s.Properties["note"].Type)
+	assert.True(t, *s.Properties["note"].Nullable)
+
+	_, err = ParseSchema([]byte(`{"type": "date"}`))
+	assert.Error(t, err)
+	_, err = ParseSchema([]byte(`{"type": ["string", "integer"]}`))
+	assert.Error(t, err)
+	_, err = ParseSchema([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestJSONSchema(t *testing.T) {
+	s := MustParseSchema(`{
+		"type": "object",
+		"properties": {
+			"order_id": {"type": "string", "description": "id"},
+			"limit": {"type": "integer", "minimum": 1},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"note": {"type": ["string", "null"]}
+		},
+		"required": ["order_id"]
+	}`)
+
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"order_id": map[string]any{"type": "string", "description": "id"},
+			"limit":    map[string]any{"type": "integer", "minimum": 1.0},
+			"tags":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"note":     map[string]any{"type": []string{"string", "null"}},
+		},
+		"required": []string{"order_id"},
+	}, JSONSchema(s))
+
+	assert.Equal(t, map[string]any{"type": "object", "properties": map[string]any{}}, JSONSchema(nil))
+}
+
+func TestCurrentTime(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, RegisterBuiltins(r))
+
+	resp, err := r.Call(context.Background(), &genai.FunctionCall{Name: "get_current_time", Args: map[string]any{"timezone": "UTC"}})
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", resp.Response["timezone"])
+	_, err = time.Parse(time.RFC3339, resp.Response["time"].(string))
+	assert.NoError(t, err)
+
+	_, err = r.Call(context.Background(), &genai.FunctionCall{Name: "get_current_time", Args: map[string]any{"timezone": "Nowhere/City"}})
+	assert.Error(t, err)
+}
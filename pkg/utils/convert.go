@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// 以下转换函数对整块切片操作，多字节格式均为小端序；
+// 输入长度不是样本大小整数倍时返回错误
+
+const (
+	int16Scale = 1 << 15
+	int32Scale = 1 << 31
+)
+
+func checkAligned(data []byte, sampleSize int) error {
+	if len(data)%sampleSize != 0 {
+		return fmt.Errorf("audio data length %d is not a multiple of %d", len(data), sampleSize)
+	}
+	return nil
+}
+
+// []int32 -> []byte (小端)
+func Int32SliceToByteSlice(samples []int32) []byte {
+	out := make([]byte, len(samples)*4)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(out[4*i:], uint32(v))
+	}
+	return out
+}
+
+// []byte -> []int32 (小端)
+func ByteSliceToInt32Slice(data []byte) ([]int32, error) {
+	if err := checkAligned(data, 4); err != nil {
+		return nil, err
+	}
+	samples := make([]int32, len(data)/4)
+	for i := range samples {
+		samples[i] = int32(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return samples, nil
+}
+
+// []float32 -> []byte (IEEE 754 小端)
+func Float32SliceToByteSlice(samples []float32) []byte {
+	out := make([]byte, len(samples)*4)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(out[4*i:], math.Float32bits(v))
+	}
+	return out
+}
+
+// []byte -> []float32 (IEEE 754 小端)
+func ByteSliceToFloat32Slice(data []byte) ([]float32, error) {
+	if err := checkAligned(data, 4); err != nil {
+		return nil, err
+	}
+	samples := make([]float32, len(data)/4)
+	for i := range samples {
+		samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return samples, nil
+}
+
+// Int16SliceToFloat32Slice 将 S16 样本归一化到 [-1, 1)
+func Int16SliceToFloat32Slice(samples []int16) []float32 {
+	out := make([]float32, len(samples))
+	for i, v := range samples {
+		out[i] = float32(v) / int16Scale
+	}
+	return out
+}
+
+// Float32SliceToInt16Slice 将 [-1, 1] 的浮点样本量化为 S16，超出范围的值饱和
+func Float32SliceToInt16Slice(samples []float32) []int16 {
+	out := make([]int16, len(samples))
+	for i, v := range samples {
+		out[i] = int16(clampRound(float64(v)*int16Scale, math.MinInt16, math.MaxInt16))
+	}
+	return out
+}
+
+// Int16SliceToInt32Slice 将 S16 样本扩展到 32 位满量程
+func Int16SliceToInt32Slice(samples []int16) []int32 {
+	out := make([]int32, len(samples))
+	for i, v := range samples {
+		out[i] = int32(v) << 16
+	}
+	return out
+}
+
+// Int32SliceToInt16Slice 将 32 位样本截断到 S16（四舍五入并饱和）
+func Int32SliceToInt16Slice(samples []int32) []int16 {
+	out := make([]int16, len(samples))
+	for i, v := range samples {
+		out[i] = int16(min((int64(v)+1<<15)>>16, math.MaxInt16))
+	}
+	return out
+}
+
+// Int32SliceToFloat32Slice 将 32 位样本归一化到 [-1, 1)
+func Int32SliceToFloat32Slice(samples []int32) []float32 {
+	out := make([]float32, len(samples))
+	for i, v := range samples {
+		out[i] = float32(float64(v) / int32Scale)
+	}
+	return out
+}
+
+// Float32SliceToInt32Slice 将 [-1, 1] 的浮点样本量化为 32 位整数，超出范围的值饱和
+func Float32SliceToInt32Slice(samples []float32) []int32 {
+	out := make([]int32, len(samples))
+	for i, v := range samples {
+		out[i] = int32(clampRound(float64(v)*int32Scale, math.MinInt32, math.MaxInt32))
+	}
+	return out
+}
+
+// Uint8SliceToInt16Slice 将无符号 8 位样本（128 为零点）转换为 S16
+func Uint8SliceToInt16Slice(data []byte) []int16 {
+	out := make([]int16, len(data))
+	for i, v := range data {
+		out[i] = int16(int(v)-128) << 8
+	}
+	return out
+}
+
+// Int16SliceToUint8Slice 将 S16 样本转换为无符号 8 位（四舍五入并饱和）
+func Int16SliceToUint8Slice(samples []int16) []byte {
+	out := make([]byte, len(samples))
+	for i, v := range samples {
+		out[i] = byte(min((int(v)+128)>>8, 127) + 128)
+	}
+	return out
+}
+
+// MuLawToInt16Slice 解码 G.711 µ-law
+func MuLawToInt16Slice(data []byte) []int16 {
+	out := make([]int16, len(data))
+	for i, v := range data {
+		out[i] = muLawDecodeTable[v]
+	}
+	return out
+}
+
+// Int16SliceToMuLaw 编码为 G.711 µ-law
+func Int16SliceToMuLaw(samples []int16) []byte {
+	out := make([]byte, len(samples))
+	for i, v := range samples {
+		out[i] = MuLawEncode(v)
+	}
+	return out
+}
+
+// ALawToInt16Slice 解码 G.711 A-law
+func ALawToInt16Slice(data []byte) []int16 {
+	out := make([]int16, len(data))
+	for i, v := range data {
+		out[i] = aLawDecodeTable[v]
+	}
+	return out
+}
+
+// Int16SliceToALaw 编码为 G.711 A-law
+func Int16SliceToALaw(samples []int16) []byte {
+	out := make([]byte, len(samples))
+	for i, v := range samples {
+		out[i] = ALawEncode(v)
+	}
+	return out
+}
+
+const (
+	muLawBias = 0x84
+	muLawClip = 32635
+)
+
+var (
+	muLawDecodeTable [256]int16
+	aLawDecodeTable  [256]int16
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		muLawDecodeTable[i] = MuLawDecode(byte(i))
+		aLawDecodeTable[i] = ALawDecode(byte(i))
+	}
+}
+
+// MuLawEncode 将一个 S16 样本编码为 µ-law（ITU-T G.711）
+func MuLawEncode(sample int16) byte {
+	v := int(sample)
+	var sign int
+	if v < 0 {
+		v = -v
+		sign = 0x80
+	}
+	if v > muLawClip {
+		v = muLawClip
+	}
+	v += muLawBias
+
+	exponent := 7
+	for mask := 0x4000; v&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (v >> (exponent + 3)) & 0x0F
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// MuLawDecode 将一个 µ-law 字节解码为 S16 样本
+func MuLawDecode(u byte) int16 {
+	u = ^u
+	exponent := int(u>>4) & 0x07
+	mantissa := int(u & 0x0F)
+	v := ((mantissa << 3) + muLawBias) << exponent
+	v -= muLawBias
+	if u&0x80 != 0 {
+		return int16(-v)
+	}
+	return int16(v)
+}
+
+// A-law 各段的上界（13 位幅度）
+var aLawSegmentEnd = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+// ALawEncode 将一个 S16 样本编码为 A-law（ITU-T G.711）
+func ALawEncode(sample int16) byte {
+	pcm := int(sample) >> 3
+	var mask byte
+	if pcm >= 0 {
+		mask = 0xD5
+	} else {
+		mask = 0x55
+		pcm = -pcm - 1
+	}
+
+	seg := 0
+	for seg < len(aLawSegmentEnd) && pcm > aLawSegmentEnd[seg] {
+		seg++
+	}
+	if seg >= len(aLawSegmentEnd) {
+		return 0x7F ^ mask
+	}
+
+	aval := byte(seg << 4)
+	if seg < 2 {
+		aval |= byte(pcm>>1) & 0x0F
+	} else {
+		aval |= byte(pcm>>seg) & 0x0F
+	}
+	return aval ^ mask
+}
+
+// ALawDecode 将一个 A-law 字节解码为 S16 样本
+func ALawDecode(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0F) << 4
+	seg := int(a&0x70) >> 4
+	switch seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+func clampRound(v, lo, hi float64) float64 {
+	v = math.Round(v)
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "encoding/binary"
+
 // []int16 -> []byte (小端)
 func Int16SliceToByteSlice(samples []int16) []byte {
 	out := make([]byte, len(samples)*2)
@@ -12,21 +14,17 @@ func Int16SliceToByteSlice(samples []int16) []byte {
 }
 
 // []byte -> []int16 (小端)
-func ByteSliceToInt16Slice(data []byte) []int16 {
-
-	if len(data)%2 != 0 {
-		panic("audio data length must be multiple of 2")
+func ByteSliceToInt16Slice(data []byte) ([]int16, error) {
+	if err := checkAligned(data, 2); err != nil {
+		return nil, err
 	}
 	sampleCount := len(data) / 2
 	samples := make([]int16, sampleCount)
 
 	for i := 0; i < sampleCount; i++ {
 		// 小端序：低位在前，高位在后
-		low := data[2*i]
-		high := data[2*i+1]
-		// 合并成 int16
-		samples[i] = int16(low) | int16(high)<<8
+		samples[i] = int16(binary.LittleEndian.Uint16(data[2*i:]))
 	}
 
-	return samples
+	return samples, nil
 }